@@ -69,26 +69,36 @@ const (
 	RowFunc    = "row"
 	SetVar     = "setvar"
 	GetVar     = "getvar"
+	GetSysVar  = "getsysvar"
 	Values     = "values"
+	Default    = "default"
+	// SetCollation is expr COLLATE collation_name.
+	SetCollation = "setcollation"
 
 	// common functions
 	Coalesce = "coalesce"
 	Greatest = "greatest"
+	Least    = "least"
+	Interval = "interval"
 
 	// math functions
-	Abs     = "abs"
-	Ceil    = "ceil"
-	Ceiling = "ceiling"
-	Ln      = "ln"
-	Log     = "log"
-	Log2    = "log2"
-	Log10   = "log10"
-	Pow     = "pow"
-	Power   = "power"
-	Rand    = "rand"
-	Round   = "round"
+	Abs      = "abs"
+	BitCount = "bit_count"
+	Ceil     = "ceil"
+	Ceiling  = "ceiling"
+	Ln       = "ln"
+	Log      = "log"
+	Log2     = "log2"
+	Log10    = "log10"
+	Pow      = "pow"
+	Power    = "power"
+	Rand     = "rand"
+	Round    = "round"
 
 	// time functions
+	AddDate          = "adddate"
+	AddTime          = "addtime"
+	ConvertTz        = "convert_tz"
 	Curdate          = "curdate"
 	CurrentDate      = "current_date"
 	CurrentTime      = "current_time"
@@ -97,58 +107,114 @@ const (
 	Date             = "date"
 	DateArith        = "date_arith"
 	DateFormat       = "date_format"
+	TimeFormat       = "time_format"
 	Day              = "day"
 	DayName          = "dayname"
 	DayOfMonth       = "dayofmonth"
 	DayOfWeek        = "dayofweek"
 	DayOfYear        = "dayofyear"
 	Extract          = "extract"
+	FromDays         = "from_days"
+	GetFormat        = "get_format"
 	Hour             = "hour"
+	LastDay          = "last_day"
 	MicroSecond      = "microsecond"
 	Minute           = "minute"
 	Month            = "month"
 	MonthName        = "monthname"
 	Now              = "now"
+	PeriodAdd        = "period_add"
+	PeriodDiff       = "period_diff"
+	Quarter          = "quarter"
 	Second           = "second"
+	SecToTime        = "sec_to_time"
 	StrToDate        = "str_to_date"
+	SubDate          = "subdate"
+	SubTime          = "subtime"
 	Sysdate          = "sysdate"
 	Time             = "time"
 	TimeDiff         = "timediff"
+	Timestamp        = "timestamp"
+	ToDays           = "to_days"
 	UTCDate          = "utc_date"
+	UTCTime          = "utc_time"
+	UTCTimestamp     = "utc_timestamp"
 	Week             = "week"
 	Weekday          = "weekday"
 	WeekOfYear       = "weekofyear"
 	Year             = "year"
 	YearWeek         = "yearweek"
 	FromUnixTime     = "from_unixtime"
+	UnixTimestamp    = "unix_timestamp"
 
 	// string functions
-	ASCII          = "ascii"
-	Concat         = "concat"
-	ConcatWS       = "concat_ws"
-	Convert        = "convert"
-	Lcase          = "lcase"
-	Left           = "left"
-	Length         = "length"
-	Locate         = "locate"
-	Lower          = "lower"
-	Ltrim          = "ltrim"
-	Repeat         = "repeat"
-	Replace        = "replace"
-	Reverse        = "reverse"
-	Rtrim          = "rtrim"
-	Space          = "space"
-	Strcmp         = "strcmp"
-	Substring      = "substring"
-	SubstringIndex = "substring_index"
-	Trim           = "trim"
-	Upper          = "upper"
-	Ucase          = "ucase"
-	Hex            = "hex"
-	Unhex          = "unhex"
-	Rpad           = "rpad"
+	ASCII           = "ascii"
+	CharLength      = "char_length"
+	CharacterLength = "character_length"
+	Concat          = "concat"
+	ConcatWS        = "concat_ws"
+	Elt             = "elt"
+	ExportSet       = "export_set"
+	Field           = "field"
+	Format          = "format"
+	Instr           = "instr"
+	Convert         = "convert"
+	Lcase           = "lcase"
+	Left            = "left"
+	Length          = "length"
+	Locate          = "locate"
+	Lower           = "lower"
+	MakeSet         = "make_set"
+	Mid             = "mid"
+	OctetLength     = "octet_length"
+	Ord             = "ord"
+	Ltrim           = "ltrim"
+	Repeat          = "repeat"
+	Replace         = "replace"
+	Reverse         = "reverse"
+	Right           = "right"
+	Rtrim           = "rtrim"
+	Soundex         = "soundex"
+	Space           = "space"
+	Strcmp          = "strcmp"
+	Substr          = "substr"
+	Substring       = "substring"
+	SubstringIndex  = "substring_index"
+	Trim            = "trim"
+	Upper           = "upper"
+	Ucase           = "ucase"
+	Hex             = "hex"
+	Unhex           = "unhex"
+	Lpad            = "lpad"
+	Rpad            = "rpad"
+	WeightString    = "weight_string"
+
+	// json functions
+	JSONType          = "json_type"
+	JSONValid         = "json_valid"
+	JSONArrayAppend   = "json_array_append"
+	JSONArrayInsert   = "json_array_insert"
+	JSONMerge         = "json_merge"
+	JSONMergePreserve = "json_merge_preserve"
+	JSONContains      = "json_contains"
+	JSONContainsPath  = "json_contains_path"
+
+	// spatial functions
+	Point          = "point"
+	STAsText       = "st_astext"
+	STGeomFromText = "st_geomfromtext"
+
+	// encryption and compression functions
+	Decode      = "decode"
+	Encode      = "encode"
+	OldPassword = "old_password"
+	Password    = "password"
+	RandomBytes = "random_bytes"
 
 	// information functions
+	Charset      = "charset"
+	Coercibility = "coercibility"
+	Collation    = "collation"
 	ConnectionID = "connection_id"
 	CurrentUser  = "current_user"
 	Database     = "database"
@@ -164,7 +230,14 @@ const (
 	Nullif = "nullif"
 
 	// miscellaneous functions
-	Sleep = "sleep"
+	AnyValue       = "any_value"
+	FormatBytes    = "format_bytes"
+	FormatPicoTime = "format_pico_time"
+	IsUUID         = "is_uuid"
+	NameConst      = "name_const"
+	Sleep          = "sleep"
+	BinToUUID      = "bin_to_uuid"
+	UUIDToBin      = "uuid_to_bin"
 
 	// get_lock() and release_lock() is parsed but do nothing.
 	// It is used for preventing error in Ruby's activerecord migrations.
@@ -172,6 +245,27 @@ const (
 	ReleaseLock = "release_lock"
 )
 
+// registeredFuncNames are the names of the functions added outside of TiDB, which have no grammar
+// rule of their own. The parser parses a call to one of them into a FuncCallExpr.
+var registeredFuncNames = make(map[string]struct{})
+
+// RegisterFuncName makes the parser parse calls to the function name, see evaluator.RegisterFunction.
+// Like the function registry, it isn't guarded and must only be called at init time.
+func RegisterFuncName(name string) {
+	registeredFuncNames[strings.ToLower(name)] = struct{}{}
+}
+
+// UnregisterFuncName reverts RegisterFuncName.
+func UnregisterFuncName(name string) {
+	delete(registeredFuncNames, strings.ToLower(name))
+}
+
+// IsRegisteredFuncName reports whether name was registered by RegisterFuncName.
+func IsRegisteredFuncName(name string) bool {
+	_, ok := registeredFuncNames[strings.ToLower(name)]
+	return ok
+}
+
 // FuncCallExpr is for function expression.
 type FuncCallExpr struct {
 	funcNode
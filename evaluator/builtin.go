@@ -18,11 +18,15 @@
 package evaluator
 
 import (
+	"sort"
 	"strings"
 
+	"github.com/juju/errors"
 	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/parser/opcode"
+	"github.com/pingcap/tidb/util/charset"
 	"github.com/pingcap/tidb/util/types"
 )
 
@@ -39,33 +43,49 @@ type Func struct {
 	MaxArgs int
 }
 
+// CheckArgCount returns ErrIncorrectParameterCount if the function name, registered as f,
+// can't be called with n arguments.
+func (f Func) CheckArgCount(name string, n int) error {
+	if n < f.MinArgs || (f.MaxArgs != -1 && n > f.MaxArgs) {
+		return ErrIncorrectParameterCount.GenByArgs(name)
+	}
+	return nil
+}
+
 // Funcs holds all registered builtin functions.
 var Funcs = map[string]Func{
 	// common functions
 	ast.Coalesce: {builtinCoalesce, 1, -1},
 	ast.IsNull:   {builtinIsNull, 1, 1},
 	ast.Greatest: {builtinGreatest, 2, -1},
+	ast.Least:    {builtinLeast, 2, -1},
+	ast.Interval: {builtinInterval, 2, -1},
 
 	// math functions
-	ast.Abs:     {builtinAbs, 1, 1},
-	ast.Ceil:    {builtinCeil, 1, 1},
-	ast.Ceiling: {builtinCeil, 1, 1},
-	ast.Ln:      {builtinLog, 1, 1},
-	ast.Log:     {builtinLog, 1, 2},
-	ast.Log2:    {builtinLog2, 1, 1},
-	ast.Log10:   {builtinLog10, 1, 1},
-	ast.Pow:     {builtinPow, 2, 2},
-	ast.Power:   {builtinPow, 2, 2},
-	ast.Rand:    {builtinRand, 0, 1},
-	ast.Round:   {builtinRound, 1, 2},
+	ast.Abs:      {builtinAbs, 1, 1},
+	ast.BitCount: {builtinBitCount, 1, 1},
+	ast.Ceil:     {builtinCeil, 1, 1},
+	ast.Ceiling:  {builtinCeil, 1, 1},
+	ast.Ln:       {builtinLog, 1, 1},
+	ast.Log:      {builtinLog, 1, 2},
+	ast.Log2:     {builtinLog2, 1, 1},
+	ast.Log10:    {builtinLog10, 1, 1},
+	ast.Pow:      {builtinPow, 2, 2},
+	ast.Power:    {builtinPow, 2, 2},
+	ast.Rand:     {builtinRand, 0, 1},
+	ast.Round:    {builtinRound, 1, 2},
 
 	// time functions
+	ast.AddDate:          {builtinAddDate, 2, 2},
+	ast.AddTime:          {builtinAddTime, 2, 2},
+	ast.ConvertTz:        {builtinConvertTZ, 3, 3},
 	ast.Curdate:          {builtinCurrentDate, 0, 0},
 	ast.CurrentDate:      {builtinCurrentDate, 0, 0},
 	ast.CurrentTime:      {builtinCurrentTime, 0, 1},
 	ast.Date:             {builtinDate, 1, 1},
 	ast.DateArith:        {builtinDateArith, 3, 3},
 	ast.DateFormat:       {builtinDateFormat, 2, 2},
+	ast.TimeFormat:       {builtinTimeFormat, 2, 2},
 	ast.CurrentTimestamp: {builtinNow, 0, 1},
 	ast.Curtime:          {builtinCurrentTime, 0, 1},
 	ast.Day:              {builtinDay, 1, 1},
@@ -74,52 +94,109 @@ var Funcs = map[string]Func{
 	ast.DayOfWeek:        {builtinDayOfWeek, 1, 1},
 	ast.DayOfYear:        {builtinDayOfYear, 1, 1},
 	ast.Extract:          {builtinExtract, 2, 2},
+	ast.FromDays:         {builtinFromDays, 1, 1},
+	ast.GetFormat:        {builtinGetFormat, 2, 2},
 	ast.Hour:             {builtinHour, 1, 1},
+	ast.LastDay:          {builtinLastDay, 1, 1},
 	ast.MicroSecond:      {builtinMicroSecond, 1, 1},
 	ast.Minute:           {builtinMinute, 1, 1},
 	ast.Month:            {builtinMonth, 1, 1},
 	ast.MonthName:        {builtinMonthName, 1, 1},
 	ast.Now:              {builtinNow, 0, 1},
+	ast.PeriodAdd:        {builtinPeriodAdd, 2, 2},
+	ast.PeriodDiff:       {builtinPeriodDiff, 2, 2},
+	ast.Quarter:          {builtinQuarter, 1, 1},
 	ast.Second:           {builtinSecond, 1, 1},
+	ast.SecToTime:        {builtinSecToTime, 1, 1},
 	ast.StrToDate:        {builtinStrToDate, 2, 2},
+	ast.SubDate:          {builtinSubDate, 2, 2},
+	ast.SubTime:          {builtinSubTime, 2, 2},
 	ast.Sysdate:          {builtinSysDate, 0, 1},
 	ast.Time:             {builtinTime, 1, 1},
+	ast.Timestamp:        {builtinTimestamp, 1, 2},
+	ast.ToDays:           {builtinToDays, 1, 1},
 	ast.UTCDate:          {builtinUTCDate, 0, 0},
+	ast.UTCTime:          {builtinUTCTime, 0, 1},
+	ast.UTCTimestamp:     {builtinUTCTimestamp, 0, 1},
 	ast.Week:             {builtinWeek, 1, 2},
 	ast.Weekday:          {builtinWeekDay, 1, 1},
 	ast.WeekOfYear:       {builtinWeekOfYear, 1, 1},
 	ast.Year:             {builtinYear, 1, 1},
 	ast.YearWeek:         {builtinYearWeek, 1, 2},
 	ast.FromUnixTime:     {builtinFromUnixTime, 1, 2},
+	ast.UnixTimestamp:    {builtinUnixTimestamp, 0, 1},
 	ast.TimeDiff:         {builtinTimeDiff, 2, 2},
 
 	// string functions
-	ast.ASCII:          {builtinASCII, 1, 1},
-	ast.Concat:         {builtinConcat, 1, -1},
-	ast.ConcatWS:       {builtinConcatWS, 2, -1},
-	ast.Convert:        {builtinConvert, 2, 2},
-	ast.Lcase:          {builtinLower, 1, 1},
-	ast.Left:           {builtinLeft, 2, 2},
-	ast.Length:         {builtinLength, 1, 1},
-	ast.Locate:         {builtinLocate, 2, 3},
-	ast.Lower:          {builtinLower, 1, 1},
-	ast.Ltrim:          {trimFn(strings.TrimLeft, spaceChars), 1, 1},
-	ast.Repeat:         {builtinRepeat, 2, 2},
-	ast.Replace:        {builtinReplace, 3, 3},
-	ast.Reverse:        {builtinReverse, 1, 1},
-	ast.Rtrim:          {trimFn(strings.TrimRight, spaceChars), 1, 1},
-	ast.Space:          {builtinSpace, 1, 1},
-	ast.Strcmp:         {builtinStrcmp, 2, 2},
-	ast.Substring:      {builtinSubstring, 2, 3},
-	ast.SubstringIndex: {builtinSubstringIndex, 3, 3},
-	ast.Trim:           {builtinTrim, 1, 3},
-	ast.Upper:          {builtinUpper, 1, 1},
-	ast.Ucase:          {builtinUpper, 1, 1},
-	ast.Hex:            {builtinHex, 1, 1},
-	ast.Unhex:          {builtinUnHex, 1, 1},
-	ast.Rpad:           {builtinRpad, 3, 3},
+	ast.ASCII:           {builtinASCII, 1, 1},
+	ast.CharLength:      {builtinCharLength, 1, 1},
+	ast.CharacterLength: {builtinCharLength, 1, 1},
+	ast.Concat:          {builtinConcat, 1, -1},
+	ast.ConcatWS:        {builtinConcatWS, 2, -1},
+	ast.Elt:             {builtinElt, 2, -1},
+	ast.ExportSet:       {builtinExportSet, 3, 5},
+	ast.Field:           {builtinField, 2, -1},
+	ast.Format:          {builtinFormat, 2, 3},
+	ast.Instr:           {builtinInstr, 2, 2},
+	ast.Convert:         {builtinConvert, 2, 2},
+	ast.Lcase:           {builtinLower, 1, 1},
+	ast.Left:            {builtinLeft, 2, 2},
+	ast.Length:          {builtinLength, 1, 1},
+	ast.OctetLength:     {builtinOctetLength, 1, 1},
+	ast.Ord:             {builtinOrd, 1, 1},
+	ast.Locate:          {builtinLocate, 2, 3},
+	ast.Lower:           {builtinLower, 1, 1},
+	ast.MakeSet:         {builtinMakeSet, 2, -1},
+	ast.Mid:             {builtinSubstring, 2, 3},
+	ast.Ltrim:           {trimFn(strings.TrimLeft, spaceChars), 1, 1},
+	ast.Repeat:          {builtinRepeat, 2, 2},
+	ast.Replace:         {builtinReplace, 3, 3},
+	ast.Reverse:         {builtinReverse, 1, 1},
+	ast.Right:           {builtinRight, 2, 2},
+	ast.Rtrim:           {trimFn(strings.TrimRight, spaceChars), 1, 1},
+	ast.Soundex:         {builtinSoundex, 1, 1},
+	ast.Space:           {builtinSpace, 1, 1},
+	ast.Strcmp:          {builtinStrcmp, 2, 2},
+	ast.Substr:          {builtinSubstring, 2, 3},
+	ast.Substring:       {builtinSubstring, 2, 3},
+	ast.SubstringIndex:  {builtinSubstringIndex, 3, 3},
+	ast.Trim:            {builtinTrim, 1, 3},
+	ast.Upper:           {builtinUpper, 1, 1},
+	ast.Ucase:           {builtinUpper, 1, 1},
+	ast.Hex:             {builtinHex, 1, 1},
+	ast.Unhex:           {builtinUnHex, 1, 1},
+	ast.Lpad:            {builtinLpad, 3, 3},
+	ast.Rpad:            {builtinRpad, 3, 3},
+	ast.WeightString:    {builtinWeightString, 1, 3},
+
+	// json functions
+	ast.JSONType:        {builtinJSONType, 1, 1},
+	ast.JSONValid:       {builtinJSONValid, 1, 1},
+	ast.JSONArrayAppend: {builtinJSONArrayAppend, 3, -1},
+	ast.JSONArrayInsert: {builtinJSONArrayInsert, 3, -1},
+	ast.JSONMerge:       {builtinJSONMerge, 2, -1},
+	// JSON_MERGE_PRESERVE() is a synonym for JSON_MERGE().
+	// See https://dev.mysql.com/doc/refman/5.7/en/json-modification-functions.html#function_json-merge-preserve
+	ast.JSONMergePreserve: {builtinJSONMerge, 2, -1},
+	ast.JSONContains:      {builtinJSONContains, 2, 3},
+	ast.JSONContainsPath:  {builtinJSONContainsPath, 3, -1},
+
+	// spatial functions
+	ast.Point:          {builtinPoint, 2, 2},
+	ast.STAsText:       {builtinSTAsText, 1, 1},
+	ast.STGeomFromText: {builtinSTGeomFromText, 1, 1},
+
+	// encryption and compression functions
+	ast.Decode:      {builtinDecode, 2, 2},
+	ast.Encode:      {builtinEncode, 2, 2},
+	ast.OldPassword: {builtinOldPassword, 1, 1},
+	ast.Password:    {builtinPassword, 1, 1},
+	ast.RandomBytes: {builtinRandomBytes, 1, 1},
 
 	// information functions
+	ast.Charset:      {builtinCharset, 1, 1},
+	ast.Coercibility: {builtinCoercibility, 1, 1},
+	ast.Collation:    {builtinCollation, 1, 1},
 	ast.ConnectionID: {builtinConnectionID, 0, 0},
 	ast.CurrentUser:  {builtinCurrentUser, 0, 0},
 	ast.Database:     {builtinDatabase, 0, 0},
@@ -137,7 +214,14 @@ var Funcs = map[string]Func{
 	ast.Nullif: {builtinNullIf, 2, 2},
 
 	// miscellaneous functions
-	ast.Sleep: {builtinSleep, 1, 1},
+	ast.AnyValue:       {builtinAnyValue, 1, 1},
+	ast.FormatBytes:    {builtinFormatBytes, 1, 1},
+	ast.FormatPicoTime: {builtinFormatPicoTime, 1, 1},
+	ast.IsUUID:         {builtinIsUUID, 1, 1},
+	ast.NameConst:      {builtinNameConst, 2, 2},
+	ast.Sleep:          {builtinSleep, 1, 1},
+	ast.BinToUUID:      {builtinBinToUUID, 1, 2},
+	ast.UUIDToBin:      {builtinUUIDToBin, 1, 2},
 
 	// get_lock() and release_lock() is parsed but do nothing.
 	// It is used for preventing error in Ruby's activerecord migrations.
@@ -145,40 +229,233 @@ var Funcs = map[string]Func{
 	ast.ReleaseLock: {builtinReleaseLock, 1, 1},
 
 	// only used by new plan
-	ast.AndAnd:     {builtinAndAnd, 2, 2},
-	ast.OrOr:       {builtinOrOr, 2, 2},
-	ast.GE:         {compareFuncFactory(opcode.GE), 2, 2},
-	ast.LE:         {compareFuncFactory(opcode.LE), 2, 2},
-	ast.EQ:         {compareFuncFactory(opcode.EQ), 2, 2},
-	ast.NE:         {compareFuncFactory(opcode.NE), 2, 2},
-	ast.LT:         {compareFuncFactory(opcode.LT), 2, 2},
-	ast.GT:         {compareFuncFactory(opcode.GT), 2, 2},
-	ast.NullEQ:     {compareFuncFactory(opcode.NullEQ), 2, 2},
-	ast.Plus:       {arithmeticFuncFactory(opcode.Plus), 2, 2},
-	ast.Minus:      {arithmeticFuncFactory(opcode.Minus), 2, 2},
-	ast.Mod:        {arithmeticFuncFactory(opcode.Mod), 2, 2},
-	ast.Div:        {arithmeticFuncFactory(opcode.Div), 2, 2},
-	ast.Mul:        {arithmeticFuncFactory(opcode.Mul), 2, 2},
-	ast.IntDiv:     {arithmeticFuncFactory(opcode.IntDiv), 2, 2},
-	ast.LeftShift:  {bitOpFactory(opcode.LeftShift), 2, 2},
-	ast.RightShift: {bitOpFactory(opcode.RightShift), 2, 2},
-	ast.And:        {bitOpFactory(opcode.And), 2, 2},
-	ast.Or:         {bitOpFactory(opcode.Or), 2, 2},
-	ast.Xor:        {bitOpFactory(opcode.Xor), 2, 2},
-	ast.LogicXor:   {builtinLogicXor, 2, 2},
-	ast.UnaryNot:   {unaryOpFactory(opcode.Not), 1, 1},
-	ast.BitNeg:     {unaryOpFactory(opcode.BitNeg), 1, 1},
-	ast.UnaryPlus:  {unaryOpFactory(opcode.Plus), 1, 1},
-	ast.UnaryMinus: {unaryOpFactory(opcode.Minus), 1, 1},
-	ast.In:         {builtinIn, 1, -1},
-	ast.IsTruth:    {isTrueOpFactory(opcode.IsTruth), 1, 1},
-	ast.IsFalsity:  {isTrueOpFactory(opcode.IsFalsity), 1, 1},
-	ast.Like:       {builtinLike, 3, 3},
-	ast.Regexp:     {builtinRegexp, 2, 2},
-	ast.Case:       {builtinCaseWhen, 1, -1},
-	ast.RowFunc:    {builtinRow, 2, -1},
-	ast.SetVar:     {builtinSetVar, 2, 2},
-	ast.GetVar:     {builtinGetVar, 1, 1},
+	ast.AndAnd:       {builtinAndAnd, 2, 2},
+	ast.OrOr:         {builtinOrOr, 2, 2},
+	ast.GE:           {compareFuncFactory(opcode.GE), 2, 2},
+	ast.LE:           {compareFuncFactory(opcode.LE), 2, 2},
+	ast.EQ:           {compareFuncFactory(opcode.EQ), 2, 2},
+	ast.NE:           {compareFuncFactory(opcode.NE), 2, 2},
+	ast.LT:           {compareFuncFactory(opcode.LT), 2, 2},
+	ast.GT:           {compareFuncFactory(opcode.GT), 2, 2},
+	ast.NullEQ:       {compareFuncFactory(opcode.NullEQ), 2, 2},
+	ast.Plus:         {builtinArithmeticPlus, 2, 2},
+	ast.Minus:        {builtinArithmeticMinus, 2, 2},
+	ast.Mod:          {arithmeticFuncFactory(opcode.Mod), 2, 2},
+	ast.Div:          {builtinArithmeticDiv, 2, 2},
+	ast.Mul:          {builtinArithmeticMul, 2, 2},
+	ast.IntDiv:       {arithmeticFuncFactory(opcode.IntDiv), 2, 2},
+	ast.LeftShift:    {bitOpFactory(opcode.LeftShift), 2, 2},
+	ast.RightShift:   {bitOpFactory(opcode.RightShift), 2, 2},
+	ast.And:          {bitOpFactory(opcode.And), 2, 2},
+	ast.Or:           {bitOpFactory(opcode.Or), 2, 2},
+	ast.Xor:          {bitOpFactory(opcode.Xor), 2, 2},
+	ast.LogicXor:     {builtinLogicXor, 2, 2},
+	ast.UnaryNot:     {builtinUnaryNot, 1, 1},
+	ast.BitNeg:       {unaryOpFactory(opcode.BitNeg), 1, 1},
+	ast.UnaryPlus:    {unaryOpFactory(opcode.Plus), 1, 1},
+	ast.UnaryMinus:   {builtinUnaryMinus, 1, 1},
+	ast.In:           {builtinIn, 1, -1},
+	ast.IsTruth:      {isTrueOpFactory(opcode.IsTruth), 1, 1},
+	ast.IsFalsity:    {isTrueOpFactory(opcode.IsFalsity), 1, 1},
+	ast.Like:         {builtinLike, 3, 3},
+	ast.Regexp:       {builtinRegexp, 2, 2},
+	ast.Case:         {builtinCaseWhen, 1, -1},
+	ast.RowFunc:      {builtinRow, 2, -1},
+	ast.SetVar:       {builtinSetVar, 2, 2},
+	ast.GetVar:       {builtinGetVar, 1, 1},
+	ast.GetSysVar:    {builtinGetSysVar, 2, 2},
+	ast.SetCollation: {builtinSetCollation, 2, 2},
+}
+
+// TypeInferer infers the result type of a builtin function from the types of its arguments.
+// A string result type with no Charset takes the connection charset. Arguments with no Charset,
+// such as literals, are coercible to the charset of the others.
+type TypeInferer func(args []*types.FieldType) (*types.FieldType, error)
+
+// TypeInferers holds the result type inference of builtin functions, keyed like Funcs.
+// The planner falls back to its own rules for functions not listed here.
+var TypeInferers = map[string]TypeInferer{
+	ast.ASCII:           inferLonglong,
+	ast.Length:          inferLonglong,
+	ast.OctetLength:     inferLonglong,
+	ast.Ord:             inferLonglong,
+	ast.CharLength:      inferLonglong,
+	ast.CharacterLength: inferLonglong,
+	ast.Locate:          inferLonglong,
+	ast.Instr:           inferLonglong,
+	ast.BitCount:        inferLonglong,
+	ast.Strcmp:          inferLonglong,
+	ast.Field:           inferLonglong,
+	ast.Elt:             inferVarString,
+	ast.MakeSet:         inferVarString,
+	ast.ExportSet:       inferVarString,
+	ast.Format:          inferVarString,
+	ast.Concat:          inferConcat,
+	ast.ConcatWS:        inferConcatWS,
+	ast.Left:            inferSameLength,
+	ast.Lower:           inferSameLength,
+	ast.Lcase:           inferSameLength,
+	ast.Upper:           inferSameLength,
+	ast.Ucase:           inferSameLength,
+	ast.Reverse:         inferSameLength,
+	ast.Right:           inferSameLength,
+	ast.Substr:          inferSameLength,
+	ast.Substring:       inferSameLength,
+	ast.Mid:             inferSameLength,
+	ast.Ltrim:           inferSameLength,
+	ast.Rtrim:           inferSameLength,
+	ast.Trim:            inferSameLength,
+	ast.Point:           inferGeometry,
+	ast.STAsText:        inferVarString,
+	ast.STGeomFromText:  inferGeometry,
+	ast.AnyValue:        inferArgType,
+	ast.FormatBytes:     inferVarString,
+	ast.FormatPicoTime:  inferVarString,
+	ast.IsUUID:          inferLonglong,
+	ast.BinToUUID:       inferVarString,
+	ast.UUIDToBin:       inferVarBinary,
+	ast.NameConst:       inferNameConst,
+}
+
+func inferLonglong(_ []*types.FieldType) (*types.FieldType, error) {
+	return types.NewFieldType(mysql.TypeLonglong), nil
+}
+
+func inferVarString(_ []*types.FieldType) (*types.FieldType, error) {
+	return newVarStringType(types.UnspecifiedLength), nil
+}
+
+func inferVarBinary(_ []*types.FieldType) (*types.FieldType, error) {
+	tp := newVarStringType(types.UnspecifiedLength)
+	tp.Charset, tp.Collate = charset.CharsetBin, charset.CollationBin
+	tp.Flag |= mysql.BinaryFlag
+	return tp, nil
+}
+
+// inferArgType types a function returning its first argument as that argument.
+func inferArgType(args []*types.FieldType) (*types.FieldType, error) {
+	tp := *args[0]
+	return &tp, nil
+}
+
+func inferNameConst(args []*types.FieldType) (*types.FieldType, error) {
+	return inferArgType(args[1:])
+}
+
+// newVarStringType returns a VARCHAR type of flen characters.
+func newVarStringType(flen int) *types.FieldType {
+	tp := types.NewFieldType(mysql.TypeVarString)
+	tp.Flen = flen
+	return tp
+}
+
+// stringLength returns the length in characters of a string type, unspecified for other types.
+func stringLength(tp *types.FieldType) int {
+	switch tp.Tp {
+	case mysql.TypeVarString, mysql.TypeVarchar, mysql.TypeString:
+		return tp.Flen
+	}
+	return types.UnspecifiedLength
+}
+
+// sumLength adds up the lengths of the string types args, or is unspecified if one of them is.
+func sumLength(args []*types.FieldType) int {
+	flen := 0
+	for _, arg := range args {
+		l := stringLength(arg)
+		if l == types.UnspecifiedLength {
+			return types.UnspecifiedLength
+		}
+		flen += l
+	}
+	return flen
+}
+
+// inferConcat types CONCAT as a VARCHAR as long as all its arguments together.
+func inferConcat(args []*types.FieldType) (*types.FieldType, error) {
+	tp := newVarStringType(sumLength(args))
+	return tp, errors.Trace(unifyCharset(tp, args, ast.Concat))
+}
+
+// inferConcatWS types CONCAT_WS as a VARCHAR as long as its strings and the separators between them.
+func inferConcatWS(args []*types.FieldType) (*types.FieldType, error) {
+	flen := sumLength(args[1:])
+	if flen != types.UnspecifiedLength && len(args) > 2 {
+		if sep := stringLength(args[0]); sep == types.UnspecifiedLength {
+			flen = types.UnspecifiedLength
+		} else {
+			flen += sep * (len(args) - 2)
+		}
+	}
+	tp := newVarStringType(flen)
+	return tp, errors.Trace(unifyCharset(tp, args, ast.ConcatWS))
+}
+
+// inferSameLength types a function returning part or a transformation of its first argument as a
+// VARCHAR no longer than that argument.
+func inferSameLength(args []*types.FieldType) (*types.FieldType, error) {
+	return newVarStringType(stringLength(args[0])), nil
+}
+
+// unifyCharset sets the charset and collation of tp to the ones MySQL derives for the operation op
+// on the string args, which are all taken to be of IMPLICIT coercibility. A mix of collations that
+// can't be resolved is an error.
+func unifyCharset(tp *types.FieldType, args []*types.FieldType, op string) error {
+	operands := make([]CollationOperand, 0, len(args))
+	for _, arg := range args {
+		if !(arg.Tp == mysql.TypeVarString || types.IsTypeChar(arg.Tp) || types.IsTypeBlob(arg.Tp)) || arg.Charset == "" {
+			continue
+		}
+		operands = append(operands, CollationOperand{Charset: arg.Charset, Collate: arg.Collate, Coercibility: CoercibilityImplicit})
+	}
+	co, err := AggregateCollation(op, operands...)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	tp.Charset, tp.Collate = co.Charset, co.Collate
+	return nil
+}
+
+// RegisterFunction adds fn to Funcs under name and registers the name to the parser, see
+// ast.RegisterFuncName, so that SQL can call it. Function names are case-insensitive and
+// registering a name already in Funcs is an error.
+// Funcs isn't guarded, so functions must be registered at init time, before any statement
+// is evaluated. A function that reads ctx or returns a different result each call must also
+// be added to DynamicFuncs to keep it from being constant folded.
+func RegisterFunction(name string, fn Func) error {
+	name = strings.ToLower(name)
+	if _, ok := Funcs[name]; ok {
+		return errors.Errorf("function %s is already registered", name)
+	}
+	if fn.F == nil {
+		return errors.Errorf("function %s has no implementation", name)
+	}
+	Funcs[name] = fn
+	ast.RegisterFuncName(name)
+	return nil
+}
+
+// UnregisterFunction removes the function registered under name from Funcs. Like
+// RegisterFunction, it must not be called while statements are evaluated.
+func UnregisterFunction(name string) error {
+	name = strings.ToLower(name)
+	if _, ok := Funcs[name]; !ok {
+		return errors.Errorf("function %s is not registered", name)
+	}
+	delete(Funcs, name)
+	ast.UnregisterFuncName(name)
+	return nil
+}
+
+// SupportedFunctions returns the sorted names of the functions in Funcs, those registered with
+// RegisterFunction included.
+func SupportedFunctions() []string {
+	names := make([]string, 0, len(Funcs))
+	for name := range Funcs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
 }
 
 // DynamicFuncs are those functions that
@@ -195,15 +472,61 @@ var DynamicFuncs = map[string]int{
 	"user":           0,
 	"version":        0,
 	"sleep":          0,
+	ast.RandomBytes:  0,
 	ast.GetVar:       0,
 	ast.SetVar:       0,
+	ast.Values:       0,
+	ast.Default:      0,
+	ast.UTCDate:      0,
+	ast.UTCTime:      0,
+	ast.UTCTimestamp: 0,
+}
+
+// LazyArgs are the arguments of a lazily evaluated function, each evaluated only when asked for.
+type LazyArgs interface {
+	// Len returns the number of arguments.
+	Len() int
+	// Eval evaluates the i-th argument.
+	Eval(i int) (types.Datum, error)
+}
+
+// DatumArgs are arguments evaluated up front, they let F of a lazy function share its Lazy code.
+type DatumArgs []types.Datum
+
+// Len implements LazyArgs interface.
+func (a DatumArgs) Len() int {
+	return len(a)
+}
+
+// Eval implements LazyArgs interface.
+func (a DatumArgs) Eval(i int) (types.Datum, error) {
+	return a[i], nil
+}
+
+// LazyBuiltinFunc is the function signature for builtin functions that evaluate their arguments
+// themselves, as they need them.
+type LazyBuiltinFunc func(LazyArgs, context.Context) (types.Datum, error)
+
+// LazyFuncs are those functions that must not evaluate all their arguments up front, like IF()
+// skipping the branch not taken. Where the arguments are expressions, the function is called
+// with them through its LazyBuiltinFunc here instead of its F in Funcs.
+var LazyFuncs = map[string]LazyBuiltinFunc{
+	ast.Case:     lazyCaseWhen,
+	ast.Coalesce: lazyCoalesce,
+	ast.If:       lazyIf,
+	ast.Ifnull:   lazyIfNull,
 }
 
 // See http://dev.mysql.com/doc/refman/5.7/en/comparison-operators.html#function_coalesce
 func builtinCoalesce(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
-	for _, d = range args {
-		if !d.IsNull() {
-			return d, nil
+	return lazyCoalesce(DatumArgs(args), ctx)
+}
+
+func lazyCoalesce(args LazyArgs, _ context.Context) (d types.Datum, err error) {
+	for i := 0; i < args.Len(); i++ {
+		d, err = args.Eval(i)
+		if err != nil || !d.IsNull() {
+			return d, errors.Trace(err)
 		}
 	}
 	return d, nil
@@ -219,25 +542,90 @@ func builtinIsNull(args []types.Datum, _ context.Context) (d types.Datum, err er
 	return d, nil
 }
 
+// See https://dev.mysql.com/doc/refman/5.7/en/comparison-operators.html#function_interval
+// The bounds after args[0] must be sorted ascending, so a binary search finds the
+// first bound greater than args[0]. NULL bounds are treated as less than any value.
+func builtinInterval(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
+	if args[0].IsNull() {
+		d.SetInt64(-1)
+		return d, nil
+	}
+	sc := ctx.GetSessionVars().StmtCtx
+	bounds := args[1:]
+	idx := sort.Search(len(bounds), func(i int) bool {
+		if err != nil || bounds[i].IsNull() {
+			return false
+		}
+		var a, b types.Datum
+		a, b, err = types.CoerceDatum(sc, args[0], bounds[i])
+		if err != nil {
+			return false
+		}
+		var cmp int
+		cmp, err = b.CompareDatum(sc, a)
+		return cmp > 0
+	})
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	d.SetInt64(int64(idx))
+	return d, nil
+}
+
 // See http://dev.mysql.com/doc/refman/5.7/en/comparison-operators.html#function_greatest
 func builtinGreatest(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
-	max := 0
+	return greatestOrLeast(args, ctx, 1)
+}
+
+// See http://dev.mysql.com/doc/refman/5.7/en/comparison-operators.html#function_least
+func builtinLeast(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
+	return greatestOrLeast(args, ctx, -1)
+}
+
+// greatestOrLeast returns the greatest argument if sign is 1, or the least if it is -1. Any NULL
+// argument makes the result NULL. Dates and datetimes compare as points in time, and if they are
+// mixed the result is a datetime.
+func greatestOrLeast(args []types.Datum, ctx context.Context, sign int) (d types.Datum, err error) {
 	sc := ctx.GetSessionVars().StmtCtx
-	for i := 0; i < len(args); i++ {
+	idx := 0
+	for i := range args {
 		if args[i].IsNull() {
-			d.SetNull()
-			return
+			return d, nil
 		}
-
 		var cmp int
-		if cmp, err = args[i].CompareDatum(sc, args[max]); err != nil {
-			return
+		if cmp, err = args[i].CompareDatum(sc, args[idx]); err != nil {
+			return d, errors.Trace(err)
 		}
-
-		if cmp > 0 {
-			max = i
+		if cmp*sign > 0 {
+			idx = i
 		}
 	}
-	d = args[max]
-	return
+	d = args[idx]
+	if d.Kind() != types.KindMysqlTime {
+		return d, nil
+	}
+	fsp := 0
+	mixed := false
+	for _, arg := range args {
+		if arg.Kind() != types.KindMysqlTime {
+			return d, nil
+		}
+		t := arg.GetMysqlTime()
+		if t.Type != d.GetMysqlTime().Type {
+			mixed = true
+		}
+		// A DATE has no fractional part, whatever its Fsp says.
+		if t.Type != mysql.TypeDate && t.Fsp > fsp {
+			fsp = t.Fsp
+		}
+	}
+	if mixed {
+		t, err := d.GetMysqlTime().Convert(mysql.TypeDatetime)
+		if err != nil {
+			return d, errors.Trace(err)
+		}
+		t.Fsp = fsp
+		d.SetMysqlTime(t)
+	}
+	return d, nil
 }
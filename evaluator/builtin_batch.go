@@ -0,0 +1,210 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package evaluator
+
+import (
+	"unicode"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/util/hack"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// Column is a batch of values of a function argument or result, a datum per row.
+type Column struct {
+	Datums []types.Datum
+}
+
+// NewColumn returns a Column of rows NULL values.
+func NewColumn(rows int) *Column {
+	return &Column{Datums: make([]types.Datum, rows)}
+}
+
+// batchFunc evaluates a builtin function over a batch of rows, args holding a column per argument.
+type batchFunc func(args []*Column, ctx context.Context) (*Column, error)
+
+// batchFuncs holds the batch fast path of the hot builtin functions, keyed like Funcs.
+var batchFuncs = map[string]batchFunc{
+	ast.Length:    batchLength,
+	ast.Lower:     batchMapCase(unicode.ToLower),
+	ast.Lcase:     batchMapCase(unicode.ToLower),
+	ast.Upper:     batchMapCase(unicode.ToUpper),
+	ast.Ucase:     batchMapCase(unicode.ToUpper),
+	ast.Substring: batchSubstring,
+	ast.Substr:    batchSubstring,
+	ast.Mid:       batchSubstring,
+	ast.Concat:    batchConcat,
+}
+
+// EvalColumn evaluates the builtin function name over a batch of rows, args holding a column
+// per argument with a value for each row. Functions with no batch fast path are evaluated row by
+// row, a function without arguments once for each row.
+func EvalColumn(name string, args []*Column, rows int, ctx context.Context) (*Column, error) {
+	f, ok := Funcs[name]
+	if !ok {
+		return nil, errors.Errorf("function %s is not registered", name)
+	}
+	if err := f.CheckArgCount(name, len(args)); err != nil {
+		return nil, errors.Trace(err)
+	}
+	for _, arg := range args {
+		if len(arg.Datums) != rows {
+			return nil, errors.Errorf("arguments of %s have %d rows, expect %d", name, len(arg.Datums), rows)
+		}
+	}
+	if batch, ok := batchFuncs[name]; ok && len(args) > 0 {
+		return batch(args, ctx)
+	}
+	return evalRows(f.F, args, rows, ctx)
+}
+
+// rowCount returns the number of rows of the batch args, which has at least one argument.
+func rowCount(args []*Column) int {
+	return len(args[0].Datums)
+}
+
+// evalRows evaluates f on each of the rows of args.
+func evalRows(f BuiltinFunc, args []*Column, rows int, ctx context.Context) (*Column, error) {
+	result := NewColumn(rows)
+	row := make([]types.Datum, len(args))
+	for i := range result.Datums {
+		for j, arg := range args {
+			row[j] = arg.Datums[i]
+		}
+		d, err := f(row, ctx)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		result.Datums[i] = d
+	}
+	return result, nil
+}
+
+func batchLength(args []*Column, _ context.Context) (*Column, error) {
+	result := NewColumn(rowCount(args))
+	for i, d := range args[0].Datums {
+		switch d.Kind() {
+		case types.KindNull:
+		case types.KindString, types.KindBytes:
+			result.Datums[i].SetInt64(int64(len(d.GetBytes())))
+		default:
+			s, err := d.ToString()
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			result.Datums[i].SetInt64(int64(len(s)))
+		}
+	}
+	return result, nil
+}
+
+func batchMapCase(f func(rune) rune) batchFunc {
+	return func(args []*Column, _ context.Context) (*Column, error) {
+		result := NewColumn(rowCount(args))
+		// The results share a single buffer rather than taking an allocation each.
+		size := 0
+		for _, d := range args[0].Datums {
+			size += len(d.GetBytes())
+		}
+		buf := make([]byte, 0, size)
+		ends := make([]int, len(result.Datums))
+		for i, d := range args[0].Datums {
+			if !d.IsNull() {
+				s, err := d.ToString()
+				if err != nil {
+					return nil, errors.Trace(err)
+				}
+				if isBinaryString(d) {
+					buf = append(buf, s...)
+				} else {
+					buf = appendMapCase(buf, s, f)
+				}
+			}
+			ends[i] = len(buf)
+		}
+		start := 0
+		for i, d := range args[0].Datums {
+			if !d.IsNull() {
+				result.Datums[i].SetString(hack.String(buf[start:ends[i]]))
+			}
+			start = ends[i]
+		}
+		return result, nil
+	}
+}
+
+func batchSubstring(args []*Column, ctx context.Context) (*Column, error) {
+	result := NewColumn(rowCount(args))
+	hasLen := len(args) == 3
+	for i, str := range args[0].Datums {
+		pos := args[1].Datums[i]
+		var length types.Datum
+		if hasLen {
+			length = args[2].Datums[i]
+		}
+		if str.IsNull() || pos.IsNull() || (hasLen && length.IsNull()) {
+			continue
+		}
+		if str.Kind() == types.KindString && pos.Kind() == types.KindInt64 && (!hasLen || length.Kind() == types.KindInt64) {
+			result.Datums[i].SetString(substring(str.GetString(), pos.GetInt64(), length.GetInt64(), hasLen, isBinaryString(str)))
+			continue
+		}
+		// Leave the conversions and their errors to the row at a time function.
+		row := []types.Datum{str, pos}
+		if hasLen {
+			row = append(row, length)
+		}
+		d, err := builtinSubstring(row, ctx)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		result.Datums[i] = d
+	}
+	return result, nil
+}
+
+func batchConcat(args []*Column, ctx context.Context) (*Column, error) {
+	result := NewColumn(rowCount(args))
+	var buf []byte
+	for i := range result.Datums {
+		buf = buf[:0]
+		isNull := false
+		for _, arg := range args {
+			d := arg.Datums[i]
+			if d.IsNull() {
+				isNull = true
+				break
+			}
+			if d.Kind() == types.KindString || d.Kind() == types.KindBytes {
+				buf = append(buf, d.GetBytes()...)
+			} else {
+				s, err := d.ToString()
+				if err != nil {
+					return nil, errors.Trace(err)
+				}
+				buf = append(buf, s...)
+			}
+			if !checkResultLen(ctx, "concat", int64(len(buf))) {
+				isNull = true
+				break
+			}
+		}
+		if !isNull {
+			result.Datums[i].SetString(string(buf))
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,136 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package evaluator
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/util/mock"
+	"github.com/pingcap/tidb/util/testleak"
+	"github.com/pingcap/tidb/util/testutil"
+	"github.com/pingcap/tidb/util/types"
+)
+
+func newColumn(values ...interface{}) *Column {
+	return &Column{Datums: types.MakeDatums(values...)}
+}
+
+func (s *testEvaluatorSuite) TestEvalColumn(c *C) {
+	defer testleak.AfterTest(c)()
+	str := newColumn("Hello", nil, "", "ÀbÇ", 12, []byte("World"))
+	notNullStr := newColumn("Hello", "TiDB", "", "ÀbÇ", 12, []byte("World"))
+	tbl := []struct {
+		name string
+		args []*Column
+	}{
+		{ast.Length, []*Column{str}},
+		{ast.Lower, []*Column{str}},
+		{ast.Lcase, []*Column{str}},
+		{ast.Upper, []*Column{str}},
+		{ast.Ucase, []*Column{str}},
+		{ast.Substring, []*Column{notNullStr, newColumn(2, 1, 1, -2, 1, 3)}},
+		{ast.Substring, []*Column{notNullStr, newColumn(2, 1, 1, 1, 1, -3), newColumn(3, 1, 0, 2, -1, 2)}},
+		{ast.Concat, []*Column{str, newColumn("!", "!", nil, 1.5, "x", "")}},
+		// Functions without a batch fast path are evaluated row by row.
+		{ast.Repeat, []*Column{notNullStr, newColumn(2, 2, 2, 2, 2, 0)}},
+		{ast.Reverse, []*Column{str}},
+	}
+	for _, t := range tbl {
+		result, err := EvalColumn(t.name, t.args, len(str.Datums), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(result.Datums, HasLen, len(str.Datums))
+		f := Funcs[t.name].F
+		for i := range str.Datums {
+			row := make([]types.Datum, len(t.args))
+			for j, arg := range t.args {
+				row[j] = arg.Datums[i]
+			}
+			expect, err := f(row, s.ctx)
+			c.Assert(err, IsNil)
+			c.Assert(result.Datums[i], testutil.DatumEquals, expect, Commentf("%s row %d", t.name, i))
+		}
+	}
+
+	result, err := EvalColumn(ast.Substring, []*Column{str, newColumn(1, 1, 1, nil, 1, 1), newColumn(1, 1, 1, 1, nil, 1)}, len(str.Datums), s.ctx)
+	c.Assert(err, IsNil)
+	for i, d := range result.Datums {
+		c.Assert(d.IsNull(), Equals, i == 1 || i == 3 || i == 4, Commentf("row %d", i))
+	}
+
+	// A result longer than max_allowed_packet is NULL with a warning.
+	vars := s.ctx.GetSessionVars()
+	vars.Systems["max_allowed_packet"] = "8"
+	defer delete(vars.Systems, "max_allowed_packet")
+	result, err = EvalColumn(ast.Concat, []*Column{newColumn("1234", "12345"), newColumn("5678", "6789")}, 2, s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(result.Datums[0].GetString(), Equals, "12345678")
+	c.Assert(result.Datums[1].IsNull(), IsTrue)
+
+	// A function without arguments gives a result for each row.
+	result, err = EvalColumn(ast.ConnectionID, nil, 3, s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(result.Datums, HasLen, 3)
+	for i, d := range result.Datums {
+		c.Assert(d, testutil.DatumEquals, types.NewDatum(s.ctx.GetSessionVars().ConnectionID), Commentf("row %d", i))
+	}
+
+	_, err = EvalColumn("no_such_function", []*Column{str}, len(str.Datums), s.ctx)
+	c.Assert(err, NotNil)
+	_, err = EvalColumn(ast.Lower, []*Column{str, str}, len(str.Datums), s.ctx)
+	c.Assert(err, NotNil)
+	_, err = EvalColumn(ast.Concat, []*Column{str, newColumn("a")}, len(str.Datums), s.ctx)
+	c.Assert(err, NotNil)
+	_, err = EvalColumn(ast.Lower, []*Column{str}, 1, s.ctx)
+	c.Assert(err, NotNil)
+}
+
+const benchRows = 100000
+
+func newLowerBenchColumn() *Column {
+	col := NewColumn(benchRows)
+	for i := range col.Datums {
+		col.Datums[i].SetString(fmt.Sprintf("TiDB Row %d", i))
+	}
+	return col
+}
+
+func BenchmarkLowerRows(b *testing.B) {
+	ctx := mock.NewContext()
+	col := newLowerBenchColumn()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		result := NewColumn(benchRows)
+		for j, d := range col.Datums {
+			var err error
+			result.Datums[j], err = builtinLower([]types.Datum{d}, ctx)
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkLowerBatch(b *testing.B) {
+	ctx := mock.NewContext()
+	args := []*Column{newLowerBenchColumn()}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := EvalColumn(ast.Lower, args, benchRows, ctx); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -21,42 +21,47 @@ import (
 
 // See https://dev.mysql.com/doc/refman/5.7/en/control-flow-functions.html#function_if
 func builtinIf(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
+	return lazyIf(DatumArgs(args), ctx)
+}
+
+func lazyIf(args LazyArgs, ctx context.Context) (d types.Datum, err error) {
 	// if(expr1, expr2, expr3)
 	// if expr1 is true, return expr2, otherwise, return expr3
-	v1 := args[0]
-	v2 := args[1]
-	v3 := args[2]
-
+	v1, err := args.Eval(0)
+	if err != nil {
+		return d, errors.Trace(err)
+	}
 	if v1.IsNull() {
-		return v3, nil
+		return args.Eval(2)
 	}
 
 	b, err := v1.ToBool(ctx.GetSessionVars().StmtCtx)
 	if err != nil {
-		d := types.Datum{}
 		return d, errors.Trace(err)
 	}
 
 	// TODO: check return type, must be numeric or string
 	if b == 1 {
-		return v2, nil
+		return args.Eval(1)
 	}
 
-	return v3, nil
+	return args.Eval(2)
 }
 
 // See https://dev.mysql.com/doc/refman/5.7/en/control-flow-functions.html#function_ifnull
-func builtinIfNull(args []types.Datum, _ context.Context) (d types.Datum, err error) {
+func builtinIfNull(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
+	return lazyIfNull(DatumArgs(args), ctx)
+}
+
+func lazyIfNull(args LazyArgs, _ context.Context) (d types.Datum, err error) {
 	// ifnull(expr1, expr2)
 	// if expr1 is not null, return expr1, otherwise, return expr2
-	v1 := args[0]
-	v2 := args[1]
-
-	if !v1.IsNull() {
-		return v1, nil
+	v1, err := args.Eval(0)
+	if err != nil || !v1.IsNull() {
+		return v1, errors.Trace(err)
 	}
 
-	return v2, nil
+	return args.Eval(1)
 }
 
 // See https://dev.mysql.com/doc/refman/5.7/en/control-flow-functions.html#function_nullif
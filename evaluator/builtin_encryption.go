@@ -0,0 +1,176 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package evaluator
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/util"
+	"github.com/pingcap/tidb/util/types"
+)
+
+const sqlCryptMaxRand = 0x3FFFFFFF
+
+// sqlCrypt is the legacy stream cipher behind ENCODE() and DECODE().
+// See https://github.com/mysql/mysql-server/blob/5.7/sql/sql_crypt.cc
+type sqlCrypt struct {
+	seed1, seed2 uint32
+	shift        uint32
+	encodeBuff   [256]byte
+	decodeBuff   [256]byte
+}
+
+func newSQLCrypt(password []byte) *sqlCrypt {
+	nr, nr2 := util.OldPasswordHash(password)
+	sc := &sqlCrypt{seed1: nr % sqlCryptMaxRand, seed2: nr2 % sqlCryptMaxRand}
+	for i := range sc.decodeBuff {
+		sc.decodeBuff[i] = byte(i)
+	}
+	for i := range sc.decodeBuff {
+		idx := uint32(sc.rand() * 255.0)
+		sc.decodeBuff[idx], sc.decodeBuff[i] = sc.decodeBuff[i], sc.decodeBuff[idx]
+	}
+	for i, b := range sc.decodeBuff {
+		sc.encodeBuff[b] = byte(i)
+	}
+	return sc
+}
+
+// rand is my_rnd of MySQL, a linear congruential generator.
+func (sc *sqlCrypt) rand() float64 {
+	sc.seed1 = (sc.seed1*3 + sc.seed2) % sqlCryptMaxRand
+	sc.seed2 = (sc.seed1 + sc.seed2 + 33) % sqlCryptMaxRand
+	return float64(sc.seed1) / sqlCryptMaxRand
+}
+
+func (sc *sqlCrypt) encode(b []byte) []byte {
+	res := make([]byte, len(b))
+	for i, c := range b {
+		sc.shift ^= uint32(sc.rand() * 255.0)
+		res[i] = sc.encodeBuff[c] ^ byte(sc.shift)
+		sc.shift ^= uint32(c)
+	}
+	return res
+}
+
+func (sc *sqlCrypt) decode(b []byte) []byte {
+	res := make([]byte, len(b))
+	for i, c := range b {
+		sc.shift ^= uint32(sc.rand() * 255.0)
+		res[i] = sc.decodeBuff[c^byte(sc.shift)]
+		sc.shift ^= uint32(res[i])
+	}
+	return res
+}
+
+// sqlCryptArgs returns the string and password arguments of ENCODE() and
+// DECODE(), isNull is true if any of them is NULL.
+func sqlCryptArgs(args []types.Datum) (str, password []byte, isNull bool, err error) {
+	if args[0].IsNull() || args[1].IsNull() {
+		return nil, nil, true, nil
+	}
+	s, err := args[0].ToString()
+	if err != nil {
+		return nil, nil, false, errors.Trace(err)
+	}
+	p, err := args[1].ToString()
+	if err != nil {
+		return nil, nil, false, errors.Trace(err)
+	}
+	return []byte(s), []byte(p), false, nil
+}
+
+// See https://dev.mysql.com/doc/refman/5.7/en/encryption-functions.html#function_encode
+func builtinEncode(args []types.Datum, _ context.Context) (d types.Datum, err error) {
+	str, password, isNull, err := sqlCryptArgs(args)
+	if err != nil || isNull {
+		return d, errors.Trace(err)
+	}
+	d.SetBytes(newSQLCrypt(password).encode(str))
+	return d, nil
+}
+
+// See https://dev.mysql.com/doc/refman/5.7/en/encryption-functions.html#function_decode
+func builtinDecode(args []types.Datum, _ context.Context) (d types.Datum, err error) {
+	str, password, isNull, err := sqlCryptArgs(args)
+	if err != nil || isNull {
+		return d, errors.Trace(err)
+	}
+	d.SetBytes(newSQLCrypt(password).decode(str))
+	return d, nil
+}
+
+const maxRandomBytesLength = 1024
+
+// See https://dev.mysql.com/doc/refman/5.7/en/encryption-functions.html#function_random-bytes
+func builtinRandomBytes(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
+	if args[0].IsNull() {
+		return d, nil
+	}
+	n, err := args[0].ToInt64(ctx.GetSessionVars().StmtCtx)
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	if n < 1 || n > maxRandomBytesLength {
+		return d, ErrDataOutOfRange.GenByArgs("length", ast.RandomBytes)
+	}
+	buf := make([]byte, n)
+	if _, err = rand.Read(buf); err != nil {
+		return d, errors.Trace(err)
+	}
+	d.SetBytes(buf)
+	return d, nil
+}
+
+// See https://dev.mysql.com/doc/refman/5.7/en/encryption-functions.html#function_password
+func builtinPassword(args []types.Datum, _ context.Context) (d types.Datum, err error) {
+	if args[0].IsNull() {
+		return d, nil
+	}
+	pass, err := args[0].ToString()
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	if len(pass) == 0 {
+		d.SetString("")
+		return d, nil
+	}
+	hash := util.Sha1Hash(util.Sha1Hash([]byte(pass)))
+	d.SetString("*" + strings.ToUpper(hex.EncodeToString(hash)))
+	return d, nil
+}
+
+// See https://dev.mysql.com/doc/refman/5.6/en/encryption-functions.html#function_old-password
+func builtinOldPassword(args []types.Datum, _ context.Context) (d types.Datum, err error) {
+	if args[0].IsNull() {
+		return d, nil
+	}
+	pass, err := args[0].ToString()
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	if len(pass) == 0 {
+		d.SetString("")
+		return d, nil
+	}
+	nr, nr2 := util.OldPasswordHash([]byte(pass))
+	d.SetString(fmt.Sprintf("%08x%08x", nr, nr2))
+	return d, nil
+}
@@ -0,0 +1,133 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package evaluator
+
+import (
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/util/testleak"
+	"github.com/pingcap/tidb/util/testutil"
+	"github.com/pingcap/tidb/util/types"
+)
+
+func (s *testEvaluatorSuite) TestEncodeAndDecode(c *C) {
+	defer testleak.AfterTest(c)()
+	tbl := []struct {
+		str      string
+		password string
+	}{
+		{"", ""},
+		{"pingcap", ""},
+		{"pingcap", "1234567890"},
+		{"一二三四五六七八九十", "password"},
+		{"\x00\xff binary \t data", "p a s s"},
+	}
+	for _, t := range tbl {
+		encoded, err := builtinEncode(types.MakeDatums(t.str, t.password), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(encoded.Kind(), Equals, types.KindBytes)
+		c.Assert(encoded.GetBytes(), HasLen, len(t.str))
+		if len(t.str) > 0 {
+			c.Assert(encoded.GetString(), Not(Equals), t.str)
+		}
+
+		decoded, err := builtinDecode([]types.Datum{encoded, types.NewStringDatum(t.password)}, s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(decoded.GetString(), Equals, t.str)
+	}
+
+	// Spaces and tabs in the password are ignored.
+	encoded, err := builtinEncode(types.MakeDatums("pingcap", "pass word"), s.ctx)
+	c.Assert(err, IsNil)
+	decoded, err := builtinDecode([]types.Datum{encoded, types.NewStringDatum("password\t")}, s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(decoded.GetString(), Equals, "pingcap")
+
+	// A wrong password garbles the data.
+	decoded, err = builtinDecode([]types.Datum{encoded, types.NewStringDatum("passw0rd")}, s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(decoded.GetBytes(), HasLen, len("pingcap"))
+	c.Assert(decoded.GetString(), Not(Equals), "pingcap")
+
+	for _, args := range [][]interface{}{{nil, "p"}, {"s", nil}} {
+		d, err := builtinEncode(types.MakeDatums(args...), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(d.IsNull(), IsTrue)
+		d, err = builtinDecode(types.MakeDatums(args...), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(d.IsNull(), IsTrue)
+	}
+}
+
+func (s *testEvaluatorSuite) TestRandomBytes(c *C) {
+	defer testleak.AfterTest(c)()
+	for _, n := range []int64{1, 16, 1024} {
+		d, err := builtinRandomBytes(types.MakeDatums(n), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(d.Kind(), Equals, types.KindBytes)
+		c.Assert(d.GetBytes(), HasLen, int(n))
+	}
+
+	d1, err := builtinRandomBytes(types.MakeDatums(32), s.ctx)
+	c.Assert(err, IsNil)
+	d2, err := builtinRandomBytes(types.MakeDatums(32), s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(d1.GetBytes(), Not(DeepEquals), d2.GetBytes())
+
+	for _, n := range []int64{0, -1, 1025} {
+		_, err = builtinRandomBytes(types.MakeDatums(n), s.ctx)
+		c.Assert(ErrDataOutOfRange.Equal(err), IsTrue)
+	}
+
+	d, err := builtinRandomBytes(types.MakeDatums(nil), s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(d.IsNull(), IsTrue)
+}
+
+func (s *testEvaluatorSuite) TestPassword(c *C) {
+	defer testleak.AfterTest(c)()
+	tbl := []struct {
+		Input  interface{}
+		Expect interface{}
+	}{
+		{"mypass", "*6C8989366EAF75BB670AD8EA7A7FC1176A95CEF4"},
+		{"123", "*23AE809DDACAF96AF0FD78ED04B6A265E05AA257"},
+		{"", ""},
+		{nil, nil},
+	}
+	dtbl := tblToDtbl(tbl)
+	for _, t := range dtbl {
+		d, err := builtinPassword(t["Input"], s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(d, testutil.DatumEquals, t["Expect"][0])
+	}
+}
+
+func (s *testEvaluatorSuite) TestOldPassword(c *C) {
+	defer testleak.AfterTest(c)()
+	tbl := []struct {
+		Input  interface{}
+		Expect interface{}
+	}{
+		{"mypass", "6f8c114b58f2ce9e"},
+		{"my pass", "6f8c114b58f2ce9e"},
+		{"", ""},
+		{nil, nil},
+	}
+	dtbl := tblToDtbl(tbl)
+	for _, t := range dtbl {
+		d, err := builtinOldPassword(t["Input"], s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(d, testutil.DatumEquals, t["Expect"][0])
+	}
+}
@@ -19,8 +19,10 @@ package evaluator
 
 import (
 	"github.com/juju/errors"
+	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/util/charset"
 	"github.com/pingcap/tidb/util/types"
 )
 
@@ -94,3 +96,103 @@ func builtinVersion(args []types.Datum, ctx context.Context) (d types.Datum, err
 	d.SetString(mysql.ServerVersion)
 	return d, nil
 }
+
+// datumCollation returns the collation carried by d. A datum without collation
+// metadata falls back to the default collation of its kind: the connection
+// default for strings, binary for everything else.
+func datumCollation(d types.Datum) *charset.Collation {
+	binID := int(mysql.CollationNames[charset.CollationBin])
+	id := int(d.Collation())
+	if id == 0 {
+		id = binID
+		if d.Kind() == types.KindString {
+			id = mysql.DefaultCollationID
+		}
+	}
+	c, err := charset.GetCollationByID(id)
+	if err != nil {
+		c, _ = charset.GetCollationByID(binID)
+	}
+	return c
+}
+
+// See https://dev.mysql.com/doc/refman/5.7/en/information-functions.html#function_charset
+func builtinCharset(args []types.Datum, _ context.Context) (d types.Datum, err error) {
+	d.SetString(datumCollation(args[0]).CharsetName)
+	return d, nil
+}
+
+// See https://dev.mysql.com/doc/refman/5.7/en/information-functions.html#function_collation
+func builtinCollation(args []types.Datum, _ context.Context) (d types.Datum, err error) {
+	d.SetString(datumCollation(args[0]).Name)
+	return d, nil
+}
+
+// Coercibility values of an expression, from the strongest to the weakest.
+// See https://dev.mysql.com/doc/refman/5.7/en/charset-collation-expressions.html
+const (
+	CoercibilityExplicit  = 0
+	CoercibilityNone      = 1
+	CoercibilityImplicit  = 2
+	CoercibilitySysconst  = 3
+	CoercibilityCoercible = 4
+	CoercibilityIgnorable = 5
+)
+
+// SysconstFuncs are the functions whose results are system constants, of SYSCONST coercibility.
+var SysconstFuncs = map[string]struct{}{
+	ast.Charset:     {},
+	ast.Collation:   {},
+	ast.CurrentUser: {},
+	ast.Database:    {},
+	ast.Schema:      {},
+	ast.User:        {},
+	ast.Version:     {},
+}
+
+// ValueCoercibility returns the coercibility of the literal value d: IGNORABLE for NULL and
+// COERCIBLE otherwise.
+func ValueCoercibility(d types.Datum) int {
+	if d.IsNull() {
+		return CoercibilityIgnorable
+	}
+	return CoercibilityCoercible
+}
+
+// FuncCoercibility returns the coercibility of the result of the function fn from the types of its
+// arguments, argTypes, and their coercibilities, which argCoercibility returns by the position of
+// the argument. It is EXPLICIT for expr COLLATE collation_name, SYSCONST for SysconstFuncs and
+// the strongest coercibility of the string and NULL arguments otherwise, COERCIBLE if there are
+// none. Arguments of other types, like the conditions of CASE, take no part.
+// The plan, on the AST, and expression.Coercibility, on expressions, both resolve it here.
+func FuncCoercibility(fn string, argTypes []*types.FieldType, argCoercibility func(i int) int) int {
+	if fn == ast.SetCollation {
+		return CoercibilityExplicit
+	}
+	if _, ok := SysconstFuncs[fn]; ok {
+		return CoercibilitySysconst
+	}
+	co, hasString := CoercibilityIgnorable, false
+	for i, tp := range argTypes {
+		if tp == nil || (tp.Tp != mysql.TypeNull && !types.IsTypeString(tp.Tp)) {
+			continue
+		}
+		hasString = true
+		if c := argCoercibility(i); c < co {
+			co = c
+		}
+	}
+	if !hasString {
+		return CoercibilityCoercible
+	}
+	return co
+}
+
+// See https://dev.mysql.com/doc/refman/5.7/en/information-functions.html#function_coercibility
+// Only the value of the argument is visible here, so it is treated as a literal.
+// The coercibility of columns and functions is resolved from the expression
+// when the function is built, see expression.NewFunction.
+func builtinCoercibility(args []types.Datum, _ context.Context) (d types.Datum, err error) {
+	d.SetInt64(int64(ValueCoercibility(args[0])))
+	return d, nil
+}
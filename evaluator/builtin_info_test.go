@@ -87,3 +87,78 @@ func (s *testEvaluatorSuite) TestVersion(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(v.GetString(), Equals, mysql.ServerVersion)
 }
+
+func (s *testEvaluatorSuite) TestCharsetAndCollation(c *C) {
+	defer testleak.AfterTest(c)()
+	latin1 := types.NewStringDatum("a")
+	latin1.SetCollation(mysql.CollationNames["latin1_swedish_ci"])
+	tbl := []struct {
+		arg       types.Datum
+		charset   string
+		collation string
+	}{
+		{types.NewStringDatum("abc"), "utf8", "utf8_general_ci"},
+		{types.NewBytesDatum([]byte("abc")), "binary", "binary"},
+		{types.NewIntDatum(1), "binary", "binary"},
+		{types.Datum{}, "binary", "binary"},
+		{latin1, "latin1", "latin1_swedish_ci"},
+	}
+	for _, t := range tbl {
+		d, err := builtinCharset([]types.Datum{t.arg}, s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(d.GetString(), Equals, t.charset)
+		d, err = builtinCollation([]types.Datum{t.arg}, s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(d.GetString(), Equals, t.collation)
+	}
+}
+
+func (s *testEvaluatorSuite) TestCoercibility(c *C) {
+	defer testleak.AfterTest(c)()
+	d, err := builtinCoercibility(types.MakeDatums("abc"), s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(d.GetInt64(), Equals, int64(CoercibilityCoercible))
+	d, err = builtinCoercibility(types.MakeDatums(nil), s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(d.GetInt64(), Equals, int64(CoercibilityIgnorable))
+}
+
+func (s *testEvaluatorSuite) TestSetCollation(c *C) {
+	defer testleak.AfterTest(c)()
+	d, err := builtinSetCollation(types.MakeDatums("a", "UTF8_BIN"), s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(d.Collation(), Equals, mysql.CollationNames["utf8_bin"])
+	_, err = builtinSetCollation(types.MakeDatums("a", "utf8_unknown_ci"), s.ctx)
+	c.Assert(ErrUnknownCollation.Equal(err), IsTrue)
+	// Values other than strings have no collation.
+	d, err = builtinSetCollation(types.MakeDatums(1, "utf8_bin"), s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(d.GetInt64(), Equals, int64(1))
+}
+
+func (s *testEvaluatorSuite) TestFuncCoercibility(c *C) {
+	defer testleak.AfterTest(c)()
+	str := types.NewFieldType(mysql.TypeVarchar)
+	num := types.NewFieldType(mysql.TypeLonglong)
+	null := types.NewFieldType(mysql.TypeNull)
+	tbl := []struct {
+		fn       string
+		argTypes []*types.FieldType
+		args     []int
+		expect   int
+	}{
+		{ast.Concat, []*types.FieldType{str, str}, []int{CoercibilityImplicit, CoercibilityCoercible}, CoercibilityImplicit},
+		{ast.Concat, []*types.FieldType{null}, []int{CoercibilityIgnorable}, CoercibilityIgnorable},
+		{ast.Concat, nil, nil, CoercibilityCoercible},
+		// The index of ELT() or the condition of IF() isn't a string, it takes no part.
+		{ast.Elt, []*types.FieldType{num, str}, []int{CoercibilityImplicit, CoercibilityCoercible}, CoercibilityCoercible},
+		{ast.If, []*types.FieldType{num, str, str}, []int{CoercibilityImplicit, CoercibilityCoercible, CoercibilityCoercible}, CoercibilityCoercible},
+		{ast.Hex, []*types.FieldType{num}, []int{CoercibilityImplicit}, CoercibilityCoercible},
+		{ast.SetCollation, []*types.FieldType{str, str}, []int{CoercibilityImplicit, CoercibilityCoercible}, CoercibilityExplicit},
+		{ast.User, nil, nil, CoercibilitySysconst},
+	}
+	for _, t := range tbl {
+		co := FuncCoercibility(t.fn, t.argTypes, func(i int) int { return t.args[i] })
+		c.Assert(co, Equals, t.expect, Commentf("%s%v", t.fn, t.args))
+	}
+}
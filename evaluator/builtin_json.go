@@ -0,0 +1,731 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package evaluator
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/parser/opcode"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// parseJSON decodes a JSON document. Numbers are kept as json.Number so that
+// integers and doubles can be told apart.
+func parseJSON(s string) (interface{}, error) {
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, ErrInvalidJSONText.GenByArgs(err)
+	}
+	// The whole text must be a single JSON value.
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, ErrInvalidJSONText.GenByArgs("The document root must not be followed by other values.")
+	}
+	return v, nil
+}
+
+// datumToJSON parses the string form of d as a JSON document.
+func datumToJSON(d types.Datum) (interface{}, error) {
+	s, err := d.ToString()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return parseJSON(s)
+}
+
+// jsonPathLeg is one step of a JSON path expression, either a member
+// access like .key or an array access like [1].
+type jsonPathLeg struct {
+	isIndex bool
+	index   int
+	key     string
+}
+
+// parseJSONPath parses a path expression like '$.a[1]."b c"'.
+// Wildcards are not supported.
+func parseJSONPath(path string) ([]jsonPathLeg, error) {
+	s := strings.TrimSpace(path)
+	if len(s) == 0 || s[0] != '$' {
+		return nil, ErrInvalidJSONPath.GenByArgs(path)
+	}
+	var legs []jsonPathLeg
+	i := 1
+	for i < len(s) {
+		switch s[i] {
+		case ' ', '\t':
+			i++
+		case '.':
+			i++
+			for i < len(s) && s[i] == ' ' {
+				i++
+			}
+			if i >= len(s) {
+				return nil, ErrInvalidJSONPath.GenByArgs(path)
+			}
+			if s[i] == '"' {
+				j := i + 1
+				for j < len(s) && s[j] != '"' {
+					if s[j] == '\\' {
+						j++
+					}
+					j++
+				}
+				if j >= len(s) {
+					return nil, ErrInvalidJSONPath.GenByArgs(path)
+				}
+				key, err := strconv.Unquote(s[i : j+1])
+				if err != nil {
+					return nil, ErrInvalidJSONPath.GenByArgs(path)
+				}
+				legs = append(legs, jsonPathLeg{key: key})
+				i = j + 1
+				continue
+			}
+			j := i
+			for j < len(s) && s[j] != '.' && s[j] != '[' && s[j] != ' ' {
+				j++
+			}
+			if j == i || s[i:j] == "*" {
+				return nil, ErrInvalidJSONPath.GenByArgs(path)
+			}
+			legs = append(legs, jsonPathLeg{key: s[i:j]})
+			i = j
+		case '[':
+			j := strings.IndexByte(s[i:], ']')
+			if j == -1 {
+				return nil, ErrInvalidJSONPath.GenByArgs(path)
+			}
+			idx, err := strconv.Atoi(strings.TrimSpace(s[i+1 : i+j]))
+			if err != nil || idx < 0 {
+				return nil, ErrInvalidJSONPath.GenByArgs(path)
+			}
+			legs = append(legs, jsonPathLeg{isIndex: true, index: idx})
+			i += j + 1
+		default:
+			return nil, ErrInvalidJSONPath.GenByArgs(path)
+		}
+	}
+	return legs, nil
+}
+
+// datumToJSONPath parses the string form of d as a JSON path expression.
+func datumToJSONPath(d types.Datum) ([]jsonPathLeg, error) {
+	s, err := d.ToString()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return parseJSONPath(s)
+}
+
+// jsonModify replaces the value located by legs in v with fn(value) and
+// returns the modified document. v is left untouched if the path does not exist.
+// As in MySQL, [0] on a non-array value refers to the value itself.
+func jsonModify(v interface{}, legs []jsonPathLeg, fn func(interface{}) interface{}) interface{} {
+	if len(legs) == 0 {
+		return fn(v)
+	}
+	leg := legs[0]
+	if leg.isIndex {
+		arr, ok := v.([]interface{})
+		if !ok {
+			if leg.index == 0 {
+				return jsonModify(v, legs[1:], fn)
+			}
+			return v
+		}
+		if leg.index < len(arr) {
+			arr[leg.index] = jsonModify(arr[leg.index], legs[1:], fn)
+		}
+		return arr
+	}
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return v
+	}
+	if child, ok := obj[leg.key]; ok {
+		obj[leg.key] = jsonModify(child, legs[1:], fn)
+	}
+	return obj
+}
+
+// jsonExtract returns the value located by legs in v.
+func jsonExtract(v interface{}, legs []jsonPathLeg) (interface{}, bool) {
+	for _, leg := range legs {
+		if leg.isIndex {
+			arr, ok := v.([]interface{})
+			if !ok {
+				if leg.index == 0 {
+					continue
+				}
+				return nil, false
+			}
+			if leg.index >= len(arr) {
+				return nil, false
+			}
+			v = arr[leg.index]
+			continue
+		}
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if v, ok = obj[leg.key]; !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// datumToJSONScalar converts a SQL value to the JSON value it stands for when
+// used as an argument of the JSON modification functions. Strings are not parsed.
+func datumToJSONScalar(d types.Datum) (interface{}, error) {
+	switch d.Kind() {
+	case types.KindNull:
+		return nil, nil
+	case types.KindInt64, types.KindUint64, types.KindFloat32, types.KindFloat64, types.KindMysqlDecimal:
+		s, err := d.ToString()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		return json.Number(s), nil
+	default:
+		s, err := d.ToString()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		return s, nil
+	}
+}
+
+// jsonKeys orders object keys the way MySQL stores them: shorter keys first,
+// then by byte order.
+type jsonKeys []string
+
+func (ks jsonKeys) Len() int      { return len(ks) }
+func (ks jsonKeys) Swap(i, j int) { ks[i], ks[j] = ks[j], ks[i] }
+func (ks jsonKeys) Less(i, j int) bool {
+	if len(ks[i]) != len(ks[j]) {
+		return len(ks[i]) < len(ks[j])
+	}
+	return ks[i] < ks[j]
+}
+
+// jsonToString serializes a decoded JSON value in the format MySQL uses for output.
+func jsonToString(v interface{}) string {
+	var buf bytes.Buffer
+	writeJSON(&buf, v)
+	return buf.String()
+}
+
+func writeJSON(buf *bytes.Buffer, v interface{}) {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		keys := make(jsonKeys, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Sort(keys)
+		buf.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			writeJSONString(buf, k)
+			buf.WriteString(": ")
+			writeJSON(buf, x[k])
+		}
+		buf.WriteByte('}')
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, elem := range x {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			writeJSON(buf, elem)
+		}
+		buf.WriteByte(']')
+	case string:
+		writeJSONString(buf, x)
+	case json.Number:
+		buf.WriteString(string(x))
+	case bool:
+		buf.WriteString(strconv.FormatBool(x))
+	default:
+		buf.WriteString("null")
+	}
+}
+
+func writeJSONString(buf *bytes.Buffer, s string) {
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	// Encode always appends a newline.
+	buf.Truncate(buf.Len() - 1)
+}
+
+// jsonIsInteger reports whether a JSON number has no fractional or exponent part.
+func jsonIsInteger(n json.Number) bool {
+	return !strings.ContainsAny(string(n), ".eE")
+}
+
+// jsonTypeName returns the MySQL type name of a decoded JSON value.
+func jsonTypeName(v interface{}) string {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		return "OBJECT"
+	case []interface{}:
+		return "ARRAY"
+	case string:
+		return "STRING"
+	case json.Number:
+		if jsonIsInteger(x) {
+			return "INTEGER"
+		}
+		return "DOUBLE"
+	case bool:
+		return "BOOLEAN"
+	default:
+		return "NULL"
+	}
+}
+
+// See https://dev.mysql.com/doc/refman/5.7/en/json-attribute-functions.html#function_json-type
+func builtinJSONType(args []types.Datum, _ context.Context) (d types.Datum, err error) {
+	if args[0].IsNull() {
+		return d, nil
+	}
+	v, err := datumToJSON(args[0])
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	d.SetString(jsonTypeName(v))
+	return d, nil
+}
+
+// See https://dev.mysql.com/doc/refman/5.7/en/json-attribute-functions.html#function_json-valid
+func builtinJSONValid(args []types.Datum, _ context.Context) (d types.Datum, err error) {
+	if args[0].IsNull() {
+		return d, nil
+	}
+	s, err := args[0].ToString()
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	_, err = parseJSON(s)
+	d.SetInt64(boolToInt64(err == nil))
+	return d, nil
+}
+
+// jsonModifyPairs applies fn to the document in args[0] for each (path, value)
+// pair in args[1:], from left to right.
+func jsonModifyPairs(args []types.Datum, funcName string,
+	fn func(doc interface{}, legs []jsonPathLeg, value interface{}) (interface{}, error)) (d types.Datum, err error) {
+	if len(args)%2 != 1 {
+		return d, ErrIncorrectParameterCount.GenByArgs(funcName)
+	}
+	if args[0].IsNull() {
+		return d, nil
+	}
+	doc, err := datumToJSON(args[0])
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	for i := 1; i < len(args); i += 2 {
+		if args[i].IsNull() {
+			return d, nil
+		}
+		legs, err := datumToJSONPath(args[i])
+		if err != nil {
+			return d, errors.Trace(err)
+		}
+		value, err := datumToJSONScalar(args[i+1])
+		if err != nil {
+			return d, errors.Trace(err)
+		}
+		doc, err = fn(doc, legs, value)
+		if err != nil {
+			return d, errors.Trace(err)
+		}
+	}
+	d.SetString(jsonToString(doc))
+	return d, nil
+}
+
+// See https://dev.mysql.com/doc/refman/5.7/en/json-modification-functions.html#function_json-array-append
+func builtinJSONArrayAppend(args []types.Datum, _ context.Context) (d types.Datum, err error) {
+	return jsonModifyPairs(args, ast.JSONArrayAppend, func(doc interface{}, legs []jsonPathLeg, value interface{}) (interface{}, error) {
+		return jsonModify(doc, legs, func(old interface{}) interface{} {
+			// A scalar or object target is autowrapped into an array first.
+			if arr, ok := old.([]interface{}); ok {
+				return append(arr, value)
+			}
+			return []interface{}{old, value}
+		}), nil
+	})
+}
+
+// See https://dev.mysql.com/doc/refman/5.7/en/json-modification-functions.html#function_json-array-insert
+func builtinJSONArrayInsert(args []types.Datum, _ context.Context) (d types.Datum, err error) {
+	return jsonModifyPairs(args, ast.JSONArrayInsert, func(doc interface{}, legs []jsonPathLeg, value interface{}) (interface{}, error) {
+		if len(legs) == 0 || !legs[len(legs)-1].isIndex {
+			return nil, ErrInvalidJSONPath.Gen("A path expression is not a path to a cell in an array.")
+		}
+		pos := legs[len(legs)-1].index
+		return jsonModify(doc, legs[:len(legs)-1], func(old interface{}) interface{} {
+			arr, ok := old.([]interface{})
+			if !ok {
+				return old
+			}
+			// An index past the end of the array appends the value.
+			if pos >= len(arr) {
+				return append(arr, value)
+			}
+			arr = append(arr, nil)
+			copy(arr[pos+1:], arr[pos:])
+			arr[pos] = value
+			return arr
+		}), nil
+	})
+}
+
+// jsonMergePreserve merges b into a. Arrays are concatenated, objects are
+// merged with the values of duplicate keys merged recursively, and any other
+// value is autowrapped into an array before concatenating.
+func jsonMergePreserve(a, b interface{}) interface{} {
+	objA, okA := a.(map[string]interface{})
+	objB, okB := b.(map[string]interface{})
+	if okA && okB {
+		for k, vb := range objB {
+			if va, ok := objA[k]; ok {
+				objA[k] = jsonMergePreserve(va, vb)
+			} else {
+				objA[k] = vb
+			}
+		}
+		return objA
+	}
+	arrA, ok := a.([]interface{})
+	if !ok {
+		arrA = []interface{}{a}
+	}
+	if arrB, ok := b.([]interface{}); ok {
+		return append(arrA, arrB...)
+	}
+	return append(arrA, b)
+}
+
+// See https://dev.mysql.com/doc/refman/5.7/en/json-modification-functions.html#function_json-merge
+func builtinJSONMerge(args []types.Datum, _ context.Context) (d types.Datum, err error) {
+	docs := make([]interface{}, 0, len(args))
+	for _, arg := range args {
+		if arg.IsNull() {
+			return d, nil
+		}
+		doc, err := datumToJSON(arg)
+		if err != nil {
+			return d, errors.Trace(err)
+		}
+		docs = append(docs, doc)
+	}
+	merged := docs[0]
+	for _, doc := range docs[1:] {
+		merged = jsonMergePreserve(merged, doc)
+	}
+	d.SetString(jsonToString(merged))
+	return d, nil
+}
+
+// jsonScalarEqual compares two JSON scalars. Numbers are compared by value.
+func jsonScalarEqual(a, b interface{}) bool {
+	switch x := a.(type) {
+	case json.Number:
+		y, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		if x == y {
+			return true
+		}
+		fx, errx := x.Float64()
+		fy, erry := y.Float64()
+		return errx == nil && erry == nil && fx == fy
+	case string:
+		y, ok := b.(string)
+		return ok && x == y
+	case bool:
+		y, ok := b.(bool)
+		return ok && x == y
+	case nil:
+		return b == nil
+	}
+	return false
+}
+
+// jsonContains reports whether candidate is contained in target, following
+// the rules of JSON_CONTAINS().
+func jsonContains(target, candidate interface{}) bool {
+	switch x := target.(type) {
+	case []interface{}:
+		if y, ok := candidate.([]interface{}); ok {
+			for _, c := range y {
+				if !jsonContains(x, c) {
+					return false
+				}
+			}
+			return true
+		}
+		for _, t := range x {
+			if jsonContains(t, candidate) {
+				return true
+			}
+		}
+		return false
+	case map[string]interface{}:
+		y, ok := candidate.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for k, c := range y {
+			t, ok := x[k]
+			if !ok || !jsonContains(t, c) {
+				return false
+			}
+		}
+		return true
+	}
+	switch candidate.(type) {
+	case []interface{}, map[string]interface{}:
+		return false
+	}
+	return jsonScalarEqual(target, candidate)
+}
+
+// See https://dev.mysql.com/doc/refman/5.7/en/json-search-functions.html#function_json-contains
+func builtinJSONContains(args []types.Datum, _ context.Context) (d types.Datum, err error) {
+	for _, arg := range args {
+		if arg.IsNull() {
+			return d, nil
+		}
+	}
+	target, err := datumToJSON(args[0])
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	candidate, err := datumToJSON(args[1])
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	if len(args) == 3 {
+		legs, err := datumToJSONPath(args[2])
+		if err != nil {
+			return d, errors.Trace(err)
+		}
+		var ok bool
+		if target, ok = jsonExtract(target, legs); !ok {
+			return d, nil
+		}
+	}
+	d.SetInt64(boolToInt64(jsonContains(target, candidate)))
+	return d, nil
+}
+
+// See https://dev.mysql.com/doc/refman/5.7/en/json-search-functions.html#function_json-contains-path
+func builtinJSONContainsPath(args []types.Datum, _ context.Context) (d types.Datum, err error) {
+	for _, arg := range args {
+		if arg.IsNull() {
+			return d, nil
+		}
+	}
+	doc, err := datumToJSON(args[0])
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	mode, err := args[1].ToString()
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	var all bool
+	switch strings.ToLower(mode) {
+	case "one":
+	case "all":
+		all = true
+	default:
+		return d, ErrInvalidJSONContainsPathType
+	}
+	// For 'one' we look for any existing path, for 'all' for any missing one.
+	found := all
+	for _, arg := range args[2:] {
+		legs, err := datumToJSONPath(arg)
+		if err != nil {
+			return d, errors.Trace(err)
+		}
+		if _, ok := jsonExtract(doc, legs); ok != all {
+			found = ok
+			break
+		}
+	}
+	d.SetInt64(boolToInt64(found))
+	return d, nil
+}
+
+// builtinCastJSON implements CAST(... AS JSON): strings are parsed as JSON documents, other values
+// become the JSON scalars they stand for.
+// See https://dev.mysql.com/doc/refman/5.7/en/json-creation-functions.html
+func builtinCastJSON(args []types.Datum, _ context.Context) (d types.Datum, err error) {
+	var v interface{}
+	switch args[0].Kind() {
+	case types.KindNull:
+		return d, nil
+	case types.KindString, types.KindBytes:
+		v, err = parseJSON(args[0].GetString())
+	default:
+		v, err = datumToJSONScalar(args[0])
+	}
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	d.SetString(jsonToString(v))
+	return d, nil
+}
+
+// jsonTypePrecedence ranks the type of a decoded JSON value the way MySQL orders values of
+// different types, from NULL, numbers, strings and objects up to arrays and booleans.
+// See https://dev.mysql.com/doc/refman/5.7/en/json.html#json-comparison
+func jsonTypePrecedence(v interface{}) int {
+	switch v.(type) {
+	case json.Number:
+		return 1
+	case string:
+		return 2
+	case map[string]interface{}:
+		return 3
+	case []interface{}:
+		return 4
+	case bool:
+		return 5
+	default:
+		return 0
+	}
+}
+
+// compareJSONNumber compares two JSON numbers, integers exactly and others as doubles.
+func compareJSONNumber(x, y json.Number) int {
+	if jsonIsInteger(x) && jsonIsInteger(y) {
+		a, erra := strconv.ParseInt(string(x), 10, 64)
+		b, errb := strconv.ParseInt(string(y), 10, 64)
+		if erra == nil && errb == nil {
+			return types.CompareInt64(a, b)
+		}
+	}
+	a, _ := x.Float64()
+	b, _ := y.Float64()
+	return types.CompareFloat64(a, b)
+}
+
+// compareJSON compares two decoded JSON values. Values of different types are ordered by the
+// precedence of their types. Numbers compare by value, strings by bytes, arrays element by
+// element and false is less than true. MySQL only defines equality of objects, they are
+// ordered by their serialized forms here.
+func compareJSON(a, b interface{}) int {
+	pa, pb := jsonTypePrecedence(a), jsonTypePrecedence(b)
+	if pa != pb {
+		return types.CompareInt64(int64(pa), int64(pb))
+	}
+	switch x := a.(type) {
+	case json.Number:
+		return compareJSONNumber(x, b.(json.Number))
+	case string:
+		return types.CompareString(x, b.(string))
+	case map[string]interface{}:
+		return types.CompareString(jsonToString(x), jsonToString(b))
+	case []interface{}:
+		y := b.([]interface{})
+		for i := 0; i < len(x) && i < len(y); i++ {
+			if n := compareJSON(x[i], y[i]); n != 0 {
+				return n
+			}
+		}
+		return types.CompareInt64(int64(len(x)), int64(len(y)))
+	case bool:
+		return types.CompareInt64(boolToInt64(x), boolToInt64(b.(bool)))
+	}
+	return 0
+}
+
+// CompareJSON compares two JSON documents in the order MySQL sorts JSON values. SQL NULL is
+// less than any document.
+func CompareJSON(a, b types.Datum) (int, error) {
+	if a.IsNull() || b.IsNull() {
+		return a.CompareDatum(nil, b)
+	}
+	x, err := datumToJSON(a)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	y, err := datumToJSON(b)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	return compareJSON(x, y), nil
+}
+
+var jsonCompareOps = map[string]opcode.Op{
+	ast.LT:     opcode.LT,
+	ast.LE:     opcode.LE,
+	ast.EQ:     opcode.EQ,
+	ast.NullEQ: opcode.NullEQ,
+	ast.GT:     opcode.GT,
+	ast.GE:     opcode.GE,
+	ast.NE:     opcode.NE,
+}
+
+// JSONCompareFuncFactory returns the handler of the comparison funcName, like ast.LT, of which the
+// arguments that isJSON tells are JSON documents. Other arguments are compared as the JSON scalars
+// they stand for. It returns false if funcName is not a comparison.
+func JSONCompareFuncFactory(funcName string, isJSON []bool) (BuiltinFunc, bool) {
+	op, ok := jsonCompareOps[funcName]
+	if !ok {
+		return nil, false
+	}
+	return func(args []types.Datum, _ context.Context) (d types.Datum, err error) {
+		a, b := args[0], args[1]
+		if a.IsNull() || b.IsNull() {
+			if op == opcode.NullEQ {
+				d.SetInt64(boolToInt64(a.IsNull() && b.IsNull()))
+			}
+			return d, nil
+		}
+		values := make([]interface{}, len(args))
+		for i, arg := range args {
+			if isJSON[i] {
+				values[i], err = datumToJSON(arg)
+			} else {
+				values[i], err = datumToJSONScalar(arg)
+			}
+			if err != nil {
+				return d, errors.Trace(err)
+			}
+		}
+		return compareResult(op, compareJSON(values[0], values[1]))
+	}, true
+}
@@ -0,0 +1,360 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package evaluator
+
+import (
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/util/testleak"
+	"github.com/pingcap/tidb/util/testutil"
+	"github.com/pingcap/tidb/util/types"
+)
+
+func (s *testEvaluatorSuite) TestJSONType(c *C) {
+	defer testleak.AfterTest(c)()
+	tbl := []struct {
+		Input  interface{}
+		Expect interface{}
+	}{
+		{nil, nil},
+		{`{"a": 1}`, "OBJECT"},
+		{`{}`, "OBJECT"},
+		{`[1, 2, "3"]`, "ARRAY"},
+		{`"abc"`, "STRING"},
+		{`3`, "INTEGER"},
+		{`-3`, "INTEGER"},
+		{`3.14`, "DOUBLE"},
+		{`1e3`, "DOUBLE"},
+		{`true`, "BOOLEAN"},
+		{`false`, "BOOLEAN"},
+		{`null`, "NULL"},
+		{` [ ] `, "ARRAY"},
+	}
+	dtbl := tblToDtbl(tbl)
+	for _, t := range dtbl {
+		d, err := builtinJSONType(t["Input"], s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(d, testutil.DatumEquals, t["Expect"][0])
+	}
+
+	for _, str := range []string{"", "abc", "{", `{"a" 1}`, "[1, 2", "1 2"} {
+		_, err := builtinJSONType(types.MakeDatums(str), s.ctx)
+		c.Assert(err, NotNil)
+		c.Assert(ErrInvalidJSONText.Equal(err), IsTrue)
+	}
+}
+
+func (s *testEvaluatorSuite) TestJSONValid(c *C) {
+	defer testleak.AfterTest(c)()
+	tbl := []struct {
+		Input  interface{}
+		Expect interface{}
+	}{
+		{nil, nil},
+		{`{"a": [1, {"b": null}]}`, 1},
+		{`[]`, 1},
+		{`"abc"`, 1},
+		{`-12.5e-3`, 1},
+		{`true`, 1},
+		{`null`, 1},
+		{``, 0},
+		{`abc`, 0},
+		{`{"a": 1,}`, 0},
+		{`[1, 2`, 0},
+		{`{'a': 1}`, 0},
+		{`1 2`, 0},
+		{`{"a": 1} x`, 0},
+	}
+	dtbl := tblToDtbl(tbl)
+	for _, t := range dtbl {
+		f := Funcs[ast.JSONValid]
+		d, err := f.F(t["Input"], s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(d, testutil.DatumEquals, t["Expect"][0])
+	}
+}
+
+func (s *testEvaluatorSuite) TestJSONArrayAppend(c *C) {
+	defer testleak.AfterTest(c)()
+	tbl := []struct {
+		Input  []interface{}
+		Expect interface{}
+	}{
+		{[]interface{}{`["a", ["b", "c"], "d"]`, "$[1]", 1}, `["a", ["b", "c", 1], "d"]`},
+		{[]interface{}{`["a", ["b", "c"], "d"]`, "$[0]", 2}, `[["a", 2], ["b", "c"], "d"]`},
+		{[]interface{}{`["a", ["b", "c"], "d"]`, "$[1][0]", 3}, `["a", [["b", 3], "c"], "d"]`},
+		{[]interface{}{`{"a": 1, "b": [2, 3], "c": 4}`, "$.b", "x"}, `{"a": 1, "b": [2, 3, "x"], "c": 4}`},
+		{[]interface{}{`{"a": 1, "b": [2, 3], "c": 4}`, "$.c", "y"}, `{"a": 1, "b": [2, 3], "c": [4, "y"]}`},
+		{[]interface{}{`{"a": 1}`, "$", "z"}, `[{"a": 1}, "z"]`},
+		{[]interface{}{`1`, "$", 2}, `[1, 2]`},
+		{[]interface{}{`{"a": 1}`, "$.b", 2}, `{"a": 1}`},
+		{[]interface{}{`[1]`, "$[5]", 2}, `[1]`},
+		{[]interface{}{`{"a": []}`, "$.a", 1, "$.a", 2}, `{"a": [1, 2]}`},
+		{[]interface{}{`{"a": []}`, "$.a", nil}, `{"a": [null]}`},
+		{[]interface{}{nil, "$", 1}, nil},
+		{[]interface{}{`[1]`, nil, 1}, nil},
+	}
+	dtbl := tblToDtbl(tbl)
+	for _, t := range dtbl {
+		d, err := builtinJSONArrayAppend(t["Input"], s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(d, testutil.DatumEquals, t["Expect"][0])
+	}
+
+	_, err := builtinJSONArrayAppend(types.MakeDatums(`[1]`, "$", 1, "$"), s.ctx)
+	c.Assert(ErrIncorrectParameterCount.Equal(err), IsTrue)
+	_, err = builtinJSONArrayAppend(types.MakeDatums(`[1]`, "a", 1), s.ctx)
+	c.Assert(ErrInvalidJSONPath.Equal(err), IsTrue)
+	_, err = builtinJSONArrayAppend(types.MakeDatums(`[1`, "$", 1), s.ctx)
+	c.Assert(ErrInvalidJSONText.Equal(err), IsTrue)
+}
+
+func (s *testEvaluatorSuite) TestJSONArrayInsert(c *C) {
+	defer testleak.AfterTest(c)()
+	tbl := []struct {
+		Input  []interface{}
+		Expect interface{}
+	}{
+		{[]interface{}{`["a", {"b": [1, 2]}, [3, 4]]`, "$[1]", "x"}, `["a", "x", {"b": [1, 2]}, [3, 4]]`},
+		{[]interface{}{`["a", {"b": [1, 2]}, [3, 4]]`, "$[0]", "x"}, `["x", "a", {"b": [1, 2]}, [3, 4]]`},
+		{[]interface{}{`["a", {"b": [1, 2]}, [3, 4]]`, "$[100]", "x"}, `["a", {"b": [1, 2]}, [3, 4], "x"]`},
+		{[]interface{}{`["a", {"b": [1, 2]}, [3, 4]]`, "$[1].b[0]", "x"}, `["a", {"b": ["x", 1, 2]}, [3, 4]]`},
+		{[]interface{}{`["a", {"b": [1, 2]}, [3, 4]]`, "$[2][1]", "y"}, `["a", {"b": [1, 2]}, [3, "y", 4]]`},
+		{[]interface{}{`["a", {"b": [1, 2]}, [3, 4]]`, "$[0]", "x", "$[2][1]", "y"}, `["x", "a", {"b": [1, 2]}, [3, 4]]`},
+		{[]interface{}{`{"a": 1}`, "$.a[0]", 2}, `{"a": 1}`},
+		{[]interface{}{`[]`, "$[0]", 1}, `[1]`},
+		{[]interface{}{nil, "$[0]", 1}, nil},
+	}
+	dtbl := tblToDtbl(tbl)
+	for _, t := range dtbl {
+		d, err := builtinJSONArrayInsert(t["Input"], s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(d, testutil.DatumEquals, t["Expect"][0])
+	}
+
+	_, err := builtinJSONArrayInsert(types.MakeDatums(`[1]`, "$[0]"), s.ctx)
+	c.Assert(ErrIncorrectParameterCount.Equal(err), IsTrue)
+	_, err = builtinJSONArrayInsert(types.MakeDatums(`{"a": 1}`, "$.a", 1), s.ctx)
+	c.Assert(ErrInvalidJSONPath.Equal(err), IsTrue)
+	_, err = builtinJSONArrayInsert(types.MakeDatums(`[1]`, "$", 1), s.ctx)
+	c.Assert(ErrInvalidJSONPath.Equal(err), IsTrue)
+}
+
+func (s *testEvaluatorSuite) TestJSONMerge(c *C) {
+	defer testleak.AfterTest(c)()
+	tbl := []struct {
+		Input  []interface{}
+		Expect interface{}
+	}{
+		{[]interface{}{`{"a": 1, "b": 2}`, `{"c": 3}`}, `{"a": 1, "b": 2, "c": 3}`},
+		{[]interface{}{`{"a": 1, "b": 2}`, `{"c": 3, "a": 4}`}, `{"a": [1, 4], "b": 2, "c": 3}`},
+		{[]interface{}{`{"a": [1]}`, `{"a": {"b": 2}}`}, `{"a": [1, {"b": 2}]}`},
+		{[]interface{}{`{"a": {"b": 1}}`, `{"a": {"c": 2}}`}, `{"a": {"b": 1, "c": 2}}`},
+		{[]interface{}{`[1, 2]`, `[3]`}, `[1, 2, 3]`},
+		{[]interface{}{`[1, 2]`, `true`}, `[1, 2, true]`},
+		{[]interface{}{`"a"`, `[1, 2]`}, `["a", 1, 2]`},
+		{[]interface{}{`1`, `2`}, `[1, 2]`},
+		{[]interface{}{`{"a": 1}`, `[2]`}, `[{"a": 1}, 2]`},
+		{[]interface{}{`[1]`, `{"a": 1}`, `"x"`}, `[1, {"a": 1}, "x"]`},
+		{[]interface{}{`{"a": 1}`, `{"a": 2}`, `{"a": 3}`}, `{"a": [1, 2, 3]}`},
+		{[]interface{}{`null`, `1`}, `[null, 1]`},
+		{[]interface{}{`[1]`, nil}, nil},
+		{[]interface{}{nil, `[1]`, `[2]`}, nil},
+	}
+	dtbl := tblToDtbl(tbl)
+	for _, t := range dtbl {
+		for _, name := range []string{ast.JSONMerge, ast.JSONMergePreserve} {
+			d, err := Funcs[name].F(t["Input"], s.ctx)
+			c.Assert(err, IsNil)
+			c.Assert(d, testutil.DatumEquals, t["Expect"][0])
+		}
+	}
+
+	_, err := builtinJSONMerge(types.MakeDatums(`[1]`, `[2`), s.ctx)
+	c.Assert(ErrInvalidJSONText.Equal(err), IsTrue)
+}
+
+func (s *testEvaluatorSuite) TestJSONContains(c *C) {
+	defer testleak.AfterTest(c)()
+	tbl := []struct {
+		Input  []interface{}
+		Expect interface{}
+	}{
+		// scalar containment
+		{[]interface{}{`1`, `1`}, 1},
+		{[]interface{}{`1`, `1.0`}, 1},
+		{[]interface{}{`1`, `2`}, 0},
+		{[]interface{}{`"a"`, `"a"`}, 1},
+		{[]interface{}{`"1"`, `1`}, 0},
+		{[]interface{}{`null`, `null`}, 1},
+		{[]interface{}{`1`, `[1]`}, 0},
+		// array subset containment
+		{[]interface{}{`[1, 2, 3]`, `2`}, 1},
+		{[]interface{}{`[1, 2, 3]`, `[3, 1]`}, 1},
+		{[]interface{}{`[1, 2, 3]`, `[1, 4]`}, 0},
+		{[]interface{}{`[1, [2, 3]]`, `[3]`}, 1},
+		{[]interface{}{`[1, 2]`, `[]`}, 1},
+		// object containment
+		{[]interface{}{`{"a": 1, "b": {"c": 2}}`, `{"b": {"c": 2}}`}, 1},
+		{[]interface{}{`{"a": 1, "b": {"c": 2}}`, `{"a": 2}`}, 0},
+		{[]interface{}{`{"a": 1}`, `1`}, 0},
+		// with path
+		{[]interface{}{`{"a": 1, "b": [1, 2]}`, `1`, `$.a`}, 1},
+		{[]interface{}{`{"a": 1, "b": [1, 2]}`, `2`, `$.b`}, 1},
+		{[]interface{}{`{"a": 1, "b": [1, 2]}`, `2`, `$.a`}, 0},
+		{[]interface{}{`{"a": 1}`, `1`, `$.c`}, nil},
+		// NULL propagates
+		{[]interface{}{nil, `1`}, nil},
+		{[]interface{}{`1`, nil}, nil},
+		{[]interface{}{`[1]`, `1`, nil}, nil},
+	}
+	dtbl := tblToDtbl(tbl)
+	for _, t := range dtbl {
+		d, err := builtinJSONContains(t["Input"], s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(d, testutil.DatumEquals, t["Expect"][0])
+	}
+
+	_, err := builtinJSONContains(types.MakeDatums(`[1`, `1`), s.ctx)
+	c.Assert(ErrInvalidJSONText.Equal(err), IsTrue)
+	_, err = builtinJSONContains(types.MakeDatums(`[1]`, `x`), s.ctx)
+	c.Assert(ErrInvalidJSONText.Equal(err), IsTrue)
+}
+
+func (s *testEvaluatorSuite) TestJSONContainsPath(c *C) {
+	defer testleak.AfterTest(c)()
+	tbl := []struct {
+		Input  []interface{}
+		Expect interface{}
+	}{
+		{[]interface{}{`{"a": 1, "b": 2, "c": {"d": 4}}`, "one", "$.a", "$.e"}, 1},
+		{[]interface{}{`{"a": 1, "b": 2, "c": {"d": 4}}`, "all", "$.a", "$.e"}, 0},
+		{[]interface{}{`{"a": 1, "b": 2, "c": {"d": 4}}`, "ALL", "$.a", "$.c.d"}, 1},
+		{[]interface{}{`{"a": 1, "b": 2, "c": {"d": 4}}`, "one", "$.c.e", "$.e"}, 0},
+		{[]interface{}{`[1, [2, 3]]`, "one", "$[1][0]"}, 1},
+		{[]interface{}{`[1, [2, 3]]`, "all", "$[2]"}, 0},
+		{[]interface{}{nil, "one", "$"}, nil},
+		{[]interface{}{`[1]`, "one", nil}, nil},
+	}
+	dtbl := tblToDtbl(tbl)
+	for _, t := range dtbl {
+		d, err := builtinJSONContainsPath(t["Input"], s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(d, testutil.DatumEquals, t["Expect"][0])
+	}
+
+	_, err := builtinJSONContainsPath(types.MakeDatums(`[1]`, "some", "$"), s.ctx)
+	c.Assert(ErrInvalidJSONContainsPathType.Equal(err), IsTrue)
+	_, err = builtinJSONContainsPath(types.MakeDatums(`[1`, "one", "$"), s.ctx)
+	c.Assert(ErrInvalidJSONText.Equal(err), IsTrue)
+	_, err = builtinJSONContainsPath(types.MakeDatums(`[1]`, "one", "a"), s.ctx)
+	c.Assert(ErrInvalidJSONPath.Equal(err), IsTrue)
+}
+
+func (s *testEvaluatorSuite) TestCastJSON(c *C) {
+	defer testleak.AfterTest(c)()
+	f, err := CastFuncFactory(types.NewFieldType(mysql.TypeJSON))
+	c.Assert(err, IsNil)
+	tbl := []struct {
+		input  interface{}
+		expect interface{}
+	}{
+		{nil, nil},
+		{`{"b": [1, 2.5], "a": null}`, `{"a": null, "b": [1, 2.5]}`},
+		{" [true, false] ", "[true, false]"},
+		{`"abc"`, `"abc"`},
+		{"1", "1"},
+		{int64(-3), "-3"},
+		{uint64(18446744073709551615), "18446744073709551615"},
+		{1.5, "1.5"},
+		{types.NewDecFromStringForTest("1.20"), "1.20"},
+	}
+	for _, t := range tbl {
+		d, err := f(types.MakeDatums(t.input), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(d, testutil.DatumEquals, types.NewDatum(t.expect))
+	}
+	for _, input := range []string{"abc", "", "{", "[1] [2]"} {
+		_, err = f(types.MakeDatums(input), s.ctx)
+		c.Assert(ErrInvalidJSONText.Equal(err), IsTrue, Commentf("%s", input))
+	}
+}
+
+func (s *testEvaluatorSuite) TestCompareJSON(c *C) {
+	defer testleak.AfterTest(c)()
+	// In ascending order, with equal values next to each other.
+	docs := []string{
+		"null", "null",
+		"-1", "1", "1.0", "1.5", "10",
+		`""`, `"B"`, `"a"`, `"ab"`,
+		`{"a": 1}`, `{"b": 0}`,
+		"[]", "[1]", "[1, 2]", "[2]", `["a"]`,
+		"false", "true",
+	}
+	equal := map[string]string{"null": "null", "1": "1.0"}
+	for i, a := range docs {
+		for j, b := range docs {
+			n, err := CompareJSON(types.NewStringDatum(a), types.NewStringDatum(b))
+			c.Assert(err, IsNil)
+			switch {
+			case a == b || equal[a] == b || equal[b] == a:
+				c.Assert(n, Equals, 0, Commentf("%s %s", a, b))
+			case i < j:
+				c.Assert(n, Equals, -1, Commentf("%s %s", a, b))
+			default:
+				c.Assert(n, Equals, 1, Commentf("%s %s", a, b))
+			}
+		}
+	}
+
+	n, err := CompareJSON(types.Datum{}, types.NewStringDatum("null"))
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, -1)
+	_, err = CompareJSON(types.NewStringDatum("{"), types.NewStringDatum("null"))
+	c.Assert(err, NotNil)
+}
+
+func (s *testEvaluatorSuite) TestJSONCompareFunc(c *C) {
+	defer testleak.AfterTest(c)()
+	_, ok := JSONCompareFuncFactory(ast.Plus, []bool{true, true})
+	c.Assert(ok, IsFalse)
+
+	tbl := []struct {
+		funcName string
+		isJSON   []bool
+		args     []interface{}
+		expect   interface{}
+	}{
+		{ast.EQ, []bool{true, true}, []interface{}{"[1, 2]", "[1,2]"}, int64(1)},
+		{ast.LT, []bool{true, true}, []interface{}{`"z"`, "{}"}, int64(1)},
+		{ast.GT, []bool{true, true}, []interface{}{"true", "[1]"}, int64(1)},
+		// The other argument is a JSON scalar, the string is not parsed.
+		{ast.EQ, []bool{true, false}, []interface{}{`"abc"`, "abc"}, int64(1)},
+		{ast.EQ, []bool{false, true}, []interface{}{"1", "1"}, int64(0)},
+		{ast.EQ, []bool{true, false}, []interface{}{"1.0", 1}, int64(1)},
+		{ast.GE, []bool{true, false}, []interface{}{"[]", 100}, int64(1)},
+		{ast.NE, []bool{true, false}, []interface{}{"2", 1.5}, int64(1)},
+		{ast.LE, []bool{true, true}, []interface{}{nil, "1"}, nil},
+		{ast.NullEQ, []bool{true, true}, []interface{}{nil, "1"}, int64(0)},
+		{ast.NullEQ, []bool{true, true}, []interface{}{nil, nil}, int64(1)},
+	}
+	for _, t := range tbl {
+		f, ok := JSONCompareFuncFactory(t.funcName, t.isJSON)
+		c.Assert(ok, IsTrue)
+		d, err := f(types.MakeDatums(t.args...), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(d, testutil.DatumEquals, types.NewDatum(t.expect), Commentf("%v", t))
+	}
+}
@@ -18,11 +18,13 @@
 package evaluator
 
 import (
+	"fmt"
 	"math"
 	"math/rand"
 
 	"github.com/juju/errors"
 	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/util/types"
 )
 
@@ -40,6 +42,9 @@ func builtinAbs(args []types.Datum, ctx context.Context) (d types.Datum, err err
 			d.SetInt64(iv)
 			return d, nil
 		}
+		if iv == math.MinInt64 {
+			return handleOverflow(ctx, types.Datum{}, types.ErrOverflow, "BIGINT", fmt.Sprintf("abs(%d)", iv))
+		}
 		d.SetInt64(-iv)
 		return d, nil
 	default:
@@ -51,6 +56,25 @@ func builtinAbs(args []types.Datum, ctx context.Context) (d types.Datum, err err
 	}
 }
 
+// builtinBitCount counts the bits set in its argument taken as an unsigned 64-bit integer,
+// so a negative value is counted in its two's complement form.
+// See https://dev.mysql.com/doc/refman/5.7/en/bit-functions.html#function_bit-count
+func builtinBitCount(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
+	if args[0].IsNull() {
+		return d, nil
+	}
+	u, err := toUintArg(ctx.GetSessionVars().StmtCtx, args[0])
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	var n int64
+	for ; u != 0; u &= u - 1 {
+		n++
+	}
+	d.SetInt64(n)
+	return d, nil
+}
+
 // See http://dev.mysql.com/doc/refman/5.7/en/mathematical-functions.html#function_ceiling
 func builtinCeil(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
 	if args[0].IsNull() ||
@@ -137,16 +161,36 @@ func builtinLog10(args []types.Datum, ctx context.Context) (d types.Datum, err e
 
 }
 
+// globalRand is the RandSource backed by the shared source of math/rand.
+type globalRand struct{}
+
+func (globalRand) Seed(seed int64) {
+	rand.Seed(seed)
+}
+
+func (globalRand) Float64() float64 {
+	return rand.Float64()
+}
+
+// randSource returns the random number source of the session.
+func randSource(ctx context.Context) variable.RandSource {
+	if r := ctx.GetSessionVars().Rand; r != nil {
+		return r
+	}
+	return globalRand{}
+}
+
 // See http://dev.mysql.com/doc/refman/5.7/en/mathematical-functions.html#function_rand
 func builtinRand(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
+	r := randSource(ctx)
 	if len(args) == 1 && !args[0].IsNull() {
 		seed, err := args[0].ToInt64(ctx.GetSessionVars().StmtCtx)
 		if err != nil {
 			return d, errors.Trace(err)
 		}
-		rand.Seed(seed)
+		r.Seed(seed)
 	}
-	d.SetFloat64(rand.Float64())
+	d.SetFloat64(r.Float64())
 	return d, nil
 }
 
@@ -166,14 +210,22 @@ func builtinPow(args []types.Datum, ctx context.Context) (d types.Datum, err err
 	return d, nil
 }
 
+// roundHalfAway rounds x to scale digits after the decimal point, a negative scale rounds to
+// tens, hundreds and so on. Halves are rounded away from zero. ROUND(), FORMAT() and
+// CAST(... AS DECIMAL) round exact values with it, so they agree with each other.
+// See https://dev.mysql.com/doc/refman/5.7/en/precision-math-rounding.html
+func roundHalfAway(x *types.MyDecimal, scale int) (*types.MyDecimal, error) {
+	to := new(types.MyDecimal)
+	err := x.Round(to, scale)
+	return to, errors.Trace(err)
+}
+
 // See http://dev.mysql.com/doc/refman/5.7/en/mathematical-functions.html#function_round
 func builtinRound(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
-	sc := ctx.GetSessionVars().StmtCtx
-	x, err := args[0].ToFloat64(sc)
-	if err != nil {
-		return d, errors.Trace(err)
+	if args[0].IsNull() {
+		return d, nil
 	}
-
+	sc := ctx.GetSessionVars().StmtCtx
 	dec := 0
 	if len(args) == 2 {
 		y, err1 := args[1].ToInt64(sc)
@@ -182,6 +234,35 @@ func builtinRound(args []types.Datum, ctx context.Context) (d types.Datum, err e
 		}
 		dec = int(y)
 	}
+
+	switch args[0].Kind() {
+	case types.KindInt64, types.KindUint64, types.KindMysqlDecimal:
+		// Exact values are rounded exactly and keep their type.
+		x, err := args[0].ToDecimal(sc)
+		if err != nil {
+			return d, errors.Trace(err)
+		}
+		x, err = roundHalfAway(x, dec)
+		if err != nil {
+			return d, errors.Trace(err)
+		}
+		switch args[0].Kind() {
+		case types.KindInt64:
+			i, err := x.ToInt()
+			d.SetInt64(i)
+			return handleOverflow(ctx, d, err, "BIGINT", fmt.Sprintf("round(%v)", args[0].GetValue()))
+		case types.KindUint64:
+			u, err := x.ToUint()
+			d.SetUint64(u)
+			return handleOverflow(ctx, d, err, "BIGINT UNSIGNED", fmt.Sprintf("round(%v)", args[0].GetValue()))
+		}
+		d.SetMysqlDecimal(x)
+		return d, nil
+	}
+	x, err := args[0].ToFloat64(sc)
+	if err != nil {
+		return d, errors.Trace(err)
+	}
 	d.SetFloat64(types.Round(x, dec))
 	return d, nil
 }
@@ -14,7 +14,12 @@
 package evaluator
 
 import (
+	"math"
+	"math/rand"
+	"time"
+
 	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/util/testleak"
 	"github.com/pingcap/tidb/util/testutil"
 	"github.com/pingcap/tidb/util/types"
@@ -43,6 +48,39 @@ func (s *testEvaluatorSuite) TestAbs(c *C) {
 	}
 }
 
+func (s *testEvaluatorSuite) TestBitCount(c *C) {
+	defer testleak.AfterTest(c)()
+	tbl := []struct {
+		Arg interface{}
+		Ret interface{}
+	}{
+		{nil, nil},
+		{int64(0), int64(0)},
+		{int64(8), int64(1)},
+		{int64(29), int64(4)},
+		{uint64(math.MaxUint64), int64(64)},
+		{int64(-1), int64(64)},
+		{int64(-2), int64(63)},
+		{int64(math.MinInt64), int64(1)},
+		{"0", int64(0)},
+		{"29", int64(4)},
+		{"-1", int64(64)},
+		{"7abc", int64(3)},
+	}
+	// A string only counts for its leading integer, the rest is truncated.
+	sc := s.ctx.GetSessionVars().StmtCtx
+	sc.TruncateAsWarning = true
+	defer func() { sc.TruncateAsWarning = false }()
+
+	Dtbl := tblToDtbl(tbl)
+
+	for _, t := range Dtbl {
+		v, err := builtinBitCount(t["Arg"], s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v, testutil.DatumEquals, t["Ret"][0])
+	}
+}
+
 func (s *testEvaluatorSuite) TestCeil(c *C) {
 	defer testleak.AfterTest(c)()
 	tbl := []struct {
@@ -111,6 +149,45 @@ func (s *testEvaluatorSuite) TestRand(c *C) {
 	c.Assert(v.GetFloat64(), GreaterEqual, float64(0))
 }
 
+func (s *testEvaluatorSuite) TestRandSource(c *C) {
+	defer testleak.AfterTest(c)()
+	vars := s.ctx.GetSessionVars()
+	defer func() {
+		vars.Rand = nil
+		vars.Clock = nil
+	}()
+
+	// run evaluates NOW() and three RAND() calls, reseeding after the first one.
+	run := func() []types.Datum {
+		vars.Rand = rand.New(rand.NewSource(1))
+		vars.Clock = fixedClock(time.Unix(1451606400, 0))
+		var ret []types.Datum
+		v, err := builtinNow(nil, s.ctx)
+		c.Assert(err, IsNil)
+		ret = append(ret, v)
+		for _, args := range [][]interface{}{nil, {3}, nil} {
+			v, err = builtinRand(types.MakeDatums(args...), s.ctx)
+			c.Assert(err, IsNil)
+			ret = append(ret, v)
+		}
+		return ret
+	}
+	first := run()
+	// Using the shared source in between must not change the results.
+	rand.Seed(time.Now().UnixNano())
+	rand.Float64()
+	second := run()
+	c.Assert(first, HasLen, 4)
+	for i := range first {
+		c.Assert(first[i], testutil.DatumEquals, second[i])
+	}
+	c.Assert(first[0].GetMysqlTime().String(), Equals, time.Unix(1451606400, 0).In(vars.TimeZone()).Format("2006-01-02 15:04:05"))
+
+	// RAND(N) seeds the session source, so it returns the same value as a fresh source with seed N.
+	c.Assert(first[2].GetFloat64(), Equals, rand.New(rand.NewSource(3)).Float64())
+	c.Assert(first[1].GetFloat64(), Equals, rand.New(rand.NewSource(1)).Float64())
+}
+
 func (s *testEvaluatorSuite) TestPow(c *C) {
 	defer testleak.AfterTest(c)()
 	tbl := []struct {
@@ -170,4 +247,86 @@ func (s *testEvaluatorSuite) TestRound(c *C) {
 		c.Assert(err, IsNil)
 		c.Assert(v, testutil.DatumEquals, t["Ret"][0])
 	}
+
+	// Exact values round halves away from zero and keep their type.
+	exactTbl := []struct {
+		arg   interface{}
+		scale int64
+		ret   interface{}
+	}{
+		{types.NewDecFromStringForTest("2.5"), 0, types.NewDecFromStringForTest("3")},
+		{types.NewDecFromStringForTest("-2.5"), 0, types.NewDecFromStringForTest("-3")},
+		{types.NewDecFromStringForTest("1.005"), 2, types.NewDecFromStringForTest("1.01")},
+		{types.NewDecFromStringForTest("-1.005"), 2, types.NewDecFromStringForTest("-1.01")},
+		{types.NewDecFromStringForTest("1234.5"), -2, types.NewDecFromStringForTest("1200")},
+		{int64(15), -1, int64(20)},
+		{int64(-15), -1, int64(-20)},
+		{int64(14), 0, int64(14)},
+		{uint64(25), -1, uint64(30)},
+		{nil, 0, nil},
+	}
+	for _, t := range exactTbl {
+		v, err := builtinRound(types.MakeDatums(t.arg, t.scale), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v, testutil.DatumEquals, types.NewDatum(t.ret), Commentf("%v", t.arg))
+	}
+}
+
+// TestRoundHalfAwayAgreement checks ROUND(), FORMAT() and CAST(... AS DECIMAL) round the same
+// halves the same way.
+func (s *testEvaluatorSuite) TestRoundHalfAwayAgreement(c *C) {
+	defer testleak.AfterTest(c)()
+	tbl := []struct {
+		arg   string
+		scale int
+	}{
+		{"2.5", 0},
+		{"-2.5", 0},
+		{"0.5", 0},
+		{"-0.5", 0},
+		{"1.45", 1},
+		{"-1.45", 1},
+		{"1.005", 2},
+		{"-1.005", 2},
+		{"9.995", 2},
+		{"0.0005", 3},
+	}
+	// Casting drops digits, which a SELECT reports as a warning.
+	sc := s.ctx.GetSessionVars().StmtCtx
+	sc.TruncateAsWarning = true
+	defer func() { sc.TruncateAsWarning = false }()
+	for _, t := range tbl {
+		arg := types.NewDecFromStringForTest(t.arg)
+		round, err := builtinRound(types.MakeDatums(arg, t.scale), s.ctx)
+		c.Assert(err, IsNil)
+		format, err := builtinFormat(types.MakeDatums(arg, t.scale), s.ctx)
+		c.Assert(err, IsNil)
+		tp := types.NewFieldType(mysql.TypeNewDecimal)
+		tp.Flen, tp.Decimal = 20, t.scale
+		castFunc, err := CastFuncFactory(tp)
+		c.Assert(err, IsNil)
+		cast, err := castFunc(types.MakeDatums(arg), s.ctx)
+		c.Assert(err, IsNil)
+
+		c.Assert(format.GetString(), Equals, round.GetMysqlDecimal().String(), Commentf("%v", t.arg))
+		c.Assert(cast.GetMysqlDecimal().String(), Equals, round.GetMysqlDecimal().String(), Commentf("%v", t.arg))
+	}
+
+	// The cast rounds before checking the precision, so 9.96 no longer fits DECIMAL(2,1).
+	tp := types.NewFieldType(mysql.TypeNewDecimal)
+	tp.Flen, tp.Decimal = 2, 1
+	castFunc, err := CastFuncFactory(tp)
+	c.Assert(err, IsNil)
+	sessVars := s.ctx.GetSessionVars()
+	strict := sessVars.StrictSQLMode
+	defer func() { sessVars.StrictSQLMode = strict }()
+	sessVars.StrictSQLMode = true
+	_, err = castFunc(types.MakeDatums(types.NewDecFromStringForTest("9.96")), s.ctx)
+	c.Assert(ErrDataOutOfRange.Equal(err), IsTrue)
+	sessVars.StrictSQLMode = false
+	warnings := len(sc.GetWarnings())
+	v, err := castFunc(types.MakeDatums(types.NewDecFromStringForTest("9.96")), s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(v.GetMysqlDecimal().String(), Equals, "9.9")
+	c.Assert(sc.GetWarnings(), HasLen, warnings+1)
 }
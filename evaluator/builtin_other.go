@@ -14,14 +14,23 @@
 package evaluator
 
 import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/juju/errors"
 	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/parser/opcode"
+	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/sessionctx/varsutil"
+	"github.com/pingcap/tidb/util/codec"
 	"github.com/pingcap/tidb/util/types"
 )
 
@@ -58,102 +67,235 @@ func builtinSleep(args []types.Datum, ctx context.Context) (d types.Datum, err e
 	return
 }
 
+// logicTruth returns the truth value of d for the logical operators. Any non-zero
+// number is true, including a fraction like 0.1 which ToBool rounds to 0 as a WHERE
+// clause does. Strings are compared as floating-point numbers.
+func logicTruth(sc *variable.StatementContext, d types.Datum) (bool, error) {
+	switch d.Kind() {
+	case types.KindFloat32, types.KindFloat64:
+		return d.GetFloat64() != 0, nil
+	case types.KindMysqlDecimal:
+		return d.GetMysqlDecimal().Compare(new(types.MyDecimal)) != 0, nil
+	case types.KindString, types.KindBytes:
+		f, err := types.StrToFloat(sc, d.GetString())
+		return f != 0, errors.Trace(err)
+	}
+	b, err := d.ToBool(sc)
+	return b == 1, errors.Trace(err)
+}
+
+// builtinAndAnd implements AND: it is 0 if either operand is false, otherwise
+// NULL if either operand is NULL, otherwise 1.
+// See https://dev.mysql.com/doc/refman/5.7/en/logical-operators.html#operator_and
 func builtinAndAnd(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
-	leftDatum := args[0]
-	rightDatum := args[1]
 	sc := ctx.GetSessionVars().StmtCtx
-	if !leftDatum.IsNull() {
-		var x int64
-		x, err = leftDatum.ToBool(sc)
-		if err != nil {
-			return d, errors.Trace(err)
-		} else if x == 0 {
-			// false && any other types is false
-			d.SetInt64(x)
-			return
+	hasNull := false
+	for _, arg := range args {
+		if arg.IsNull() {
+			hasNull = true
+			continue
 		}
-	}
-	if !rightDatum.IsNull() {
-		var y int64
-		y, err = rightDatum.ToBool(sc)
+		t, err := logicTruth(sc, arg)
 		if err != nil {
 			return d, errors.Trace(err)
-		} else if y == 0 {
-			d.SetInt64(y)
-			return
+		}
+		if !t {
+			d.SetInt64(zeroI64)
+			return d, nil
 		}
 	}
-	if leftDatum.IsNull() || rightDatum.IsNull() {
-		return
+	if !hasNull {
+		d.SetInt64(oneI64)
 	}
-	d.SetInt64(int64(1))
-	return
+	return d, nil
 }
 
+// builtinOrOr implements OR: it is 1 if either operand is true, otherwise
+// NULL if either operand is NULL, otherwise 0.
+// See https://dev.mysql.com/doc/refman/5.7/en/logical-operators.html#operator_or
 func builtinOrOr(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
 	sc := ctx.GetSessionVars().StmtCtx
-	leftDatum := args[0]
-	rightDatum := args[1]
-	if !leftDatum.IsNull() {
-		var x int64
-		x, err = leftDatum.ToBool(sc)
-		if err != nil {
-			return d, errors.Trace(err)
-		} else if x == 1 {
-			// false && any other types is false
-			d.SetInt64(x)
-			return
+	hasNull := false
+	for _, arg := range args {
+		if arg.IsNull() {
+			hasNull = true
+			continue
 		}
-	}
-	if !rightDatum.IsNull() {
-		var y int64
-		y, err = rightDatum.ToBool(sc)
+		t, err := logicTruth(sc, arg)
 		if err != nil {
 			return d, errors.Trace(err)
-		} else if y == 1 {
-			d.SetInt64(y)
-			return
 		}
+		if t {
+			d.SetInt64(oneI64)
+			return d, nil
+		}
+	}
+	if !hasNull {
+		d.SetInt64(zeroI64)
 	}
-	if leftDatum.IsNull() || rightDatum.IsNull() {
+	return d, nil
+}
+
+// builtinUnaryNot implements NOT: NOT NULL is NULL.
+// See https://dev.mysql.com/doc/refman/5.7/en/logical-operators.html#operator_not
+func builtinUnaryNot(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
+	if args[0].IsNull() {
 		return
 	}
-	d.SetInt64(int64(0))
-	return
+	t, err := logicTruth(ctx.GetSessionVars().StmtCtx, args[0])
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	d.SetInt64(boolToInt64(!t))
+	return d, nil
 }
 
 // See https://dev.mysql.com/doc/refman/5.7/en/case.html
 func builtinCaseWhen(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
+	return lazyCaseWhen(DatumArgs(args), ctx)
+}
+
+func lazyCaseWhen(args LazyArgs, ctx context.Context) (d types.Datum, err error) {
 	sc := ctx.GetSessionVars().StmtCtx
-	l := len(args)
+	l := args.Len()
 	for i := 0; i < l-1; i += 2 {
-		if args[i].IsNull() {
+		cond, err := args.Eval(i)
+		if err != nil {
+			return d, errors.Trace(err)
+		}
+		if cond.IsNull() {
 			continue
 		}
-		b, err1 := args[i].ToBool(sc)
+		b, err1 := cond.ToBool(sc)
 		if err1 != nil {
 			return d, errors.Trace(err1)
 		}
 		if b == 1 {
-			d = args[i+1]
-			return
+			return args.Eval(i + 1)
 		}
 	}
 	// when clause(condition, result) -> args[i], args[i+1]; (i >= 0 && i+1 < l-1)
 	// else clause -> args[l-1]
 	// If case clause has else clause, l%2 == 1.
 	if l%2 == 1 {
-		d = args[l-1]
+		return args.Eval(l - 1)
 	}
 	return
 }
 
-// See http://dev.mysql.com/doc/refman/5.7/en/any-in-some-subqueries.html
+// The ways the arguments of a comparison are compared.
+const (
+	cmpDefault = iota
+	cmpString
+	cmpReal
+)
+
+// compareType decides how the arguments of a comparison, IN() or BETWEEN are
+// compared: as strings when all of them are strings, as real numbers when they
+// mix strings and numbers, and by the pairwise coercion rules otherwise, which
+// compare integers as integers, decimals as decimals and parse a string compared
+// with a temporal value as that temporal type. ENUM and SET values are compared with
+// each other and with numbers by their numbers, so in the order of their definition,
+// and with strings by their names. NULLs do not take part in the decision.
+func compareType(args []types.Datum) int {
+	var hasNumber, hasString bool
+	for _, arg := range args {
+		switch arg.Kind() {
+		case types.KindNull:
+		case types.KindInt64, types.KindUint64, types.KindFloat32, types.KindFloat64, types.KindMysqlDecimal:
+			hasNumber = true
+		case types.KindString, types.KindBytes:
+			hasString = true
+		default:
+			return cmpDefault
+		}
+	}
+	if !hasString {
+		return cmpDefault
+	}
+	if hasNumber {
+		return cmpReal
+	}
+	return cmpString
+}
+
+// compareAs compares a and b in the way decided by compareType, strings under the collation they
+// carry.
+func compareAs(sc *variable.StatementContext, cmpType int, a, b types.Datum) (int, error) {
+	switch cmpType {
+	case cmpString:
+		collation := compareCollation(a, b)
+		return bytes.Compare(SortKey(a, collation), SortKey(b, collation)), nil
+	case cmpReal:
+		x, err := a.ToFloat64(sc)
+		if err != nil {
+			return 0, errors.Trace(err)
+		}
+		y, err := b.ToFloat64(sc)
+		if err != nil {
+			return 0, errors.Trace(err)
+		}
+		return types.CompareFloat64(x, y), nil
+	}
+	x, y, err := types.CoerceDatum(sc, a, b)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	ret, err := x.CompareDatum(sc, y)
+	return ret, errors.Trace(err)
+}
+
+// CompareNullSafe compares a and b the way <=> does, so join and probe code can match values
+// without a statement: NULL equals NULL and is less than any other value, strings are compared
+// under collation, or the collation they carry if it is empty, and other values as compareType
+// decides. It returns a negative number if a is the smaller, 0 if they are equal and a positive
+// one otherwise. Truncation is ignored, a string which isn't a number is 0 compared with a number.
+// Values which can't be compared, like a time and a string which isn't one, are ordered by their
+// kinds, then by their memcomparable encodings, so that the order stays total.
+func CompareNullSafe(a, b types.Datum, collation string) int {
+	sc := &variable.StatementContext{IgnoreTruncate: true}
+	n, err := compareNullSafe(sc, a, b, collation)
+	if err != nil {
+		return compareIncomparable(a, b)
+	}
+	return n
+}
+
+// compareIncomparable orders a and b, which can't be compared as values, by their kinds and then
+// by their memcomparable encodings. Values which can't be encoded are equal to one another.
+func compareIncomparable(a, b types.Datum) int {
+	if a.Kind() != b.Kind() {
+		return types.CompareInt64(int64(a.Kind()), int64(b.Kind()))
+	}
+	ka, _ := codec.EncodeKey(nil, a)
+	kb, _ := codec.EncodeKey(nil, b)
+	return bytes.Compare(ka, kb)
+}
+
+// compareNullSafe implements CompareNullSafe and the <=> operator.
+func compareNullSafe(sc *variable.StatementContext, a, b types.Datum, collation string) (int, error) {
+	if a.IsNull() || b.IsNull() {
+		return types.CompareInt64(boolToInt64(!a.IsNull()), boolToInt64(!b.IsNull())), nil
+	}
+	if id, ok := mysql.CollationNames[collation]; ok {
+		a.SetCollation(id)
+		b.SetCollation(id)
+	}
+	n, err := compareAs(sc, compareType([]types.Datum{a, b}), a, b)
+	return n, errors.Trace(err)
+}
+
+func isEnumOrSetKind(k byte) bool {
+	return k == types.KindMysqlEnum || k == types.KindMysqlSet
+}
+
+// See http://dev.mysql.com/doc/refman/5.7/en/comparison-operators.html#function_in
 func builtinIn(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
 	if args[0].IsNull() {
 		return
 	}
 	sc := ctx.GetSessionVars().StmtCtx
+	cmpType := compareType(args)
 	var hasNull bool
 	for _, v := range args[1:] {
 		if v.IsNull() {
@@ -161,11 +303,7 @@ func builtinIn(args []types.Datum, ctx context.Context) (d types.Datum, err erro
 			continue
 		}
 
-		a, b, err := types.CoerceDatum(sc, args[0], v)
-		if err != nil {
-			return d, errors.Trace(err)
-		}
-		ret, err := a.CompareDatum(sc, b)
+		ret, err := compareAs(sc, cmpType, args[0], v)
 		if err != nil {
 			return d, errors.Trace(err)
 		}
@@ -184,83 +322,77 @@ func builtinIn(args []types.Datum, ctx context.Context) (d types.Datum, err erro
 	return
 }
 
+// builtinLogicXor implements XOR: it is NULL if either operand is NULL, otherwise
+// 1 if exactly one operand is true.
+// See https://dev.mysql.com/doc/refman/5.7/en/logical-operators.html#operator_xor
 func builtinLogicXor(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
-	leftDatum := args[0]
-	righDatum := args[1]
-	if leftDatum.IsNull() || righDatum.IsNull() {
+	if args[0].IsNull() || args[1].IsNull() {
 		return
 	}
 	sc := ctx.GetSessionVars().StmtCtx
-	x, err := leftDatum.ToBool(sc)
+	x, err := logicTruth(sc, args[0])
 	if err != nil {
 		return d, errors.Trace(err)
 	}
-
-	y, err := righDatum.ToBool(sc)
+	y, err := logicTruth(sc, args[1])
 	if err != nil {
 		return d, errors.Trace(err)
 	}
-	if x == y {
-		d.SetInt64(zeroI64)
-	} else {
-		d.SetInt64(oneI64)
-	}
+	d.SetInt64(boolToInt64(x != y))
 	return
 }
 
+// compareFuncFactory returns the handler of the comparison operators. They return
+// NULL if either operand is NULL, except <=> which is 1 if both operands are NULL
+// and 0 if only one of them is.
+// See https://dev.mysql.com/doc/refman/5.7/en/comparison-operators.html
 func compareFuncFactory(op opcode.Op) BuiltinFunc {
 	return func(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
+		a, b := args[0], args[1]
 		sc := ctx.GetSessionVars().StmtCtx
-		var a, b = args[0], args[1]
-		if op != opcode.NullEQ {
-			a, b, err = types.CoerceDatum(sc, a, b)
+		if op == opcode.NullEQ {
+			n, err := compareNullSafe(sc, a, b, "")
 			if err != nil {
 				return d, errors.Trace(err)
 			}
+			return compareResult(op, n)
 		}
 		if a.IsNull() || b.IsNull() {
-			// for <=>, if a and b are both nil, return true.
-			// if a or b is nil, return false.
-			if op == opcode.NullEQ {
-				if a.IsNull() && b.IsNull() {
-					d.SetInt64(oneI64)
-				} else {
-					d.SetInt64(zeroI64)
-				}
-			}
 			return
 		}
 
-		n, err := a.CompareDatum(sc, b)
+		n, err := compareAs(sc, compareType(args), a, b)
 		if err != nil {
 			return d, errors.Trace(err)
 		}
-		var result bool
-		switch op {
-		case opcode.LT:
-			result = n < 0
-		case opcode.LE:
-			result = n <= 0
-		case opcode.EQ, opcode.NullEQ:
-			result = n == 0
-		case opcode.GT:
-			result = n > 0
-		case opcode.GE:
-			result = n >= 0
-		case opcode.NE:
-			result = n != 0
-		default:
-			return d, ErrInvalidOperation.Gen("invalid op %v in comparison operation", op)
-		}
-		if result {
-			d.SetInt64(oneI64)
-		} else {
-			d.SetInt64(zeroI64)
-		}
-		return
+		return compareResult(op, n)
 	}
 }
 
+// compareResult returns the result of the comparison op of two operands, n is the result of
+// comparing them, negative if the first one is the smaller.
+func compareResult(op opcode.Op, n int) (d types.Datum, err error) {
+	var result bool
+	switch op {
+	case opcode.LT:
+		result = n < 0
+	case opcode.LE:
+		result = n <= 0
+	case opcode.EQ, opcode.NullEQ:
+		result = n == 0
+	case opcode.GT:
+		result = n > 0
+	case opcode.GE:
+		result = n >= 0
+	case opcode.NE:
+		result = n != 0
+	default:
+		return d, ErrInvalidOperation.Gen("invalid op %v in comparison operation", op)
+	}
+	d.SetInt64(boolToInt64(result))
+	return d, nil
+}
+
 func bitOpFactory(op opcode.Op) BuiltinFunc {
 	return func(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
 		sc := ctx.GetSessionVars().StmtCtx
@@ -301,19 +433,115 @@ func bitOpFactory(op opcode.Op) BuiltinFunc {
 	}
 }
 
-func arithmeticFuncFactory(op opcode.Op) BuiltinFunc {
-	return func(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
-		sc := ctx.GetSessionVars().StmtCtx
-		a, err := types.CoerceArithmetic(sc, args[0])
+// coerceArithmeticArgs converts both operands of an arithmetic operator to a common numeric kind.
+func coerceArithmeticArgs(sc *variable.StatementContext, args []types.Datum) (a, b types.Datum, err error) {
+	a, err = types.CoerceArithmetic(sc, args[0])
+	if err != nil {
+		return a, b, errors.Trace(err)
+	}
+	b, err = types.CoerceArithmetic(sc, args[1])
+	if err != nil {
+		return a, b, errors.Trace(err)
+	}
+	a, b, err = types.CoerceDatum(sc, a, b)
+	return a, b, errors.Trace(err)
+}
+
+// checkArithmeticOverflow reports the overflow of "a op b" through handleOverflow.
+func checkArithmeticOverflow(ctx context.Context, d types.Datum, err error, op opcode.Op, a, b types.Datum) (types.Datum, error) {
+	if isOverflowError(err) {
+		expr := fmt.Sprintf("(%v %s %v)", a.GetValue(), arithmeticOpSymbols[op], b.GetValue())
+		return handleOverflow(ctx, types.Datum{}, err, arithmeticTypeName(a, b), expr)
+	}
+	return d, errors.Trace(err)
+}
+
+// See https://dev.mysql.com/doc/refman/5.7/en/arithmetic-functions.html#operator_plus
+func builtinArithmeticPlus(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
+	a, b, err := coerceArithmeticArgs(ctx.GetSessionVars().StmtCtx, args)
+	if err != nil || a.IsNull() || b.IsNull() {
+		return d, errors.Trace(err)
+	}
+	d, err = types.ComputePlus(a, b)
+	return checkArithmeticOverflow(ctx, d, err, opcode.Plus, a, b)
+}
+
+// See https://dev.mysql.com/doc/refman/5.7/en/arithmetic-functions.html#operator_minus
+func builtinArithmeticMinus(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
+	a, b, err := coerceArithmeticArgs(ctx.GetSessionVars().StmtCtx, args)
+	if err != nil || a.IsNull() || b.IsNull() {
+		return d, errors.Trace(err)
+	}
+	d, err = types.ComputeMinus(a, b)
+	return checkArithmeticOverflow(ctx, d, err, opcode.Minus, a, b)
+}
+
+// builtinArithmeticMul multiplies two numbers. The scale of a decimal product is
+// the sum of the scales of the operands.
+// See https://dev.mysql.com/doc/refman/5.7/en/arithmetic-functions.html#operator_times
+func builtinArithmeticMul(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
+	a, b, err := coerceArithmeticArgs(ctx.GetSessionVars().StmtCtx, args)
+	if err != nil || a.IsNull() || b.IsNull() {
+		return d, errors.Trace(err)
+	}
+	d, err = types.ComputeMul(a, b)
+	return checkArithmeticOverflow(ctx, d, err, opcode.Mul, a, b)
+}
+
+// builtinArithmeticDiv divides two numbers. Unless an operand is a floating-point
+// value, the result is a decimal whose scale is the scale of the first operand
+// plus div_precision_increment. Division by zero returns NULL, see handleDivisionByZero.
+// See https://dev.mysql.com/doc/refman/5.7/en/arithmetic-functions.html#operator_divide
+func builtinArithmeticDiv(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
+	sc := ctx.GetSessionVars().StmtCtx
+	a, b, err := coerceArithmeticArgs(sc, args)
+	if err != nil || a.IsNull() || b.IsNull() {
+		return d, errors.Trace(err)
+	}
+	if a.Kind() == types.KindFloat64 {
+		y, err := b.ToFloat64(sc)
 		if err != nil {
 			return d, errors.Trace(err)
 		}
+		if y == 0 {
+			return d, handleDivisionByZero(ctx)
+		}
+		d.SetFloat64(a.GetFloat64() / y)
+		return d, nil
+	}
+	x, err := a.ToDecimal(sc)
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	y, err := b.ToDecimal(sc)
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	to := new(types.MyDecimal)
+	err = types.DecimalDiv(x, y, to, divPrecisionIncrement(ctx))
+	if err == types.ErrDivByZero {
+		return d, handleDivisionByZero(ctx)
+	}
+	d.SetMysqlDecimal(to)
+	return checkArithmeticOverflow(ctx, d, err, opcode.Div, a, b)
+}
 
-		b, err := types.CoerceArithmetic(sc, args[1])
-		if err != nil {
-			return d, errors.Trace(err)
+// divPrecisionIncrement returns the div_precision_increment of the session.
+func divPrecisionIncrement(ctx context.Context) int {
+	incr := types.DivFracIncr
+	if v, ok := ctx.GetSessionVars().Systems["div_precision_increment"]; ok {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 && n <= types.MaxFraction {
+			incr = n
 		}
-		a, b, err = types.CoerceDatum(sc, a, b)
+	}
+	return incr
+}
+
+// arithmeticFuncFactory returns the handler of the remaining arithmetic operators, MOD and DIV.
+func arithmeticFuncFactory(op opcode.Op) BuiltinFunc {
+	return func(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
+		sc := ctx.GetSessionVars().StmtCtx
+		a, b, err := coerceArithmeticArgs(sc, args)
 		if err != nil {
 			return d, errors.Trace(err)
 		}
@@ -322,29 +550,50 @@ func arithmeticFuncFactory(op opcode.Op) BuiltinFunc {
 		}
 
 		switch op {
-		case opcode.Plus:
-			return types.ComputePlus(a, b)
-		case opcode.Minus:
-			return types.ComputeMinus(a, b)
-		case opcode.Mul:
-			return types.ComputeMul(a, b)
-		case opcode.Div:
-			return types.ComputeDiv(sc, a, b)
 		case opcode.Mod:
-			return types.ComputeMod(sc, a, b)
+			d, err = types.ComputeMod(sc, a, b)
 		case opcode.IntDiv:
-			return types.ComputeIntDiv(sc, a, b)
+			d, err = types.ComputeIntDiv(sc, a, b)
 		default:
 			return d, ErrInvalidOperation.Gen("invalid op %v in arithmetic operation", op)
 		}
+		if err == nil && d.IsNull() {
+			// Both compute NULL only for a zero divisor.
+			return d, handleDivisionByZero(ctx)
+		}
+		return checkArithmeticOverflow(ctx, d, err, op, a, b)
 	}
 }
 
+var arithmeticOpSymbols = map[opcode.Op]string{
+	opcode.Plus:   "+",
+	opcode.Minus:  "-",
+	opcode.Mul:    "*",
+	opcode.Div:    "/",
+	opcode.Mod:    "%",
+	opcode.IntDiv: "DIV",
+}
+
+// arithmeticTypeName returns the SQL type name of an arithmetic result, used in overflow messages.
+func arithmeticTypeName(a, b types.Datum) string {
+	switch {
+	case a.Kind() == types.KindMysqlDecimal || b.Kind() == types.KindMysqlDecimal:
+		return "DECIMAL"
+	case a.Kind() == types.KindFloat64 || b.Kind() == types.KindFloat64:
+		return "DOUBLE"
+	case a.Kind() == types.KindUint64 || b.Kind() == types.KindUint64:
+		return "BIGINT UNSIGNED"
+	}
+	return "BIGINT"
+}
+
 func builtinRow(row []types.Datum, _ context.Context) (d types.Datum, err error) {
 	d.SetRow(row)
 	return
 }
 
+// isTrueOpFactory returns the handler of IS TRUE or IS FALSE. Unlike most
+// operators, they never return NULL: NULL IS TRUE and NULL IS FALSE are both 0.
 func isTrueOpFactory(op opcode.Op) BuiltinFunc {
 	return func(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
 		var boolVal bool
@@ -375,16 +624,6 @@ func unaryOpFactory(op opcode.Op) BuiltinFunc {
 		}
 		sc := ctx.GetSessionVars().StmtCtx
 		switch op {
-		case opcode.Not:
-			var n int64
-			n, err = aDatum.ToBool(sc)
-			if err != nil {
-				err = errors.Trace(err)
-			} else if n == 0 {
-				d.SetInt64(1)
-			} else {
-				d.SetInt64(0)
-			}
 		case opcode.BitNeg:
 			var n int64
 			// for bit operation, we will use int64 first, then return uint64
@@ -412,43 +651,6 @@ func unaryOpFactory(op opcode.Op) BuiltinFunc {
 			default:
 				return d, ErrInvalidOperation.Gen("Unsupported type %v for op.Plus", aDatum.Kind())
 			}
-		case opcode.Minus:
-			switch aDatum.Kind() {
-			case types.KindInt64:
-				d.SetInt64(-aDatum.GetInt64())
-			case types.KindUint64:
-				d.SetInt64(-int64(aDatum.GetUint64()))
-			case types.KindFloat64:
-				d.SetFloat64(-aDatum.GetFloat64())
-			case types.KindFloat32:
-				d.SetFloat32(-aDatum.GetFloat32())
-			case types.KindMysqlDuration:
-				dec := new(types.MyDecimal)
-				err = types.DecimalSub(new(types.MyDecimal), aDatum.GetMysqlDuration().ToNumber(), dec)
-				d.SetMysqlDecimal(dec)
-			case types.KindMysqlTime:
-				dec := new(types.MyDecimal)
-				err = types.DecimalSub(new(types.MyDecimal), aDatum.GetMysqlTime().ToNumber(), dec)
-				d.SetMysqlDecimal(dec)
-			case types.KindString, types.KindBytes:
-				f, err1 := types.StrToFloat(sc, aDatum.GetString())
-				err = errors.Trace(err1)
-				d.SetFloat64(-f)
-			case types.KindMysqlDecimal:
-				dec := new(types.MyDecimal)
-				err = types.DecimalSub(new(types.MyDecimal), aDatum.GetMysqlDecimal(), dec)
-				d.SetMysqlDecimal(dec)
-			case types.KindMysqlHex:
-				d.SetFloat64(-aDatum.GetMysqlHex().ToNumber())
-			case types.KindMysqlBit:
-				d.SetFloat64(-aDatum.GetMysqlBit().ToNumber())
-			case types.KindMysqlEnum:
-				d.SetFloat64(-aDatum.GetMysqlEnum().ToNumber())
-			case types.KindMysqlSet:
-				d.SetFloat64(-aDatum.GetMysqlSet().ToNumber())
-			default:
-				return d, ErrInvalidOperation.Gen("Unsupported type %v for op.Minus", aDatum.Kind())
-			}
 		default:
 			return d, ErrInvalidOperation.Gen("Unsupported op %v for unary op", op)
 		}
@@ -456,6 +658,61 @@ func unaryOpFactory(op opcode.Op) BuiltinFunc {
 	}
 }
 
+// builtinUnaryMinus negates a number, keeping the type of the operand. Strings are
+// parsed as floating-point numbers first.
+// See https://dev.mysql.com/doc/refman/5.7/en/arithmetic-functions.html#operator_unary-minus
+func builtinUnaryMinus(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
+	a := args[0]
+	sc := ctx.GetSessionVars().StmtCtx
+	switch a.Kind() {
+	case types.KindNull:
+		return
+	case types.KindInt64:
+		v := a.GetInt64()
+		if v == math.MinInt64 {
+			return handleOverflow(ctx, d, types.ErrOverflow, "BIGINT", fmt.Sprintf("-(%d)", v))
+		}
+		d.SetInt64(-v)
+	case types.KindUint64:
+		v := a.GetUint64()
+		if v > uint64(math.MaxInt64)+1 {
+			return handleOverflow(ctx, d, types.ErrOverflow, "BIGINT", fmt.Sprintf("-(%d)", v))
+		}
+		d.SetInt64(int64(-v))
+	case types.KindFloat64:
+		d.SetFloat64(-a.GetFloat64())
+	case types.KindFloat32:
+		d.SetFloat32(-a.GetFloat32())
+	case types.KindMysqlDecimal:
+		dec := new(types.MyDecimal)
+		err = types.DecimalSub(new(types.MyDecimal), a.GetMysqlDecimal(), dec)
+		d.SetMysqlDecimal(dec)
+	case types.KindMysqlDuration:
+		dec := new(types.MyDecimal)
+		err = types.DecimalSub(new(types.MyDecimal), a.GetMysqlDuration().ToNumber(), dec)
+		d.SetMysqlDecimal(dec)
+	case types.KindMysqlTime:
+		dec := new(types.MyDecimal)
+		err = types.DecimalSub(new(types.MyDecimal), a.GetMysqlTime().ToNumber(), dec)
+		d.SetMysqlDecimal(dec)
+	case types.KindString, types.KindBytes:
+		f, err1 := types.StrToFloat(sc, a.GetString())
+		err = errors.Trace(err1)
+		d.SetFloat64(-f)
+	case types.KindMysqlHex:
+		d.SetFloat64(-a.GetMysqlHex().ToNumber())
+	case types.KindMysqlBit:
+		d.SetFloat64(-a.GetMysqlBit().ToNumber())
+	case types.KindMysqlEnum:
+		d.SetFloat64(-a.GetMysqlEnum().ToNumber())
+	case types.KindMysqlSet:
+		d.SetFloat64(-a.GetMysqlSet().ToNumber())
+	default:
+		return d, ErrInvalidOperation.Gen("Unsupported type %v for op.Minus", a.Kind())
+	}
+	return d, errors.Trace(err)
+}
+
 // CastFuncFactory produces builtin function according to field types.
 // See https://dev.mysql.com/doc/refman/5.7/en/cast-functions.html
 func CastFuncFactory(tp *types.FieldType) (BuiltinFunc, error) {
@@ -463,37 +720,196 @@ func CastFuncFactory(tp *types.FieldType) (BuiltinFunc, error) {
 	// Parser has restricted this.
 	case mysql.TypeString, mysql.TypeDuration, mysql.TypeDatetime,
 		mysql.TypeDate, mysql.TypeLonglong, mysql.TypeNewDecimal:
+		var inRepertoire func(rune) bool
+		if tp.Tp == mysql.TypeString && tp.Charset != "" {
+			// CAST(... AS CHAR CHARACTER SET cs) converts the string to cs.
+			var err error
+			if inRepertoire, err = charsetRepertoire(tp.Charset); err != nil {
+				return nil, errors.Trace(err)
+			}
+		}
 		return func(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
 			d = args[0]
 			if d.IsNull() {
 				return
 			}
-			return d.ConvertTo(ctx.GetSessionVars().StmtCtx, tp)
+			sc := ctx.GetSessionVars().StmtCtx
+			truncated := false
+			if tp.Tp == mysql.TypeNewDecimal && tp.Decimal != types.UnspecifiedLength {
+				// Round before the conversion checks the precision, so a value rounding beyond it overflows.
+				x, err := d.ToDecimal(sc)
+				if err != nil {
+					return d, errors.Trace(err)
+				}
+				rounded, err := roundHalfAway(x, tp.Decimal)
+				if err != nil {
+					return d, errors.Trace(err)
+				}
+				truncated = rounded.Compare(x) != 0
+				d.SetMysqlDecimal(rounded)
+			}
+			if inRepertoire != nil && (d.Kind() == types.KindString || d.Kind() == types.KindBytes) {
+				d.SetString(castToCharset(sc, d.GetString(), tp.Charset, inRepertoire))
+			}
+			v, err := d.ConvertTo(sc, tp)
+			if isOverflowError(err) {
+				// The converted value is clamped to the range of the target type.
+				return handleOverflow(ctx, v, err, strings.ToUpper(types.TypeStr(tp.Tp)), fmt.Sprintf("cast(%v)", args[0].GetValue()))
+			}
+			if err == nil && truncated {
+				err = sc.HandleTruncate(types.ErrTruncated)
+			}
+			if err != nil {
+				return v, errors.Trace(err)
+			}
+			return checkDate(ctx, v)
 		}, nil
+	case mysql.TypeJSON:
+		return builtinCastJSON, nil
 	}
 	return nil, errors.Errorf("unknown cast type - %v", tp)
 }
 
+// builtinSetVar assigns args[1] to the user variable named args[0], as in @x := expr, and returns
+// it. References to the variable evaluated later, in the same statement too, read the value.
 func builtinSetVar(args []types.Datum, ctx context.Context) (types.Datum, error) {
-	sessionVars := ctx.GetSessionVars()
 	varName, _ := args[0].ToString()
-	if !args[1].IsNull() {
-		strVal, err := args[1].ToString()
+	varsutil.SetUserVar(ctx.GetSessionVars(), varName, args[1])
+	return args[1], nil
+}
+
+// builtinSetCollation returns the string args[0] in the collation named args[1], as in
+// expr COLLATE collation_name. The plan checks the collation applies to the charset of args[0].
+func builtinSetCollation(args []types.Datum, _ context.Context) (types.Datum, error) {
+	d := args[0]
+	if k := d.Kind(); k != types.KindString && k != types.KindBytes {
+		return d, nil
+	}
+	name, err := args[1].ToString()
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	id, ok := mysql.CollationNames[strings.ToLower(name)]
+	if !ok {
+		return d, ErrUnknownCollation.GenByArgs(name)
+	}
+	d.SetCollation(id)
+	return d, nil
+}
+
+// builtinGetVar reads the user variable named args[0], NULL if it isn't set.
+func builtinGetVar(args []types.Datum, ctx context.Context) (types.Datum, error) {
+	varName, _ := args[0].ToString()
+	return varsutil.GetUserVar(ctx.GetSessionVars(), varName), nil
+}
+
+// builtinGetSysVar reads the system variable named args[0], from its global value if args[1] is
+// true and from its session value otherwise. A session variable not read yet in the session takes
+// its global value.
+func builtinGetSysVar(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
+	sessionVars := ctx.GetSessionVars()
+	name, _ := args[0].ToString()
+	sysVar, ok := variable.SysVars[name]
+	if !ok {
+		return d, variable.UnknownSystemVar.GenByArgs(name)
+	}
+	if sysVar.Scope == variable.ScopeNone {
+		return types.NewDatum(sysVar.Value), nil
+	}
+	globalVars := sessionVars.GlobalVarsAccessor
+	if isGlobal := args[1].GetInt64() != 0; isGlobal {
+		value, err := globalVars.GetGlobalSysVar(name)
 		if err != nil {
-			return types.Datum{}, errors.Trace(err)
+			return d, errors.Trace(err)
 		}
-		sessionVars.Users[varName] = strings.ToLower(strVal)
+		return types.NewDatum(value), nil
+	}
+	d = varsutil.GetSystemVar(sessionVars, name)
+	if !d.IsNull() {
+		return d, nil
 	}
+	if sysVar.Scope&variable.ScopeGlobal == 0 {
+		d.SetString(sysVar.Value)
+		return d, nil
+	}
+	// Get global system variable and fill it in session.
+	globalVal, err := globalVars.GetGlobalSysVar(name)
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	d.SetString(globalVal)
+	err = varsutil.SetSystemVar(sessionVars, name, d)
+	return d, errors.Trace(err)
+}
+
+// ANY_VALUE returns its argument, which tells that any value of a column not in the GROUP BY list
+// of a query will do.
+// See https://dev.mysql.com/doc/refman/5.7/en/miscellaneous-functions.html#function_any-value
+func builtinAnyValue(args []types.Datum, _ context.Context) (d types.Datum, err error) {
+	return args[0], nil
+}
+
+// NAME_CONST returns its value. The planner makes its name the name of the result column.
+// See https://dev.mysql.com/doc/refman/5.7/en/miscellaneous-functions.html#function_name-const
+func builtinNameConst(args []types.Datum, _ context.Context) (d types.Datum, err error) {
 	return args[1], nil
 }
 
-func builtinGetVar(args []types.Datum, ctx context.Context) (types.Datum, error) {
-	sessionVars := ctx.GetSessionVars()
-	varName, _ := args[0].ToString()
-	if v, ok := sessionVars.Users[varName]; ok {
-		return types.NewDatum(v), nil
+// A unit of a human readable quantity, values of at least size are shown in it.
+type quantityUnit struct {
+	size float64
+	name string
+}
+
+// byteUnits and picoTimeUnits are ordered from the largest unit down to the base unit.
+var (
+	byteUnits = []quantityUnit{
+		{1 << 60, "EiB"}, {1 << 50, "PiB"}, {1 << 40, "TiB"}, {1 << 30, "GiB"}, {1 << 20, "MiB"}, {1 << 10, "KiB"},
+		{1, "bytes"},
+	}
+	picoTimeUnits = []quantityUnit{
+		{86400e12, "d"}, {3600e12, "h"}, {60e12, "min"}, {1e12, "s"}, {1e9, "ms"}, {1e6, "us"}, {1e3, "ns"},
+		{1, "ps"},
+	}
+)
+
+// formatQuantity shows v in the largest of units it reaches, with two decimals, or as a whole
+// number of the base unit.
+func formatQuantity(v float64, units []quantityUnit) string {
+	for _, u := range units[:len(units)-1] {
+		if math.Abs(v) >= u.size {
+			return fmt.Sprintf("%.2f %s", v/u.size, u.name)
+		}
+	}
+	return fmt.Sprintf("%d %s", int64(v), units[len(units)-1].name)
+}
+
+// FORMAT_BYTES shows a byte count in the largest binary unit it reaches, like '1.50 KiB'.
+// See https://dev.mysql.com/doc/refman/8.0/en/performance-schema-functions.html#function_format-bytes
+func builtinFormatBytes(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
+	if args[0].IsNull() {
+		return d, nil
+	}
+	v, err := args[0].ToFloat64(ctx.GetSessionVars().StmtCtx)
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	d.SetString(formatQuantity(v, byteUnits))
+	return d, nil
+}
+
+// FORMAT_PICO_TIME shows a time in picoseconds in the largest unit it reaches, like '1.23 ms'.
+// See https://dev.mysql.com/doc/refman/8.0/en/performance-schema-functions.html#function_format-pico-time
+func builtinFormatPicoTime(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
+	if args[0].IsNull() {
+		return d, nil
+	}
+	v, err := args[0].ToFloat64(ctx.GetSessionVars().StmtCtx)
+	if err != nil {
+		return d, errors.Trace(err)
 	}
-	return types.Datum{}, nil
+	d.SetString(formatQuantity(v, picoTimeUnits))
+	return d, nil
 }
 
 // The lock function will do nothing.
@@ -511,11 +927,11 @@ func builtinReleaseLock(args []types.Datum, _ context.Context) (d types.Datum, e
 }
 
 // BuildinValuesFactory generates values builtin function.
+// Outside of an INSERT ... ON DUPLICATE KEY UPDATE statement there are no values to refer to, so it returns NULL.
 func BuildinValuesFactory(v *ast.ValuesExpr) BuiltinFunc {
 	return func(_ []types.Datum, ctx context.Context) (d types.Datum, err error) {
 		values := ctx.GetSessionVars().CurrInsertValues
 		if values == nil {
-			err = errors.New("Session current insert values is nil")
 			return
 		}
 		row := values.([]types.Datum)
@@ -527,3 +943,172 @@ func BuildinValuesFactory(v *ast.ValuesExpr) BuiltinFunc {
 		return
 	}
 }
+
+// BuildinDefaultFactory generates default builtin function for the column.
+// See http://dev.mysql.com/doc/refman/5.7/en/miscellaneous-functions.html#function_default
+func BuildinDefaultFactory(col *model.ColumnInfo) BuiltinFunc {
+	return func(_ []types.Datum, ctx context.Context) (d types.Datum, err error) {
+		if col.DefaultValue == nil {
+			if col.Tp == mysql.TypeEnum {
+				// For enum type, if no default value and not null is set,
+				// the default value is the first element of the enum list.
+				if mysql.HasNotNullFlag(col.Flag) && len(col.Elems) > 0 {
+					d.SetString(col.Elems[0])
+					return d.ConvertTo(ctx.GetSessionVars().StmtCtx, &col.FieldType)
+				}
+				return
+			}
+			if mysql.HasNoDefaultValueFlag(col.Flag) {
+				return d, ErrNoDefaultValue.GenByArgs(col.Name.O)
+			}
+			return
+		}
+		if col.Tp == mysql.TypeTimestamp || col.Tp == mysql.TypeDatetime {
+			if s, ok := col.DefaultValue.(string); ok && strings.ToUpper(s) == "CURRENT_TIMESTAMP" {
+				fsp := col.Decimal
+				if fsp < 0 {
+					fsp = 0
+				}
+				d, err = builtinNow([]types.Datum{types.NewIntDatum(int64(fsp))}, ctx)
+				if err != nil {
+					return d, errors.Trace(err)
+				}
+			}
+		}
+		if d.IsNull() {
+			d.SetValue(col.DefaultValue)
+		}
+		return d.ConvertTo(ctx.GetSessionVars().StmtCtx, &col.FieldType)
+	}
+}
+
+// uuidLen is the length in bytes of a UUID.
+const uuidLen = 16
+
+// parseUUID decodes a UUID written as 32 hexadecimal digits, either plain, in the 8-4-4-4-12 groups
+// separated by dashes, or in those groups enclosed in braces.
+func parseUUID(s string) ([]byte, bool) {
+	if len(s) == 38 {
+		if s[0] != '{' || s[37] != '}' {
+			return nil, false
+		}
+		s = s[1:37]
+	}
+	if len(s) == 36 {
+		if s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+			return nil, false
+		}
+		s = s[:8] + s[9:13] + s[14:18] + s[19:23] + s[24:]
+	}
+	if len(s) != 2*uuidLen {
+		return nil, false
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, false
+	}
+	return b, true
+}
+
+// formatUUID returns the 8-4-4-4-12 form of the UUID b in lowercase.
+func formatUUID(b []byte) string {
+	s := hex.EncodeToString(b)
+	return s[:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:]
+}
+
+// swapUUIDTime moves the time-high part of a version 1 UUID in front of time-mid and time-low, so
+// that UUIDs generated one after the other sort close together. unswapUUIDTime reverses it.
+func swapUUIDTime(b []byte) []byte {
+	swapped := make([]byte, 0, uuidLen)
+	swapped = append(swapped, b[6:8]...)
+	swapped = append(swapped, b[4:6]...)
+	swapped = append(swapped, b[0:4]...)
+	return append(swapped, b[8:]...)
+}
+
+func unswapUUIDTime(b []byte) []byte {
+	unswapped := make([]byte, 0, uuidLen)
+	unswapped = append(unswapped, b[4:8]...)
+	unswapped = append(unswapped, b[2:4]...)
+	unswapped = append(unswapped, b[0:2]...)
+	return append(unswapped, b[8:]...)
+}
+
+// uuidSwapFlag returns the optional swap_flag argument of UUID_TO_BIN and BIN_TO_UUID.
+func uuidSwapFlag(args []types.Datum, ctx context.Context) (bool, error) {
+	if len(args) < 2 {
+		return false, nil
+	}
+	swap, err := args[1].ToBool(ctx.GetSessionVars().StmtCtx)
+	return swap == 1, errors.Trace(err)
+}
+
+// See https://dev.mysql.com/doc/refman/8.0/en/miscellaneous-functions.html#function_is-uuid
+func builtinIsUUID(args []types.Datum, _ context.Context) (d types.Datum, err error) {
+	if args[0].IsNull() {
+		return d, nil
+	}
+	s, err := args[0].ToString()
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	_, ok := parseUUID(s)
+	d.SetInt64(boolToInt64(ok))
+	return d, nil
+}
+
+// See https://dev.mysql.com/doc/refman/8.0/en/miscellaneous-functions.html#function_uuid-to-bin
+func builtinUUIDToBin(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
+	for _, arg := range args {
+		if arg.IsNull() {
+			return d, nil
+		}
+	}
+	s, err := args[0].ToString()
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	swap, err := uuidSwapFlag(args, ctx)
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	b, ok := parseUUID(s)
+	if !ok {
+		sc := ctx.GetSessionVars().StmtCtx
+		sc.AppendWarning(ErrWrongValueForType.GenByArgs("string", s, "uuid_to_bin"))
+		return d, nil
+	}
+	if swap {
+		b = swapUUIDTime(b)
+	}
+	d.SetBytes(b)
+	return d, nil
+}
+
+// See https://dev.mysql.com/doc/refman/8.0/en/miscellaneous-functions.html#function_bin-to-uuid
+func builtinBinToUUID(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
+	for _, arg := range args {
+		if arg.IsNull() {
+			return d, nil
+		}
+	}
+	s, err := args[0].ToString()
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	swap, err := uuidSwapFlag(args, ctx)
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	b := []byte(s)
+	if len(b) != uuidLen {
+		sc := ctx.GetSessionVars().StmtCtx
+		sc.AppendWarning(ErrWrongValueForType.GenByArgs("string", hex.EncodeToString(b), "bin_to_uuid"))
+		return d, nil
+	}
+	if swap {
+		b = unswapUUIDTime(b)
+	}
+	d.SetString(formatUUID(b))
+	return d, nil
+}
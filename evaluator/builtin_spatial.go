@@ -0,0 +1,148 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package evaluator
+
+import (
+	"encoding/binary"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// Only points are supported, as the well-known binary (WKB) and text (WKT) representations below.
+// See https://dev.mysql.com/doc/refman/5.7/en/gis-data-formats.html
+const (
+	wkbXDR       = 0 // big endian
+	wkbNDR       = 1 // little endian
+	wkbPoint     = 1
+	wkbPointSize = 1 + 4 + 8 + 8
+)
+
+// encodeWKBPoint returns the little endian WKB of the point (x, y).
+func encodeWKBPoint(x, y float64) []byte {
+	b := make([]byte, wkbPointSize)
+	b[0] = wkbNDR
+	binary.LittleEndian.PutUint32(b[1:], wkbPoint)
+	binary.LittleEndian.PutUint64(b[5:], math.Float64bits(x))
+	binary.LittleEndian.PutUint64(b[13:], math.Float64bits(y))
+	return b
+}
+
+// decodeWKBPoint decodes the WKB of a point in either byte order.
+func decodeWKBPoint(b []byte) (x, y float64, ok bool) {
+	if len(b) != wkbPointSize {
+		return 0, 0, false
+	}
+	var order binary.ByteOrder
+	switch b[0] {
+	case wkbXDR:
+		order = binary.BigEndian
+	case wkbNDR:
+		order = binary.LittleEndian
+	default:
+		return 0, 0, false
+	}
+	if order.Uint32(b[1:]) != wkbPoint {
+		return 0, 0, false
+	}
+	x = math.Float64frombits(order.Uint64(b[5:]))
+	y = math.Float64frombits(order.Uint64(b[13:]))
+	if math.IsNaN(x) || math.IsNaN(y) || math.IsInf(x, 0) || math.IsInf(y, 0) {
+		return 0, 0, false
+	}
+	return x, y, true
+}
+
+// parseWKTPoint parses a point like "POINT(1 2)", case insensitively and allowing spaces around
+// the parentheses.
+func parseWKTPoint(s string) (x, y float64, ok bool) {
+	s = strings.TrimSpace(s)
+	if len(s) < len("point") || !strings.EqualFold(s[:len("point")], "point") {
+		return 0, 0, false
+	}
+	s = strings.TrimSpace(s[len("point"):])
+	if !strings.HasPrefix(s, "(") || !strings.HasSuffix(s, ")") {
+		return 0, 0, false
+	}
+	coords := strings.Fields(s[1 : len(s)-1])
+	if len(coords) != 2 {
+		return 0, 0, false
+	}
+	x, err := strconv.ParseFloat(coords[0], 64)
+	if err != nil || math.IsInf(x, 0) || math.IsNaN(x) {
+		return 0, 0, false
+	}
+	y, err = strconv.ParseFloat(coords[1], 64)
+	if err != nil || math.IsInf(y, 0) || math.IsNaN(y) {
+		return 0, 0, false
+	}
+	return x, y, true
+}
+
+// formatWKTPoint returns the WKT of the point (x, y), its coordinates formatted like doubles.
+func formatWKTPoint(x, y float64) string {
+	return "POINT(" + strconv.FormatFloat(x, 'f', -1, 64) + " " + strconv.FormatFloat(y, 'f', -1, 64) + ")"
+}
+
+func inferGeometry(_ []*types.FieldType) (*types.FieldType, error) {
+	return types.NewFieldType(mysql.TypeGeometry), nil
+}
+
+// See https://dev.mysql.com/doc/refman/5.7/en/gis-mysql-specific-functions.html#function_point
+func builtinPoint(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
+	if args[0].IsNull() || args[1].IsNull() {
+		return d, nil
+	}
+	sc := ctx.GetSessionVars().StmtCtx
+	x, err := args[0].ToFloat64(sc)
+	if err != nil {
+		return d, nil
+	}
+	y, err := args[1].ToFloat64(sc)
+	if err != nil {
+		return d, nil
+	}
+	d.SetBytes(encodeWKBPoint(x, y))
+	return d, nil
+}
+
+// See https://dev.mysql.com/doc/refman/5.7/en/gis-format-conversion-functions.html#function_st-astext
+func builtinSTAsText(args []types.Datum, _ context.Context) (d types.Datum, err error) {
+	if args[0].IsNull() {
+		return d, nil
+	}
+	if x, y, ok := decodeWKBPoint(args[0].GetBytes()); ok {
+		d.SetString(formatWKTPoint(x, y))
+	}
+	return d, nil
+}
+
+// See https://dev.mysql.com/doc/refman/5.7/en/gis-wkt-functions.html#function_st-geomfromtext
+func builtinSTGeomFromText(args []types.Datum, _ context.Context) (d types.Datum, err error) {
+	if args[0].IsNull() {
+		return d, nil
+	}
+	s, err := args[0].ToString()
+	if err != nil {
+		return d, nil
+	}
+	if x, y, ok := parseWKTPoint(s); ok {
+		d.SetBytes(encodeWKBPoint(x, y))
+	}
+	return d, nil
+}
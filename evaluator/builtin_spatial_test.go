@@ -0,0 +1,89 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package evaluator
+
+import (
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/util/testleak"
+	"github.com/pingcap/tidb/util/types"
+)
+
+func (s *testEvaluatorSuite) TestPointRoundTrip(c *C) {
+	defer testleak.AfterTest(c)()
+	tbl := []struct {
+		x   interface{}
+		y   interface{}
+		wkt string
+	}{
+		{1, 2, "POINT(1 2)"},
+		{-1.5, 0.25, "POINT(-1.5 0.25)"},
+		{"3", "4.5", "POINT(3 4.5)"},
+		{0, -0.000001, "POINT(0 -0.000001)"},
+		{1e20, 123456789, "POINT(100000000000000000000 123456789)"},
+	}
+	for _, t := range tbl {
+		wkb, err := builtinPoint(types.MakeDatums(t.x, t.y), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(wkb.GetBytes(), HasLen, wkbPointSize)
+
+		wkt, err := builtinSTAsText([]types.Datum{wkb}, s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(wkt.GetString(), Equals, t.wkt)
+
+		geom, err := builtinSTGeomFromText([]types.Datum{wkt}, s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(geom.GetBytes(), DeepEquals, wkb.GetBytes())
+	}
+
+	// WKT is read case insensitively with any spacing, and WKB in either byte order.
+	geom, err := builtinSTGeomFromText(types.MakeDatums(" point ( 1   2 ) "), s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(geom.GetBytes(), DeepEquals, encodeWKBPoint(1, 2))
+	xdr := []byte{0, 0, 0, 0, 1, 0x3f, 0xf0, 0, 0, 0, 0, 0, 0, 0x40, 0, 0, 0, 0, 0, 0, 0}
+	wkt, err := builtinSTAsText(types.MakeDatums(xdr), s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(wkt.GetString(), Equals, "POINT(1 2)")
+}
+
+func (s *testEvaluatorSuite) TestSpatialInvalidInput(c *C) {
+	defer testleak.AfterTest(c)()
+	tbl := []struct {
+		name string
+		args []interface{}
+	}{
+		{ast.Point, []interface{}{nil, 1}},
+		{ast.Point, []interface{}{1, nil}},
+		{ast.STAsText, []interface{}{nil}},
+		{ast.STAsText, []interface{}{"POINT(1 2)"}},
+		{ast.STAsText, []interface{}{[]byte{1, 2, 0, 0, 0}}},
+		{ast.STAsText, []interface{}{encodeWKBPoint(1, 2)[:20]}},
+		{ast.STAsText, []interface{}{append([]byte{1, 2, 0, 0, 0}, encodeWKBPoint(1, 2)[5:]...)}},
+		{ast.STAsText, []interface{}{12}},
+		{ast.STGeomFromText, []interface{}{nil}},
+		{ast.STGeomFromText, []interface{}{""}},
+		{ast.STGeomFromText, []interface{}{"POINT(1)"}},
+		{ast.STGeomFromText, []interface{}{"POINT(1 2 3)"}},
+		{ast.STGeomFromText, []interface{}{"POINT(1 a)"}},
+		{ast.STGeomFromText, []interface{}{"POINT 1 2"}},
+		{ast.STGeomFromText, []interface{}{"POINT(NaN 2)"}},
+		{ast.STGeomFromText, []interface{}{"LINESTRING(0 0, 1 1)"}},
+		{ast.STGeomFromText, []interface{}{"POINTS(1 2)"}},
+	}
+	for _, t := range tbl {
+		d, err := Funcs[t.name].F(types.MakeDatums(t.args...), s.ctx)
+		c.Assert(err, IsNil, Commentf("%s%v", t.name, t.args))
+		c.Assert(d.Kind(), Equals, types.KindNull, Commentf("%s%v", t.name, t.args))
+	}
+}
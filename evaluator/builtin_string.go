@@ -18,79 +18,165 @@
 package evaluator
 
 import (
+	"bytes"
 	"encoding/hex"
 	"fmt"
 	"math"
+	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/juju/errors"
-	"github.com/ngaut/log"
 	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/terror"
 	"github.com/pingcap/tidb/util/charset"
-	"github.com/pingcap/tidb/util/hack"
 	"github.com/pingcap/tidb/util/stringutil"
 	"github.com/pingcap/tidb/util/types"
-	"golang.org/x/text/transform"
 )
 
 // See https://dev.mysql.com/doc/refman/5.7/en/string-functions.html
 func builtinLength(args []types.Datum, _ context.Context) (d types.Datum, err error) {
+	if args[0].Kind() == types.KindMysqlBit {
+		d.SetInt64(int64(bitByteWidth(args[0].GetMysqlBit())))
+		return d, nil
+	}
+	s, isNull, err := stringArg(args[0])
+	if isNull || err != nil {
+		return d, errors.Trace(err)
+	}
+	d.SetInt64(int64(len(s)))
+	return d, nil
+}
+
+// stringArg returns arg as a string, or isNull if it is NULL. String functions get their argument
+// through it rather than checking for NULL themselves, so that none of them misses returning NULL
+// for a NULL argument.
+func stringArg(arg types.Datum) (s string, isNull bool, err error) {
+	if arg.IsNull() {
+		return "", true, nil
+	}
+	s, err = arg.ToString()
+	return s, false, errors.Trace(err)
+}
+
+// See https://dev.mysql.com/doc/refman/5.7/en/string-functions.html#function_octet-length
+func builtinOctetLength(args []types.Datum, ctx context.Context) (types.Datum, error) {
+	// OCTET_LENGTH is a synonym for LENGTH, both count bytes.
+	return builtinLength(args, ctx)
+}
+
+// See https://dev.mysql.com/doc/refman/5.7/en/string-functions.html#function_char-length
+func builtinCharLength(args []types.Datum, _ context.Context) (d types.Datum, err error) {
 	switch args[0].Kind() {
-	case types.KindNull:
+	case types.KindMysqlHex:
+		// Binary strings have a character per byte.
+		d.SetInt64(int64(len(args[0].GetMysqlHex().ToString())))
 		return d, nil
-	default:
-		s, err := args[0].ToString()
-		if err != nil {
-			return d, errors.Trace(err)
-		}
-		d.SetInt64(int64(len(s)))
+	case types.KindMysqlBit:
+		d.SetInt64(int64(bitByteWidth(args[0].GetMysqlBit())))
 		return d, nil
+	case types.KindMysqlSet:
+		d.SetInt64(int64(utf8.RuneCountInString(args[0].GetMysqlSet().Name)))
+		return d, nil
+	case types.KindMysqlEnum:
+		d.SetInt64(int64(utf8.RuneCountInString(args[0].GetMysqlEnum().Name)))
+		return d, nil
+	}
+	s, isNull, err := stringArg(args[0])
+	if isNull || err != nil {
+		return d, errors.Trace(err)
 	}
+	d.SetInt64(int64(utf8.RuneCountInString(s)))
+	return d, nil
+}
+
+// bitByteWidth returns the length of the binary string of a BIT value, a byte per started 8 bits.
+func bitByteWidth(b types.Bit) int {
+	return (b.Width + 7) / 8
 }
 
 // See https://dev.mysql.com/doc/refman/5.7/en/string-functions.html#function_ascii
 func builtinASCII(args []types.Datum, _ context.Context) (d types.Datum, err error) {
-	switch args[0].Kind() {
-	case types.KindNull:
-		return d, nil
-	default:
-		s, err := args[0].ToString()
-		if err != nil {
-			return d, errors.Trace(err)
-		}
-		if len(s) == 0 {
-			d.SetInt64(0)
-			return d, nil
-		}
-		d.SetInt64(int64(s[0]))
+	s, isNull, err := stringArg(args[0])
+	if isNull || err != nil {
+		return d, errors.Trace(err)
+	}
+	d.SetInt64(firstByte(s))
+	return d, nil
+}
+
+// firstByte returns the numeric value of the leading byte of s, or 0 if s is empty. ASCII works on
+// bytes whatever the charset, so for a multibyte character it is the first byte of its encoding.
+func firstByte(s string) int64 {
+	if len(s) == 0 {
+		return 0
+	}
+	return int64(s[0])
+}
+
+// See https://dev.mysql.com/doc/refman/5.7/en/string-functions.html#function_ord
+func builtinOrd(args []types.Datum, _ context.Context) (d types.Datum, err error) {
+	s, isNull, err := stringArg(args[0])
+	if isNull || err != nil {
+		return d, errors.Trace(err)
+	}
+	_, size := utf8.DecodeRuneInString(s)
+	if size <= 1 {
+		d.SetInt64(firstByte(s))
 		return d, nil
 	}
+	// A multibyte leading character is the base 256 number made of its bytes.
+	var code int64
+	for i := 0; i < size; i++ {
+		code = code*256 + int64(s[i])
+	}
+	d.SetInt64(code)
+	return d, nil
 }
 
 // See https://dev.mysql.com/doc/refman/5.7/en/string-functions.html#function_concat
-func builtinConcat(args []types.Datum, _ context.Context) (d types.Datum, err error) {
-	var s []byte
+func builtinConcat(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
+	// Size the buffer once from the string arguments, so that long argument lists don't
+	// reallocate it over and over.
+	var size int64
 	for _, a := range args {
 		if a.IsNull() {
 			return d, nil
 		}
+		if a.Kind() == types.KindString || a.Kind() == types.KindBytes {
+			size += int64(len(a.GetBytes()))
+		}
+	}
+	maxPacket := maxAllowedPacket(ctx)
+	if size > maxPacket {
+		size = maxPacket
+	}
+	var buf bytes.Buffer
+	buf.Grow(int(size))
+	for _, a := range args {
 		var ss string
 		ss, err = a.ToString()
 		if err != nil {
 			return d, errors.Trace(err)
 		}
-		s = append(s, []byte(ss)...)
+		if !checkResultLen(ctx, "concat", int64(buf.Len()+len(ss))) {
+			return d, nil
+		}
+		buf.WriteString(ss)
 	}
-	d.SetBytesAsString(s)
+	d.SetBytesAsString(buf.Bytes())
 	return d, nil
 }
 
 // See https://dev.mysql.com/doc/refman/5.7/en/string-functions.html#function_concat-ws
-func builtinConcatWS(args []types.Datum, _ context.Context) (d types.Datum, err error) {
+func builtinConcatWS(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
 	var sep string
 	s := make([]string, 0, len(args))
+	var resultLen int64
 	for i, a := range args {
 		if a.IsNull() {
 			if i == 0 {
@@ -107,6 +193,13 @@ func builtinConcatWS(args []types.Datum, _ context.Context) (d types.Datum, err
 			sep = ss
 			continue
 		}
+		resultLen += int64(len(ss))
+		if len(s) > 0 {
+			resultLen += int64(len(sep))
+		}
+		if !checkResultLen(ctx, "concat_ws", resultLen) {
+			return d, nil
+		}
 		s = append(s, ss)
 	}
 
@@ -116,77 +209,153 @@ func builtinConcatWS(args []types.Datum, _ context.Context) (d types.Datum, err
 
 // See https://dev.mysql.com/doc/refman/5.7/en/string-functions.html#function_left
 func builtinLeft(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
+	if args[0].IsNull() || args[1].IsNull() {
+		return d, nil
+	}
 	str, err := args[0].ToString()
 	if err != nil {
 		return d, errors.Trace(err)
 	}
-	length, err := args[1].ToInt64(ctx.GetSessionVars().StmtCtx)
+	length, err := toIntArg(ctx.GetSessionVars().StmtCtx, args[1])
 	if err != nil {
 		return d, errors.Trace(err)
 	}
-	l := int(length)
-	if l < 0 {
-		l = 0
-	} else if l > len(str) {
-		l = len(str)
+	d.SetString(substring(str, 1, length, true, isBinaryString(args[0])))
+	return d, nil
+}
+
+// See https://dev.mysql.com/doc/refman/5.7/en/string-functions.html#function_right
+func builtinRight(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
+	if args[0].IsNull() || args[1].IsNull() {
+		return d, nil
+	}
+	str, err := args[0].ToString()
+	if err != nil {
+		return d, errors.Trace(err)
 	}
-	d.SetString(str[:l])
+	length, err := toIntArg(ctx.GetSessionVars().StmtCtx, args[1])
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	binary := isBinaryString(args[0])
+	n := charLen(str, binary)
+	if length > n {
+		length = n
+	} else if length < 0 {
+		length = 0
+	}
+	d.SetString(sliceChars(str, binary, n-length, n))
 	return d, nil
 }
 
+// isBinaryString reports whether d is a binary string, which string functions handle as a
+// sequence of bytes rather than of characters.
+func isBinaryString(d types.Datum) bool {
+	switch d.Kind() {
+	case types.KindMysqlHex, types.KindMysqlBit:
+		return true
+	case types.KindString, types.KindBytes:
+		return d.Collation() == mysql.BinaryCollationID
+	}
+	return false
+}
+
+// reverseBytes returns s with its bytes in reverse order.
+func reverseBytes(s string) string {
+	b := make([]byte, len(s))
+	for i := 0; i < len(s); i++ {
+		b[len(s)-1-i] = s[i]
+	}
+	return string(b)
+}
+
+// charLen returns the length of s in characters, or in bytes if s is binary.
+func charLen(s string, binary bool) int64 {
+	if binary {
+		return int64(len(s))
+	}
+	return int64(utf8.RuneCountInString(s))
+}
+
+// sliceChars returns the characters of s from start up to end, counting bytes if s is binary.
+func sliceChars(s string, binary bool, start, end int64) string {
+	if binary || int64(len(s)) == charLen(s, false) {
+		return s[start:end]
+	}
+	var i, startOffset int64
+	for offset := range s {
+		if i == start {
+			startOffset = int64(offset)
+		}
+		if i == end {
+			return s[startOffset:offset]
+		}
+		i++
+	}
+	if start == i {
+		startOffset = int64(len(s))
+	}
+	return s[startOffset:]
+}
+
 // See https://dev.mysql.com/doc/refman/5.7/en/string-functions.html#function_repeat
-func builtinRepeat(args []types.Datum, _ context.Context) (d types.Datum, err error) {
+func builtinRepeat(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
+	if args[0].IsNull() || args[1].IsNull() {
+		return d, nil
+	}
 	str, err := args[0].ToString()
 	if err != nil {
 		return d, err
 	}
 	ch := fmt.Sprintf("%v", str)
-	num := 0
-	x := args[1]
-	switch x.Kind() {
-	case types.KindInt64:
-		num = int(x.GetInt64())
-	case types.KindUint64:
-		num = int(x.GetUint64())
-	}
-	if num < 1 {
+	num, err := toIntArg(ctx.GetSessionVars().StmtCtx, args[1])
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	if num < 1 || len(ch) == 0 {
 		d.SetString("")
 		return d, nil
 	}
-	d.SetString(strings.Repeat(ch, num))
+	resultLen := int64(math.MaxInt64)
+	if num <= math.MaxInt64/int64(len(ch)) {
+		resultLen = num * int64(len(ch))
+	}
+	if !checkResultLen(ctx, "repeat", resultLen) {
+		return d, nil
+	}
+	d.SetString(strings.Repeat(ch, int(num)))
 	return d, nil
 }
 
 // See https://dev.mysql.com/doc/refman/5.7/en/string-functions.html#function_lower
 func builtinLower(args []types.Datum, _ context.Context) (d types.Datum, err error) {
 	x := args[0]
-	switch x.Kind() {
-	case types.KindNull:
-		return d, nil
-	default:
-		s, err := x.ToString()
-		if err != nil {
-			return d, errors.Trace(err)
-		}
-		d.SetString(strings.ToLower(s))
+	s, isNull, err := stringArg(x)
+	if isNull || err != nil {
+		return d, errors.Trace(err)
+	}
+	if isBinaryString(x) {
+		// Binary strings have no letter case.
+		d.SetString(s)
 		return d, nil
 	}
+	d.SetString(mapCase(s, unicode.ToLower))
+	return d, nil
 }
 
 // See https://dev.mysql.com/doc/refman/5.7/en/string-functions.html#function_reverse
 func builtinReverse(args []types.Datum, _ context.Context) (d types.Datum, err error) {
 	x := args[0]
-	switch x.Kind() {
-	case types.KindNull:
-		return d, nil
-	default:
-		s, err := x.ToString()
-		if err != nil {
-			return d, errors.Trace(err)
-		}
-		d.SetString(stringutil.Reverse(s))
+	s, isNull, err := stringArg(x)
+	if isNull || err != nil {
+		return d, errors.Trace(err)
+	}
+	if isBinaryString(x) {
+		d.SetString(reverseBytes(s))
 		return d, nil
 	}
+	d.SetString(stringutil.Reverse(s))
+	return d, nil
 }
 
 // See http://dev.mysql.com/doc/refman/5.7/en/string-functions.html#function_space
@@ -196,13 +365,7 @@ func builtinSpace(args []types.Datum, ctx context.Context) (d types.Datum, err e
 		return d, nil
 	}
 	sc := ctx.GetSessionVars().StmtCtx
-	if x.Kind() == types.KindString || x.Kind() == types.KindBytes {
-		if _, e := types.StrToInt(sc, x.GetString()); e != nil {
-			return d, errors.Trace(e)
-		}
-	}
-
-	v, err := x.ToInt64(ctx.GetSessionVars().StmtCtx)
+	v, err := toIntArg(sc, x)
 	if err != nil {
 		return d, errors.Trace(err)
 	}
@@ -211,35 +374,143 @@ func builtinSpace(args []types.Datum, ctx context.Context) (d types.Datum, err e
 		v = 0
 	}
 
-	if v > math.MaxInt32 {
-		d.SetNull()
-	} else {
-		d.SetString(strings.Repeat(" ", int(v)))
+	if !checkResultLen(ctx, "space", v) {
+		return d, nil
 	}
+	d.SetString(strings.Repeat(" ", int(v)))
 	return d, nil
 }
 
+// checkResultLen tells whether the function name may return a string of n bytes, which must not be
+// longer than max_allowed_packet. A function which may not returns NULL, checkResultLen appends
+// the warning MySQL gives for it.
+func checkResultLen(ctx context.Context, name string, n int64) bool {
+	maxPacket := maxAllowedPacket(ctx)
+	if n <= maxPacket {
+		return true
+	}
+	sc := ctx.GetSessionVars().StmtCtx
+	sc.AppendWarning(ErrWarnAllowedPacketOverflowed.GenByArgs(name, maxPacket))
+	return false
+}
+
+// maxAllowedPacket returns the max_allowed_packet of the session, which limits
+// the length of the string a function may produce.
+func maxAllowedPacket(ctx context.Context) int64 {
+	v, ok := ctx.GetSessionVars().Systems["max_allowed_packet"]
+	if !ok {
+		v = variable.SysVars["max_allowed_packet"].Value
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n <= 0 {
+		return math.MaxInt32
+	}
+	return n
+}
+
+// toIntArg converts the integer argument d of a string function. A string takes its leading
+// numeric part like MySQL does, "  42abc" being 42, and the truncation is an error, a warning or
+// ignored as the statement context says. A value out of the int64 range is clamped with a warning.
+func toIntArg(sc *variable.StatementContext, d types.Datum) (int64, error) {
+	var (
+		v   int64
+		err error
+	)
+	if d.Kind() == types.KindString || d.Kind() == types.KindBytes {
+		v, err = types.StrToInt(sc, d.GetString())
+	} else {
+		v, err = d.ToInt64(sc)
+	}
+	if err != nil && terror.ErrorEqual(err, types.ErrOverflow) {
+		sc.AppendWarning(err)
+		return v, nil
+	}
+	return v, errors.Trace(err)
+}
+
+// toUintArg is toIntArg for arguments taken as unsigned 64-bit integers, like bit masks. An unsigned
+// value is kept as is and a negative one is taken in its two's complement form.
+func toUintArg(sc *variable.StatementContext, d types.Datum) (uint64, error) {
+	if d.Kind() == types.KindUint64 {
+		return d.GetUint64(), nil
+	}
+	v, err := toIntArg(sc, d)
+	return uint64(v), errors.Trace(err)
+}
+
 // See https://dev.mysql.com/doc/refman/5.7/en/string-functions.html#function_upper
 func builtinUpper(args []types.Datum, _ context.Context) (d types.Datum, err error) {
 	x := args[0]
-	switch x.Kind() {
-	case types.KindNull:
+	s, isNull, err := stringArg(x)
+	if isNull || err != nil {
+		return d, errors.Trace(err)
+	}
+	if isBinaryString(x) {
+		// Binary strings have no letter case.
+		d.SetString(s)
 		return d, nil
-	default:
-		s, err := x.ToString()
-		if err != nil {
-			return d, errors.Trace(err)
+	}
+	d.SetString(mapCase(s, unicode.ToUpper))
+	return d, nil
+}
+
+// mapCase applies the case mapping f to every character of s. LOWER and UPPER follow the Unicode
+// default simple case mapping, one character to one character like utf8_general_ci, and apply no
+// locale-specific rules such as the Turkish dotted and dotless i. Bytes that aren't valid UTF-8 are
+// kept as they are rather than replaced by U+FFFD, so binary data passes through unchanged.
+// Mapping ASCII which f leaves unchanged, the common case, returns s itself without allocating.
+func mapCase(s string, f func(rune) rune) string {
+	if isMappedASCII(s, f) {
+		return s
+	}
+	return string(appendMapCase(make([]byte, 0, len(s)), s, f))
+}
+
+// isMappedASCII reports whether s is ASCII which the case mapping f leaves unchanged.
+func isMappedASCII(s string, f func(rune) rune) bool {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c >= utf8.RuneSelf || f(rune(c)) != rune(c) {
+			return false
 		}
-		d.SetString(strings.ToUpper(s))
-		return d, nil
 	}
+	return true
+}
+
+// appendMapCase appends s with the case mapping f applied to buf, like mapCase.
+func appendMapCase(buf []byte, s string, f func(rune) rune) []byte {
+	if isMappedASCII(s, f) {
+		return append(buf, s...)
+	}
+	var tmp [utf8.UTFMax]byte
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			buf = append(buf, s[i])
+		} else {
+			n := utf8.EncodeRune(tmp[:], f(r))
+			buf = append(buf, tmp[:n]...)
+		}
+		i += size
+	}
+	return buf
 }
 
 // See https://dev.mysql.com/doc/refman/5.7/en/string-comparison-functions.html
-func builtinStrcmp(args []types.Datum, _ context.Context) (d types.Datum, err error) {
+// Two ENUM or SET values are compared by their numbers, which follow the order of the members in
+// the definition, rather than by their names, and strings are compared in the collation derived
+// for them, as the comparison operators compare them.
+func builtinStrcmp(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
 	if args[0].IsNull() || args[1].IsNull() {
 		return d, nil
 	}
+	if isEnumOrSetKind(args[0].Kind()) && isEnumOrSetKind(args[1].Kind()) {
+		res, err := args[0].CompareDatum(ctx.GetSessionVars().StmtCtx, args[1])
+		if err != nil {
+			return d, errors.Trace(err)
+		}
+		d.SetInt64(int64(res))
+		return d, nil
+	}
 	left, err := args[0].ToString()
 	if err != nil {
 		return d, errors.Trace(err)
@@ -248,12 +519,14 @@ func builtinStrcmp(args []types.Datum, _ context.Context) (d types.Datum, err er
 	if err != nil {
 		return d, errors.Trace(err)
 	}
-	res := types.CompareString(left, right)
+	collation := compareCollation(args[0], args[1])
+	res := bytes.Compare(SortKey(types.NewStringDatum(left), collation), SortKey(types.NewStringDatum(right), collation))
 	d.SetInt64(int64(res))
 	return d, nil
 }
 
 // See https://dev.mysql.com/doc/refman/5.7/en/string-functions.html#function_replace
+// REPLACE searches case sensitively whatever the collation of its arguments, like MySQL.
 func builtinReplace(args []types.Datum, _ context.Context) (d types.Datum, err error) {
 	for _, arg := range args {
 		if arg.IsNull() {
@@ -273,90 +546,157 @@ func builtinReplace(args []types.Datum, _ context.Context) (d types.Datum, err e
 	if err != nil {
 		return d, errors.Trace(err)
 	}
+	if oldStr == "" {
+		// Unlike strings.Replace, an empty search string matches nothing.
+		d.SetString(str)
+		return d, nil
+	}
 	d.SetString(strings.Replace(str, oldStr, newStr, -1))
 
 	return d, nil
 }
 
+// builtinConvert converts a string to a charset. The bytes of a binary string are taken as the
+// encoding of a string in the charset, like MySQL.
 // See https://dev.mysql.com/doc/refman/5.7/en/cast-functions.html#function_convert
-func builtinConvert(args []types.Datum, _ context.Context) (d types.Datum, err error) {
-	// Casting nil to any type returns nil
-	if args[0].Kind() != types.KindString {
+func builtinConvert(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
+	str, isNull, err := stringArg(args[0])
+	if isNull || err != nil {
+		return d, errors.Trace(err)
+	}
+	cs := args[1].GetString()
+	t, err := transcoderOf(cs)
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	sc := ctx.GetSessionVars().StmtCtx
+	if isBinaryString(args[0]) {
+		decoded, err := t.Decode(str)
+		if err != nil {
+			sc.AppendWarning(ErrInvalidCharacterString.GenByArgs(cs, fmt.Sprintf("%X", str)))
+			return d, nil
+		}
+		d.SetString(decoded)
 		return d, nil
 	}
+	d.SetString(castToCharset(sc, str, cs, repertoireOf(cs, t)))
+	return d, nil
+}
 
-	str := args[0].GetString()
-	Charset := args[1].GetString()
-
-	if strings.ToLower(Charset) == "ascii" {
-		d.SetString(str)
-		return d, nil
-	} else if strings.ToLower(Charset) == "utf8mb4" {
-		d.SetString(str)
-		return d, nil
+// charsetRepertoire returns whether a character can be represented in the charset cs, or nil if
+// every character can. It returns ErrUnknownCharacterSet if cs has no transcoder.
+func charsetRepertoire(cs string) (inRepertoire func(rune) bool, err error) {
+	t, err := transcoderOf(cs)
+	if err != nil {
+		return nil, errors.Trace(err)
 	}
+	return repertoireOf(cs, t), nil
+}
 
-	encoding, _ := charset.Lookup(Charset)
-	if encoding == nil {
-		return d, errors.Errorf("unknown encoding: %s", Charset)
+// repertoireOf returns the repertoire of the charset cs, whose transcoder is t, like
+// charsetRepertoire.
+func repertoireOf(cs string, t Transcoder) func(rune) bool {
+	switch strings.ToLower(cs) {
+	case charset.CharsetUTF8MB4, charset.CharsetBin:
+		return nil
 	}
+	return func(r rune) bool {
+		_, err := t.Encode(string(r))
+		return err == nil
+	}
+}
 
-	target, _, err := transform.String(encoding.NewDecoder(), str)
-	if err != nil {
-		log.Errorf("Convert %s to %s with error: %v", str, Charset, err)
-		return d, errors.Trace(err)
+// castToCharset returns s as a string of the charset cs, which charsetRepertoire tells the
+// characters of. The characters cs can't represent become '?', with a warning.
+func castToCharset(sc *variable.StatementContext, s, cs string, inRepertoire func(rune) bool) string {
+	if inRepertoire == nil {
+		return s
 	}
-	d.SetString(target)
-	return d, nil
+	var buf bytes.Buffer
+	lossy := false
+	for _, r := range s {
+		if !inRepertoire(r) {
+			r, lossy = '?', true
+		}
+		buf.WriteRune(r)
+	}
+	if !lossy {
+		return s
+	}
+	sc.AppendWarning(ErrInvalidCharacterString.GenByArgs(cs, s))
+	return buf.String()
 }
 
-func builtinSubstring(args []types.Datum, _ context.Context) (d types.Datum, err error) {
+func builtinSubstring(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
 	// The meaning of the elements of args.
 	// arg[0] -> StrExpr
 	// arg[1] -> Pos
 	// arg[2] -> Len (Optional)
+	for _, arg := range args {
+		if arg.IsNull() {
+			return d, nil
+		}
+	}
 	str, err := args[0].ToString()
 	if err != nil {
-		return d, errors.Errorf("Substring invalid args, need string but get %T", args[0].GetValue())
+		return d, convertError(err, "STRING", args[0])
 	}
 
-	if args[1].Kind() != types.KindInt64 {
-		return d, errors.Errorf("Substring invalid pos args, need int but get %T", args[1].GetValue())
+	sc := ctx.GetSessionVars().StmtCtx
+	pos, err := toIntArg(sc, args[1])
+	if err != nil {
+		return d, errors.Trace(err)
 	}
-	pos := args[1].GetInt64()
 
 	length, hasLen := int64(-1), false
 	if len(args) == 3 {
-		if args[2].Kind() != types.KindInt64 {
-			return d, errors.Errorf("Substring invalid pos args, need int but get %T", args[2].GetValue())
+		if length, err = toIntArg(sc, args[2]); err != nil {
+			return d, errors.Trace(err)
 		}
-		length, hasLen = args[2].GetInt64(), true
+		hasLen = true
 	}
+	d.SetString(substring(str, pos, length, hasLen, isBinaryString(args[0])))
+	return d, nil
+}
+
+// substring returns the part of str starting at pos, length characters long if hasLen. A binary
+// str is sliced by bytes.
+func substring(str string, pos, length int64, hasLen, binary bool) string {
 	// The forms without a len argument return a substring from string str starting at position pos.
 	// The forms with a len argument return a substring len characters long from string str, starting at position pos.
 	// The forms that use FROM are standard SQL syntax. It is also possible to use a negative value for pos.
 	// In this case, the beginning of the substring is pos characters from the end of the string, rather than the beginning.
 	// A negative value may be used for pos in any of the forms of this function.
-	if pos < 0 {
-		pos = int64(len(str)) + pos
-	} else {
-		pos--
+	start, end := substringRange(charLen(str, binary), pos, length, hasLen)
+	return sliceChars(str, binary, start, end)
+}
+
+// substringRange returns the characters [start, end) that SUBSTRING takes from a string of n
+// characters. A position out of the string, a zero position and a length that isn't positive all
+// give an empty range, whether the arguments are constants or computed.
+func substringRange(n, pos, length int64, hasLen bool) (start, end int64) {
+	switch {
+	case pos < 0:
+		start = n + pos
+	case pos > 0:
+		start = pos - 1
+	default:
+		return n, n
 	}
-	if pos > int64(len(str)) || pos < int64(0) {
-		pos = int64(len(str))
+	if start < 0 || start > n {
+		return n, n
 	}
-	if hasLen {
-		if end := pos + length; end < pos {
-			d.SetString("")
-		} else if end > int64(len(str)) {
-			d.SetString(str[pos:])
-		} else {
-			d.SetString(str[pos:end])
-		}
-	} else {
-		d.SetString(str[pos:])
+	if !hasLen {
+		return start, n
 	}
-	return d, nil
+	if length <= 0 {
+		return start, start
+	}
+	// Compare to what is left rather than adding, length may be as large as math.MaxInt64.
+	if length >= n-start {
+		return start, n
+	}
+	return start, start + length
 }
 
 // See https://dev.mysql.com/doc/refman/5.7/en/string-functions.html#function_substring-index
@@ -367,19 +707,19 @@ func builtinSubstringIndex(args []types.Datum, ctx context.Context) (d types.Dat
 	// args[2] -> Count
 	str, err := args[0].ToString()
 	if err != nil {
-		return d, errors.Errorf("Substring_Index invalid args, need string but get %T", args[0].GetValue())
+		return d, convertError(err, "STRING", args[0])
 	}
 
 	delim, err := args[1].ToString()
 	if err != nil {
-		return d, errors.Errorf("Substring_Index invalid delim, need string but get %T", args[1].GetValue())
+		return d, convertError(err, "STRING", args[1])
 	}
 	if len(delim) == 0 {
 		d.SetString("")
 		return d, nil
 	}
 
-	c, err := args[2].ToInt64(ctx.GetSessionVars().StmtCtx)
+	c, err := toIntArg(ctx.GetSessionVars().StmtCtx, args[2])
 	if err != nil {
 		return d, errors.Trace(err)
 	}
@@ -406,58 +746,105 @@ func builtinSubstringIndex(args []types.Datum, ctx context.Context) (d types.Dat
 	return d, nil
 }
 
+// builtinLocate returns the position of the first occurrence of substr in str, at or after pos if
+// given, or 0 if there is none. Positions count characters, or bytes if either string is binary. A
+// pos below 1 or past the end of str finds nothing.
 // See https://dev.mysql.com/doc/refman/5.7/en/string-functions.html#function_locate
 func builtinLocate(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
 	// The meaning of the elements of args.
 	// args[0] -> SubStr
 	// args[1] -> Str
 	// args[2] -> Pos
-	// eval str
-	if args[1].IsNull() {
-		return d, nil
+	for _, arg := range args {
+		if arg.IsNull() {
+			return d, nil
+		}
 	}
 	str, err := args[1].ToString()
 	if err != nil {
 		return d, errors.Trace(err)
 	}
-	// eval substr
-	if args[0].IsNull() {
-		return d, nil
-	}
 	subStr, err := args[0].ToString()
 	if err != nil {
 		return d, errors.Trace(err)
 	}
-	// eval pos
-	pos := int64(0)
+	binary := isBinaryString(args[0]) || isBinaryString(args[1])
+	n := charLen(str, binary)
+	// start is the 0-based character the search starts from.
+	start := int64(0)
 	if len(args) == 3 {
-		p, err := args[2].ToInt64(ctx.GetSessionVars().StmtCtx)
+		p, err := toIntArg(ctx.GetSessionVars().StmtCtx, args[2])
 		if err != nil {
 			return d, errors.Trace(err)
 		}
-		pos = p - 1
-		if pos < 0 || pos > int64(len(str)) {
-			d.SetInt64(0)
-			return d, nil
-		}
-		if pos > int64(len(str)-len(subStr)) {
+		// An empty substr is found right after the last character, nothing is found beyond.
+		if p < 1 || p > n+1 {
 			d.SetInt64(0)
 			return d, nil
 		}
+		start = p - 1
+		str = sliceChars(str, binary, start, n)
 	}
 	if len(subStr) == 0 {
-		d.SetInt64(pos + 1)
+		d.SetInt64(start + 1)
 		return d, nil
 	}
-	i := strings.Index(str[pos:], subStr)
+	var i int
+	if isCaseInsensitive(args[0], args[1]) {
+		i = indexFold(str, subStr)
+	} else {
+		i = strings.Index(str, subStr)
+	}
 	if i == -1 {
 		d.SetInt64(0)
 		return d, nil
 	}
-	d.SetInt64(int64(i) + pos + 1)
+	d.SetInt64(start + charLen(str[:i], binary) + 1)
 	return d, nil
 }
 
+// See https://dev.mysql.com/doc/refman/5.7/en/string-functions.html#function_instr
+func builtinInstr(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
+	// INSTR(str, substr) is LOCATE(substr, str).
+	return builtinLocate([]types.Datum{args[1], args[0]}, ctx)
+}
+
+// isCaseInsensitive reports whether strings are compared case insensitively with each other: only
+// if the collation derived for them, see CollationFuncs, is a _ci one. A string without collation
+// is taken as binary.
+func isCaseInsensitive(args ...types.Datum) bool {
+	for _, arg := range args {
+		name, ok := mysql.Collations[arg.Collation()]
+		if !ok || !isCICollation(name) {
+			return false
+		}
+	}
+	return true
+}
+
+// indexFold returns the index of the first instance of the non-empty substr in s, comparing
+// characters as _ci collations do, see foldCI, or -1 if substr isn't in s.
+func indexFold(s, substr string) int {
+	for i := range s {
+		if hasPrefixFold(s[i:], substr) {
+			return i
+		}
+	}
+	return -1
+}
+
+// hasPrefixFold reports whether s begins with prefix, comparing characters as _ci collations do.
+func hasPrefixFold(s, prefix string) bool {
+	for _, r := range prefix {
+		c, size := utf8.DecodeRuneInString(s)
+		if size == 0 || foldCI(c) != foldCI(r) {
+			return false
+		}
+		s = s[size:]
+	}
+	return true
+}
+
 const spaceChars = "\n\t\r "
 
 // See http://dev.mysql.com/doc/refman/5.7/en/string-functions.html#function_hex
@@ -465,12 +852,21 @@ func builtinHex(args []types.Datum, ctx context.Context) (d types.Datum, err err
 	switch args[0].Kind() {
 	case types.KindNull:
 		return d, nil
-	case types.KindString:
-		x, err := args[0].ToString()
+	case types.KindString, types.KindBytes:
+		// A string, binary ones too, is hexed byte by byte, which UNHEX reverses.
+		d.SetString(strings.ToUpper(hex.EncodeToString(args[0].GetBytes())))
+		return d, nil
+	case types.KindMysqlEnum, types.KindMysqlSet, types.KindMysqlTime, types.KindMysqlDuration:
+		// These are strings to HEX, hexed as they are shown.
+		s, err := args[0].ToString()
 		if err != nil {
 			return d, errors.Trace(err)
 		}
-		d.SetString(strings.ToUpper(hex.EncodeToString(hack.Slice(x))))
+		d.SetString(strings.ToUpper(hex.EncodeToString([]byte(s))))
+		return d, nil
+	case types.KindMysqlBit:
+		// A BIT value is a number to HEX, the one its bytes make up.
+		d.SetString(fmt.Sprintf("%X", args[0].GetMysqlBit().Value))
 		return d, nil
 	case types.KindInt64, types.KindUint64, types.KindMysqlHex, types.KindFloat32, types.KindFloat64, types.KindMysqlDecimal:
 		x, _ := args[0].Cast(ctx.GetSessionVars().StmtCtx, types.NewFieldType(mysql.TypeLonglong))
@@ -478,7 +874,7 @@ func builtinHex(args []types.Datum, ctx context.Context) (d types.Datum, err err
 		d.SetString(strings.ToUpper(h))
 		return d, nil
 	default:
-		return d, errors.Errorf("Hex invalid args, need int or string but get %T", args[0].GetValue())
+		return d, ErrInvalidOperation.Gen("Hex invalid args, need int or string but get %T", args[0].GetValue())
 	}
 }
 
@@ -487,12 +883,8 @@ func builtinUnHex(args []types.Datum, ctx context.Context) (d types.Datum, err e
 	switch args[0].Kind() {
 	case types.KindNull:
 		return d, nil
-	case types.KindString:
-		x, err := args[0].ToString()
-		if err != nil {
-			return d, errors.Trace(err)
-		}
-		bytes, err := hex.DecodeString(x)
+	case types.KindString, types.KindBytes:
+		bytes, err := unhex(args[0].GetString())
 		if err != nil {
 			return d, nil
 		}
@@ -503,23 +895,30 @@ func builtinUnHex(args []types.Datum, ctx context.Context) (d types.Datum, err e
 		if x.IsNull() {
 			return d, nil
 		}
-		bytes, err := hex.DecodeString(x.GetString())
+		bytes, err := unhex(x.GetString())
 		if err != nil {
 			return d, nil
 		}
 		d.SetString(string(bytes))
 		return d, nil
 	default:
-		return d, errors.Errorf("Unhex invalid args, need int or string but get %T", args[0].GetValue())
+		return d, ErrInvalidOperation.Gen("Unhex invalid args, need int or string but get %T", args[0].GetValue())
+	}
+}
+
+// unhex decodes the hexadecimal digits s, an odd number of them taking a leading 0 like MySQL.
+func unhex(s string) ([]byte, error) {
+	if len(s)%2 != 0 {
+		s = "0" + s
 	}
+	return hex.DecodeString(s)
 }
 
+// builtinTrim takes str, then the direction for TRIM(direction FROM str), or remstr and the
+// direction, TrimBothDefault if it is omitted, for TRIM([direction] remstr FROM str). Without
+// remstr, spaces are trimmed.
 // See https://dev.mysql.com/doc/refman/5.7/en/string-functions.html#function_trim
 func builtinTrim(args []types.Datum, _ context.Context) (d types.Datum, err error) {
-	// args[0] -> Str
-	// args[1] -> RemStr
-	// args[2] -> Direction
-	// eval str
 	if args[0].IsNull() {
 		return d, nil
 	}
@@ -528,23 +927,26 @@ func builtinTrim(args []types.Datum, _ context.Context) (d types.Datum, err erro
 		return d, errors.Trace(err)
 	}
 	remstr := ""
-	// eval remstr
-	if len(args) > 1 {
-		if args[1].Kind() != types.KindNull {
-			remstr, err = args[1].ToString()
-			if err != nil {
-				return d, errors.Trace(err)
-			}
+	direction := ast.TrimBothDefault
+	switch len(args) {
+	case 2:
+		direction = args[1].GetValue().(ast.TrimDirectionType)
+	case 3:
+		if args[1].IsNull() {
+			return d, nil
+		}
+		remstr, err = args[1].ToString()
+		if err != nil {
+			return d, errors.Trace(err)
+		}
+		if remstr == "" {
+			// An empty remstr, unlike a missing one, removes nothing.
+			d.SetString(str)
+			return d, nil
 		}
-	}
-	// do trim
-	var result string
-	var direction ast.TrimDirectionType
-	if len(args) > 2 {
 		direction = args[2].GetValue().(ast.TrimDirectionType)
-	} else {
-		direction = ast.TrimBothDefault
 	}
+	var result string
 	if direction == ast.TrimLeading {
 		if len(remstr) > 0 {
 			result = trimLeft(str, remstr)
@@ -572,11 +974,8 @@ func builtinTrim(args []types.Datum, _ context.Context) (d types.Datum, err erro
 // See https://dev.mysql.com/doc/refman/5.7/en/string-functions.html#function_rtrim
 func trimFn(fn func(string, string) string, cutset string) BuiltinFunc {
 	return func(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
-		if args[0].IsNull() {
-			return d, nil
-		}
-		str, err := args[0].ToString()
-		if err != nil {
+		str, isNull, err := stringArg(args[0])
+		if isNull || err != nil {
 			return d, errors.Trace(err)
 		}
 		d.SetString(fn(str, cutset))
@@ -604,36 +1003,409 @@ func trimRight(str, remstr string) string {
 	}
 }
 
+// See https://dev.mysql.com/doc/refman/5.7/en/string-functions.html#function_lpad
+func builtinLpad(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
+	return pad(args, ctx, true)
+}
+
 // See https://dev.mysql.com/doc/refman/5.7/en/string-functions.html#function_rpad
 func builtinRpad(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
-	// RPAD(str,len,padstr)
-	// args[0] string, args[1] int, args[2] string
+	return pad(args, ctx, false)
+}
+
+// pad implements LPAD(str,len,padstr) and RPAD(str,len,padstr). The length counts characters,
+// so multibyte strings are cut and padded by whole characters.
+func pad(args []types.Datum, ctx context.Context, left bool) (d types.Datum, err error) {
+	if args[0].IsNull() || args[1].IsNull() || args[2].IsNull() {
+		return d, nil
+	}
 	str, err := args[0].ToString()
 	if err != nil {
 		return d, errors.Trace(err)
 	}
-	length, err := args[1].ToInt64(ctx.GetSessionVars().StmtCtx)
+	sc := ctx.GetSessionVars().StmtCtx
+	length, err := toIntArg(sc, args[1])
 	if err != nil {
 		return d, errors.Trace(err)
 	}
+	padStr, err := args[2].ToString()
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+
+	runes, padRunes := []rune(str), []rune(padStr)
+	if length < 0 || (int64(len(runes)) < length && len(padRunes) == 0) {
+		return d, nil
+	}
+	name := "rpad"
+	if left {
+		name = "lpad"
+	}
+	// Every character takes at least a byte, so check the length before building the result.
+	if !checkResultLen(ctx, name, length) {
+		return d, nil
+	}
+
 	l := int(length)
+	if l <= len(runes) {
+		d.SetString(string(runes[:l]))
+		return d, nil
+	}
+	padding := make([]rune, 0, l-len(runes))
+	for len(padding) < l-len(runes) {
+		padding = append(padding, padRunes...)
+	}
+	padding = padding[:l-len(runes)]
+	var result string
+	if left {
+		result = string(padding) + str
+	} else {
+		result = str + string(padding)
+	}
+	if !checkResultLen(ctx, name, int64(len(result))) {
+		return d, nil
+	}
+	d.SetString(result)
+	return d, nil
+}
 
-	padStr, err := args[2].ToString()
+// soundexCodes maps the letters A-Z to their Soundex digits, '0' means the
+// letter is not coded.
+const soundexCodes = "01230120022455012623010202"
+
+func soundexCode(r rune) byte {
+	if r >= 'A' && r <= 'Z' {
+		return soundexCodes[r-'A']
+	}
+	return '0'
+}
+
+// See https://dev.mysql.com/doc/refman/5.7/en/string-functions.html#function_soundex
+// Unlike the standard Soundex, the result is not truncated to four characters.
+func builtinSoundex(args []types.Datum, _ context.Context) (d types.Datum, err error) {
+	s, isNull, err := stringArg(args[0])
+	if isNull || err != nil {
+		return d, errors.Trace(err)
+	}
+	var (
+		buf   []byte
+		last  byte
+		first = true
+	)
+	for _, r := range s {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		r = unicode.ToUpper(r)
+		code := soundexCode(r)
+		switch {
+		case first:
+			buf = append(buf, string(r)...)
+			first = false
+		case code != '0' && code != last:
+			buf = append(buf, code)
+		case r == 'H' || r == 'W':
+			// Unlike vowels, H and W don't separate two letters with the same code.
+			continue
+		}
+		last = code
+	}
+	if len(buf) == 0 {
+		d.SetString("")
+		return d, nil
+	}
+	for n := utf8.RuneCount(buf); n < 4; n++ {
+		buf = append(buf, '0')
+	}
+	d.SetString(string(buf))
+	return d, nil
+}
+
+// See https://dev.mysql.com/doc/refman/5.7/en/string-functions.html#function_weight-string
+// The optional AS CHAR(n) or AS BINARY(n) clause is passed as two extra arguments,
+// the padding kind and the length.
+func builtinWeightString(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
+	if args[0].IsNull() {
+		return d, nil
+	}
+	str, err := args[0].ToString()
 	if err != nil {
 		return d, errors.Trace(err)
 	}
+	var (
+		padding string
+		length  int
+	)
+	if len(args) == 3 {
+		if padding, err = args[1].ToString(); err != nil {
+			return d, errors.Trace(err)
+		}
+		padding = strings.ToUpper(padding)
+		n, err := toIntArg(ctx.GetSessionVars().StmtCtx, args[2])
+		if err != nil {
+			return d, errors.Trace(err)
+		}
+		if n > 0 {
+			length = int(n)
+		}
+	} else if len(args) != 1 {
+		return d, ErrIncorrectParameterCount.GenByArgs(ast.WeightString)
+	}
 
-	if l < 0 || (len(str) < l && padStr == "") {
-		d.SetNull()
+	co := datumCollation(args[0])
+	if padding == "BINARY" || co.Name == charset.CollationBin {
+		b := []byte(str)
+		if padding != "" {
+			b = padBytes(b, length, 0)
+		}
+		d.SetBytes(b)
 		return d, nil
 	}
+	switch padding {
+	case "":
+		str = strings.TrimRight(str, " ")
+	case "CHAR":
+		runes := []rune(str)
+		if len(runes) > length {
+			runes = runes[:length]
+		}
+		for len(runes) < length {
+			runes = append(runes, ' ')
+		}
+		str = string(runes)
+	default:
+		return d, ErrInvalidOperation.Gen("unknown WEIGHT_STRING padding %s", padding)
+	}
+	d.SetBytes(collationWeights(str, co))
+	return d, nil
+}
+
+// padBytes pads b with pad up to n bytes, or truncates it to n bytes.
+func padBytes(b []byte, n int, pad byte) []byte {
+	if len(b) >= n {
+		return b[:n]
+	}
+	for len(b) < n {
+		b = append(b, pad)
+	}
+	return b
+}
+
+// collationWeights returns the sort weights of str under co. Case insensitive
+// collations weigh every character as foldCI does. Single byte
+// charsets use one byte per character, otherwise _ci collations use two bytes
+// and _bin collations three bytes per character, as MySQL does.
+func collationWeights(str string, co *charset.Collation) []byte {
+	ci := strings.HasSuffix(co.Name, "_ci")
+	weights := make([]byte, 0, len(str)*2)
+	for _, r := range str {
+		if ci {
+			r = foldCI(r)
+		}
+		switch {
+		case co.CharsetName == "latin1" || co.CharsetName == "ascii":
+			if r > 0xFF {
+				r = '?'
+			}
+			weights = append(weights, byte(r))
+		case ci:
+			if r > 0xFFFF {
+				r = unicode.ReplacementChar
+			}
+			weights = append(weights, byte(r>>8), byte(r))
+		default:
+			weights = append(weights, byte(r>>16), byte(r>>8), byte(r))
+		}
+	}
+	return weights
+}
+
+// See https://dev.mysql.com/doc/refman/5.7/en/string-functions.html#function_field
+// The arguments are compared as IN() compares them, see compareType.
+func builtinField(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
+	d.SetInt64(0)
+	if args[0].IsNull() {
+		return d, nil
+	}
+	sc := ctx.GetSessionVars().StmtCtx
+	cmpType := compareType(args)
+	for i, arg := range args[1:] {
+		if arg.IsNull() {
+			continue
+		}
+		cmp, err := compareAs(sc, cmpType, args[0], arg)
+		if err != nil {
+			return d, errors.Trace(err)
+		}
+		if cmp == 0 {
+			d.SetInt64(int64(i + 1))
+			return d, nil
+		}
+	}
+	return d, nil
+}
 
-	tailLen := l - len(str)
-	if tailLen > 0 {
-		repeatCount := tailLen/len(padStr) + 1
-		str = str + strings.Repeat(padStr, repeatCount)
+// See https://dev.mysql.com/doc/refman/5.7/en/string-functions.html#function_elt
+func builtinElt(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
+	if args[0].IsNull() {
+		return d, nil
+	}
+	n, err := toIntArg(ctx.GetSessionVars().StmtCtx, args[0])
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	if n < 1 || n >= int64(len(args)) || args[n].IsNull() {
+		return d, nil
+	}
+	s, err := args[n].ToString()
+	if err != nil {
+		return d, errors.Trace(err)
 	}
-	d.SetString(str[:l])
+	d.SetString(s)
+	return d, nil
+}
 
+// See https://dev.mysql.com/doc/refman/5.7/en/string-functions.html#function_make-set
+func builtinMakeSet(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
+	if args[0].IsNull() {
+		return d, nil
+	}
+	bits, err := toUintArg(ctx.GetSessionVars().StmtCtx, args[0])
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	var sets []string
+	for i, arg := range args[1:] {
+		// Only 64 bits can be set, the strings past the 64th are never picked.
+		if i >= 64 {
+			break
+		}
+		if bits&(1<<uint(i)) == 0 || arg.IsNull() {
+			continue
+		}
+		s, err := arg.ToString()
+		if err != nil {
+			return d, errors.Trace(err)
+		}
+		sets = append(sets, s)
+	}
+	d.SetString(strings.Join(sets, ","))
 	return d, nil
 }
+
+// exportSetMaxBits is the most bits EXPORT_SET() reports, a larger or negative number of bits is
+// clipped to it.
+const exportSetMaxBits = 64
+
+// builtinExportSet returns a string with an on string for every bit set in bits and an off string
+// for every bit not set, from the lowest bit up, joined by separator.
+// See https://dev.mysql.com/doc/refman/5.7/en/string-functions.html#function_export-set
+func builtinExportSet(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
+	for _, arg := range args {
+		if arg.IsNull() {
+			return d, nil
+		}
+	}
+	sc := ctx.GetSessionVars().StmtCtx
+	bits, err := toUintArg(sc, args[0])
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	on, err := args[1].ToString()
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	off, err := args[2].ToString()
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	separator := ","
+	if len(args) > 3 {
+		separator, err = args[3].ToString()
+		if err != nil {
+			return d, errors.Trace(err)
+		}
+	}
+	n := int64(exportSetMaxBits)
+	if len(args) > 4 {
+		n, err = toIntArg(sc, args[4])
+		if err != nil {
+			return d, errors.Trace(err)
+		}
+		if n < 0 || n > exportSetMaxBits {
+			n = exportSetMaxBits
+		}
+	}
+	res := make([]string, 0, n)
+	for i := uint(0); i < uint(n); i++ {
+		if bits&(1<<i) != 0 {
+			res = append(res, on)
+		} else {
+			res = append(res, off)
+		}
+	}
+	d.SetString(strings.Join(res, separator))
+	return d, nil
+}
+
+// formatMaxDecimals is the most decimal places FORMAT() rounds to.
+const formatMaxDecimals = 30
+
+// builtinFormat formats a number like '#,###,###.##', rounded to the given decimal places.
+// Only the en_US locale is supported, the optional locale argument is ignored.
+// See https://dev.mysql.com/doc/refman/5.7/en/string-functions.html#function_format
+func builtinFormat(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
+	if args[0].IsNull() || args[1].IsNull() {
+		return d, nil
+	}
+	sc := ctx.GetSessionVars().StmtCtx
+	scale, err := toIntArg(sc, args[1])
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	if scale < 0 {
+		scale = 0
+	} else if scale > formatMaxDecimals {
+		scale = formatMaxDecimals
+	}
+	x, err := args[0].ToDecimal(sc)
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	x, err = roundHalfAway(x, int(scale))
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	d.SetString(formatNumber(x.String(), int(scale)))
+	return d, nil
+}
+
+// formatNumber groups the integer digits of the decimal number s by thousands, and pads or cuts
+// its fraction to scale digits.
+func formatNumber(s string, scale int) string {
+	var sign string
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	intPart, fracPart := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		intPart, fracPart = s[:i], s[i+1:]
+	}
+	var buf bytes.Buffer
+	buf.WriteString(sign)
+	for i, c := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteRune(c)
+	}
+	if scale > 0 {
+		buf.WriteByte('.')
+		if len(fracPart) > scale {
+			fracPart = fracPart[:scale]
+		}
+		buf.WriteString(fracPart)
+		buf.WriteString(strings.Repeat("0", scale-len(fracPart)))
+	}
+	return buf.String()
+}
@@ -0,0 +1,46 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package evaluator
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// FuzzToIntArg checks that toIntArg converts any string without panicking, and a plain decimal
+// integer exactly and without a warning. Run it with go test -fuzz=FuzzToIntArg.
+func FuzzToIntArg(f *testing.F) {
+	for _, s := range []string{"  42abc", "", "+3", "3.9", "-0", "1e18", "1e19", "9223372036854775808", ".5e1", "--1", "0e1."} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		sc := &variable.StatementContext{TruncateAsWarning: true}
+		v, err := toIntArg(sc, types.NewStringDatum(s))
+		if err != nil {
+			return
+		}
+		if n, perr := strconv.ParseInt(strings.TrimSpace(s), 10, 64); perr == nil {
+			if v != n || len(sc.GetWarnings()) != 0 {
+				t.Fatalf("toIntArg(%q) = %d with %d warnings, want %d", s, v, len(sc.GetWarnings()), n)
+			}
+		}
+	})
+}
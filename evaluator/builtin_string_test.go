@@ -14,13 +14,21 @@
 package evaluator
 
 import (
-	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
 	"strings"
+	"testing"
 	"time"
 
+	"github.com/juju/errors"
 	. "github.com/pingcap/check"
 	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/terror"
+	"github.com/pingcap/tidb/util/mock"
 	"github.com/pingcap/tidb/util/testleak"
 	"github.com/pingcap/tidb/util/testutil"
 	"github.com/pingcap/tidb/util/types"
@@ -43,6 +51,14 @@ func (s *testEvaluatorSuite) TestLength(c *C) {
 		{types.Bit{Value: 1, Width: 8}, 1},
 		{types.Hex{Value: 1}, 1},
 		{types.Set{Value: 1, Name: "abc"}, 3},
+		// Numbers at their extremes keep every digit, and only huge or tiny floats take an exponent.
+		{int64(math.MinInt64), 20},
+		{uint64(math.MaxUint64), 20},
+		{123456789012345.0, 15},
+		{1e15, 4},
+		{-1e300, 6},
+		{1e-15, 17},
+		{1e-16, 5},
 	}
 
 	dtbl := tblToDtbl(tbl)
@@ -54,6 +70,118 @@ func (s *testEvaluatorSuite) TestLength(c *C) {
 	}
 }
 
+func (s *testEvaluatorSuite) TestNumberToStringArg(c *C) {
+	defer testleak.AfterTest(c)()
+	// The string functions see numbers in the same form as CONCAT.
+	tbl := []struct {
+		input interface{}
+		str   string
+	}{
+		{int64(math.MinInt64), "-9223372036854775808"},
+		{uint64(math.MaxUint64), "18446744073709551615"},
+		{3.14, "3.14"},
+		{float32(2.5), "2.5"},
+		{1e20, "1e20"},
+		{-1.25e-20, "-1.25e-20"},
+	}
+	for _, t := range tbl {
+		args := types.MakeDatums(t.input)
+		str, isNull, err := stringArg(args[0])
+		c.Assert(err, IsNil)
+		c.Assert(isNull, IsFalse)
+		c.Assert(str, Equals, t.str)
+		for name, want := range map[string]string{
+			ast.Concat:  t.str,
+			ast.Lower:   strings.ToLower(t.str),
+			ast.Upper:   strings.ToUpper(t.str),
+			ast.Reverse: reverseBytes(t.str),
+		} {
+			v, err := Funcs[name].F(args, s.ctx)
+			c.Assert(err, IsNil)
+			c.Assert(v.GetString(), Equals, want, Commentf("%s(%v)", name, t.input))
+		}
+		v, err := builtinASCII(args, s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v.GetInt64(), Equals, int64(t.str[0]))
+	}
+}
+
+func (s *testEvaluatorSuite) TestStringFuncNull(c *C) {
+	defer testleak.AfterTest(c)()
+	// Every string function returns NULL when all its arguments are NULL, however many it takes.
+	// FIELD() returns 0 instead, and SUBSTRING_INDEX() fails, see TestSubstringIndex.
+	names := []string{
+		ast.ASCII, ast.CharLength, ast.CharacterLength, ast.Concat, ast.ConcatWS, ast.Elt, ast.ExportSet,
+		ast.Format, ast.Instr, ast.Convert, ast.Lcase, ast.Left, ast.Length, ast.OctetLength, ast.Ord,
+		ast.Locate, ast.Lower, ast.MakeSet, ast.Mid, ast.Ltrim, ast.Repeat, ast.Replace, ast.Reverse,
+		ast.Right, ast.Rtrim, ast.Soundex, ast.Space, ast.Strcmp, ast.Substr, ast.Substring, ast.Trim,
+		ast.Upper, ast.Ucase, ast.Hex, ast.Unhex, ast.Lpad, ast.Rpad, ast.WeightString,
+	}
+	for _, name := range names {
+		f, ok := Funcs[name]
+		c.Assert(ok, IsTrue, Commentf("%s", name))
+		maxArgs := f.MaxArgs
+		if maxArgs == -1 {
+			maxArgs = f.MinArgs + 2
+		}
+		for n := f.MinArgs; n <= maxArgs; n++ {
+			v, err := f.F(make([]types.Datum, n), s.ctx)
+			c.Assert(err, IsNil, Commentf("%s with %d arguments", name, n))
+			c.Assert(v.IsNull(), IsTrue, Commentf("%s with %d arguments", name, n))
+		}
+	}
+
+	s1, isNull, err := stringArg(types.NewDatum(nil))
+	c.Assert(err, IsNil)
+	c.Assert(isNull, IsTrue)
+	c.Assert(s1, Equals, "")
+	s1, isNull, err = stringArg(types.NewDatum(12))
+	c.Assert(err, IsNil)
+	c.Assert(isNull, IsFalse)
+	c.Assert(s1, Equals, "12")
+}
+
+func (s *testEvaluatorSuite) TestOctetLengthAndCharLength(c *C) {
+	defer testleak.AfterTest(c)()
+	tbl := []struct {
+		Input       interface{}
+		OctetLength interface{}
+		CharLength  interface{}
+	}{
+		{"abc", 3, 3},
+		{"", 0, 0},
+		{"你好", 6, 2},
+		{"a你b好c", 9, 5},
+		{[]byte("你好"), 6, 2},
+		{types.Hex{Value: 0xe4bda0}, 3, 3},
+		{types.Bit{Value: 0xe4bda0, Width: 24}, 3, 3},
+		{types.Bit{Value: 1, Width: 1}, 1, 1},
+		{types.Bit{Value: 0, Width: 9}, 2, 2},
+		{types.Bit{Value: 0x1ff, Width: 64}, 8, 8},
+		{types.Set{Value: 3, Name: "a,b"}, 3, 3},
+		{types.Set{Value: 1, Name: "你好"}, 6, 2},
+		{types.Set{}, 0, 0},
+		{types.Enum{Value: 2, Name: "medium"}, 6, 6},
+		{types.Enum{Value: 1, Name: "好"}, 3, 1},
+		{types.Enum{}, 0, 0},
+		{123, 3, 3},
+		{nil, nil, nil},
+	}
+	for _, t := range tbl {
+		args := types.MakeDatums(t.Input)
+		v, err := builtinOctetLength(args, s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v, testutil.DatumEquals, types.NewDatum(t.OctetLength), Commentf("%v", t.Input))
+		// OCTET_LENGTH and LENGTH agree.
+		l, err := builtinLength(args, s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(l, testutil.DatumEquals, v)
+		v, err = builtinCharLength(args, s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v, testutil.DatumEquals, types.NewDatum(t.CharLength), Commentf("%v", t.Input))
+	}
+}
+
 func (s *testEvaluatorSuite) TestASCII(c *C) {
 	defer testleak.AfterTest(c)()
 	v, err := builtinASCII(types.MakeDatums([]interface{}{nil}...), s.ctx)
@@ -79,6 +207,56 @@ func (s *testEvaluatorSuite) TestASCII(c *C) {
 
 	v, err = builtinASCII(types.MakeDatums([]interface{}{errors.New("must error")}...), s.ctx)
 	c.Assert(err, NotNil)
+
+	// A binary string gives its raw first byte.
+	v, err = builtinASCII(types.MakeDatums([]byte{0xff, 0x01}), s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(v.GetInt64(), Equals, int64(255))
+}
+
+func (s *testEvaluatorSuite) TestOrd(c *C) {
+	defer testleak.AfterTest(c)()
+	v, err := builtinOrd(types.MakeDatums(nil), s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(v.Kind(), Equals, types.KindNull)
+
+	for _, t := range []struct {
+		input interface{}
+		ascii int64
+		ord   int64
+	}{
+		{"", 0, 0},
+		{"A", 65, 65},
+		{"2abc", 50, 50},
+		{2, 50, 50},
+		{[]byte{0xff, 0x01}, 255, 255},
+		{"é", 0xc3, 0xc3a9},
+		{"你好", 0xe4, 0xe4bda0},
+		{"😀", 0xf0, 0xf09f9880},
+	} {
+		v, err = builtinOrd(types.MakeDatums(t.input), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v.GetInt64(), Equals, t.ord, Commentf("ord(%v)", t.input))
+
+		v, err = builtinASCII(types.MakeDatums(t.input), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v.GetInt64(), Equals, t.ascii, Commentf("ascii(%v)", t.input))
+	}
+}
+
+func (s *testEvaluatorSuite) TestASCIIOrdParity(c *C) {
+	defer testleak.AfterTest(c)()
+	// ASCII and ORD share firstByte, so they agree wherever there is no multibyte leading
+	// character, starting with NULL and the empty string.
+	for _, input := range []interface{}{nil, "", []byte{}, "a", []byte{0x80}} {
+		args := types.MakeDatums(input)
+		ascii, err := Funcs[ast.ASCII].F(args, s.ctx)
+		c.Assert(err, IsNil)
+		ord, err := Funcs[ast.Ord].F(args, s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(ord.IsNull(), Equals, input == nil, Commentf("%v", input))
+		c.Assert(ord, testutil.DatumEquals, ascii, Commentf("%v", input))
+	}
 }
 
 func (s *testEvaluatorSuite) TestConcat(c *C) {
@@ -102,6 +280,142 @@ func (s *testEvaluatorSuite) TestConcat(c *C) {
 	args = []interface{}{errors.New("must error")}
 	_, err = builtinConcat(types.MakeDatums(args...), s.ctx)
 	c.Assert(err, NotNil)
+
+	// A result longer than max_allowed_packet is NULL with a warning.
+	vars := s.ctx.GetSessionVars()
+	vars.Systems["max_allowed_packet"] = "1024"
+	defer delete(vars.Systems, "max_allowed_packet")
+	half := strings.Repeat("x", 512)
+	v, err = builtinConcat(types.MakeDatums(half, half), s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(v.GetString(), HasLen, 1024)
+	sc := vars.StmtCtx
+	warnCnt := len(sc.GetWarnings())
+	v, err = builtinConcat(types.MakeDatums(half, half, "y"), s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(v.Kind(), Equals, types.KindNull)
+	c.Assert(sc.GetWarnings(), HasLen, warnCnt+1)
+}
+
+func newConcatBenchArgs() []types.Datum {
+	args := make([]types.Datum, 100)
+	for i := range args {
+		args[i].SetString("TiDB " + strconv.Itoa(i))
+	}
+	return args
+}
+
+func (s *testEvaluatorSuite) TestConcatManyArgs(c *C) {
+	defer testleak.AfterTest(c)()
+	args := newConcatBenchArgs()
+	var expect string
+	for i := range args {
+		expect += args[i].GetString()
+	}
+	v, err := builtinConcat(args, s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(v.GetString(), Equals, expect)
+	args[99].SetNull()
+	v, err = builtinConcat(args, s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(v.Kind(), Equals, types.KindNull)
+}
+
+func BenchmarkConcat(b *testing.B) {
+	ctx := mock.NewContext()
+	args := newConcatBenchArgs()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := builtinConcat(args, ctx); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func (s *testEvaluatorSuite) TestConcatCharset(c *C) {
+	defer testleak.AfterTest(c)()
+	newStringType := func(chs, coll string) *types.FieldType {
+		tp := types.NewFieldType(mysql.TypeVarchar)
+		tp.Flen = 10
+		tp.Charset, tp.Collate = chs, coll
+		return tp
+	}
+	utf8 := newStringType("utf8", "utf8_general_ci")
+	utf8Bin := newStringType("utf8", "utf8_bin")
+	utf8Unicode := newStringType("utf8", "utf8_unicode_ci")
+	utf8mb4 := newStringType("utf8mb4", "utf8mb4_general_ci")
+	latin1 := newStringType("latin1", "latin1_swedish_ci")
+	ascii := newStringType("ascii", "ascii_general_ci")
+	binary := newStringType("binary", "binary")
+	literal := newStringType("", "")
+	number := types.NewFieldType(mysql.TypeLonglong)
+	number.Charset, number.Collate = "binary", "binary"
+
+	tbl := []struct {
+		args    []*types.FieldType
+		charset string
+		collate string
+	}{
+		{[]*types.FieldType{utf8, latin1}, "utf8", "utf8_general_ci"},
+		{[]*types.FieldType{latin1, utf8}, "utf8", "utf8_general_ci"},
+		{[]*types.FieldType{latin1, ascii}, "latin1", "latin1_swedish_ci"},
+		{[]*types.FieldType{utf8, utf8mb4, latin1}, "utf8mb4", "utf8mb4_general_ci"},
+		{[]*types.FieldType{utf8, utf8Bin}, "utf8", "utf8_bin"},
+		{[]*types.FieldType{utf8Bin, utf8Unicode}, "utf8", "utf8_bin"},
+		{[]*types.FieldType{utf8, binary}, "binary", "binary"},
+		{[]*types.FieldType{latin1, literal, number}, "latin1", "latin1_swedish_ci"},
+		{[]*types.FieldType{literal, number}, "", ""},
+	}
+	for _, t := range tbl {
+		tp, err := TypeInferers[ast.Concat](t.args)
+		c.Assert(err, IsNil)
+		c.Assert(tp.Charset, Equals, t.charset, Commentf("%v", t.args))
+		c.Assert(tp.Collate, Equals, t.collate, Commentf("%v", t.args))
+	}
+
+	_, err := TypeInferers[ast.Concat]([]*types.FieldType{utf8, utf8Unicode})
+	c.Assert(terror.ErrorEqual(err, ErrIllegalMixOfCollations), IsTrue)
+	c.Assert(err.Error(), Matches, ".*Illegal mix of collations \\(utf8_general_ci,IMPLICIT\\) and \\(utf8_unicode_ci,IMPLICIT\\) for operation 'concat'")
+}
+
+func (s *testEvaluatorSuite) TestCheckResultLen(c *C) {
+	defer testleak.AfterTest(c)()
+	vars := s.ctx.GetSessionVars()
+	vars.Systems["max_allowed_packet"] = "1024"
+	defer delete(vars.Systems, "max_allowed_packet")
+	sc := vars.StmtCtx
+	x512 := strings.Repeat("x", 512)
+
+	c.Assert(checkResultLen(s.ctx, "f", 1024), IsTrue)
+	warnCnt := len(sc.GetWarnings())
+	c.Assert(checkResultLen(s.ctx, "f", 1025), IsFalse)
+	c.Assert(sc.GetWarnings(), HasLen, warnCnt+1)
+	c.Assert(terror.ErrorEqual(sc.GetWarnings()[warnCnt], ErrWarnAllowedPacketOverflowed), IsTrue)
+
+	// Each function returns a string of max_allowed_packet bytes, and NULL with a warning for
+	// a byte more.
+	for _, t := range []struct {
+		name     string
+		fitting  []interface{}
+		overflow []interface{}
+	}{
+		{ast.Space, []interface{}{1024}, []interface{}{1025}},
+		{ast.Repeat, []interface{}{"ab", 512}, []interface{}{"ab", 513}},
+		{ast.Lpad, []interface{}{"a", 1024, "xy"}, []interface{}{"a", 1025, "xy"}},
+		{ast.Rpad, []interface{}{"a", 1024, "xy"}, []interface{}{"a", 1025, "xy"}},
+		{ast.Concat, []interface{}{x512, x512}, []interface{}{x512, x512, "x"}},
+		{ast.ConcatWS, []interface{}{"", x512, x512}, []interface{}{",", x512, x512}},
+	} {
+		v, err := Funcs[t.name].F(types.MakeDatums(t.fitting...), s.ctx)
+		c.Assert(err, IsNil, Commentf("%s", t.name))
+		c.Assert(v.GetString(), HasLen, 1024, Commentf("%s", t.name))
+		warnCnt := len(sc.GetWarnings())
+		v, err = Funcs[t.name].F(types.MakeDatums(t.overflow...), s.ctx)
+		c.Assert(err, IsNil, Commentf("%s", t.name))
+		c.Assert(v.Kind(), Equals, types.KindNull, Commentf("%s", t.name))
+		c.Assert(sc.GetWarnings(), HasLen, warnCnt+1, Commentf("%s", t.name))
+	}
 }
 
 func (s *testEvaluatorSuite) TestConcatWS(c *C) {
@@ -121,6 +435,57 @@ func (s *testEvaluatorSuite) TestConcatWS(c *C) {
 	args = types.MakeDatums([]interface{}{errors.New("must error")}...)
 	_, err = builtinConcatWS(args, s.ctx)
 	c.Assert(err, NotNil)
+
+	// A NULL separator makes the result NULL, NULL members only are an empty string.
+	v, err = builtinConcatWS(types.MakeDatums(nil, "a", "b"), s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(v.Kind(), Equals, types.KindNull)
+	v, err = builtinConcatWS(types.MakeDatums(",", nil, nil), s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(v.Kind(), Equals, types.KindString)
+	c.Assert(v.GetString(), Equals, "")
+
+	// A result longer than max_allowed_packet is NULL with a warning.
+	vars := s.ctx.GetSessionVars()
+	vars.Systems["max_allowed_packet"] = "1024"
+	defer delete(vars.Systems, "max_allowed_packet")
+	half := strings.Repeat("x", 511)
+	v, err = builtinConcatWS(types.MakeDatums("--", half, nil, half), s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(v.GetString(), HasLen, 1024)
+	sc := vars.StmtCtx
+	warnCnt := len(sc.GetWarnings())
+	v, err = builtinConcatWS(types.MakeDatums("---", half, half), s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(v.Kind(), Equals, types.KindNull)
+	c.Assert(sc.GetWarnings(), HasLen, warnCnt+1)
+
+	// Numbers, separators too, join in the form they are shown in.
+	for _, t := range []struct {
+		args   []interface{}
+		result string
+	}{
+		{[]interface{}{",", 1, 2.5}, "1,2.5"},
+		{[]interface{}{",", int64(-7), uint64(18446744073709551615)}, "-7,18446744073709551615"},
+		{[]interface{}{",", float32(0.1), 1e20, 1.0}, "0.1,1e20,1"},
+		{[]interface{}{",", types.NewDecFromStringForTest("2.50"), types.NewDecFromStringForTest("-0.0100")}, "2.50,-0.0100"},
+		{[]interface{}{0, "a", "b"}, "a0b"},
+		{[]interface{}{1.5, 1, 2}, "11.52"},
+		{[]interface{}{"", 1, nil, 2}, "12"},
+	} {
+		v, err = builtinConcatWS(types.MakeDatums(t.args...), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v, testutil.DatumEquals, types.NewStringDatum(t.result), Commentf("%v", t.args))
+	}
+
+	utf8 := types.NewFieldType(mysql.TypeVarchar)
+	utf8.Charset, utf8.Collate = "utf8", "utf8_general_ci"
+	latin1 := types.NewFieldType(mysql.TypeVarchar)
+	latin1.Charset, latin1.Collate = "latin1", "latin1_swedish_ci"
+	tp, err := TypeInferers[ast.ConcatWS]([]*types.FieldType{latin1, latin1, utf8})
+	c.Assert(err, IsNil)
+	c.Assert(tp.Charset, Equals, "utf8")
+	c.Assert(tp.Collate, Equals, "utf8_general_ci")
 }
 
 func (s *testEvaluatorSuite) TestLeft(c *C) {
@@ -147,6 +512,69 @@ func (s *testEvaluatorSuite) TestLeft(c *C) {
 	args = types.MakeDatums([]interface{}{"abcdefg", "xxx"}...)
 	_, err = builtinLeft(args, s.ctx)
 	c.Assert(err, NotNil)
+
+	for _, args := range [][]interface{}{{nil, 1}, {"abcdefg", nil}, {nil, nil}} {
+		v, err = builtinLeft(types.MakeDatums(args...), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v.IsNull(), IsTrue)
+	}
+}
+
+func (s *testEvaluatorSuite) TestRight(c *C) {
+	defer testleak.AfterTest(c)()
+	tbl := []struct {
+		str    interface{}
+		length interface{}
+		result interface{}
+	}{
+		{"abcdefg", 2, "fg"},
+		{"abcdefg", -1, ""},
+		{"abcdefg", 0, ""},
+		{"abcdefg", 100, "abcdefg"},
+		{1234, 3, "234"},
+		{"你好世界", 3, "好世界"},
+		{nil, 1, nil},
+		{"abcdefg", nil, nil},
+	}
+	for _, t := range tbl {
+		v, err := builtinRight(types.MakeDatums(t.str, t.length), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v.GetValue(), Equals, t.result)
+	}
+
+	_, err := builtinRight(types.MakeDatums("abcdefg", "xxx"), s.ctx)
+	c.Assert(err, NotNil)
+}
+
+func (s *testEvaluatorSuite) TestBinaryStringSlicing(c *C) {
+	defer testleak.AfterTest(c)()
+	text := types.NewStringDatum("你好abc")
+	binary := types.NewBytesDatum([]byte("你好abc"))
+	binary.SetCollation(mysql.BinaryCollationID)
+	hex, err := types.ParseHex("0xE4BDA0E5A5BD")
+	c.Assert(err, IsNil)
+
+	tbl := []struct {
+		f      BuiltinFunc
+		args   []types.Datum
+		result string
+	}{
+		{builtinLeft, []types.Datum{text, types.NewIntDatum(2)}, "你好"},
+		{builtinLeft, []types.Datum{binary, types.NewIntDatum(2)}, "\xe4\xbd"},
+		{builtinLeft, []types.Datum{types.NewDatum(hex), types.NewIntDatum(3)}, "你"},
+		{builtinRight, []types.Datum{text, types.NewIntDatum(4)}, "好abc"},
+		{builtinRight, []types.Datum{binary, types.NewIntDatum(4)}, "\xbdabc"},
+		{builtinSubstring, []types.Datum{text, types.NewIntDatum(2), types.NewIntDatum(2)}, "好a"},
+		{builtinSubstring, []types.Datum{binary, types.NewIntDatum(2), types.NewIntDatum(2)}, "\xbd\xa0"},
+		{builtinSubstring, []types.Datum{text, types.NewIntDatum(-4)}, "好abc"},
+		{builtinSubstring, []types.Datum{binary, types.NewIntDatum(-4)}, "\xbdabc"},
+		{builtinSubstring, []types.Datum{text, types.NewIntDatum(6)}, ""},
+	}
+	for i, t := range tbl {
+		v, err := t.f(t.args, s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v.GetString(), Equals, t.result, Commentf("%d", i))
+	}
 }
 
 func (s *testEvaluatorSuite) TestRepeat(c *C) {
@@ -175,6 +603,31 @@ func (s *testEvaluatorSuite) TestRepeat(c *C) {
 	v, err = builtinRepeat(types.MakeDatums(args...), s.ctx)
 	c.Assert(err, IsNil)
 	c.Assert(v.GetString(), Equals, "")
+
+	for _, args = range [][]interface{}{{nil, 2}, {"a", nil}} {
+		v, err = builtinRepeat(types.MakeDatums(args...), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v.Kind(), Equals, types.KindNull)
+	}
+
+	// A result longer than max_allowed_packet is NULL with a warning.
+	vars := s.ctx.GetSessionVars()
+	vars.Systems["max_allowed_packet"] = "1024"
+	defer delete(vars.Systems, "max_allowed_packet")
+	v, err = builtinRepeat(types.MakeDatums("ab", 512), s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(v.GetString(), HasLen, 1024)
+	sc := vars.StmtCtx
+	for _, n := range []interface{}{int64(513), uint64(math.MaxUint64)} {
+		warnCnt := len(sc.GetWarnings())
+		v, err = builtinRepeat(types.MakeDatums("ab", n), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v.Kind(), Equals, types.KindNull, Commentf("%v", n))
+		// The count out of the int64 range adds its own overflow warning.
+		warnings := sc.GetWarnings()
+		c.Assert(len(warnings) > warnCnt, IsTrue)
+		c.Assert(terror.ErrorEqual(warnings[len(warnings)-1], ErrWarnAllowedPacketOverflowed), IsTrue)
+	}
 }
 
 func (s *testEvaluatorSuite) TestLowerAndUpper(c *C) {
@@ -206,6 +659,66 @@ func (s *testEvaluatorSuite) TestLowerAndUpper(c *C) {
 		c.Assert(err, IsNil)
 		c.Assert(d.GetString(), Equals, strings.ToUpper(t["Expect"][0].GetString()))
 	}
+
+	unicodeTbl := []struct {
+		input string
+		lower string
+		upper string
+	}{
+		{"ÀÉÎõüÇ", "àéîõüç", "ÀÉÎÕÜÇ"},
+		{"ΑβΓδΩ", "αβγδω", "ΑΒΓΔΩ"},
+		{"ПриВЕТ", "привет", "ПРИВЕТ"},
+		// No Turkish rules: i and I map to each other, the dotted and dotless i to their simple mappings.
+		{"iIİı", "iiiı", "IIİI"},
+		// ß has no single character upper case.
+		{"straße", "straße", "STRAßE"},
+		// Invalid UTF-8 is kept byte for byte.
+		{"a\xffB\xc3", "a\xffb\xc3", "A\xffB\xc3"},
+		// ASCII, which may have nothing to map.
+		{"", "", ""},
+		{"hello, world! 123", "hello, world! 123", "HELLO, WORLD! 123"},
+		{"HELLO @[`{", "hello @[`{", "HELLO @[`{"},
+		{"abcÉ", "abcé", "ABCÉ"},
+	}
+	for _, t := range unicodeTbl {
+		d, err = builtinLower(types.MakeDatums(t.input), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(d.GetString(), Equals, t.lower, Commentf("lower(%q)", t.input))
+
+		d, err = builtinUpper(types.MakeDatums(t.input), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(d.GetString(), Equals, t.upper, Commentf("upper(%q)", t.input))
+	}
+
+	// Binary strings are left untouched.
+	binary := types.NewBytesDatum([]byte("AbÀ"))
+	binary.SetCollation(mysql.BinaryCollationID)
+	d, err = builtinLower([]types.Datum{binary}, s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(d.GetString(), Equals, "AbÀ")
+	d, err = builtinUpper([]types.Datum{binary}, s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(d.GetString(), Equals, "AbÀ")
+
+	// ASCII which is in the case already is returned without an allocation.
+	lower, upper := types.MakeDatums("tidb row 1"), types.MakeDatums("TIDB ROW 1")
+	allocs := testing.AllocsPerRun(100, func() {
+		builtinLower(lower, s.ctx)
+		builtinUpper(upper, s.ctx)
+	})
+	c.Assert(allocs, Equals, float64(0))
+}
+
+func BenchmarkLowerASCII(b *testing.B) {
+	ctx := mock.NewContext()
+	args := types.MakeDatums("tidb row 1")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := builtinLower(args, ctx); err != nil {
+			b.Fatal(err)
+		}
+	}
 }
 
 func (s *testEvaluatorSuite) TestReverse(c *C) {
@@ -233,6 +746,55 @@ func (s *testEvaluatorSuite) TestReverse(c *C) {
 	}
 }
 
+func (s *testEvaluatorSuite) TestBinaryStringCaseAndReverse(c *C) {
+	defer testleak.AfterTest(c)()
+	binary := types.NewBytesDatum([]byte("AbC你好"))
+	binary.SetCollation(mysql.BinaryCollationID)
+	hex, err := types.ParseHex("0x41E4BDA0")
+	c.Assert(err, IsNil)
+	bit := types.NewDatum(types.Bit{Value: 0x6142, Width: 16})
+
+	tbl := []struct {
+		arg     types.Datum
+		reverse string
+	}{
+		// A binary string is reversed byte by byte, splitting multi-byte characters.
+		{binary, "\xbd\xa5\xe5\xa0\xbd\xe4CbA"},
+		{types.NewDatum(hex), "\xa0\xbd\xe4A"},
+		{bit, "Ba"},
+	}
+	for _, t := range tbl {
+		str, err := t.arg.ToString()
+		c.Assert(err, IsNil)
+		d, err := builtinReverse([]types.Datum{t.arg}, s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(d.GetString(), Equals, t.reverse)
+		// UPPER and LOWER leave binary strings unchanged.
+		d, err = builtinUpper([]types.Datum{t.arg}, s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(d.GetString(), Equals, str)
+		d, err = builtinLower([]types.Datum{t.arg}, s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(d.GetString(), Equals, str)
+	}
+
+	// The same text in a non-binary collation is handled by characters.
+	text := types.NewStringDatum("AbC你好")
+	d, err := builtinReverse([]types.Datum{text}, s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(d.GetString(), Equals, "好你CbA")
+	d, err = builtinUpper([]types.Datum{text}, s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(d.GetString(), Equals, "ABC你好")
+
+	// The batch fast path agrees.
+	col := &Column{Datums: []types.Datum{binary, text}}
+	result, err := EvalColumn(ast.Lower, []*Column{col}, len(col.Datums), s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(result.Datums[0].GetString(), Equals, "AbC你好")
+	c.Assert(result.Datums[1].GetString(), Equals, "abc你好")
+}
+
 func (s *testEvaluatorSuite) TestStrcmp(c *C) {
 	defer testleak.AfterTest(c)()
 	tbl := []struct {
@@ -260,6 +822,55 @@ func (s *testEvaluatorSuite) TestStrcmp(c *C) {
 		c.Assert(err, IsNil)
 		c.Assert(d, testutil.DatumEquals, t["Expect"][0])
 	}
+
+	// Strings are compared in the collation they carry.
+	for _, collation := range []string{"utf8_general_ci", "utf8_bin"} {
+		a, b := types.NewDatum("abc"), types.NewDatum("ABC")
+		a.SetCollation(mysql.CollationNames[collation])
+		b.SetCollation(mysql.CollationNames[collation])
+		d, err := builtinStrcmp([]types.Datum{a, b}, s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(d.GetInt64() == 0, Equals, collation == "utf8_general_ci", Commentf("collation %s", collation))
+	}
+
+	// ENUM and SET values are compared in the order of their definition, not by name, by STRCMP
+	// and by the comparison operators alike.
+	elems := []string{"z", "b", "a"}
+	enum := func(name string) types.Datum {
+		e, err := types.ParseEnumName(elems, name)
+		c.Assert(err, IsNil)
+		return types.NewDatum(e)
+	}
+	set := func(name string) types.Datum {
+		e, err := types.ParseSetName(elems, name)
+		c.Assert(err, IsNil)
+		return types.NewDatum(e)
+	}
+	for _, t := range []struct {
+		lhs, rhs types.Datum
+		expect   int64
+	}{
+		{enum("z"), enum("a"), -1},
+		{enum("a"), enum("b"), 1},
+		{enum("b"), enum("b"), 0},
+		{set("z"), set("a"), -1},
+		{set("a,z"), set("b"), 1},
+		{set("b,z"), set("z,b"), 0},
+		{enum("b"), set("b"), 0},
+		// With a string, the name is compared.
+		{enum("z"), types.NewStringDatum("a"), 1},
+		{types.NewStringDatum("a"), set("a"), 0},
+	} {
+		d, err := builtinStrcmp([]types.Datum{t.lhs, t.rhs}, s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(d.GetInt64(), Equals, t.expect, Commentf("strcmp(%v, %v)", t.lhs.GetValue(), t.rhs.GetValue()))
+		d, err = Funcs[ast.LT].F([]types.Datum{t.lhs, t.rhs}, s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(d.GetInt64(), Equals, boolToInt64(t.expect < 0), Commentf("%v < %v", t.lhs.GetValue(), t.rhs.GetValue()))
+		d, err = Funcs[ast.EQ].F([]types.Datum{t.lhs, t.rhs}, s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(d.GetInt64(), Equals, boolToInt64(t.expect == 0), Commentf("%v = %v", t.lhs.GetValue(), t.rhs.GetValue()))
+	}
 }
 
 func (s *testEvaluatorSuite) TestReplace(c *C) {
@@ -274,6 +885,10 @@ func (s *testEvaluatorSuite) TestReplace(c *C) {
 		{[]interface{}{"12345", 2, 222}, "1222345"},
 		{[]interface{}{"12325", 2, "a"}, "1a3a5"},
 		{[]interface{}{12345, 2, "aa"}, "1aa345"},
+		{[]interface{}{"Abc", "a", "x"}, "Abc"},
+		{[]interface{}{"abcabc", "", "x"}, "abcabc"},
+		{[]interface{}{"数据库数据", "数据", "data"}, "data库data"},
+		{[]interface{}{"café CAFÉ", "é", "e"}, "cafe CAFÉ"},
 	}
 
 	dtbl := tblToDtbl(tbl)
@@ -283,6 +898,15 @@ func (s *testEvaluatorSuite) TestReplace(c *C) {
 		c.Assert(err, IsNil)
 		c.Assert(d, testutil.DatumEquals, t["Expect"][0])
 	}
+
+	// The search stays case sensitive under a _ci collation.
+	args := types.MakeDatums("Abc aBC", "a", "x")
+	for i := range args {
+		args[i].SetCollation(mysql.CollationNames["utf8_general_ci"])
+	}
+	d, err := builtinReplace(args, s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(d.GetString(), Equals, "Abc xBC")
 }
 
 func (s *testEvaluatorSuite) TestSubstring(c *C) {
@@ -292,6 +916,12 @@ func (s *testEvaluatorSuite) TestSubstring(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(d.GetString(), Equals, "")
 
+	for _, args := range [][]interface{}{{nil, 2}, {"hello", nil}, {"hello", 2, nil}} {
+		d, err = builtinSubstring(types.MakeDatums(args...), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(d.Kind(), Equals, types.KindNull)
+	}
+
 	tbl := []struct {
 		str    string
 		pos    int64
@@ -316,6 +946,13 @@ func (s *testEvaluatorSuite) TestSubstring(c *C) {
 		{"Sakila", -5, -3, ""},
 		{"Sakila", -1000, 3, ""},
 		{"Sakila", 1000, 2, ""},
+		{"Sakila", 0, 3, ""},
+		{"Sakila", 0, -1, ""},
+		{"Sakila", 7, 1, ""},
+		{"Sakila", -6, 0, ""},
+		{"Sakila", 2, math.MaxInt64, "akila"},
+		{"Sakila", -2, math.MaxInt64, "la"},
+		{"Sakila", 2, math.MinInt64, ""},
 		{"", 2, 3, ""},
 	}
 	for _, v := range tbl {
@@ -333,8 +970,17 @@ func (s *testEvaluatorSuite) TestSubstring(c *C) {
 		c.Assert(err, IsNil)
 		c.Assert(r1.Kind(), Equals, types.KindString)
 		c.Assert(r.GetString(), Equals, r1.GetString())
+
+		// MID and SUBSTR are synonyms for SUBSTRING.
+		mid, err := Funcs[ast.Mid].F(args, s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(mid, testutil.DatumEquals, r)
+		substr, err := Funcs[ast.Substr].F(args, s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(substr, testutil.DatumEquals, r)
 	}
-	errTbl := []struct {
+	// Numeric strings and floats are taken as integers.
+	convTbl := []struct {
 		str    interface{}
 		pos    interface{}
 		len    interface{}
@@ -342,16 +988,32 @@ func (s *testEvaluatorSuite) TestSubstring(c *C) {
 	}{
 		{"foobarbar", "4", -1, "barbar"},
 		{"Quadratically", 5, "6", "ratica"},
+		{"Quadratically", 4.6, " 2 ", "ra"},
+		// Lengths computed by an expression are normalized like constant ones.
+		{"Sakila", 2, "-1", ""},
+		{"Sakila", 2, "0", ""},
+		{"Sakila", 2, -0.4, ""},
+		{"Sakila", 2, 0.6, "a"},
+		{"Sakila", 2, types.NewDecFromInt(-3), ""},
+		{"Sakila", 2, "99999999999999999999", "akila"},
+		{"Sakila", 2, uint64(math.MaxUint64), "akila"},
 	}
-	for _, v := range errTbl {
+	for _, v := range convTbl {
 		f := Funcs[ast.Substring]
 		args := types.MakeDatums(v.str, v.pos)
 		if v.len != -1 {
 			args = append(args, types.NewDatum(v.len))
 		}
-		_, err := f.F(args, s.ctx)
-		c.Assert(err, NotNil)
+		r, err := f.F(args, s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(r.GetString(), Equals, v.result)
 	}
+
+	// A string that can't be taken from the argument is reported as a truncated value.
+	_, err = Funcs[ast.Substring].F(types.MakeDatums(errors.New("must error"), 1), s.ctx)
+	c.Assert(terror.ErrorEqual(err, ErrTruncatedWrongValue), IsTrue)
+	sqlErr := errors.Cause(err).(*terror.Error).ToSQLError()
+	c.Assert(sqlErr.Code, Equals, uint16(mysql.ErrTruncatedWrongValue))
 }
 
 func (s *testEvaluatorSuite) TestConvert(c *C) {
@@ -363,6 +1025,10 @@ func (s *testEvaluatorSuite) TestConvert(c *C) {
 	}{
 		{"haha", "utf8", "haha"},
 		{"haha", "ascii", "haha"},
+		{"数据", "gbk", "数据"},
+		// What the charset can't represent becomes '?'.
+		{"a😀", "utf8", "a?"},
+		{"数据", "latin1", "??"},
 	}
 	for _, v := range tbl {
 		f := Funcs[ast.Convert]
@@ -372,6 +1038,35 @@ func (s *testEvaluatorSuite) TestConvert(c *C) {
 		c.Assert(r.GetString(), Equals, v.result)
 	}
 
+	// A binary string is decoded from the charset.
+	sc := s.ctx.GetSessionVars().StmtCtx
+	for _, v := range []struct {
+		bytes  string
+		cs     string
+		result interface{}
+	}{
+		{"\xd6\xd0\xce\xc4", "gbk", "中文"},
+		{"h\xe9llo", "latin1", "héllo"},
+		{"\xe4\xb8\xad", "utf8", "中"},
+		{"\xff", "utf8", nil},
+	} {
+		arg := types.NewBytesDatum([]byte(v.bytes))
+		arg.SetCollation(mysql.BinaryCollationID)
+		warnCnt := len(sc.GetWarnings())
+		r, err := Funcs[ast.Convert].F([]types.Datum{arg, types.NewStringDatum(v.cs)}, s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(r, testutil.DatumEquals, types.NewDatum(v.result), Commentf("%q %s", v.bytes, v.cs))
+		if v.result == nil {
+			c.Assert(terror.ErrorEqual(sc.GetWarnings()[warnCnt], ErrInvalidCharacterString), IsTrue)
+		}
+	}
+	r, err := Funcs[ast.Convert].F(types.MakeDatums(nil, "utf8"), s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(r.IsNull(), IsTrue)
+	r, err = Funcs[ast.Convert].F(types.MakeDatums(12, "ascii"), s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(r.GetString(), Equals, "12")
+
 	// Test case for error
 	errTbl := []struct {
 		str    interface{}
@@ -384,7 +1079,59 @@ func (s *testEvaluatorSuite) TestConvert(c *C) {
 		f := Funcs[ast.Convert]
 		_, err := f.F(types.MakeDatums(v.str, v.cs), s.ctx)
 		c.Assert(err, NotNil)
+		c.Assert(terror.ErrorEqual(err, ErrUnknownCharacterSet), IsTrue)
+		c.Assert(err.Error(), Matches, ".*Unknown character set: '"+v.cs+"'")
+		sqlErr := errors.Cause(err).(*terror.Error).ToSQLError()
+		c.Assert(sqlErr.Code, Equals, uint16(mysql.ErrUnknownCharacterSet))
+	}
+}
+
+func (s *testEvaluatorSuite) TestCastCharset(c *C) {
+	defer testleak.AfterTest(c)()
+	tbl := []struct {
+		arg    interface{}
+		cs     string
+		flen   int
+		result interface{}
+		lossy  bool
+	}{
+		{"haha", "utf8", types.UnspecifiedLength, "haha", false},
+		{"数据库", "utf8", types.UnspecifiedLength, "数据库", false},
+		{"a😀", "utf8", types.UnspecifiedLength, "a?", true},
+		{"a😀", "utf8mb4", types.UnspecifiedLength, "a😀", false},
+		{"haha", "ascii", types.UnspecifiedLength, "haha", false},
+		{"héllo", "ascii", types.UnspecifiedLength, "h?llo", true},
+		{"数据", "ascii", 2, "??", true},
+		{"é", "latin1", 1, "é", false},
+		{"héllo", "latin1", types.UnspecifiedLength, "héllo", false},
+		{"数据", "latin1", types.UnspecifiedLength, "??", true},
+		{123, "ascii", types.UnspecifiedLength, "123", false},
+		{nil, "ascii", types.UnspecifiedLength, nil, false},
+	}
+	sc := s.ctx.GetSessionVars().StmtCtx
+	for _, t := range tbl {
+		tp := types.NewFieldType(mysql.TypeString)
+		tp.Flen, tp.Charset = t.flen, t.cs
+		f, err := CastFuncFactory(tp)
+		c.Assert(err, IsNil)
+		warnCnt := len(sc.GetWarnings())
+		v, err := f(types.MakeDatums(t.arg), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v, testutil.DatumEquals, types.NewDatum(t.result), Commentf("%v %s", t.arg, t.cs))
+		if !t.lossy {
+			c.Assert(sc.GetWarnings(), HasLen, warnCnt, Commentf("%v %s", t.arg, t.cs))
+			continue
+		}
+		warnings := sc.GetWarnings()
+		c.Assert(warnings, HasLen, warnCnt+1, Commentf("%v %s", t.arg, t.cs))
+		c.Assert(terror.ErrorEqual(warnings[warnCnt], ErrInvalidCharacterString), IsTrue)
 	}
+
+	// An unknown charset is an error, as in CONVERT().
+	tp := types.NewFieldType(mysql.TypeString)
+	tp.Charset = "wrongcharset"
+	_, err := CastFuncFactory(tp)
+	c.Assert(terror.ErrorEqual(err, ErrUnknownCharacterSet), IsTrue)
 }
 
 func (s *testEvaluatorSuite) TestSubstringIndex(c *C) {
@@ -470,21 +1217,122 @@ func (s *testEvaluatorSuite) TestSpace(c *C) {
 		c.Assert(d, testutil.DatumEquals, t["Expect"][0])
 	}
 
-	// TODO: the error depends on statement context, add those back when statemen context is supported.
-	//wrong := []struct {
-	//	Input string
-	//}{
-	//	{"abc"},
-	//	{"3.3"},
-	//	{""},
-	//}
+	sc := s.ctx.GetSessionVars().StmtCtx
+	oldIgnoreTruncate, oldTruncateAsWarning := sc.IgnoreTruncate, sc.TruncateAsWarning
+	defer func() {
+		sc.IgnoreTruncate, sc.TruncateAsWarning = oldIgnoreTruncate, oldTruncateAsWarning
+	}()
+
+	// A non-integer argument is an error in strict mode and a warning otherwise.
+	wrong := []struct {
+		Input string
+	}{
+		{"abc"},
+		{""},
+	}
+	dwrong := tblToDtbl(wrong)
+	sc.IgnoreTruncate, sc.TruncateAsWarning = false, false
+	for _, t := range dwrong {
+		_, err = builtinSpace(t["Input"], s.ctx)
+		c.Assert(err, NotNil, Commentf("%v", t["Input"]))
+	}
+	sc.TruncateAsWarning = true
+	for _, t := range dwrong {
+		warnCnt := len(sc.GetWarnings())
+		d, err = builtinSpace(t["Input"], s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(d.GetString(), Equals, "")
+		c.Assert(sc.GetWarnings(), HasLen, warnCnt+1)
+	}
+
+	// A result longer than max_allowed_packet is NULL with a warning.
+	vars := s.ctx.GetSessionVars()
+	vars.Systems["max_allowed_packet"] = "1024"
+	defer delete(vars.Systems, "max_allowed_packet")
+	d, err = builtinSpace(types.MakeDatums(1024), s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(d.GetString(), HasLen, 1024)
+	warnCnt := len(sc.GetWarnings())
+	d, err = builtinSpace(types.MakeDatums(1025), s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(d.Kind(), Equals, types.KindNull)
+	warnings := sc.GetWarnings()
+	c.Assert(warnings, HasLen, warnCnt+1)
+	c.Assert(terror.ErrorEqual(warnings[warnCnt], ErrWarnAllowedPacketOverflowed), IsTrue)
+}
 
-	//
-	//dwrong := tblToDtbl(wrong)
-	//for _, t := range dwrong {
-	//	_, err = builtinSpace(t["Input"], s.ctx)
-	//	c.Assert(err, NotNil)
-	//}
+func (s *testEvaluatorSuite) TestToIntArg(c *C) {
+	defer testleak.AfterTest(c)()
+	tbl := []struct {
+		arg       interface{}
+		result    int64
+		truncated bool
+		overflow  bool
+	}{
+		{"  42abc", 42, true, false},
+		{"", 0, true, false},
+		{"+3", 3, false, false},
+		{"-3", -3, false, false},
+		{" 7 ", 7, false, false},
+		{"3.9", 3, false, false},
+		{"1e2", 100, false, false},
+		{"abc", 0, true, false},
+		{"1.5e1x", 15, true, false},
+		{"99999999999999999999", math.MaxInt64, false, true},
+		{"-99999999999999999999", math.MinInt64, false, true},
+		{[]byte("12"), 12, false, false},
+		{3.9, 4, false, false},
+		{-2, -2, false, false},
+		{uint64(math.MaxUint64), math.MaxInt64, false, true},
+	}
+	for _, t := range tbl {
+		sc := &variable.StatementContext{TruncateAsWarning: true}
+		v, err := toIntArg(sc, types.NewDatum(t.arg))
+		c.Assert(err, IsNil, Commentf("%v", t.arg))
+		c.Assert(v, Equals, t.result, Commentf("%v", t.arg))
+
+		// A strict statement fails on the truncation but not on the overflow.
+		_, err = toIntArg(&variable.StatementContext{}, types.NewDatum(t.arg))
+		c.Assert(err != nil, Equals, t.truncated, Commentf("%v", t.arg))
+
+		warnings := sc.GetWarnings()
+		if !t.truncated && !t.overflow {
+			c.Assert(warnings, HasLen, 0, Commentf("%v", t.arg))
+			continue
+		}
+		c.Assert(warnings, HasLen, 1, Commentf("%v", t.arg))
+		if t.truncated {
+			c.Assert(terror.ErrorEqual(warnings[0], types.ErrTruncated), IsTrue)
+		}
+		if t.overflow {
+			c.Assert(terror.ErrorEqual(warnings[0], types.ErrOverflow), IsTrue)
+		}
+	}
+
+	// The string functions convert their integer arguments alike.
+	sc := s.ctx.GetSessionVars().StmtCtx
+	defer func(truncateAsWarning bool) {
+		sc.TruncateAsWarning = truncateAsWarning
+	}(sc.TruncateAsWarning)
+	sc.TruncateAsWarning = true
+	for _, f := range []struct {
+		f      BuiltinFunc
+		args   []interface{}
+		result string
+	}{
+		{builtinLeft, []interface{}{"abcdef", "  2abc"}, "ab"},
+		{builtinRight, []interface{}{"abcdef", "+2"}, "ef"},
+		{builtinSubstring, []interface{}{"abcdef", "2x", "3.9"}, "bcd"},
+		{builtinSpace, []interface{}{"3 spaces"}, "   "},
+		{builtinRepeat, []interface{}{"ab", "2.5"}, "abab"},
+		{builtinRepeat, []interface{}{"ab", 2.5}, "ababab"},
+		{builtinSubstringIndex, []interface{}{"a.b.c", ".", "2nd"}, "a.b"},
+		{builtinElt, []interface{}{"2", "a", "b"}, "b"},
+	} {
+		d, err := f.f(types.MakeDatums(f.args...), s.ctx)
+		c.Assert(err, IsNil, Commentf("%v", f.args))
+		c.Assert(d.GetString(), Equals, f.result, Commentf("%v", f.args))
+	}
 }
 
 func (s *testEvaluatorSuite) TestLocate(c *C) {
@@ -519,6 +1367,23 @@ func (s *testEvaluatorSuite) TestLocate(c *C) {
 		{"", "foobar", 2, 2},
 		{"foobar", "", 1, 0},
 		{"", "", 2, 0},
+		{"", "", 1, 1},
+		// A position below 1 or past the end finds nothing.
+		{"bar", "foobarbar", 0, 0},
+		{"", "foobar", 0, 0},
+		{"bar", "foobarbar", -1, 0},
+		{"bar", "foobarbar", math.MinInt64, 0},
+		{"bar", "foobarbar", 8, 0},
+		{"bar", "foobarbar", 10, 0},
+		{"bar", "foobarbar", math.MaxInt64, 0},
+		{"", "foobar", 7, 7},
+		{"", "foobar", 8, 0},
+		{"r", "foobar", 6, 6},
+		// Positions count characters.
+		{"好", "你好你好", 3, 4},
+		{"好", "你好你好", 1, 2},
+		{"", "你好", 3, 3},
+		{"", "你好", 4, 0},
 	}
 	for _, v := range tbl2 {
 		f := Funcs[ast.Locate]
@@ -554,14 +1419,73 @@ func (s *testEvaluatorSuite) TestLocate(c *C) {
 		{nil, "", 1},
 		{"foo", nil, -1},
 		{nil, "bar", 0},
+		{"foo", "bar", nil},
 	}
 	for _, v := range errTbl2 {
 		f := Funcs[ast.Locate]
-		r, _ := f.F(types.MakeDatums(v.subStr, v.Str), s.ctx)
+		r, _ := f.F(types.MakeDatums(v.subStr, v.Str, v.pos), s.ctx)
 		c.Assert(r.Kind(), Equals, types.KindNull)
 	}
 }
 
+func (s *testEvaluatorSuite) TestLocateBinary(c *C) {
+	defer testleak.AfterTest(c)()
+	// Positions in a binary string count bytes.
+	str := types.NewBytesDatum([]byte("你好你好"))
+	str.SetCollation(mysql.BinaryCollationID)
+	sub := types.NewStringDatum("好")
+	r, err := builtinLocate([]types.Datum{sub, str}, s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(r.GetInt64(), Equals, int64(4))
+	r, err = builtinLocate([]types.Datum{sub, str, types.NewIntDatum(5)}, s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(r.GetInt64(), Equals, int64(10))
+	r, err = builtinLocate([]types.Datum{sub, str, types.NewIntDatum(11)}, s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(r.GetInt64(), Equals, int64(0))
+}
+
+func (s *testEvaluatorSuite) TestLocateCollation(c *C) {
+	defer testleak.AfterTest(c)()
+	withCollation := func(str, collation string) types.Datum {
+		d := types.NewStringDatum(str)
+		if collation != "" {
+			d.SetCollation(mysql.CollationNames[collation])
+		}
+		return d
+	}
+	tbl := []struct {
+		subStr    string
+		str       string
+		collation string
+		ci        int64
+		bin       int64
+	}{
+		{"ABC", "xabcy", "utf8_general_ci", 2, 0},
+		{"abc", "xabcy", "utf8_general_ci", 2, 2},
+		{"bAr", "FOOBARBAR", "utf8_general_ci", 4, 0},
+		{"ÉTÉ", "un été", "utf8_general_ci", 4, 0},
+		{"e", "café", "utf8_general_ci", 4, 0},
+		{"Y", "xabcy", "latin1_swedish_ci", 5, 0},
+	}
+	for _, t := range tbl {
+		ci, err := builtinLocate([]types.Datum{withCollation(t.subStr, t.collation), withCollation(t.str, t.collation)}, s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(ci.GetInt64(), Equals, t.ci, Commentf("locate(%s, %s) under %s", t.subStr, t.str, t.collation))
+
+		// A _bin collation, binary or no collation at all on either side makes the search case sensitive.
+		for _, collation := range []string{"utf8_bin", "binary", ""} {
+			bin, err := builtinLocate([]types.Datum{withCollation(t.subStr, t.collation), withCollation(t.str, collation)}, s.ctx)
+			c.Assert(err, IsNil)
+			c.Assert(bin.GetInt64(), Equals, t.bin, Commentf("locate(%s, %s) under %s", t.subStr, t.str, collation))
+		}
+
+		instr, err := builtinInstr([]types.Datum{withCollation(t.str, t.collation), withCollation(t.subStr, t.collation)}, s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(instr, testutil.DatumEquals, ci)
+	}
+}
+
 func (s *testEvaluatorSuite) TestTrim(c *C) {
 	defer testleak.AfterTest(c)()
 	tbl := []struct {
@@ -570,13 +1494,28 @@ func (s *testEvaluatorSuite) TestTrim(c *C) {
 		dir    ast.TrimDirectionType
 		result interface{}
 	}{
-		{"  bar   ", nil, ast.TrimBothDefault, "bar"},
 		{"xxxbarxxx", "x", ast.TrimLeading, "barxxx"},
 		{"xxxbarxxx", "x", ast.TrimBoth, "bar"},
 		{"barxxyz", "xyz", ast.TrimTrailing, "barx"},
 		{nil, "xyz", ast.TrimBoth, nil},
 		{1, 2, ast.TrimBoth, "1"},
-		{"  \t\rbar\n   ", nil, ast.TrimBothDefault, "bar"},
+		{"  xx  ", "", ast.TrimBoth, "  xx  "},
+		{"  xx  ", "", ast.TrimLeading, "  xx  "},
+		{"  xx  ", "", ast.TrimTrailing, "  xx  "},
+		{"", "", ast.TrimBoth, ""},
+		{nil, "", ast.TrimBoth, nil},
+		// TRIM(remstr FROM str) trims both sides.
+		{"xxyyxx", "x", ast.TrimBothDefault, "yy"},
+		{"xyxyaxyxy", "xy", ast.TrimBothDefault, "a"},
+		{"xxyy", "x", ast.TrimBothDefault, "yy"},
+		{"yyxx", "x", ast.TrimBothDefault, "yy"},
+		{"xxxx", "x", ast.TrimBothDefault, ""},
+		{"  bar  ", " ", ast.TrimBothDefault, "bar"},
+		{" bar ", "", ast.TrimBothDefault, " bar "},
+		// A NULL remstr makes the result NULL, whatever the direction.
+		{"bar", nil, ast.TrimBothDefault, nil},
+		{"bar", nil, ast.TrimLeading, nil},
+		{"bar", nil, ast.TrimTrailing, nil},
 	}
 	for _, v := range tbl {
 		f := Funcs[ast.Trim]
@@ -585,6 +1524,24 @@ func (s *testEvaluatorSuite) TestTrim(c *C) {
 		c.Assert(r, testutil.DatumEquals, types.NewDatum(v.result))
 	}
 
+	// TRIM(str) and TRIM(direction FROM str) trim spaces.
+	for _, v := range []struct {
+		args   []interface{}
+		result interface{}
+	}{
+		{[]interface{}{"  bar  "}, "bar"},
+		{[]interface{}{"  \t\rbar\n   "}, "bar"},
+		{[]interface{}{"  bar  ", ast.TrimBoth}, "bar"},
+		{[]interface{}{"  bar  ", ast.TrimLeading}, "bar  "},
+		{[]interface{}{"  bar  ", ast.TrimTrailing}, "  bar"},
+		{[]interface{}{nil}, nil},
+		{[]interface{}{nil, ast.TrimLeading}, nil},
+	} {
+		r, err := builtinTrim(types.MakeDatums(v.args...), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(r, testutil.DatumEquals, types.NewDatum(v.result), Commentf("%v", v.args))
+	}
+
 	for _, v := range []struct {
 		str, result interface{}
 		fn          string
@@ -630,6 +1587,29 @@ func (s *testEvaluatorSuite) TestHexFunc(c *C) {
 
 	}
 }
+func (s *testEvaluatorSuite) TestHexBinaryAndBit(c *C) {
+	defer testleak.AfterTest(c)()
+	bin := types.NewBytesDatum([]byte("\x00\xffab"))
+	bin.SetCollation(mysql.BinaryCollationID)
+	tbl := []struct {
+		arg    types.Datum
+		expect string
+	}{
+		{bin, "00FF6162"},
+		{types.NewBytesDatum([]byte{}), ""},
+		{types.NewDatum(types.Bit{Value: 5, Width: 12}), "5"},
+		{types.NewDatum(types.Bit{Value: 0x0102, Width: 16}), "102"},
+		{types.NewDatum(types.Bit{Value: 0, Width: 1}), "0"},
+		{types.NewDatum(types.Enum{Name: "b", Value: 2}), "62"},
+		{types.NewDatum(types.Set{Name: "a,b", Value: 3}), "612C62"},
+	}
+	for _, t := range tbl {
+		d, err := builtinHex([]types.Datum{t.arg}, s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(d, testutil.DatumEquals, types.NewStringDatum(t.expect), Commentf("%v", t.arg))
+	}
+}
+
 func (s *testEvaluatorSuite) TestUnhexFunc(c *C) {
 	defer testleak.AfterTest(c)()
 	tbl := []struct {
@@ -638,6 +1618,8 @@ func (s *testEvaluatorSuite) TestUnhexFunc(c *C) {
 	}{
 		{"4D7953514C", "MySQL"},
 		{"31323334", "1234"},
+		{"4d7953514c", "MySQL"},
+		{"123", "\x01\x23"},
 		{"", ""},
 	}
 
@@ -648,6 +1630,47 @@ func (s *testEvaluatorSuite) TestUnhexFunc(c *C) {
 		c.Assert(d, testutil.DatumEquals, t["Expect"][0])
 
 	}
+
+	d, err := builtinUnHex(types.MakeDatums([]byte("41")), s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(d.GetString(), Equals, "A")
+
+	for _, input := range []string{"4G", "x"} {
+		d, err = builtinUnHex(types.MakeDatums(input), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(d.Kind(), Equals, types.KindNull)
+	}
+}
+
+func (s *testEvaluatorSuite) TestHexRoundTrip(c *C) {
+	defer testleak.AfterTest(c)()
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		b := make([]byte, r.Intn(64))
+		r.Read(b)
+		for _, x := range []types.Datum{types.NewBytesDatum(b), types.NewStringDatum(string(b))} {
+			h, err := builtinHex([]types.Datum{x}, s.ctx)
+			c.Assert(err, IsNil)
+			c.Assert(h.GetString(), HasLen, 2*len(b))
+			d, err := builtinUnHex([]types.Datum{h}, s.ctx)
+			c.Assert(err, IsNil)
+			c.Assert(d.GetString(), Equals, string(b), Commentf("unhex(hex(%q))", b))
+		}
+	}
+
+	// An integer is hexed as a number, a numeric string byte by byte.
+	for _, t := range []struct {
+		x      interface{}
+		result string
+	}{
+		{255, "FF"},
+		{"255", "323535"},
+		{[]byte("255"), "323535"},
+	} {
+		d, err := builtinHex(types.MakeDatums(t.x), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(d.GetString(), Equals, t.result)
+	}
 }
 
 func (s *testEvaluatorSuite) TestRpad(c *C) {
@@ -665,6 +1688,9 @@ func (s *testEvaluatorSuite) TestRpad(c *C) {
 		{"hi", 5, "", nil},
 		{"hi", 5, "ab", "hiaba"},
 		{"hi", 6, "ab", "hiabab"},
+		{"a", 5, "你", "a你你你你"},
+		{"你好", 3, "ab", "你好a"},
+		{"你好世界", 2, "?", "你好"},
 	}
 	for _, test := range tests {
 		str := types.NewStringDatum(test.str)
@@ -680,3 +1706,271 @@ func (s *testEvaluatorSuite) TestRpad(c *C) {
 		}
 	}
 }
+
+func (s *testEvaluatorSuite) TestLpad(c *C) {
+	defer testleak.AfterTest(c)()
+	tbl := []struct {
+		args   []interface{}
+		expect interface{}
+	}{
+		{[]interface{}{"hi", 5, "?"}, "???hi"},
+		{[]interface{}{"hi", 1, "?"}, "h"},
+		{[]interface{}{"hi", 0, "?"}, ""},
+		{[]interface{}{"hi", -1, "?"}, nil},
+		{[]interface{}{"hi", 1, ""}, "h"},
+		{[]interface{}{"hi", 5, ""}, nil},
+		{[]interface{}{"hi", 5, "ab"}, "abahi"},
+		{[]interface{}{"a", 5, "你"}, "你你你你a"},
+		{[]interface{}{"你好", 3, "ab"}, "a你好"},
+		{[]interface{}{nil, 5, "?"}, nil},
+		{[]interface{}{"hi", nil, "?"}, nil},
+		{[]interface{}{"hi", 5, nil}, nil},
+	}
+	for _, t := range tbl {
+		v, err := builtinLpad(types.MakeDatums(t.args...), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v, testutil.DatumEquals, types.NewDatum(t.expect), Commentf("%v", t.args))
+	}
+}
+
+func (s *testEvaluatorSuite) TestPadMaxAllowedPacket(c *C) {
+	defer testleak.AfterTest(c)()
+	// A result longer than max_allowed_packet is NULL with a warning.
+	vars := s.ctx.GetSessionVars()
+	vars.Systems["max_allowed_packet"] = "1024"
+	defer delete(vars.Systems, "max_allowed_packet")
+	sc := vars.StmtCtx
+	for _, f := range []BuiltinFunc{builtinLpad, builtinRpad} {
+		v, err := f(types.MakeDatums("a", 1024, "x"), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v.GetString(), HasLen, 1024)
+
+		warnCnt := len(sc.GetWarnings())
+		v, err = f(types.MakeDatums("a", 1025, "x"), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v.Kind(), Equals, types.KindNull)
+		// 1024 multibyte characters take more than 1024 bytes.
+		v, err = f(types.MakeDatums("a", 1024, "你"), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v.Kind(), Equals, types.KindNull)
+		warnings := sc.GetWarnings()
+		c.Assert(warnings, HasLen, warnCnt+2)
+		c.Assert(terror.ErrorEqual(warnings[warnCnt], ErrWarnAllowedPacketOverflowed), IsTrue)
+	}
+}
+
+func (s *testEvaluatorSuite) TestSoundex(c *C) {
+	defer testleak.AfterTest(c)()
+	tbl := []struct {
+		Input  interface{}
+		Expect interface{}
+	}{
+		{"Hello", "H400"},
+		{"Robert", "R163"},
+		{"Rupert", "R163"},
+		{"Rubin", "R150"},
+		{"Tymczak", "T522"},
+		{"Ashcraft", "A2613"},
+		{"Ashhcraft", "A2613"},
+		{"Bwb", "B000"},
+		{"Pfister", "P236"},
+		{"Quadratically", "Q36324"},
+		{"  123 lee", "L000"},
+		{"a-b", "A100"},
+		{"123 !?", ""},
+		{"", ""},
+		{nil, nil},
+	}
+	dtbl := tblToDtbl(tbl)
+	for _, t := range dtbl {
+		d, err := builtinSoundex(t["Input"], s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(d, testutil.DatumEquals, t["Expect"][0])
+	}
+}
+
+func (s *testEvaluatorSuite) TestWeightString(c *C) {
+	defer testleak.AfterTest(c)()
+	utf8Bin := types.NewStringDatum("aB")
+	utf8Bin.SetCollation(mysql.CollationNames["utf8_bin"])
+	latin1 := types.NewStringDatum("aB")
+	latin1.SetCollation(mysql.CollationNames["latin1_swedish_ci"])
+	tbl := []struct {
+		args   []types.Datum
+		expect interface{}
+	}{
+		// utf8_general_ci folds case.
+		{types.MakeDatums("aB"), []byte{0, 'A', 0, 'B'}},
+		{types.MakeDatums("Ab "), []byte{0, 'A', 0, 'B'}},
+		// Accented Latin-1 letters are weighed as the letters without accents.
+		{types.MakeDatums("é"), []byte{0, 'E'}},
+		{types.MakeDatums("Çà"), []byte{0, 'C', 0, 'A'}},
+		{types.MakeDatums("ß"), []byte{0, 'S'}},
+		{types.MakeDatums("Æ"), []byte{0, 0xC6}},
+		{[]types.Datum{utf8Bin}, []byte{0, 0, 'a', 0, 0, 'B'}},
+		{[]types.Datum{latin1}, []byte{'A', 'B'}},
+		// binary strings are returned unchanged.
+		{types.MakeDatums([]byte("aB ")), []byte("aB ")},
+		// length modifiers
+		{types.MakeDatums("ab", "CHAR", 4), []byte{0, 'A', 0, 'B', 0, ' ', 0, ' '}},
+		{types.MakeDatums("abc", "CHAR", 2), []byte{0, 'A', 0, 'B'}},
+		{types.MakeDatums("ab", "BINARY", 4), []byte{'a', 'b', 0, 0}},
+		{types.MakeDatums("abc", "BINARY", 1), []byte("a")},
+		{types.MakeDatums(nil), nil},
+	}
+	for _, t := range tbl {
+		d, err := builtinWeightString(t.args, s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(d, testutil.DatumEquals, types.NewDatum(t.expect))
+	}
+
+	_, err := builtinWeightString(types.MakeDatums("a", "DECIMAL", 1), s.ctx)
+	c.Assert(err, NotNil)
+}
+
+func (s *testEvaluatorSuite) TestField(c *C) {
+	defer testleak.AfterTest(c)()
+	tbl := []struct {
+		args   []interface{}
+		result int64
+	}{
+		{[]interface{}{"ej", "Hej", "ej", "Heja", "hej", "foo"}, 2},
+		{[]interface{}{"fo", "Hej", "ej", "Heja", "hej", "foo"}, 0},
+		// Mixed strings and numbers are compared as doubles.
+		{[]interface{}{"1", 1, 2}, 1},
+		{[]interface{}{1, "1", "2"}, 1},
+		{[]interface{}{"1.0", "1", 1.0}, 1},
+		{[]interface{}{2, "1", 2.0}, 2},
+		// Strings only are compared as strings, numbers only as numbers.
+		{[]interface{}{"1.0", "1", "1.00"}, 0},
+		{[]interface{}{1, 1.0}, 1},
+		{[]interface{}{types.NewDecFromInt(2), 1, 2.0}, 2},
+		{[]interface{}{nil, nil, 1}, 0},
+		{[]interface{}{"a", nil, "a"}, 2},
+	}
+	for _, t := range tbl {
+		v, err := builtinField(types.MakeDatums(t.args...), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v.GetInt64(), Equals, t.result, Commentf("%v", t.args))
+	}
+	// Strings are compared under the collation derived for them.
+	for collation, expect := range map[string]int64{"utf8_general_ci": 2, "utf8_bin": 0} {
+		args := types.MakeDatums("a", "B", "A")
+		for i := range args {
+			args[i].SetCollation(mysql.CollationNames[collation])
+		}
+		v, err := builtinField(args, s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v.GetInt64(), Equals, expect, Commentf("%s", collation))
+	}
+}
+
+func (s *testEvaluatorSuite) TestElt(c *C) {
+	defer testleak.AfterTest(c)()
+	tbl := []struct {
+		args   []interface{}
+		result interface{}
+	}{
+		{[]interface{}{1, "Aa", "Bb", "Cc"}, "Aa"},
+		{[]interface{}{3, "Aa", "Bb", "Cc"}, "Cc"},
+		{[]interface{}{"2", "Aa", "Bb", "Cc"}, "Bb"},
+		{[]interface{}{2, "Aa", 2}, "2"},
+		{[]interface{}{0, "Aa", "Bb"}, nil},
+		{[]interface{}{3, "Aa", "Bb"}, nil},
+		{[]interface{}{2, "Aa", nil}, nil},
+		{[]interface{}{nil, "Aa"}, nil},
+	}
+	for _, t := range tbl {
+		v, err := builtinElt(types.MakeDatums(t.args...), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v, testutil.DatumEquals, types.NewDatum(t.result), Commentf("%v", t.args))
+	}
+}
+
+func (s *testEvaluatorSuite) TestMakeSet(c *C) {
+	defer testleak.AfterTest(c)()
+	tbl := []struct {
+		args   []interface{}
+		result interface{}
+	}{
+		{[]interface{}{1, "a", "b", "c"}, "a"},
+		{[]interface{}{1 | 4, "hello", "nice", "world"}, "hello,world"},
+		{[]interface{}{1 | 4, "hello", "nice", nil, "world"}, "hello"},
+		{[]interface{}{"3", "a", 2, "c"}, "a,2"},
+		{[]interface{}{0, "a", "b"}, ""},
+		{[]interface{}{nil, "a", "b"}, nil},
+	}
+	for _, t := range tbl {
+		v, err := builtinMakeSet(types.MakeDatums(t.args...), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v, testutil.DatumEquals, types.NewDatum(t.result), Commentf("%v", t.args))
+	}
+
+	// The highest bit picks the 64th string, and a 65th string is never picked.
+	args := []interface{}{uint64(1 << 63)}
+	for i := 1; i <= 65; i++ {
+		args = append(args, fmt.Sprintf("s%d", i))
+	}
+	v, err := builtinMakeSet(types.MakeDatums(args...), s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(v.GetString(), Equals, "s64")
+	args[0] = int64(-1)
+	v, err = builtinMakeSet(types.MakeDatums(args...), s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(strings.Count(v.GetString(), ","), Equals, 63)
+	c.Assert(strings.HasSuffix(v.GetString(), ",s64"), IsTrue)
+}
+
+func (s *testEvaluatorSuite) TestExportSet(c *C) {
+	defer testleak.AfterTest(c)()
+	tbl := []struct {
+		args   []interface{}
+		result interface{}
+	}{
+		{[]interface{}{5, "Y", "N", ",", 4}, "Y,N,Y,N"},
+		{[]interface{}{6, "1", "0", ",", 10}, "0,1,1,0,0,0,0,0,0,0"},
+		{[]interface{}{5, "Y", "N", "", 3}, "YNY"},
+		{[]interface{}{"5", "Y", "N", "-", "2"}, "Y-N"},
+		{[]interface{}{0, "Y", "N", ",", 0}, ""},
+		{[]interface{}{uint64(1 << 63), "1", "0", "", 64}, strings.Repeat("0", 63) + "1"},
+		{[]interface{}{-1, "1", "0", "", 100}, strings.Repeat("1", 64)},
+		{[]interface{}{1, "1", "0", "", -1}, "1" + strings.Repeat("0", 63)},
+		{[]interface{}{1, "1", "0", ""}, "1" + strings.Repeat("0", 63)},
+		{[]interface{}{3, "1", "0"}, "1,1" + strings.Repeat(",0", 62)},
+		{[]interface{}{nil, "Y", "N"}, nil},
+		{[]interface{}{5, nil, "N"}, nil},
+		{[]interface{}{5, "Y", "N", nil}, nil},
+		{[]interface{}{5, "Y", "N", ",", nil}, nil},
+	}
+	for _, t := range tbl {
+		v, err := builtinExportSet(types.MakeDatums(t.args...), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v, testutil.DatumEquals, types.NewDatum(t.result), Commentf("%v", t.args))
+	}
+}
+
+func (s *testEvaluatorSuite) TestFormat(c *C) {
+	defer testleak.AfterTest(c)()
+	tbl := []struct {
+		args   []interface{}
+		result interface{}
+	}{
+		{[]interface{}{types.NewDecFromStringForTest("1234567.891"), 2}, "1,234,567.89"},
+		{[]interface{}{types.NewDecFromStringForTest("-1234567.891"), 2}, "-1,234,567.89"},
+		{[]interface{}{types.NewDecFromStringForTest("2.5"), 0}, "3"},
+		{[]interface{}{types.NewDecFromStringForTest("-2.5"), 0}, "-3"},
+		{[]interface{}{"1.005", 2}, "1.01"},
+		{[]interface{}{12, 3}, "12.000"},
+		{[]interface{}{123, 0}, "123"},
+		{[]interface{}{1234.5, -1}, "1,235"},
+		{[]interface{}{999.999, 2}, "1,000.00"},
+		{[]interface{}{1234.5, 1, "de_DE"}, "1,234.5"},
+		{[]interface{}{nil, 2}, nil},
+		{[]interface{}{1234.5, nil}, nil},
+	}
+	for _, t := range tbl {
+		v, err := builtinFormat(types.MakeDatums(t.args...), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v, testutil.DatumEquals, types.NewDatum(t.result), Commentf("%v", t.args))
+	}
+}
@@ -14,9 +14,20 @@
 package evaluator
 
 import (
+	"fmt"
+	"math"
 	"reflect"
+	"sort"
+	"strings"
 
+	"github.com/juju/errors"
 	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/terror"
 	"github.com/pingcap/tidb/util/testleak"
 	"github.com/pingcap/tidb/util/testutil"
 	"github.com/pingcap/tidb/util/types"
@@ -91,6 +102,68 @@ func (s *testEvaluatorSuite) TestGreatestFunc(c *C) {
 	c.Assert(v.IsNull(), IsTrue)
 }
 
+func (s *testEvaluatorSuite) TestLeastFunc(c *C) {
+	defer testleak.AfterTest(c)()
+
+	v, err := builtinLeast(types.MakeDatums(2, 0, 5), s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(v.GetInt64(), Equals, int64(0))
+
+	v, err = builtinLeast(types.MakeDatums("B", "A", "C"), s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(v.GetString(), Equals, "A")
+
+	// LEAST() returns NULL if any argument is NULL, even the least one is not.
+	v, err = builtinLeast(types.MakeDatums(1, 2, nil), s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(v.IsNull(), IsTrue)
+}
+
+func (s *testEvaluatorSuite) TestGreatestLeastTemporal(c *C) {
+	defer testleak.AfterTest(c)()
+	parse := func(s string, tp byte, fsp int) types.Datum {
+		t, err := types.ParseTime(s, tp, fsp)
+		c.Assert(err, IsNil)
+		return types.NewDatum(t)
+	}
+	tbl := []struct {
+		args     []types.Datum
+		greatest string
+		least    string
+	}{
+		// Dates of different lengths compare as points in time, not as strings.
+		{[]types.Datum{parse("2017-01-10", mysql.TypeDate, 0), parse("2017-01-02", mysql.TypeDate, 0), parse("2016-12-31", mysql.TypeDate, 0)},
+			"2017-01-10", "2016-12-31"},
+		{[]types.Datum{parse("2017-01-10 10:00:00", mysql.TypeDatetime, 0), parse("2017-01-10 09:00:00", mysql.TypeDatetime, 0)},
+			"2017-01-10 10:00:00", "2017-01-10 09:00:00"},
+		// Mixed dates and datetimes return a datetime.
+		{[]types.Datum{parse("2017-01-10", mysql.TypeDate, 0), parse("2017-01-10 00:00:01", mysql.TypeDatetime, 0)},
+			"2017-01-10 00:00:01", "2017-01-10 00:00:00"},
+		{[]types.Datum{parse("2017-01-11", mysql.TypeDate, 0), parse("2017-01-10 12:30:00.5", mysql.TypeDatetime, 1)},
+			"2017-01-11 00:00:00.0", "2017-01-10 12:30:00.5"},
+		// The fsp of a DATE argument doesn't count.
+		{[]types.Datum{parse("2017-01-01", mysql.TypeDate, types.MaxFsp), parse("2017-01-01 10:00:00", mysql.TypeDatetime, 0)},
+			"2017-01-01 10:00:00", "2017-01-01 00:00:00"},
+	}
+	for _, t := range tbl {
+		v, err := builtinGreatest(t.args, s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v.GetMysqlTime().String(), Equals, t.greatest)
+		v, err = builtinLeast(t.args, s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v.GetMysqlTime().String(), Equals, t.least)
+	}
+
+	// A NULL argument among dates forces NULL.
+	args := []types.Datum{parse("2017-01-10", mysql.TypeDate, 0), {}, parse("2017-01-11", mysql.TypeDate, 0)}
+	v, err := builtinGreatest(args, s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(v.IsNull(), IsTrue)
+	v, err = builtinLeast(args, s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(v.IsNull(), IsTrue)
+}
+
 func (s *testEvaluatorSuite) TestIsNullFunc(c *C) {
 	defer testleak.AfterTest(c)()
 
@@ -101,6 +174,13 @@ func (s *testEvaluatorSuite) TestIsNullFunc(c *C) {
 	v, err = builtinIsNull(types.MakeDatums(nil), s.ctx)
 	c.Assert(err, IsNil)
 	c.Assert(v.GetInt64(), Equals, int64(1))
+
+	// Falsy values are not NULL.
+	for _, arg := range []interface{}{0, "", 0.0} {
+		v, err = builtinIsNull(types.MakeDatums(arg), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v.GetInt64(), Equals, int64(0), Commentf("%v", arg))
+	}
 }
 
 func (s *testEvaluatorSuite) TestLock(c *C) {
@@ -118,3 +198,543 @@ func (s *testEvaluatorSuite) TestLock(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(v.GetInt64(), Equals, int64(1))
 }
+
+func (s *testEvaluatorSuite) TestAnyValue(c *C) {
+	defer testleak.AfterTest(c)()
+	f, ok := Funcs[ast.AnyValue]
+	c.Assert(ok, IsTrue)
+	c.Assert(f.CheckArgCount(ast.AnyValue, 0), NotNil)
+	c.Assert(f.CheckArgCount(ast.AnyValue, 2), NotNil)
+
+	for _, arg := range types.MakeDatums(nil, 1, uint64(2), 1.5, "abc", []byte("x"), types.NewDecFromInt(3)) {
+		v, err := f.F([]types.Datum{arg}, s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v.Kind(), Equals, arg.Kind())
+		c.Assert(v, testutil.DatumEquals, arg)
+	}
+
+	tp := types.NewFieldType(mysql.TypeVarchar)
+	tp.Flen, tp.Charset = 10, "utf8"
+	inferred, err := TypeInferers[ast.AnyValue]([]*types.FieldType{tp})
+	c.Assert(err, IsNil)
+	c.Assert(inferred, DeepEquals, tp)
+	c.Assert(inferred, Not(Equals), tp)
+}
+
+func (s *testEvaluatorSuite) TestNameConst(c *C) {
+	defer testleak.AfterTest(c)()
+	f, ok := Funcs[ast.NameConst]
+	c.Assert(ok, IsTrue)
+	c.Assert(f.CheckArgCount(ast.NameConst, 1), NotNil)
+
+	for _, value := range types.MakeDatums(nil, 14, 1.5, "abc", types.NewDecFromInt(3)) {
+		v, err := f.F([]types.Datum{types.NewStringDatum("myname"), value}, s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v.Kind(), Equals, value.Kind())
+		c.Assert(v, testutil.DatumEquals, value)
+	}
+
+	inferred, err := TypeInferers[ast.NameConst]([]*types.FieldType{types.NewFieldType(mysql.TypeVarString), types.NewFieldType(mysql.TypeDouble)})
+	c.Assert(err, IsNil)
+	c.Assert(inferred.Tp, Equals, mysql.TypeDouble)
+}
+
+func (s *testEvaluatorSuite) TestIsPredicates(c *C) {
+	defer testleak.AfterTest(c)()
+	// Every IS predicate returns a definite 1 or 0, including for NULL. IS UNKNOWN is planned as
+	// IS NULL, and the negated forms as NOT of the positive ones.
+	tbl := []struct {
+		arg                                interface{}
+		isNull, isNotNull, isTrue, isFalse int64
+		isNotTrue, isNotFalse              int64
+	}{
+		{1, 0, 1, 1, 0, 0, 1},
+		{-2, 0, 1, 1, 0, 0, 1},
+		{0.5, 0, 1, 1, 0, 0, 1},
+		{"1", 0, 1, 1, 0, 0, 1},
+		{0, 0, 1, 0, 1, 1, 0},
+		{0.0, 0, 1, 0, 1, 1, 0},
+		{nil, 1, 0, 0, 0, 1, 1},
+	}
+	eval := func(name string, arg types.Datum) int64 {
+		d, err := Funcs[name].F([]types.Datum{arg}, s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(d.Kind(), Equals, types.KindInt64)
+		return d.GetInt64()
+	}
+	not := func(name string, arg types.Datum) int64 {
+		d, err := Funcs[name].F([]types.Datum{arg}, s.ctx)
+		c.Assert(err, IsNil)
+		d, err = Funcs[ast.UnaryNot].F([]types.Datum{d}, s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(d.Kind(), Equals, types.KindInt64)
+		return d.GetInt64()
+	}
+	for _, t := range tbl {
+		arg := types.NewDatum(t.arg)
+		c.Assert(eval(ast.IsNull, arg), Equals, t.isNull)
+		c.Assert(not(ast.IsNull, arg), Equals, t.isNotNull)
+		c.Assert(eval(ast.IsTruth, arg), Equals, t.isTrue)
+		c.Assert(eval(ast.IsFalsity, arg), Equals, t.isFalse)
+		c.Assert(not(ast.IsTruth, arg), Equals, t.isNotTrue)
+		c.Assert(not(ast.IsFalsity, arg), Equals, t.isNotFalse)
+	}
+}
+
+func (s *testEvaluatorSuite) TestIn(c *C) {
+	defer testleak.AfterTest(c)()
+	sc := s.ctx.GetSessionVars().StmtCtx
+	oldIgnoreTruncate := sc.IgnoreTruncate
+	sc.IgnoreTruncate = true
+	defer func() {
+		sc.IgnoreTruncate = oldIgnoreTruncate
+	}()
+	tbl := []struct {
+		Input  []interface{}
+		Expect interface{}
+	}{
+		// numeric
+		{[]interface{}{1, 2, 1}, 1},
+		{[]interface{}{1, 2, 3}, 0},
+		{[]interface{}{1, 1.0}, 1},
+		{[]interface{}{uint64(1), int64(1)}, 1},
+		{[]interface{}{types.NewDecFromStringForTest("1.50"), 1.5}, 1},
+		// string
+		{[]interface{}{"a", "b", "a"}, 1},
+		{[]interface{}{"1.0", "1"}, 0},
+		{[]interface{}{"a", "A"}, 0},
+		{[]interface{}{[]byte("a"), "a"}, 1},
+		// strings mixed with numbers are compared as real numbers
+		{[]interface{}{"1.0", "1", 2}, 1},
+		{[]interface{}{1, "1.0"}, 1},
+		{[]interface{}{"abc", 0}, 1},
+		{[]interface{}{"2", "2.5", 3}, 0},
+		// NULL in the list
+		{[]interface{}{1, nil, 1}, 1},
+		{[]interface{}{1, nil, 2}, nil},
+		{[]interface{}{"a", "b", nil}, nil},
+		// NULL on the left side
+		{[]interface{}{nil, 1, 2}, nil},
+		{[]interface{}{nil, nil}, nil},
+	}
+	dtbl := tblToDtbl(tbl)
+	for _, t := range dtbl {
+		d, err := builtinIn(t["Input"], s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(d, testutil.DatumEquals, t["Expect"][0], Commentf("%v", t["Input"]))
+	}
+	// Strings are compared under the collation derived for them.
+	for collation, expect := range map[string]int64{"utf8_general_ci": 1, "utf8_bin": 0} {
+		args := types.MakeDatums("a", "B", "A")
+		for i := range args {
+			args[i].SetCollation(mysql.CollationNames[collation])
+		}
+		d, err := builtinIn(args, s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(d.GetInt64(), Equals, expect, Commentf("%s", collation))
+	}
+}
+
+func (s *testEvaluatorSuite) TestRegisterFunction(c *C) {
+	defer testleak.AfterTest(c)()
+	plusOne := func(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
+		if args[0].IsNull() {
+			return
+		}
+		i, err := args[0].ToInt64(ctx.GetSessionVars().StmtCtx)
+		d.SetInt64(i + 1)
+		return d, errors.Trace(err)
+	}
+	c.Assert(RegisterFunction("Test_Plus_One", Func{plusOne, 1, 1}), IsNil)
+	defer UnregisterFunction("test_plus_one")
+
+	f, ok := Funcs["test_plus_one"]
+	c.Assert(ok, IsTrue)
+	v, err := f.F(types.MakeDatums(41), s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(v.GetInt64(), Equals, int64(42))
+
+	c.Assert(RegisterFunction("TEST_PLUS_ONE", Func{plusOne, 1, 1}), NotNil)
+	c.Assert(RegisterFunction(ast.Concat, Func{plusOne, 1, 1}), NotNil)
+	c.Assert(RegisterFunction("test_no_impl", Func{nil, 1, 1}), NotNil)
+
+	c.Assert(UnregisterFunction("TEST_plus_one"), IsNil)
+	_, ok = Funcs["test_plus_one"]
+	c.Assert(ok, IsFalse)
+	c.Assert(UnregisterFunction("test_plus_one"), NotNil)
+}
+
+func (s *testEvaluatorSuite) TestSupportedFunctions(c *C) {
+	defer testleak.AfterTest(c)()
+	contains := func(names []string, name string) bool {
+		i := sort.SearchStrings(names, name)
+		return i < len(names) && names[i] == name
+	}
+	names := SupportedFunctions()
+	c.Assert(names, HasLen, len(Funcs))
+	c.Assert(sort.StringsAreSorted(names), IsTrue)
+	for _, name := range []string{ast.Concat, ast.Length, ast.Now, ast.Coalesce, ast.JSONType} {
+		c.Assert(contains(names, name), IsTrue, Commentf("%s", name))
+	}
+	c.Assert(contains(names, "test_registered"), IsFalse)
+
+	noop := func(_ []types.Datum, _ context.Context) (d types.Datum, err error) {
+		return d, nil
+	}
+	c.Assert(RegisterFunction("test_registered", Func{noop, 0, 0}), IsNil)
+	defer UnregisterFunction("test_registered")
+	c.Assert(contains(SupportedFunctions(), "test_registered"), IsTrue)
+}
+
+func (s *testEvaluatorSuite) TestInterval(c *C) {
+	defer testleak.AfterTest(c)()
+	tbl := []struct {
+		Input  []interface{}
+		Expect interface{}
+	}{
+		{[]interface{}{23, 1, 15, 17, 30, 44, 200}, 3},
+		{[]interface{}{10, 1, 10, 100, 1000}, 2},
+		{[]interface{}{22, 23, 30, 44, 200}, 0},
+		{[]interface{}{1, 1}, 1},
+		{[]interface{}{0, 1}, 0},
+		{[]interface{}{200, 1, 15, 200}, 3},
+		{[]interface{}{201, 1, 15, 200}, 3},
+		{[]interface{}{1.5, 1, 1.5, 2}, 2},
+		{[]interface{}{1.4, 1, 1.5, 2}, 1},
+		{[]interface{}{"10", 1, 9, 11}, 2},
+		{[]interface{}{-1, nil, 0}, 1},
+		{[]interface{}{nil, 1, 2}, -1},
+		{[]interface{}{nil, nil}, -1},
+	}
+	dtbl := tblToDtbl(tbl)
+	for _, t := range dtbl {
+		d, err := builtinInterval(t["Input"], s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(d, testutil.DatumEquals, t["Expect"][0], Commentf("%v", t["Input"]))
+	}
+}
+
+// TestEltFieldIntervalMatrix pins down how ELT, FIELD and INTERVAL differ on the same edge cases:
+// ELT gives NULL for an index out of range, FIELD gives 0 for a value not found and INTERVAL gives
+// -1 for a NULL value.
+func (s *testEvaluatorSuite) TestEltFieldIntervalMatrix(c *C) {
+	defer testleak.AfterTest(c)()
+	tbl := []struct {
+		name   string
+		args   []interface{}
+		result interface{}
+	}{
+		// An index or value below the first.
+		{ast.Elt, []interface{}{0, "a", "b"}, nil},
+		{ast.Elt, []interface{}{-1, "a", "b"}, nil},
+		{ast.Field, []interface{}{0, 1, 2}, int64(0)},
+		{ast.Interval, []interface{}{0, 1, 2}, int64(0)},
+		{ast.Interval, []interface{}{-1, 1, 2}, int64(0)},
+		// An index or value past the last.
+		{ast.Elt, []interface{}{3, "a", "b"}, nil},
+		{ast.Elt, []interface{}{uint64(math.MaxUint64), "a", "b"}, nil},
+		{ast.Field, []interface{}{3, 1, 2}, int64(0)},
+		{ast.Interval, []interface{}{3, 1, 2}, int64(2)},
+		// The first and last in range.
+		{ast.Elt, []interface{}{1, "a", "b"}, "a"},
+		{ast.Elt, []interface{}{2, "a", "b"}, "b"},
+		{ast.Field, []interface{}{1, 1, 2}, int64(1)},
+		{ast.Field, []interface{}{2, 1, 2}, int64(2)},
+		{ast.Interval, []interface{}{1, 1, 2}, int64(1)},
+		{ast.Interval, []interface{}{2, 1, 2}, int64(2)},
+		// A NULL index or value.
+		{ast.Elt, []interface{}{nil, "a", "b"}, nil},
+		{ast.Field, []interface{}{nil, 1, nil}, int64(0)},
+		{ast.Interval, []interface{}{nil, 1, 2}, int64(-1)},
+		// A NULL in the list.
+		{ast.Elt, []interface{}{1, nil, "b"}, nil},
+		{ast.Field, []interface{}{2, nil, 2}, int64(2)},
+		{ast.Interval, []interface{}{2, nil, 2}, int64(2)},
+	}
+	for _, t := range tbl {
+		v, err := Funcs[t.name].F(types.MakeDatums(t.args...), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v, testutil.DatumEquals, types.NewDatum(t.result), Commentf("%s%v", t.name, t.args))
+	}
+
+	// None of them can be called without a list.
+	for _, name := range []string{ast.Elt, ast.Field, ast.Interval} {
+		err := Funcs[name].CheckArgCount(name, 1)
+		c.Assert(terror.ErrorEqual(err, ErrIncorrectParameterCount), IsTrue, Commentf("%s", name))
+		c.Assert(Funcs[name].CheckArgCount(name, 2), IsNil, Commentf("%s", name))
+	}
+}
+
+func (s *testEvaluatorSuite) TestValues(c *C) {
+	defer testleak.AfterTest(c)()
+	sessVars := s.ctx.GetSessionVars()
+	defer func() {
+		sessVars.CurrInsertValues = nil
+	}()
+	v := &ast.ValuesExpr{
+		Column: &ast.ColumnNameExpr{
+			Refer: &ast.ResultField{Column: &model.ColumnInfo{Offset: 1}},
+		},
+	}
+	fn := BuildinValuesFactory(v)
+
+	// Outside of an insert statement VALUES() is NULL.
+	d, err := fn(nil, s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(d.IsNull(), IsTrue)
+
+	sessVars.CurrInsertValues = types.MakeDatums(1, "abc")
+	d, err = fn(nil, s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(d, testutil.DatumEquals, types.NewDatum("abc"))
+
+	sessVars.CurrInsertValues = types.MakeDatums(1)
+	_, err = fn(nil, s.ctx)
+	c.Assert(err, NotNil)
+}
+
+func (s *testEvaluatorSuite) TestDefault(c *C) {
+	defer testleak.AfterTest(c)()
+	newCol := func(tp byte, flag uint, dflt interface{}) *model.ColumnInfo {
+		col := &model.ColumnInfo{
+			Name:         model.NewCIStr("c"),
+			FieldType:    *types.NewFieldType(tp),
+			DefaultValue: dflt,
+		}
+		col.Flag = flag
+		return col
+	}
+	tbl := []struct {
+		col    *model.ColumnInfo
+		expect interface{}
+	}{
+		{newCol(mysql.TypeLonglong, 0, "10"), int64(10)},
+		{newCol(mysql.TypeLonglong, 0, nil), nil},
+		{newCol(mysql.TypeVarchar, mysql.NotNullFlag, "abc"), "abc"},
+		{newCol(mysql.TypeDouble, 0, "1.5"), float64(1.5)},
+	}
+	for _, t := range tbl {
+		d, err := BuildinDefaultFactory(t.col)(nil, s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(d, testutil.DatumEquals, types.NewDatum(t.expect))
+	}
+
+	// Enum columns without a default value use their first element.
+	col := newCol(mysql.TypeEnum, mysql.NotNullFlag|mysql.NoDefaultValueFlag, nil)
+	col.Elems = []string{"a", "b"}
+	d, err := BuildinDefaultFactory(col)(nil, s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(d.GetMysqlEnum().String(), Equals, "a")
+
+	col = newCol(mysql.TypeDatetime, 0, "CURRENT_TIMESTAMP")
+	d, err = BuildinDefaultFactory(col)(nil, s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(d.Kind(), Equals, types.KindMysqlTime)
+
+	col = newCol(mysql.TypeLonglong, mysql.NotNullFlag|mysql.NoDefaultValueFlag, nil)
+	_, err = BuildinDefaultFactory(col)(nil, s.ctx)
+	c.Assert(ErrNoDefaultValue.Equal(err), IsTrue)
+}
+
+func (s *testEvaluatorSuite) TestFormatBytes(c *C) {
+	defer testleak.AfterTest(c)()
+	tbl := []struct {
+		arg interface{}
+		ret interface{}
+	}{
+		{nil, nil},
+		{0, "0 bytes"},
+		{1023, "1023 bytes"},
+		{-512, "-512 bytes"},
+		{1024, "1.00 KiB"},
+		{1536, "1.50 KiB"},
+		{-1536, "-1.50 KiB"},
+		{1024*1024 - 1, "1024.00 KiB"},
+		{1024 * 1024, "1.00 MiB"},
+		{5 * 1024 * 1024 * 1024, "5.00 GiB"},
+		{uint64(1) << 40, "1.00 TiB"},
+		{uint64(1) << 50, "1.00 PiB"},
+		{uint64(18446644073709551615), "16.00 EiB"},
+		{"2048", "2.00 KiB"},
+	}
+	for _, t := range tbl {
+		v, err := builtinFormatBytes(types.MakeDatums(t.arg), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v, testutil.DatumEquals, types.NewDatum(t.ret), Commentf("%v", t.arg))
+	}
+}
+
+func (s *testEvaluatorSuite) TestFormatPicoTime(c *C) {
+	defer testleak.AfterTest(c)()
+	tbl := []struct {
+		arg interface{}
+		ret interface{}
+	}{
+		{nil, nil},
+		{0, "0 ps"},
+		{999, "999 ps"},
+		{1000, "1.00 ns"},
+		{3501, "3.50 ns"},
+		{-3501, "-3.50 ns"},
+		{1000000, "1.00 us"},
+		{1230000000, "1.23 ms"},
+		{int64(1e12), "1.00 s"},
+		{int64(90e12), "1.50 min"},
+		{int64(188732396662000), "3.15 min"},
+		{int64(7200e12), "2.00 h"},
+		{int64(86400e12) * 3, "3.00 d"},
+	}
+	for _, t := range tbl {
+		v, err := builtinFormatPicoTime(types.MakeDatums(t.arg), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v, testutil.DatumEquals, types.NewDatum(t.ret), Commentf("%v", t.arg))
+	}
+}
+
+func (s *testEvaluatorSuite) TestUUIDFunctions(c *C) {
+	defer testleak.AfterTest(c)()
+	tbl := []struct {
+		arg    interface{}
+		isUUID interface{}
+	}{
+		{"6ccd780c-baba-1026-9564-5b8c656024db", 1},
+		{"6CCD780C-BABA-1026-9564-5B8C656024DB", 1},
+		{"6ccd780cbaba102695645b8c656024db", 1},
+		{"{6ccd780c-baba-1026-9564-5b8c656024db}", 1},
+		{"{6ccd780cbaba102695645b8c656024db}", 0},
+		{"6ccd780c-baba-1026-9564-5b8c656024d", 0},
+		{"6ccd780c-baba-1026-9564-5b8c656024dx", 0},
+		{"6ccd780c+baba-1026-9564-5b8c656024db", 0},
+		{"", 0},
+		{nil, nil},
+	}
+	for _, t := range tbl {
+		v, err := builtinIsUUID(types.MakeDatums(t.arg), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v, testutil.DatumEquals, types.NewDatum(t.isUUID), Commentf("%v", t.arg))
+	}
+
+	uuid := "6ccd780c-baba-1026-9564-5b8c656024db"
+	swapTbl := []struct {
+		swap interface{}
+		bin  string
+	}{
+		{0, "6CCD780CBABA102695645B8C656024DB"},
+		{1, "1026BABA6CCD780C95645B8C656024DB"},
+	}
+	for _, t := range swapTbl {
+		bin, err := builtinUUIDToBin(types.MakeDatums(strings.ToUpper(uuid), t.swap), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(fmt.Sprintf("%X", bin.GetBytes()), Equals, t.bin)
+		v, err := builtinBinToUUID([]types.Datum{bin, types.NewDatum(t.swap)}, s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v.GetString(), Equals, uuid)
+	}
+	// Without the flag nothing is swapped.
+	bin, err := builtinUUIDToBin(types.MakeDatums(uuid), s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(fmt.Sprintf("%X", bin.GetBytes()), Equals, swapTbl[0].bin)
+	v, err := builtinBinToUUID([]types.Datum{bin}, s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(v.GetString(), Equals, uuid)
+
+	// NULL arguments give NULL, invalid ones NULL with a warning.
+	sc := s.ctx.GetSessionVars().StmtCtx
+	for _, args := range [][]types.Datum{types.MakeDatums(nil), types.MakeDatums(uuid, nil)} {
+		v, err = builtinUUIDToBin(args, s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v.IsNull(), IsTrue)
+		v, err = builtinBinToUUID(args, s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v.IsNull(), IsTrue)
+	}
+	warnCnt := len(sc.GetWarnings())
+	v, err = builtinUUIDToBin(types.MakeDatums("not a uuid"), s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(v.IsNull(), IsTrue)
+	v, err = builtinBinToUUID(types.MakeDatums("too short"), s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(v.IsNull(), IsTrue)
+	warnings := sc.GetWarnings()
+	c.Assert(warnings, HasLen, warnCnt+2)
+	c.Assert(ErrWrongValueForType.Equal(warnings[warnCnt]), IsTrue)
+}
+
+type mapGlobalVars map[string]string
+
+func (m mapGlobalVars) GetGlobalSysVar(name string) (string, error) {
+	return m[name], nil
+}
+
+func (m mapGlobalVars) SetGlobalSysVar(name string, value string) error {
+	m[name] = value
+	return nil
+}
+
+func (s *testEvaluatorSuite) TestGetVar(c *C) {
+	defer testleak.AfterTest(c)()
+	sessionVars := s.ctx.GetSessionVars()
+	globalVars := mapGlobalVars{"low_priority_updates": "ON"}
+	sessionVars.GlobalVarsAccessor = globalVars
+	defer func() {
+		sessionVars.GlobalVarsAccessor = nil
+		delete(sessionVars.Systems, "low_priority_updates")
+		delete(sessionVars.Users, "a")
+	}()
+
+	// A user variable is NULL until it is set.
+	v, err := builtinGetVar(types.MakeDatums("a"), s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(v.IsNull(), IsTrue)
+	_, err = builtinSetVar(types.MakeDatums("a", "x"), s.ctx)
+	c.Assert(err, IsNil)
+	v, err = builtinGetVar(types.MakeDatums("a"), s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(v.GetString(), Equals, "x")
+
+	// Values read back with their type, and assigning NULL unsets the variable.
+	dec := types.NewDecFromStringForTest("1.50")
+	for _, value := range []types.Datum{
+		types.NewIntDatum(-3), types.NewUintDatum(7), types.NewFloat64Datum(1.5), types.NewStringDatum("AbC"),
+		types.NewBytesDatum([]byte("xY")), types.NewDecimalDatum(dec), types.NewDatum(nil),
+	} {
+		ret, err := builtinSetVar([]types.Datum{types.NewStringDatum("a"), value}, s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(ret, testutil.DatumEquals, value)
+		v, err = builtinGetVar(types.MakeDatums("a"), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v.Kind(), Equals, value.Kind())
+		c.Assert(v, testutil.DatumEquals, value)
+	}
+	// The variable doesn't share the memory of the assigned value.
+	b := []byte("abc")
+	_, err = builtinSetVar([]types.Datum{types.NewStringDatum("a"), types.NewBytesDatum(b)}, s.ctx)
+	c.Assert(err, IsNil)
+	b[0] = 'x'
+	v, err = builtinGetVar(types.MakeDatums("a"), s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(v.GetString(), Equals, "abc")
+
+	// A session variable not set in the session takes the global value.
+	v, err = builtinGetSysVar(types.MakeDatums("low_priority_updates", false), s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(v.GetString(), Equals, "ON")
+	globalVars["low_priority_updates"] = "OFF"
+	v, err = builtinGetSysVar(types.MakeDatums("low_priority_updates", false), s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(v.GetString(), Equals, "ON")
+	v, err = builtinGetSysVar(types.MakeDatums("low_priority_updates", true), s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(v.GetString(), Equals, "OFF")
+
+	// Read only variables have their fixed value, session only ones their default.
+	v, err = builtinGetSysVar(types.MakeDatums("performance_schema_max_mutex_classes", false), s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(v.GetString(), Equals, "200")
+	v, err = builtinGetSysVar(types.MakeDatums("pseudo_slave_mode", false), s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(v.GetString(), Equals, "")
+
+	_, err = builtinGetSysVar(types.MakeDatums("no_such_variable", false), s.ctx)
+	c.Assert(terror.ErrorEqual(err, variable.UnknownSystemVar), IsTrue)
+}
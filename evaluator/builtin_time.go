@@ -18,6 +18,7 @@
 package evaluator
 
 import (
+	"bytes"
 	"fmt"
 	"math"
 	"regexp"
@@ -53,6 +54,33 @@ func convertToTime(sc *variable.StatementContext, arg types.Datum, tp byte) (d t
 	return d, nil
 }
 
+// checkDate checks d, if it is a date or a datetime, against the NO_ZERO_DATE and NO_ZERO_IN_DATE
+// SQL modes, which reject the zero date and dates with a zero month or day. A rejected date is an
+// ErrIncorrectDatetimeValue, returned in strict SQL mode and otherwise appended to the statement
+// warnings with a NULL result.
+func checkDate(ctx context.Context, d types.Datum) (types.Datum, error) {
+	if d.Kind() != types.KindMysqlTime {
+		return d, nil
+	}
+	t := d.GetMysqlTime()
+	sessVars := ctx.GetSessionVars()
+	var rejected bool
+	if t.IsZero() {
+		rejected = sessVars.NoZeroDate
+	} else {
+		rejected = sessVars.NoZeroInDate && (t.Time.Month() == 0 || t.Time.Day() == 0)
+	}
+	if !rejected {
+		return d, nil
+	}
+	err := ErrIncorrectDatetimeValue.GenByArgs(t.String())
+	if sessVars.StrictSQLMode {
+		return d, errors.Trace(err)
+	}
+	sessVars.StmtCtx.AppendWarning(err)
+	return types.Datum{}, nil
+}
+
 func convertToDuration(sc *variable.StatementContext, arg types.Datum, fsp int) (d types.Datum, err error) {
 	f := types.NewFieldType(mysql.TypeDuration)
 	f.Decimal = fsp
@@ -74,8 +102,89 @@ func convertToDuration(sc *variable.StatementContext, arg types.Datum, fsp int)
 	return d, nil
 }
 
+// invalidTimeArg makes a time function return NULL for an argument that isn't a
+// valid time value, recording err as a warning.
+func invalidTimeArg(sc *variable.StatementContext, err error) (types.Datum, error) {
+	sc.AppendWarning(err)
+	return types.Datum{}, nil
+}
+
+// See https://dev.mysql.com/doc/refman/5.7/en/date-and-time-functions.html#function_date
 func builtinDate(args []types.Datum, ctx context.Context) (types.Datum, error) {
-	return convertToTime(ctx.GetSessionVars().StmtCtx, args[0], mysql.TypeDate)
+	sc := ctx.GetSessionVars().StmtCtx
+	d, err := convertToTime(sc, args[0], mysql.TypeDate)
+	if err != nil {
+		return invalidTimeArg(sc, err)
+	}
+	return d, nil
+}
+
+// timeArgFsp returns the fractional seconds precision of a time function argument.
+// The precision of a string or a number is the number of digits after its decimal point.
+func timeArgFsp(arg types.Datum) int {
+	switch arg.Kind() {
+	case types.KindMysqlTime:
+		return arg.GetMysqlTime().Fsp
+	case types.KindMysqlDuration:
+		return arg.GetMysqlDuration().Fsp
+	case types.KindString, types.KindBytes, types.KindFloat32, types.KindFloat64, types.KindMysqlDecimal:
+		str, _ := arg.ToString()
+		if idx := strings.LastIndex(str, "."); idx != -1 {
+			fsp := len(strings.TrimSpace(str[idx+1:]))
+			if fsp > types.MaxFsp {
+				fsp = types.MaxFsp
+			}
+			return fsp
+		}
+	}
+	return types.MinFsp
+}
+
+// builtinTimestamp converts its argument to a DATETIME. With a second argument,
+// that time is added to the result.
+// See https://dev.mysql.com/doc/refman/5.7/en/date-and-time-functions.html#function_timestamp
+func builtinTimestamp(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
+	for _, arg := range args {
+		if arg.IsNull() {
+			return
+		}
+	}
+	sc := ctx.GetSessionVars().StmtCtx
+	d, err = convertToTime(sc, args[0], mysql.TypeDatetime)
+	if err != nil {
+		return invalidTimeArg(sc, err)
+	}
+	t := d.GetMysqlTime()
+	t.Fsp = timeArgFsp(args[0])
+	if len(args) == 2 {
+		dur, err := convertToDuration(sc, args[1], types.MaxFsp)
+		if err != nil {
+			return invalidTimeArg(sc, err)
+		}
+		t, err = addTimeDuration(t, dur.GetMysqlDuration().Duration)
+		if err != nil {
+			return invalidTimeArg(sc, err)
+		}
+		if fsp := timeArgFsp(args[1]); fsp > t.Fsp {
+			t.Fsp = fsp
+		}
+	}
+	d.SetMysqlTime(t)
+	return d, nil
+}
+
+// addTimeDuration adds dur to the datetime t.
+func addTimeDuration(t types.Time, dur time.Duration) (types.Time, error) {
+	gt, err := t.Time.GoTime()
+	if err != nil {
+		return t, errors.Trace(err)
+	}
+	gt = gt.Add(dur)
+	if gt.Year() < 0 || gt.Year() > 9999 {
+		return t, errors.Trace(types.ErrInvalidTimeFormat)
+	}
+	t.Time = types.FromGoTime(gt)
+	return t, nil
 }
 
 func convertDatumToTime(sc *variable.StatementContext, d types.Datum) (t types.Time, err error) {
@@ -104,6 +213,262 @@ func builtinTimeDiff(args []types.Datum, ctx context.Context) (d types.Datum, er
 	return d, nil
 }
 
+// See https://dev.mysql.com/doc/refman/5.7/en/date-and-time-functions.html#function_addtime
+func builtinAddTime(args []types.Datum, ctx context.Context) (types.Datum, error) {
+	return addSubTime(args, ctx, 1)
+}
+
+// See https://dev.mysql.com/doc/refman/5.7/en/date-and-time-functions.html#function_subtime
+func builtinSubTime(args []types.Datum, ctx context.Context) (types.Datum, error) {
+	return addSubTime(args, ctx, -1)
+}
+
+// addSubTime adds sign times the TIME value args[1] to args[0]. The result has the type of
+// args[0]: a DATETIME for a datetime, a TIME (which may exceed 24 hours) for a duration and a
+// string for anything else.
+func addSubTime(args []types.Datum, ctx context.Context, sign time.Duration) (d types.Datum, err error) {
+	if args[0].IsNull() || args[1].IsNull() {
+		return
+	}
+	sc := ctx.GetSessionVars().StmtCtx
+	dur, err := convertToDuration(sc, args[1], types.MaxFsp)
+	if err != nil {
+		return invalidTimeArg(sc, err)
+	}
+	delta := sign * dur.GetMysqlDuration().Duration
+	fsp := timeArgFsp(args[0])
+	if f := timeArgFsp(args[1]); f > fsp {
+		fsp = f
+	}
+
+	switch args[0].Kind() {
+	case types.KindMysqlTime:
+		t, err := addTimeDuration(args[0].GetMysqlTime(), delta)
+		if err != nil {
+			return invalidTimeArg(sc, err)
+		}
+		if t.Type == mysql.TypeDate {
+			t.Type = mysql.TypeDatetime
+		}
+		t.Fsp = fsp
+		d.SetMysqlTime(t)
+		return d, nil
+	case types.KindMysqlDuration:
+		dur := addDuration(sc, args[0].GetMysqlDuration(), delta)
+		dur.Fsp = fsp
+		d.SetMysqlDuration(dur)
+		return d, nil
+	}
+
+	str, err := args[0].ToString()
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	// A date part marks a datetime, a leading '-' a negative time.
+	if len(str) > 0 && strings.Contains(str[1:], "-") {
+		t, err := types.ParseTime(str, mysql.TypeDatetime, types.MaxFsp)
+		if err != nil {
+			return invalidTimeArg(sc, err)
+		}
+		t, err = addTimeDuration(t, delta)
+		if err != nil {
+			return invalidTimeArg(sc, err)
+		}
+		t.Fsp = fsp
+		d.SetString(t.String())
+		return d, nil
+	}
+	base, err := types.ParseDuration(str, types.MaxFsp)
+	if err != nil {
+		return invalidTimeArg(sc, err)
+	}
+	base = addDuration(sc, base, delta)
+	base.Fsp = fsp
+	d.SetString(base.String())
+	return d, nil
+}
+
+// addDuration adds delta to dur, clipping the result to the TIME range with a warning.
+func addDuration(sc *variable.StatementContext, dur types.Duration, delta time.Duration) types.Duration {
+	dur.Duration += delta
+	if dur.Duration > types.MaxTime {
+		sc.AppendWarning(types.ErrOverflow.Gen("time value is out of range"))
+		dur.Duration = types.MaxTime
+	} else if dur.Duration < -types.MaxTime {
+		sc.AppendWarning(types.ErrOverflow.Gen("time value is out of range"))
+		dur.Duration = -types.MaxTime
+	}
+	return dur
+}
+
+// periodToMonth converts a YYMM or YYYYMM period to a number of months. Two-digit years
+// 00-69 are 2000-2069 and 70-99 are 1970-1999.
+func periodToMonth(period int64) int64 {
+	if period == 0 {
+		return 0
+	}
+	year, month := period/100, period%100
+	if year < 70 {
+		year += 2000
+	} else if year < 100 {
+		year += 1900
+	}
+	return year*12 + month - 1
+}
+
+// monthToPeriod converts a number of months back to a YYYYMM period.
+func monthToPeriod(month int64) int64 {
+	if month == 0 {
+		return 0
+	}
+	year := month / 12
+	if year < 70 {
+		year += 2000
+	} else if year < 100 {
+		year += 1900
+	}
+	return year*100 + month%12 + 1
+}
+
+// periodArgs reads the integer arguments of PERIOD_ADD and PERIOD_DIFF. ok is false if
+// an argument is NULL or a period is negative.
+func periodArgs(args []types.Datum, sc *variable.StatementContext, periods int) (vals []int64, ok bool, err error) {
+	vals = make([]int64, len(args))
+	for i, arg := range args {
+		if arg.IsNull() {
+			return nil, false, nil
+		}
+		vals[i], err = arg.ToInt64(sc)
+		if err != nil {
+			return nil, false, errors.Trace(err)
+		}
+		if i < periods && vals[i] < 0 {
+			return nil, false, nil
+		}
+	}
+	return vals, true, nil
+}
+
+// See https://dev.mysql.com/doc/refman/5.7/en/date-and-time-functions.html#function_period-add
+func builtinPeriodAdd(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
+	vals, ok, err := periodArgs(args, ctx.GetSessionVars().StmtCtx, 1)
+	if !ok || err != nil {
+		return d, errors.Trace(err)
+	}
+	if vals[0] == 0 {
+		d.SetInt64(0)
+		return d, nil
+	}
+	month := periodToMonth(vals[0]) + vals[1]
+	if month < 0 {
+		month = 0
+	}
+	d.SetInt64(monthToPeriod(month))
+	return d, nil
+}
+
+// See https://dev.mysql.com/doc/refman/5.7/en/date-and-time-functions.html#function_period-diff
+func builtinPeriodDiff(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
+	vals, ok, err := periodArgs(args, ctx.GetSessionVars().StmtCtx, 2)
+	if !ok || err != nil {
+		return d, errors.Trace(err)
+	}
+	d.SetInt64(periodToMonth(vals[0]) - periodToMonth(vals[1]))
+	return d, nil
+}
+
+// See https://dev.mysql.com/doc/refman/5.7/en/date-and-time-functions.html#function_convert-tz
+func builtinConvertTZ(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
+	for _, arg := range args {
+		if arg.IsNull() {
+			return
+		}
+	}
+	sc := ctx.GetSessionVars().StmtCtx
+	d, err = convertToTime(sc, args[0], mysql.TypeDatetime)
+	if err != nil {
+		return invalidTimeArg(sc, err)
+	}
+	from, err := parseTimeZone(args[1])
+	if err != nil {
+		return types.Datum{}, nil
+	}
+	to, err := parseTimeZone(args[2])
+	if err != nil {
+		return types.Datum{}, nil
+	}
+
+	t := d.GetMysqlTime()
+	gt := time.Date(t.Time.Year(), time.Month(t.Time.Month()), t.Time.Day(), t.Time.Hour(),
+		t.Time.Minute(), t.Time.Second(), t.Time.Microsecond()*1000, from).In(to)
+	if gt.Year() < 1 || gt.Year() > 9999 {
+		return types.Datum{}, nil
+	}
+	t.Time = types.FromGoTime(gt)
+	t.Fsp = timeArgFsp(args[0])
+	d.SetMysqlTime(t)
+	return d, nil
+}
+
+// parseTimeZone parses a time zone argument, see variable.ParseTimeZone.
+func parseTimeZone(arg types.Datum) (*time.Location, error) {
+	name, err := arg.ToString()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	loc, err := variable.ParseTimeZone(name)
+	return loc, errors.Trace(err)
+}
+
+// getFormats maps the selectors and standards of GET_FORMAT to their format strings.
+var getFormats = map[string]map[string]string{
+	"DATE": {
+		"USA":      "%m.%d.%Y",
+		"JIS":      "%Y-%m-%d",
+		"ISO":      "%Y-%m-%d",
+		"EUR":      "%d.%m.%Y",
+		"INTERNAL": "%Y%m%d",
+	},
+	"DATETIME": {
+		"USA":      "%Y-%m-%d %H.%i.%s",
+		"JIS":      "%Y-%m-%d %H:%i:%s",
+		"ISO":      "%Y-%m-%d %H:%i:%s",
+		"EUR":      "%Y-%m-%d %H.%i.%s",
+		"INTERNAL": "%Y%m%d%H%i%s",
+	},
+	"TIME": {
+		"USA":      "%h:%i:%s %p",
+		"JIS":      "%H:%i:%s",
+		"ISO":      "%H:%i:%s",
+		"EUR":      "%H.%i.%s",
+		"INTERNAL": "%H%i%s",
+	},
+}
+
+// See https://dev.mysql.com/doc/refman/5.7/en/date-and-time-functions.html#function_get-format
+func builtinGetFormat(args []types.Datum, _ context.Context) (d types.Datum, err error) {
+	if args[0].IsNull() || args[1].IsNull() {
+		return
+	}
+	selector, err := args[0].ToString()
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	standard, err := args[1].ToString()
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	selector = strings.ToUpper(selector)
+	// TIMESTAMP shares the formats of DATETIME.
+	if selector == "TIMESTAMP" {
+		selector = "DATETIME"
+	}
+	if format, ok := getFormats[selector][strings.ToUpper(standard)]; ok {
+		d.SetString(format)
+	}
+	return d, nil
+}
+
 // See http://dev.mysql.com/doc/refman/5.7/en/date-and-time-functions.html#function_date-format
 func builtinDateFormat(args []types.Datum, ctx context.Context) (types.Datum, error) {
 	var d types.Datum
@@ -121,6 +486,84 @@ func builtinDateFormat(args []types.Datum, ctx context.Context) (types.Datum, er
 	return d, nil
 }
 
+// See https://dev.mysql.com/doc/refman/5.7/en/date-and-time-functions.html#function_time-format
+func builtinTimeFormat(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
+	if args[0].IsNull() || args[1].IsNull() {
+		return d, nil
+	}
+	sc := ctx.GetSessionVars().StmtCtx
+	dur, err := convertToDuration(sc, args[0], types.MaxFsp)
+	if err != nil {
+		return invalidTimeArg(sc, err)
+	}
+	layout, err := args[1].ToString()
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	d.SetString(timeFormat(dur.GetMysqlDuration(), layout))
+	return d, nil
+}
+
+// timeFormat formats dur by the time specifiers of DATE_FORMAT() in layout, the date specifiers
+// are ignored. The hours of %H and %k may exceed 23, and a negative time starts with '-'.
+func timeFormat(dur types.Duration, layout string) string {
+	var buf bytes.Buffer
+	if dur.Duration < 0 {
+		buf.WriteByte('-')
+		dur.Duration = -dur.Duration
+	}
+	hour, minute, second, micro := dur.Hour(), dur.Minute(), dur.Second(), dur.MicroSecond()
+	hour12 := hour % 12
+	if hour12 == 0 {
+		hour12 = 12
+	}
+	ampm := "AM"
+	if hour%24 >= 12 {
+		ampm = "PM"
+	}
+	inPatternMatch := false
+	for _, b := range layout {
+		if !inPatternMatch {
+			if b == '%' {
+				inPatternMatch = true
+			} else {
+				buf.WriteRune(b)
+			}
+			continue
+		}
+		inPatternMatch = false
+		switch b {
+		case 'H':
+			fmt.Fprintf(&buf, "%02d", hour)
+		case 'k':
+			fmt.Fprintf(&buf, "%d", hour)
+		case 'h', 'I':
+			fmt.Fprintf(&buf, "%02d", hour12)
+		case 'l':
+			fmt.Fprintf(&buf, "%d", hour12)
+		case 'i':
+			fmt.Fprintf(&buf, "%02d", minute)
+		case 's', 'S':
+			fmt.Fprintf(&buf, "%02d", second)
+		case 'f':
+			fmt.Fprintf(&buf, "%06d", micro)
+		case 'p':
+			buf.WriteString(ampm)
+		case 'r':
+			fmt.Fprintf(&buf, "%02d:%02d:%02d %s", hour12, minute, second, ampm)
+		case 'T':
+			fmt.Fprintf(&buf, "%02d:%02d:%02d", hour, minute, second)
+		case '%':
+			buf.WriteByte('%')
+		case 'a', 'b', 'c', 'D', 'd', 'e', 'j', 'M', 'm', 'U', 'u', 'V', 'v', 'W', 'w', 'X', 'x', 'Y', 'y':
+			// Date specifiers are ignored.
+		default:
+			buf.WriteRune(b)
+		}
+	}
+	return buf.String()
+}
+
 // See http://dev.mysql.com/doc/refman/5.7/en/date-and-time-functions.html#function_day
 // Day is a synonym for DayOfMonth.
 func builtinDay(args []types.Datum, ctx context.Context) (types.Datum, error) {
@@ -198,6 +641,46 @@ func builtinMonth(args []types.Datum, ctx context.Context) (types.Datum, error)
 	return d, nil
 }
 
+// See https://dev.mysql.com/doc/refman/5.7/en/date-and-time-functions.html#function_quarter
+func builtinQuarter(args []types.Datum, ctx context.Context) (types.Datum, error) {
+	d, err := convertToTime(ctx.GetSessionVars().StmtCtx, args[0], mysql.TypeDate)
+	if err != nil || d.IsNull() {
+		return d, errors.Trace(err)
+	}
+
+	t := d.GetMysqlTime()
+	if t.IsZero() {
+		d.SetInt64(0)
+		return d, nil
+	}
+	d.SetInt64(int64((t.Time.Month() + 2) / 3))
+	return d, nil
+}
+
+// timeLocale holds the names used by MONTHNAME and DAYNAME for a locale.
+type timeLocale struct {
+	monthNames   []string
+	weekdayNames []string
+}
+
+// defaultTimeLocale is the name of the locale used when lc_time_names names an unsupported one.
+const defaultTimeLocale = "en_US"
+
+// timeLocales maps the supported values of lc_time_names to their names.
+var timeLocales = map[string]*timeLocale{
+	defaultTimeLocale: {monthNames: types.MonthNames, weekdayNames: types.WeekdayNames},
+}
+
+// sessionTimeLocale returns the locale named by the lc_time_names session variable.
+func sessionTimeLocale(ctx context.Context) *timeLocale {
+	if name, ok := ctx.GetSessionVars().Systems["lc_time_names"]; ok {
+		if l, ok := timeLocales[name]; ok {
+			return l
+		}
+	}
+	return timeLocales[defaultTimeLocale]
+}
+
 // See http://dev.mysql.com/doc/refman/5.7/en/date-and-time-functions.html#function_monthname
 func builtinMonthName(args []types.Datum, ctx context.Context) (types.Datum, error) {
 	d, err := builtinMonth(args, ctx)
@@ -205,15 +688,16 @@ func builtinMonthName(args []types.Datum, ctx context.Context) (types.Datum, err
 		return d, errors.Trace(err)
 	}
 
+	names := sessionTimeLocale(ctx).monthNames
 	mon := int(d.GetInt64())
-	if mon <= 0 || mon > len(types.MonthNames) {
+	if mon <= 0 || mon > len(names) {
 		d.SetNull()
 		if mon == 0 {
 			return d, nil
 		}
 		return d, errors.Errorf("no name for invalid month: %d.", mon)
 	}
-	d.SetString(types.MonthNames[mon-1])
+	d.SetString(names[mon-1])
 
 	return d, nil
 }
@@ -221,16 +705,13 @@ func builtinMonthName(args []types.Datum, ctx context.Context) (types.Datum, err
 func builtinNow(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
 	// TODO: if NOW is used in stored function or trigger, NOW will return the beginning time
 	// of the execution.
-	fsp := 0
 	sc := ctx.GetSessionVars().StmtCtx
-	if len(args) == 1 && !args[0].IsNull() {
-		if fsp, err = checkFsp(sc, args[0]); err != nil {
-			d.SetNull()
-			return d, errors.Trace(err)
-		}
+	fsp, err := fspArg(sc, args)
+	if err != nil {
+		return d, errors.Trace(err)
 	}
 
-	tr, err := types.RoundFrac(time.Now(), int(fsp))
+	tr, err := types.RoundFrac(currentTime(ctx), fsp)
 	if err != nil {
 		d.SetNull()
 		return d, errors.Trace(err)
@@ -247,18 +728,41 @@ func builtinNow(args []types.Datum, ctx context.Context) (d types.Datum, err err
 	return d, nil
 }
 
+// weekday converts arg to a date and returns its day of the week as an int, Monday being 0 and Sunday 6.
+// DAYOFWEEK, WEEKDAY and DAYNAME are all computed from it. It returns NULL for the zero date and dates
+// with a zero month or day.
+func weekday(arg types.Datum, ctx context.Context) (types.Datum, error) {
+	d, err := convertToTime(ctx.GetSessionVars().StmtCtx, arg, mysql.TypeDate)
+	if err != nil || d.IsNull() {
+		return d, errors.Trace(err)
+	}
+
+	// No need to check type here.
+	t := d.GetMysqlTime()
+	if t.IsZero() || t.Time.Month() == 0 || t.Time.Day() == 0 {
+		// TODO: log warning or return error?
+		d.SetNull()
+		return d, nil
+	}
+
+	// Go starts the week at Sunday = 0, MySQL's WEEKDAY at Monday = 0.
+	d.SetInt64((int64(t.Time.Weekday()) + 6) % 7)
+	return d, nil
+}
+
 // See http://dev.mysql.com/doc/refman/5.7/en/date-and-time-functions.html#function_dayname
 func builtinDayName(args []types.Datum, ctx context.Context) (types.Datum, error) {
-	d, err := builtinWeekDay(args, ctx)
+	d, err := weekday(args[0], ctx)
 	if err != nil || d.IsNull() {
 		return d, errors.Trace(err)
 	}
+	names := sessionTimeLocale(ctx).weekdayNames
 	weekday := d.GetInt64()
-	if (weekday < 0) || (weekday >= int64(len(types.WeekdayNames))) {
+	if (weekday < 0) || (weekday >= int64(len(names))) {
 		d.SetNull()
 		return d, errors.Errorf("no name for invalid weekday: %d.", weekday)
 	}
-	d.SetString(types.WeekdayNames[weekday])
+	d.SetString(names[weekday])
 	return d, nil
 }
 
@@ -283,21 +787,13 @@ func builtinDayOfMonth(args []types.Datum, ctx context.Context) (d types.Datum,
 
 // See http://dev.mysql.com/doc/refman/5.7/en/date-and-time-functions.html#function_dayofweek
 func builtinDayOfWeek(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
-	d, err = convertToTime(ctx.GetSessionVars().StmtCtx, args[0], mysql.TypeDate)
+	d, err = weekday(args[0], ctx)
 	if err != nil || d.IsNull() {
 		return d, errors.Trace(err)
 	}
 
-	// No need to check type here.
-	t := d.GetMysqlTime()
-	if t.IsZero() {
-		d.SetNull()
-		// TODO: log warning or return error?
-		return d, nil
-	}
-
 	// 1 is Sunday, 2 is Monday, .... 7 is Saturday
-	d.SetInt64(int64(t.Time.Weekday() + 1))
+	d.SetInt64((d.GetInt64()+1)%7 + 1)
 	return d, nil
 }
 
@@ -320,6 +816,62 @@ func builtinDayOfYear(args []types.Datum, ctx context.Context) (types.Datum, err
 	return d, nil
 }
 
+// See https://dev.mysql.com/doc/refman/5.7/en/date-and-time-functions.html#function_last-day
+func builtinLastDay(args []types.Datum, ctx context.Context) (types.Datum, error) {
+	sc := ctx.GetSessionVars().StmtCtx
+	d, err := convertToTime(sc, args[0], mysql.TypeDate)
+	if err != nil {
+		return invalidTimeArg(sc, err)
+	}
+	if d.IsNull() {
+		return d, nil
+	}
+
+	// A zero day is fine, the month is all that counts.
+	t := d.GetMysqlTime()
+	year, month := t.Time.Year(), t.Time.Month()
+	if month == 0 {
+		d.SetNull()
+		return d, nil
+	}
+	t.Time = types.FromDate(year, month, types.GetLastDay(year, month), 0, 0, 0, 0)
+	t.Type, t.Fsp = mysql.TypeDate, 0
+	d.SetMysqlTime(t)
+	return d, nil
+}
+
+// See https://dev.mysql.com/doc/refman/5.7/en/date-and-time-functions.html#function_to-days
+func builtinToDays(args []types.Datum, ctx context.Context) (types.Datum, error) {
+	d, err := convertToTime(ctx.GetSessionVars().StmtCtx, args[0], mysql.TypeDate)
+	if err != nil || d.IsNull() {
+		return d, errors.Trace(err)
+	}
+
+	t := d.GetMysqlTime()
+	if t.Time.Month() == 0 || t.Time.Day() == 0 {
+		d.SetNull()
+		return d, nil
+	}
+	d.SetInt64(int64(types.DateToDays(t.Time)))
+	return d, nil
+}
+
+// See https://dev.mysql.com/doc/refman/5.7/en/date-and-time-functions.html#function_from-days
+func builtinFromDays(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
+	if args[0].IsNull() {
+		return d, nil
+	}
+	daynr, err := args[0].ToInt64(ctx.GetSessionVars().StmtCtx)
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	if daynr < 0 || daynr > math.MaxInt32 {
+		daynr = 0
+	}
+	d.SetMysqlTime(types.Time{Time: types.DateFromDays(int(daynr)), Type: mysql.TypeDate})
+	return d, nil
+}
+
 // See http://dev.mysql.com/doc/refman/5.7/en/date-and-time-functions.html#function_week
 func builtinWeek(args []types.Datum, ctx context.Context) (types.Datum, error) {
 	d, err := convertToTime(ctx.GetSessionVars().StmtCtx, args[0], mysql.TypeDate)
@@ -352,25 +904,7 @@ func builtinWeek(args []types.Datum, ctx context.Context) (types.Datum, error) {
 
 // See http://dev.mysql.com/doc/refman/5.7/en/date-and-time-functions.html#function_weekday
 func builtinWeekDay(args []types.Datum, ctx context.Context) (types.Datum, error) {
-	d, err := convertToTime(ctx.GetSessionVars().StmtCtx, args[0], mysql.TypeDate)
-	if err != nil || d.IsNull() {
-		return d, errors.Trace(err)
-	}
-
-	// No need to check type here.
-	t := d.GetMysqlTime()
-	if t.IsZero() {
-		// TODO: log warning or return error?
-		d.SetNull()
-		return d, nil
-	}
-
-	// Monday is 0, ... Sunday = 6 in MySQL
-	// but in go, Sunday is 0, ... Saturday is 6
-	// w will do a conversion.
-	w := (int64(t.Time.Weekday()) + 6) % 7
-	d.SetInt64(w)
-	return d, nil
+	return weekday(args[0], ctx)
 }
 
 // See http://dev.mysql.com/doc/refman/5.7/en/date-and-time-functions.html#function_weekofyear
@@ -481,7 +1015,7 @@ func builtinFromUnixTime(args []types.Datum, ctx context.Context) (d types.Datum
 	if fracDigitsNumber > types.MaxFsp {
 		fsp = types.MaxFsp
 	}
-	tr, err := types.RoundFrac(time.Unix(integralPart, fractionalPart), fsp)
+	tr, err := types.RoundFrac(time.Unix(integralPart, fractionalPart).In(ctx.GetSessionVars().TimeZone()), fsp)
 	if err != nil {
 		return d, errors.Trace(err)
 	}
@@ -501,8 +1035,47 @@ func builtinFromUnixTime(args []types.Datum, ctx context.Context) (d types.Datum
 	return builtinDateFormat([]types.Datum{d, args[1]}, ctx)
 }
 
+// See https://dev.mysql.com/doc/refman/5.7/en/date-and-time-functions.html#function_unix-timestamp
+func builtinUnixTimestamp(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
+	if len(args) == 0 {
+		d.SetInt64(currentTime(ctx).Unix())
+		return d, nil
+	}
+	if args[0].IsNull() {
+		return d, nil
+	}
+	sc := ctx.GetSessionVars().StmtCtx
+	d, err = convertToTime(sc, args[0], mysql.TypeDatetime)
+	if err != nil {
+		return invalidTimeArg(sc, err)
+	}
+	// The date is taken in the session time zone.
+	t := d.GetMysqlTime().Time
+	gt := time.Date(t.Year(), time.Month(t.Month()), t.Day(), t.Hour(), t.Minute(), t.Second(),
+		t.Microsecond()*1000, ctx.GetSessionVars().TimeZone())
+	fsp := timeArgFsp(args[0])
+	if gt.Unix() < 0 || gt.Unix() > math.MaxInt32 {
+		// Out of the TIMESTAMP range.
+		d.SetInt64(0)
+		return d, nil
+	}
+	if fsp == 0 {
+		d.SetInt64(gt.Unix())
+		return d, nil
+	}
+	dec := new(types.MyDecimal)
+	if err = dec.FromString([]byte(fmt.Sprintf("%d.%06d", gt.Unix(), t.Microsecond()))); err != nil {
+		return d, errors.Trace(err)
+	}
+	if err = dec.Round(dec, fsp); err != nil {
+		return d, errors.Trace(err)
+	}
+	d.SetMysqlDecimal(dec)
+	return d, nil
+}
+
 // See https://dev.mysql.com/doc/refman/5.5/en/date-and-time-functions.html#function_str-to-date
-func builtinStrToDate(args []types.Datum, _ context.Context) (types.Datum, error) {
+func builtinStrToDate(args []types.Datum, ctx context.Context) (types.Datum, error) {
 	date := args[0].GetString()
 	format := args[1].GetString()
 	var (
@@ -517,7 +1090,7 @@ func builtinStrToDate(args []types.Datum, _ context.Context) (types.Datum, error
 	}
 
 	d.SetMysqlTime(t)
-	return d, nil
+	return checkDate(ctx, d)
 }
 
 func builtinSysDate(args []types.Datum, ctx context.Context) (types.Datum, error) {
@@ -528,8 +1101,8 @@ func builtinSysDate(args []types.Datum, ctx context.Context) (types.Datum, error
 }
 
 // See https://dev.mysql.com/doc/refman/5.7/en/date-and-time-functions.html#function_curdate
-func builtinCurrentDate(args []types.Datum, _ context.Context) (d types.Datum, err error) {
-	year, month, day := time.Now().Date()
+func builtinCurrentDate(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
+	year, month, day := currentTime(ctx).Date()
 	t := types.Time{
 		Time: types.FromDate(year, int(month), day, 0, 0, 0, 0),
 		Type: mysql.TypeDate, Fsp: 0}
@@ -539,18 +1112,35 @@ func builtinCurrentDate(args []types.Datum, _ context.Context) (d types.Datum, e
 
 // See https://dev.mysql.com/doc/refman/5.7/en/date-and-time-functions.html#function_curtime
 func builtinCurrentTime(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
-	fsp := 0
 	sc := ctx.GetSessionVars().StmtCtx
-	if len(args) == 1 && !args[0].IsNull() {
-		if fsp, err = checkFsp(sc, args[0]); err != nil {
-			d.SetNull()
-			return d, errors.Trace(err)
-		}
+	fsp, err := fspArg(sc, args)
+	if err != nil {
+		return d, errors.Trace(err)
 	}
-	d.SetString(time.Now().Format("15:04:05.000000"))
+	d.SetString(currentTime(ctx).Format("15:04:05.000000"))
 	return convertToDuration(ctx.GetSessionVars().StmtCtx, d, fsp)
 }
 
+// See https://dev.mysql.com/doc/refman/5.7/en/date-and-time-functions.html#function_sec-to-time
+func builtinSecToTime(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
+	if args[0].IsNull() {
+		return
+	}
+	sc := ctx.GetSessionVars().StmtCtx
+	secs, err := args[0].ToFloat64(sc)
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	fsp := timeArgFsp(args[0])
+	delta := time.Duration(types.Round(secs*1e6, 0)) * time.Microsecond
+	dur, err := addDuration(sc, types.Duration{Fsp: fsp}, delta).RoundFrac(fsp)
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	d.SetMysqlDuration(dur)
+	return d, nil
+}
+
 // See http://dev.mysql.com/doc/refman/5.7/en/date-and-time-functions.html#function_time
 func builtinTime(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
 	if args[0].IsNull() {
@@ -572,12 +1162,25 @@ func builtinTime(args []types.Datum, ctx context.Context) (d types.Datum, err er
 		return d, errors.Trace(err)
 	}
 
-	return convertToDuration(sc, args[0], fsp)
+	d, err = convertToDuration(sc, args[0], fsp)
+	if err != nil {
+		return invalidTimeArg(sc, err)
+	}
+	return d, nil
+}
+
+// currentTime returns the current time of the session clock in the session time zone.
+func currentTime(ctx context.Context) time.Time {
+	vars := ctx.GetSessionVars()
+	if vars.Clock != nil {
+		return vars.Clock.Now().In(vars.TimeZone())
+	}
+	return time.Now().In(vars.TimeZone())
 }
 
 // See https://dev.mysql.com/doc/refman/5.7/en/date-and-time-functions.html#function_utc-date
-func builtinUTCDate(args []types.Datum, _ context.Context) (d types.Datum, err error) {
-	year, month, day := time.Now().UTC().Date()
+func builtinUTCDate(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
+	year, month, day := currentTime(ctx).UTC().Date()
 	t := types.Time{
 		Time: types.FromGoTime(time.Date(year, month, day, 0, 0, 0, 0, time.UTC)),
 		Type: mysql.TypeDate, Fsp: types.UnspecifiedFsp}
@@ -585,6 +1188,32 @@ func builtinUTCDate(args []types.Datum, _ context.Context) (d types.Datum, err e
 	return d, nil
 }
 
+// See https://dev.mysql.com/doc/refman/5.7/en/date-and-time-functions.html#function_utc-time
+func builtinUTCTime(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
+	sc := ctx.GetSessionVars().StmtCtx
+	fsp, err := fspArg(sc, args)
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	d.SetString(currentTime(ctx).UTC().Format("15:04:05.000000"))
+	return convertToDuration(sc, d, fsp)
+}
+
+// See https://dev.mysql.com/doc/refman/5.7/en/date-and-time-functions.html#function_utc-timestamp
+func builtinUTCTimestamp(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
+	sc := ctx.GetSessionVars().StmtCtx
+	fsp, err := fspArg(sc, args)
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	tr, err := types.RoundFrac(currentTime(ctx).UTC(), fsp)
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	d.SetMysqlTime(types.Time{Time: types.FromGoTime(tr), Type: mysql.TypeDatetime, Fsp: fsp})
+	return d, nil
+}
+
 // See https://dev.mysql.com/doc/refman/5.7/en/date-and-time-functions.html#function_extract
 func builtinExtract(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
 	unit := args[0].GetString()
@@ -621,6 +1250,17 @@ func builtinExtract(args []types.Datum, ctx context.Context) (d types.Datum, err
 	return d, nil
 }
 
+// fspArg returns the fractional seconds precision given as the optional only argument of
+// a time function like NOW(fsp), 0 if it's absent or NULL.
+func fspArg(sc *variable.StatementContext, args []types.Datum) (int, error) {
+	if len(args) == 0 || args[0].IsNull() {
+		return 0, nil
+	}
+	fsp, err := checkFsp(sc, args[0])
+	return fsp, errors.Trace(err)
+}
+
+// checkFsp checks that arg is a fractional seconds precision between 0 and 6.
 func checkFsp(sc *variable.StatementContext, arg types.Datum) (int, error) {
 	fsp, err := arg.ToInt64(sc)
 	if err != nil {
@@ -687,7 +1327,16 @@ func builtinDateArith(args []types.Datum, ctx context.Context) (d types.Datum, e
 	if value.Kind() != types.KindMysqlTime {
 		return d, ErrInvalidOperation.Gen("DateArith need time type, but got %T", value.GetValue())
 	}
+	if value, err = checkDate(ctx, value); err != nil || value.IsNull() {
+		return d, errors.Trace(err)
+	}
 	result := value.GetMysqlTime()
+	// MySQL returns NULL for the zero date or a date with a zero part, which have no calendar arithmetic.
+	start, err := result.Time.GoTime()
+	if err != nil {
+		sc.AppendWarning(ErrIncorrectDatetimeValue.GenByArgs(result.String()))
+		return d, nil
+	}
 	// parse interval
 	var interval string
 	if strings.ToLower(nodeInterval.Unit) == "day" {
@@ -715,12 +1364,12 @@ func builtinDateArith(args []types.Datum, ctx context.Context) (d types.Datum, e
 	if op == ast.DateSub {
 		year, month, day, duration = -year, -month, -day, -duration
 	}
-	t, err := result.Time.GoTime()
-	if err != nil {
-		return d, errors.Trace(err)
-	}
-	t = t.Add(duration)
+	t := start.Add(duration)
 	t = t.AddDate(int(year), int(month), int(day))
+	if !isDateArithInRange(t) {
+		sc.AppendWarning(ErrDatetimeFunctionOverflow.GenByArgs("datetime"))
+		return d, nil
+	}
 	if t.Nanosecond() == 0 {
 		result.Fsp = 0
 	}
@@ -729,6 +1378,33 @@ func builtinDateArith(args []types.Datum, ctx context.Context) (d types.Datum, e
 	return d, nil
 }
 
+// See https://dev.mysql.com/doc/refman/5.7/en/date-and-time-functions.html#function_adddate
+func builtinAddDate(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
+	return dateArithMultiForms(ast.DateAdd, args, ctx)
+}
+
+// See https://dev.mysql.com/doc/refman/5.7/en/date-and-time-functions.html#function_subdate
+func builtinSubDate(args []types.Datum, ctx context.Context) (d types.Datum, err error) {
+	return dateArithMultiForms(ast.DateSub, args, ctx)
+}
+
+// dateArithMultiForms runs ADDDATE or SUBDATE. args[1] is either an ast.DateArithInterval, making the
+// call the same as DATE_ADD or DATE_SUB, or a number of days as in ADDDATE(date, days).
+func dateArithMultiForms(op ast.DateArithType, args []types.Datum, ctx context.Context) (d types.Datum, err error) {
+	interval := args[1]
+	if _, ok := interval.GetInterface().(ast.DateArithInterval); !ok {
+		days := &ast.ValueExpr{}
+		days.SetDatum(args[1])
+		interval.SetInterface(ast.DateArithInterval{Unit: "day", Interval: days})
+	}
+	return builtinDateArith([]types.Datum{types.NewDatum(op), args[0], interval}, ctx)
+}
+
+// isDateArithInRange checks that t, the result of a date arithmetic, is between 0000-01-01 and 9999-12-31.
+func isDateArithInRange(t time.Time) bool {
+	return t.Year() >= 0 && t.Year() <= 9999
+}
+
 var reg = regexp.MustCompile(`[\d]+`)
 
 func parseDayInterval(sc *variable.StatementContext, value types.Datum) (int64, error) {
@@ -14,11 +14,17 @@
 package evaluator
 
 import (
+	"fmt"
 	"math"
 	"strings"
 	"time"
 
 	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/sessionctx/varsutil"
+	"github.com/pingcap/tidb/terror"
 	"github.com/pingcap/tidb/util/mock"
 	"github.com/pingcap/tidb/util/testleak"
 	"github.com/pingcap/tidb/util/testutil"
@@ -34,6 +40,8 @@ func (s *testEvaluatorSuite) TestDate(c *C) {
 		{"2011-11-11", "2011-11-11"},
 		{nil, nil},
 		{"2011-11-11 10:10:10", "2011-11-11"},
+		{"2011-13-11", nil},
+		{"abc", nil},
 	}
 	dtblDate := tblToDtbl(tblDate)
 	for _, t := range dtblDate {
@@ -182,6 +190,50 @@ func (s *testEvaluatorSuite) TestDate(c *C) {
 	}
 }
 
+func (s *testEvaluatorSuite) TestWeekdayConsistency(c *C) {
+	defer testleak.AfterTest(c)()
+	tbl := []struct {
+		date      string
+		dayOfWeek int64
+		weekDay   int64
+		dayName   string
+	}{
+		// 2017-01-02 is a Monday, the week wraps from Saturday to Sunday in between.
+		{"2017-01-02", 2, 0, "Monday"},
+		{"2017-01-06", 6, 4, "Friday"},
+		{"2017-01-07", 7, 5, "Saturday"},
+		{"2017-01-08", 1, 6, "Sunday"},
+		{"2017-01-09 23:59:59", 2, 0, "Monday"},
+		{"2016-12-31", 7, 5, "Saturday"},
+		{"2017-01-01", 1, 6, "Sunday"},
+	}
+	for _, t := range tbl {
+		args := types.MakeDatums(t.date)
+		dayOfWeek, err := builtinDayOfWeek(args, s.ctx)
+		c.Assert(err, IsNil)
+		weekDay, err := builtinWeekDay(args, s.ctx)
+		c.Assert(err, IsNil)
+		dayName, err := builtinDayName(args, s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(dayOfWeek.GetInt64(), Equals, t.dayOfWeek, Commentf("%s", t.date))
+		c.Assert(weekDay.GetInt64(), Equals, t.weekDay, Commentf("%s", t.date))
+		c.Assert(dayName.GetString(), Equals, t.dayName, Commentf("%s", t.date))
+		// All three agree on the day: DAYOFWEEK counts from Sunday = 1, WEEKDAY from Monday = 0.
+		c.Assert(dayOfWeek.GetInt64(), Equals, (weekDay.GetInt64()+1)%7+1)
+		c.Assert(dayName.GetString(), Equals, types.WeekdayNames[weekDay.GetInt64()])
+	}
+
+	// None of them has a day for a date with a zero part.
+	for _, date := range []string{"0000-00-00", "2017-00-10", "2017-01-00"} {
+		args := types.MakeDatums(date)
+		for _, f := range []BuiltinFunc{builtinDayOfWeek, builtinWeekDay, builtinDayName} {
+			v, err := f(args, s.ctx)
+			c.Assert(err, IsNil)
+			c.Assert(v.IsNull(), IsTrue, Commentf("%s", date))
+		}
+	}
+}
+
 func (s *testEvaluatorSuite) TestDateFormat(c *C) {
 	defer testleak.AfterTest(c)()
 
@@ -216,6 +268,41 @@ func (s *testEvaluatorSuite) TestDateFormat(c *C) {
 	}
 }
 
+func (s *testEvaluatorSuite) TestTimeFormat(c *C) {
+	defer testleak.AfterTest(c)()
+	tbl := []struct {
+		time   interface{}
+		layout interface{}
+		expect interface{}
+	}{
+		{"23:12:34.123456", "%H %k %h %I %l %i %s %S %f %p", "23 23 11 11 11 12 34 34 123456 PM"},
+		{"00:05:06", "%H %k %h %l %p", "00 0 12 12 AM"},
+		{"12:00:00", "%h %p", "12 PM"},
+		{"13:01:02.5", "%r|%T|%f", "01:01:02 PM|13:01:02|500000"},
+		{"00:01:02", "%r", "12:01:02 AM"},
+		{"100:00:01", "%H %k %h %p %T", "100 100 04 AM 100:00:01"},
+		{"-01:02:03", "%H:%i:%s", "-01:02:03"},
+		{"2017-01-10 10:11:12", "%T", "10:11:12"},
+		{"10:11:12", "%Y-%m-%d %T %x %%", "-- 10:11:12  %"},
+		{"10:11:12", "abc%zxyz", "abczxyz"},
+		{"10:11:12", nil, nil},
+		{nil, "%T", nil},
+	}
+	for _, t := range tbl {
+		v, err := builtinTimeFormat(types.MakeDatums(t.time, t.layout), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v, testutil.DatumEquals, types.NewDatum(t.expect), Commentf("%v %v", t.time, t.layout))
+	}
+
+	// An invalid time is NULL with a warning.
+	sc := s.ctx.GetSessionVars().StmtCtx
+	warnCnt := len(sc.GetWarnings())
+	v, err := builtinTimeFormat(types.MakeDatums("not a time", "%T"), s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(v.IsNull(), IsTrue)
+	c.Assert(sc.GetWarnings(), HasLen, warnCnt+1)
+}
+
 func (s *testEvaluatorSuite) TestClock(c *C) {
 	defer testleak.AfterTest(c)()
 	// test hour, minute, second, micro second
@@ -297,9 +384,460 @@ func (s *testEvaluatorSuite) TestClock(c *C) {
 		_, err = builtinMicroSecond(td, s.ctx)
 		c.Assert(err, NotNil)
 
-		_, err = builtinTime(td, s.ctx)
-		c.Assert(err, NotNil)
+		// An invalid time is NULL with a warning.
+		sc := s.ctx.GetSessionVars().StmtCtx
+		warnCnt := len(sc.GetWarnings())
+		v, err = builtinTime(td, s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v.IsNull(), IsTrue)
+		c.Assert(sc.GetWarnings(), HasLen, warnCnt+1)
+	}
+}
+
+func (s *testEvaluatorSuite) TestTimestamp(c *C) {
+	defer testleak.AfterTest(c)()
+	tbl := []struct {
+		Input  []interface{}
+		Expect interface{}
+	}{
+		{[]interface{}{"2003-12-31"}, "2003-12-31 00:00:00"},
+		{[]interface{}{"2003-12-31 12:00:00.5"}, "2003-12-31 12:00:00.5"},
+		{[]interface{}{"2003-12-31 12:00:00", "12:00:00"}, "2004-01-01 00:00:00"},
+		{[]interface{}{"2003-12-31", "-1:00:00"}, "2003-12-30 23:00:00"},
+		{[]interface{}{"2003-12-31 12:00:00", "100:00:00.25"}, "2004-01-04 16:00:00.25"},
+		{[]interface{}{20031231}, "2003-12-31 00:00:00"},
+		{[]interface{}{nil}, nil},
+		{[]interface{}{"2003-12-31", nil}, nil},
+		{[]interface{}{"abc"}, nil},
+		{[]interface{}{"2003-12-31", "abc"}, nil},
+	}
+	for _, t := range tbl {
+		v, err := builtinTimestamp(types.MakeDatums(t.Input...), s.ctx)
+		c.Assert(err, IsNil)
+		if t.Expect == nil {
+			c.Assert(v.IsNull(), IsTrue, Commentf("%v", t.Input))
+			continue
+		}
+		c.Assert(v.Kind(), Equals, types.KindMysqlTime)
+		c.Assert(v.GetMysqlTime().Type, Equals, mysql.TypeDatetime)
+		c.Assert(v.GetMysqlTime().String(), Equals, t.Expect, Commentf("%v", t.Input))
+	}
+}
+
+func (s *testEvaluatorSuite) TestAddSubTime(c *C) {
+	defer testleak.AfterTest(c)()
+	dt := types.Time{
+		Time: types.FromDate(2003, 12, 31, 23, 59, 59, 0),
+		Type: mysql.TypeDatetime,
+	}
+	dur := types.Duration{Duration: 20 * time.Hour}
+	tbl := []struct {
+		Input []interface{}
+		Add   interface{}
+		Sub   interface{}
+	}{
+		{[]interface{}{dt, "1:00:01"}, "2004-01-01 01:00:00", "2003-12-31 22:59:58"},
+		{[]interface{}{dt, "1 1:1:1.5"}, "2004-01-02 01:01:00.5", "2003-12-30 22:58:57.5"},
+		{[]interface{}{dur, "10:00:00"}, "30:00:00", "10:00:00"},
+		{[]interface{}{dur, "-30:00:00"}, "-10:00:00", "50:00:00"},
+		{[]interface{}{"2003-12-31 23:59:59", "0:00:01"}, "2004-01-01 00:00:00", "2003-12-31 23:59:58"},
+		{[]interface{}{"20:00:00", "20:00:00.25"}, "40:00:00.25", "-00:00:00.25"},
+		{[]interface{}{"-10:00:00", "1:00:00"}, "-09:00:00", "-11:00:00"},
+		{[]interface{}{nil, "1:00:00"}, nil, nil},
+		{[]interface{}{dt, nil}, nil, nil},
+		{[]interface{}{dt, "abc"}, nil, nil},
+		{[]interface{}{"2003-13-31 00:00:00", "1:00:00"}, nil, nil},
+	}
+	for _, t := range tbl {
+		args := types.MakeDatums(t.Input...)
+		for _, fn := range []struct {
+			f      BuiltinFunc
+			expect interface{}
+		}{{builtinAddTime, t.Add}, {builtinSubTime, t.Sub}} {
+			v, err := fn.f(args, s.ctx)
+			c.Assert(err, IsNil)
+			if fn.expect == nil {
+				c.Assert(v.IsNull(), IsTrue, Commentf("%v", t.Input))
+				continue
+			}
+			str, err := v.ToString()
+			c.Assert(err, IsNil)
+			c.Assert(str, Equals, fn.expect, Commentf("%v", t.Input))
+		}
+	}
+
+	// The result keeps the type of the first argument.
+	v, err := builtinAddTime(types.MakeDatums(dt, "1:00:00"), s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(v.Kind(), Equals, types.KindMysqlTime)
+	v, err = builtinAddTime(types.MakeDatums(dur, "1:00:00"), s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(v.Kind(), Equals, types.KindMysqlDuration)
+
+	// A TIME result is clipped to the TIME range.
+	v, err = builtinAddTime(types.MakeDatums(dur, "830:00:00"), s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(v.GetMysqlDuration().Duration, Equals, types.MaxTime)
+}
+
+func (s *testEvaluatorSuite) TestConvertTZ(c *C) {
+	defer testleak.AfterTest(c)()
+	tbl := []struct {
+		Input  []interface{}
+		Expect interface{}
+	}{
+		{[]interface{}{"2004-01-01 12:00:00", "UTC", "Europe/Paris"}, "2004-01-01 13:00:00"},
+		{[]interface{}{"2004-07-01 12:00:00", "UTC", "Europe/Paris"}, "2004-07-01 14:00:00"},
+		{[]interface{}{"2004-07-01 14:00:00", "Europe/Paris", "UTC"}, "2004-07-01 12:00:00"},
+		// Paris moves to summer time at 2016-03-27 01:00:00 UTC.
+		{[]interface{}{"2016-03-27 00:59:59", "UTC", "Europe/Paris"}, "2016-03-27 01:59:59"},
+		{[]interface{}{"2016-03-27 01:00:00", "UTC", "Europe/Paris"}, "2016-03-27 03:00:00"},
+		{[]interface{}{"2004-01-01 12:00:00.123", "+00:00", "+10:00"}, "2004-01-01 22:00:00.123"},
+		{[]interface{}{"2004-01-01 12:00:00", "+05:30", "-08:00"}, "2003-12-31 22:30:00"},
+		{[]interface{}{"2004-01-01 12:00:00", "-12:59", "+13:00"}, "2004-01-02 13:59:00"},
+		{[]interface{}{"2004-01-01 12:00:00", "+00:00", "+13:01"}, nil},
+		{[]interface{}{"2004-01-01 12:00:00", "+00:00", "+10:60"}, nil},
+		{[]interface{}{"2004-01-01 12:00:00", "UTC", "Mars/Olympus_Mons"}, nil},
+		{[]interface{}{"9999-12-31 23:00:00", "+00:00", "+02:00"}, nil},
+		{[]interface{}{"2004-13-01 12:00:00", "+00:00", "+02:00"}, nil},
+		{[]interface{}{nil, "+00:00", "+02:00"}, nil},
+		{[]interface{}{"2004-01-01 12:00:00", nil, "+02:00"}, nil},
+	}
+	for _, t := range tbl {
+		v, err := builtinConvertTZ(types.MakeDatums(t.Input...), s.ctx)
+		c.Assert(err, IsNil)
+		if t.Expect == nil {
+			c.Assert(v.IsNull(), IsTrue, Commentf("%v", t.Input))
+			continue
+		}
+		c.Assert(v.GetMysqlTime().String(), Equals, t.Expect, Commentf("%v", t.Input))
+	}
+}
+
+func (s *testEvaluatorSuite) TestPeriod(c *C) {
+	defer testleak.AfterTest(c)()
+	addTbl := []struct {
+		Input  []interface{}
+		Expect interface{}
+	}{
+		{[]interface{}{200801, 2}, 200803},
+		{[]interface{}{200811, 2}, 200901},
+		{[]interface{}{200801, -1}, 200712},
+		{[]interface{}{801, 2}, 200803},
+		{[]interface{}{6912, 1}, 207001},
+		{[]interface{}{7001, 1}, 197002},
+		{[]interface{}{9912, 1}, 200001},
+		{[]interface{}{0, 5}, 0},
+		{[]interface{}{-200801, 2}, nil},
+		{[]interface{}{nil, 2}, nil},
+		{[]interface{}{200801, nil}, nil},
+	}
+	for _, t := range addTbl {
+		v, err := builtinPeriodAdd(types.MakeDatums(t.Input...), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v, testutil.DatumEquals, types.NewDatum(t.Expect), Commentf("%v", t.Input))
+	}
+
+	diffTbl := []struct {
+		Input  []interface{}
+		Expect interface{}
+	}{
+		{[]interface{}{200802, 200703}, 11},
+		{[]interface{}{200703, 200802}, -11},
+		{[]interface{}{200901, 200812}, 1},
+		{[]interface{}{801, 200712}, 1},
+		{[]interface{}{7001, 6912}, -1199},
+		{[]interface{}{200801, 200801}, 0},
+		{[]interface{}{-1, 200801}, nil},
+		{[]interface{}{nil, 200801}, nil},
+	}
+	for _, t := range diffTbl {
+		v, err := builtinPeriodDiff(types.MakeDatums(t.Input...), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v, testutil.DatumEquals, types.NewDatum(t.Expect), Commentf("%v", t.Input))
+	}
+}
+
+func (s *testEvaluatorSuite) TestQuarter(c *C) {
+	defer testleak.AfterTest(c)()
+	tbl := []struct {
+		Input  interface{}
+		Expect interface{}
+	}{
+		{"2008-01-01", 1},
+		{"2008-03-31", 1},
+		{"2008-04-01", 2},
+		{"2008-06-30", 2},
+		{"2008-07-01", 3},
+		{"2008-09-30", 3},
+		{"2008-10-01", 4},
+		{"2008-12-31 23:59:59", 4},
+		{"0000-00-00", 0},
+		{nil, nil},
+	}
+	for _, t := range tbl {
+		v, err := builtinQuarter(types.MakeDatums(t.Input), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v, testutil.DatumEquals, types.NewDatum(t.Expect), Commentf("%v", t.Input))
+	}
+}
+
+func (s *testEvaluatorSuite) TestMonthNameDayName(c *C) {
+	defer testleak.AfterTest(c)()
+	months := []string{"January", "February", "March", "April", "May", "June", "July",
+		"August", "September", "October", "November", "December"}
+	for i, name := range months {
+		v, err := builtinMonthName(types.MakeDatums(fmt.Sprintf("2017-%02d-15", i+1)), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v.GetString(), Equals, name)
+	}
+	// 2017-01-02 is a Monday.
+	days := []string{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday", "Sunday"}
+	for i, name := range days {
+		v, err := builtinDayName(types.MakeDatums(fmt.Sprintf("2017-01-%02d", i+2)), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v.GetString(), Equals, name)
+	}
+
+	for _, arg := range []interface{}{nil, "0000-00-00", "2017-00-00"} {
+		v, err := builtinMonthName(types.MakeDatums(arg), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v.IsNull(), IsTrue, Commentf("%v", arg))
+		v, err = builtinDayName(types.MakeDatums(arg), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v.IsNull(), IsTrue, Commentf("%v", arg))
+	}
+
+	// An unsupported lc_time_names falls back to en_US.
+	vars := s.ctx.GetSessionVars()
+	vars.Systems["lc_time_names"] = "xx_XX"
+	defer delete(vars.Systems, "lc_time_names")
+	v, err := builtinMonthName(types.MakeDatums("2017-03-01"), s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(v.GetString(), Equals, "March")
+}
+
+// fixedClock is a session clock stopped at a given time.
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time {
+	return time.Time(c)
+}
+
+func (s *testEvaluatorSuite) TestUTCFunctions(c *C) {
+	defer testleak.AfterTest(c)()
+	vars := s.ctx.GetSessionVars()
+	// 2017-01-01 07:08:09.123456 in UTC+08:00 is 2016-12-31 23:08:09.123456 in UTC.
+	vars.Clock = fixedClock(time.Date(2017, 1, 1, 7, 8, 9, 123456000, time.FixedZone("", 8*3600)))
+	err := varsutil.SetSystemVar(vars, "time_zone", types.NewStringDatum("+08:00"))
+	c.Assert(err, IsNil)
+	defer func() {
+		vars.Clock = nil
+		varsutil.SetSystemVar(vars, "time_zone", types.NewStringDatum("SYSTEM"))
+	}()
+
+	v, err := builtinUTCDate(nil, s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(v.GetMysqlTime().String(), Equals, "2016-12-31")
+
+	tbl := []struct {
+		Args      []interface{}
+		Time      string
+		Timestamp string
+	}{
+		{nil, "23:08:09", "2016-12-31 23:08:09"},
+		{[]interface{}{0}, "23:08:09", "2016-12-31 23:08:09"},
+		{[]interface{}{3}, "23:08:09.123", "2016-12-31 23:08:09.123"},
+		{[]interface{}{6}, "23:08:09.123456", "2016-12-31 23:08:09.123456"},
 	}
+	for _, t := range tbl {
+		v, err = builtinUTCTime(types.MakeDatums(t.Args...), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v.GetMysqlDuration().String(), Equals, t.Time)
+		v, err = builtinUTCTimestamp(types.MakeDatums(t.Args...), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v.GetMysqlTime().String(), Equals, t.Timestamp)
+	}
+
+	_, err = builtinUTCTimestamp(types.MakeDatums(7), s.ctx)
+	c.Assert(err, NotNil)
+	_, err = builtinUTCTime(types.MakeDatums(-1), s.ctx)
+	c.Assert(err, NotNil)
+}
+
+func (s *testEvaluatorSuite) TestTimeZone(c *C) {
+	defer testleak.AfterTest(c)()
+	vars := s.ctx.GetSessionVars()
+	// 2016-01-01 00:00:00 UTC is 1451606400.
+	vars.Clock = fixedClock(time.Unix(1451606400, 0))
+	defer func() {
+		vars.Clock = nil
+		varsutil.SetSystemVar(vars, "time_zone", types.NewStringDatum("SYSTEM"))
+	}()
+	tbl := []struct {
+		tz   string
+		time string
+	}{
+		{"America/New_York", "2015-12-31 19:00:00"},
+		{"+08:00", "2016-01-01 08:00:00"},
+		{"+00:00", "2016-01-01 00:00:00"},
+		{"UTC", "2016-01-01 00:00:00"},
+		{"Asia/Kolkata", "2016-01-01 05:30:00"},
+	}
+	for _, t := range tbl {
+		err := varsutil.SetSystemVar(vars, "time_zone", types.NewStringDatum(t.tz))
+		c.Assert(err, IsNil)
+		v, err := builtinFromUnixTime(types.MakeDatums(1451606400), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v.GetMysqlTime().String(), Equals, t.time, Commentf("from_unixtime in %s", t.tz))
+
+		v, err = builtinUnixTimestamp(types.MakeDatums(t.time), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v.GetInt64(), Equals, int64(1451606400), Commentf("unix_timestamp in %s", t.tz))
+
+		v, err = builtinNow(nil, s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v.GetMysqlTime().String(), Equals, t.time, Commentf("now in %s", t.tz))
+		v, err = builtinCurrentDate(nil, s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v.GetMysqlTime().String(), Equals, t.time[:10], Commentf("curdate in %s", t.tz))
+		v, err = builtinCurrentTime(nil, s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v.GetMysqlDuration().String(), Equals, t.time[11:], Commentf("curtime in %s", t.tz))
+		v, err = builtinUnixTimestamp(nil, s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v.GetInt64(), Equals, int64(1451606400))
+
+		// The UTC functions don't depend on the session time zone.
+		v, err = builtinUTCTimestamp(nil, s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v.GetMysqlTime().String(), Equals, "2016-01-01 00:00:00")
+	}
+
+	err := varsutil.SetSystemVar(vars, "time_zone", types.NewStringDatum("+00:00"))
+	c.Assert(err, IsNil)
+	v, err := builtinUnixTimestamp(types.MakeDatums("2016-01-01 00:00:00.50"), s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(v.GetMysqlDecimal().String(), Equals, "1451606400.50")
+	for _, date := range []string{"1969-12-31 23:59:59", "2038-01-19 03:14:08"} {
+		v, err = builtinUnixTimestamp(types.MakeDatums(date), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v.GetInt64(), Equals, int64(0), Commentf("unix_timestamp(%s)", date))
+	}
+	v, err = builtinUnixTimestamp(types.MakeDatums(nil), s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(v.Kind(), Equals, types.KindNull)
+
+	// An unknown zone is rejected and leaves the time zone as it was.
+	err = varsutil.SetSystemVar(vars, "time_zone", types.NewStringDatum("Mars/Olympus_Mons"))
+	c.Assert(terror.ErrorEqual(err, variable.ErrUnknownTimeZone), IsTrue)
+	v, err = builtinFromUnixTime(types.MakeDatums(1451606400), s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(v.GetMysqlTime().String(), Equals, "2016-01-01 00:00:00")
+}
+
+func (s *testEvaluatorSuite) TestGetFormat(c *C) {
+	defer testleak.AfterTest(c)()
+	tbl := []struct {
+		Selector interface{}
+		Standard interface{}
+		Expect   interface{}
+	}{
+		{"DATE", "USA", "%m.%d.%Y"},
+		{"DATE", "JIS", "%Y-%m-%d"},
+		{"DATE", "ISO", "%Y-%m-%d"},
+		{"DATE", "EUR", "%d.%m.%Y"},
+		{"DATE", "INTERNAL", "%Y%m%d"},
+		{"DATETIME", "USA", "%Y-%m-%d %H.%i.%s"},
+		{"DATETIME", "JIS", "%Y-%m-%d %H:%i:%s"},
+		{"DATETIME", "ISO", "%Y-%m-%d %H:%i:%s"},
+		{"DATETIME", "EUR", "%Y-%m-%d %H.%i.%s"},
+		{"DATETIME", "INTERNAL", "%Y%m%d%H%i%s"},
+		{"TIMESTAMP", "USA", "%Y-%m-%d %H.%i.%s"},
+		{"TIMESTAMP", "JIS", "%Y-%m-%d %H:%i:%s"},
+		{"TIMESTAMP", "ISO", "%Y-%m-%d %H:%i:%s"},
+		{"TIMESTAMP", "EUR", "%Y-%m-%d %H.%i.%s"},
+		{"TIMESTAMP", "INTERNAL", "%Y%m%d%H%i%s"},
+		{"TIME", "USA", "%h:%i:%s %p"},
+		{"TIME", "JIS", "%H:%i:%s"},
+		{"TIME", "ISO", "%H:%i:%s"},
+		{"TIME", "EUR", "%H.%i.%s"},
+		{"TIME", "INTERNAL", "%H%i%s"},
+		{"date", "usa", "%m.%d.%Y"},
+		{"DATE", "XYZ", nil},
+		{"YEAR", "USA", nil},
+		{"DATE", nil, nil},
+	}
+	for _, t := range tbl {
+		v, err := builtinGetFormat(types.MakeDatums(t.Selector, t.Standard), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v, testutil.DatumEquals, types.NewDatum(t.Expect), Commentf("%v %v", t.Selector, t.Standard))
+	}
+}
+
+func (s *testEvaluatorSuite) TestFsp(c *C) {
+	defer testleak.AfterTest(c)()
+	lengthWithFsp := func(base, fsp int) int64 {
+		if fsp > 0 {
+			return int64(base + 1 + fsp)
+		}
+		return int64(base)
+	}
+	for _, fsp := range []int{0, 3, 6} {
+		args := types.MakeDatums(fsp)
+		for _, f := range []BuiltinFunc{builtinNow, builtinSysDate, builtinUTCTimestamp} {
+			v, err := f(args, s.ctx)
+			c.Assert(err, IsNil)
+			c.Assert(v.GetMysqlTime().Fsp, Equals, fsp)
+			l, err := builtinLength([]types.Datum{v}, s.ctx)
+			c.Assert(err, IsNil)
+			c.Assert(l.GetInt64(), Equals, lengthWithFsp(19, fsp))
+		}
+		for _, f := range []BuiltinFunc{builtinCurrentTime, builtinUTCTime} {
+			v, err := f(args, s.ctx)
+			c.Assert(err, IsNil)
+			c.Assert(v.GetMysqlDuration().Fsp, Equals, fsp)
+			l, err := builtinLength([]types.Datum{v}, s.ctx)
+			c.Assert(err, IsNil)
+			c.Assert(l.GetInt64(), Equals, lengthWithFsp(8, fsp))
+		}
+	}
+
+	for _, fsp := range []interface{}{7, -1} {
+		args := types.MakeDatums(fsp)
+		for _, f := range []BuiltinFunc{builtinNow, builtinSysDate, builtinUTCTimestamp, builtinCurrentTime, builtinUTCTime} {
+			_, err := f(args, s.ctx)
+			c.Assert(err, NotNil)
+		}
+	}
+}
+
+func (s *testEvaluatorSuite) TestSecToTime(c *C) {
+	defer testleak.AfterTest(c)()
+	tbl := []struct {
+		Input  interface{}
+		Fsp    int
+		Expect string
+	}{
+		{2378, 0, "00:39:38"},
+		{-2378, 0, "-00:39:38"},
+		{"2378.5", 1, "00:39:38.5"},
+		{3600.125, 3, "01:00:00.125"},
+		{types.NewDecFromStringForTest("3600.123456"), 6, "01:00:00.123456"},
+		{90000, 0, "25:00:00"},
+		{3020400, 0, "838:59:59"},
+		{-3020400, 0, "-838:59:59"},
+	}
+	for _, t := range tbl {
+		v, err := builtinSecToTime(types.MakeDatums(t.Input), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v.GetMysqlDuration().Fsp, Equals, t.Fsp, Commentf("%v", t.Input))
+		c.Assert(v.GetMysqlDuration().String(), Equals, t.Expect, Commentf("%v", t.Input))
+	}
+
+	v, err := builtinSecToTime(types.MakeDatums(nil), s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(v.IsNull(), IsTrue)
 }
 
 func (s *testEvaluatorSuite) TestNow(c *C) {
@@ -470,6 +1008,74 @@ func (s *testEvaluatorSuite) TestStrToDate(c *C) {
 	}
 }
 
+func (s *testEvaluatorSuite) TestZeroDateModes(c *C) {
+	defer testleak.AfterTest(c)()
+	sessVars := s.ctx.GetSessionVars()
+	defer func() {
+		sessVars.StrictSQLMode, sessVars.NoZeroDate, sessVars.NoZeroInDate = true, false, false
+	}()
+	castDate, err := CastFuncFactory(types.NewFieldType(mysql.TypeDate))
+	c.Assert(err, IsNil)
+	// Each function gets the zero date, or one with a zero month, from the string it parses.
+	// ADDDATE is NULL with a warning for the accepted ones too, which have no calendar arithmetic,
+	// so only its rejections are checked.
+	fns := []struct {
+		name string
+		f    func(date string) (types.Datum, error)
+	}{
+		{"str_to_date", func(date string) (types.Datum, error) {
+			return builtinStrToDate(types.MakeDatums(date, "%Y-%m-%d"), s.ctx)
+		}},
+		{"cast", func(date string) (types.Datum, error) {
+			return castDate(types.MakeDatums(date), s.ctx)
+		}},
+		{"adddate", func(date string) (types.Datum, error) {
+			return builtinAddDate(types.MakeDatums(date, 1), s.ctx)
+		}},
+	}
+	tbl := []struct {
+		date         string
+		noZeroDate   bool
+		noZeroInDate bool
+		rejected     bool
+	}{
+		{"0000-00-00", false, false, false},
+		{"0000-00-00", false, true, false},
+		{"0000-00-00", true, false, true},
+		{"2020-00-15", true, false, false},
+		{"2020-00-15", false, true, true},
+		{"2020-01-00", false, true, true},
+		{"0000-01-15", true, true, false},
+	}
+	for _, t := range tbl {
+		sessVars.NoZeroDate, sessVars.NoZeroInDate = t.noZeroDate, t.noZeroInDate
+		for _, fn := range fns {
+			comment := Commentf("%s(%s) %v %v", fn.name, t.date, t.noZeroDate, t.noZeroInDate)
+			// In strict SQL mode a rejected date is an error.
+			sessVars.StrictSQLMode = true
+			_, err := fn.f(t.date)
+			if t.rejected {
+				c.Assert(terror.ErrorEqual(err, ErrIncorrectDatetimeValue), IsTrue, comment)
+			} else if fn.name != "adddate" {
+				c.Assert(err, IsNil, comment)
+			}
+			// Otherwise it is NULL with a warning.
+			sessVars.StrictSQLMode = false
+			warnCnt := len(sessVars.StmtCtx.GetWarnings())
+			d, err := fn.f(t.date)
+			c.Assert(err, IsNil, comment)
+			if t.rejected {
+				c.Assert(d.IsNull(), IsTrue, comment)
+				c.Assert(sessVars.StmtCtx.GetWarnings(), HasLen, warnCnt+1, comment)
+				c.Assert(terror.ErrorEqual(sessVars.StmtCtx.GetWarnings()[warnCnt], ErrIncorrectDatetimeValue), IsTrue, comment)
+			} else if fn.name != "adddate" {
+				c.Assert(d.IsNull(), IsFalse, comment)
+				c.Assert(sessVars.StmtCtx.GetWarnings(), HasLen, warnCnt, comment)
+			}
+		}
+	}
+}
+
 func (s *testEvaluatorSuite) TestTimeDiff(c *C) {
 	// Test cases from https://dev.mysql.com/doc/refman/5.7/en/date-and-time-functions.html#function_timediff
 	tests := []struct {
@@ -533,3 +1139,272 @@ func (s *testEvaluatorSuite) TestYearWeek(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(result.IsNull(), IsTrue)
 }
+
+func (s *testEvaluatorSuite) TestLeapYears(c *C) {
+	defer testleak.AfterTest(c)()
+	// 2000 and 2004 are leap years, the centuries 1900 and 2100 aren't.
+	tbl := []struct {
+		date      string
+		dayOfYear int64
+		week0     int64
+		week3     int64
+		lastDay   string
+	}{
+		{"1900-02-28", 59, 8, 9, "1900-02-28"},
+		{"1900-03-01", 60, 8, 9, "1900-03-31"},
+		{"1900-12-31", 365, 52, 1, "1900-12-31"},
+		{"2000-02-28", 59, 9, 9, "2000-02-29"},
+		{"2000-02-29", 60, 9, 9, "2000-02-29"},
+		{"2000-03-01", 61, 9, 9, "2000-03-31"},
+		{"2000-12-31", 366, 53, 52, "2000-12-31"},
+		{"2001-02-28", 59, 8, 9, "2001-02-28"},
+		{"2001-03-01", 60, 8, 9, "2001-03-31"},
+		{"2001-12-31", 365, 52, 1, "2001-12-31"},
+		{"2004-02-28", 59, 8, 9, "2004-02-29"},
+		{"2004-02-29", 60, 9, 9, "2004-02-29"},
+		{"2004-03-01", 61, 9, 10, "2004-03-31"},
+		{"2004-12-31", 366, 52, 53, "2004-12-31"},
+		{"2100-02-28", 59, 9, 8, "2100-02-28"},
+		{"2100-03-01", 60, 9, 9, "2100-03-31"},
+		{"2100-12-31", 365, 52, 52, "2100-12-31"},
+	}
+	for _, t := range tbl {
+		date := types.NewStringDatum(t.date)
+		v, err := builtinDayOfYear([]types.Datum{date}, s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v.GetInt64(), Equals, t.dayOfYear, Commentf("dayofyear(%s)", t.date))
+		v, err = builtinWeek([]types.Datum{date, types.NewIntDatum(0)}, s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v.GetInt64(), Equals, t.week0, Commentf("week(%s, 0)", t.date))
+		v, err = builtinWeek([]types.Datum{date, types.NewIntDatum(3)}, s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v.GetInt64(), Equals, t.week3, Commentf("week(%s, 3)", t.date))
+		v, err = builtinLastDay([]types.Datum{date}, s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v.GetMysqlTime().String(), Equals, t.lastDay, Commentf("last_day(%s)", t.date))
+	}
+
+	// February 29 of a year which isn't a leap year is no date.
+	for _, date := range []string{"1900-02-29", "2001-02-29", "2100-02-29", "2000-02-30"} {
+		v, err := builtinLastDay(types.MakeDatums(date), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v.IsNull(), IsTrue, Commentf("last_day(%s)", date))
+	}
+	for _, t := range []struct {
+		arg    interface{}
+		expect interface{}
+	}{
+		{"2003-02-00", "2003-02-28"},
+		{"2003-00-05", nil},
+		{"0000-00-00", nil},
+		{"2004-02-05 10:11:12.5", "2004-02-29"},
+		{20040205, "2004-02-29"},
+		{nil, nil},
+	} {
+		v, err := builtinLastDay(types.MakeDatums(t.arg), s.ctx)
+		c.Assert(err, IsNil)
+		if t.expect == nil {
+			c.Assert(v.IsNull(), IsTrue, Commentf("last_day(%v)", t.arg))
+			continue
+		}
+		c.Assert(v.GetMysqlTime().Type, Equals, mysql.TypeDate)
+		c.Assert(v.GetMysqlTime().String(), Equals, t.expect, Commentf("last_day(%v)", t.arg))
+	}
+}
+
+func (s *testEvaluatorSuite) TestToDaysFromDays(c *C) {
+	defer testleak.AfterTest(c)()
+	// Test cases from https://dev.mysql.com/doc/refman/5.7/en/date-and-time-functions.html#function_to-days
+	tests := []struct {
+		t      interface{}
+		expect interface{}
+	}{
+		{"2007-10-07", int64(733321)},
+		{"2008-10-07", int64(733687)},
+		{"0000-01-01", int64(1)},
+		{"0001-01-01", int64(366)},
+		{"1582-10-10", int64(578096)},
+		{"9999-12-31", int64(3652424)},
+		{950501, int64(728779)},
+		{"0000-00-00", nil},
+		{"2008-00-07", nil},
+		{"2008-10-00", nil},
+		{nil, nil},
+	}
+	for _, t := range tests {
+		v, err := builtinToDays(types.MakeDatums(t.t), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v, testutil.DatumEquals, types.NewDatum(t.expect), Commentf("%v", t.t))
+	}
+
+	fromTests := []struct {
+		daynr  interface{}
+		expect string
+	}{
+		{730669, "2000-07-03"},
+		{733321, "2007-10-07"},
+		{366, "0001-01-01"},
+		{578096, "1582-10-10"},
+		{3652424, "9999-12-31"},
+		{365, "0000-00-00"},
+		{-1, "0000-00-00"},
+		{3652425, "0000-00-00"},
+		{"730669", "2000-07-03"},
+	}
+	for _, t := range fromTests {
+		v, err := builtinFromDays(types.MakeDatums(t.daynr), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v.Kind(), Equals, types.KindMysqlTime)
+		c.Assert(v.GetMysqlTime().Type, Equals, mysql.TypeDate)
+		c.Assert(v.GetMysqlTime().String(), Equals, t.expect, Commentf("%v", t.daynr))
+	}
+	v, err := builtinFromDays(types.MakeDatums(nil), s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(v.IsNull(), IsTrue)
+
+	// FROM_DAYS undoes TO_DAYS, and DAYOFYEAR counts the days since the TO_DAYS of January 1.
+	for _, date := range []string{"0001-01-01", "1582-10-04", "1582-10-15", "1900-02-28", "1900-03-01",
+		"2000-02-29", "2000-12-31", "2016-03-01", "2017-12-31", "9999-12-31"} {
+		days, err := builtinToDays(types.MakeDatums(date), s.ctx)
+		c.Assert(err, IsNil)
+		v, err := builtinFromDays([]types.Datum{days}, s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(v.GetMysqlTime().String(), Equals, date)
+
+		first, err := builtinToDays(types.MakeDatums(date[:4]+"-01-01"), s.ctx)
+		c.Assert(err, IsNil)
+		yd, err := builtinDayOfYear(types.MakeDatums(date), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(yd.GetInt64(), Equals, days.GetInt64()-first.GetInt64()+1, Commentf("%s", date))
+	}
+}
+
+func (s *testEvaluatorSuite) TestAddDateSubDate(c *C) {
+	defer testleak.AfterTest(c)()
+	tbl := []struct {
+		date     interface{}
+		interval interface{}
+		add      interface{}
+		sub      interface{}
+	}{
+		{"2011-11-11", 1, "2011-11-12", "2011-11-10"},
+		{"2011-11-11", -31, "2011-10-11", "2011-12-12"},
+		{"2011-11-11 10:10:10", "20", "2011-12-01 10:10:10", "2011-10-22 10:10:10"},
+		{"2011-11-11", 19.88, "2011-12-01", "2011-10-22"},
+		{"2011-11-11", ast.DateArithInterval{Unit: "day", Interval: ast.NewValueExpr(1)}, "2011-11-12", "2011-11-10"},
+		{"2011-11-11", ast.DateArithInterval{Unit: "month", Interval: ast.NewValueExpr(2)}, "2012-01-11", "2011-09-11"},
+		{"2011-11-11", ast.DateArithInterval{Unit: "hour", Interval: ast.NewValueExpr(10)}, "2011-11-11 10:00:00", "2011-11-10 14:00:00"},
+		{nil, 1, nil, nil},
+		{"2011-11-11", nil, nil, nil},
+	}
+	for _, t := range tbl {
+		args := types.MakeDatums(t.date, t.interval)
+		if iv, ok := t.interval.(ast.DateArithInterval); ok {
+			args[1].SetInterface(iv)
+		}
+		for _, op := range []struct {
+			f      BuiltinFunc
+			expect interface{}
+		}{{builtinAddDate, t.add}, {builtinSubDate, t.sub}} {
+			v, err := op.f(args, s.ctx)
+			c.Assert(err, IsNil)
+			if op.expect == nil {
+				c.Assert(v.IsNull(), IsTrue, Commentf("%v", t))
+				continue
+			}
+			c.Assert(v.GetMysqlTime().String(), Equals, op.expect, Commentf("%v", t))
+		}
+	}
+}
+
+func (s *testEvaluatorSuite) TestDateArithBoundary(c *C) {
+	defer testleak.AfterTest(c)()
+	sc := s.ctx.GetSessionVars().StmtCtx
+	tbl := []struct {
+		op       ast.DateArithType
+		date     interface{}
+		unit     string
+		interval interface{}
+		expect   interface{}
+		warn     *terror.Error
+	}{
+		{ast.DateAdd, "2017-01-01", "day", 1, "2017-01-02", nil},
+		{ast.DateAdd, "9999-12-30", "day", 1, "9999-12-31", nil},
+		{ast.DateSub, "0000-01-02", "day", 1, "0000-01-01", nil},
+		{ast.DateAdd, "9999-12-31 23:59:59", "second", 1, nil, ErrDatetimeFunctionOverflow},
+		{ast.DateAdd, "9999-12-31", "day", 1, nil, ErrDatetimeFunctionOverflow},
+		{ast.DateAdd, "9999-01-01", "year", 1, nil, ErrDatetimeFunctionOverflow},
+		{ast.DateAdd, "2017-01-01", "month", 12 * 8000, nil, ErrDatetimeFunctionOverflow},
+		{ast.DateSub, "0000-01-01", "day", 1, nil, ErrDatetimeFunctionOverflow},
+		{ast.DateSub, "2017-01-01", "year", 2018, nil, ErrDatetimeFunctionOverflow},
+		{ast.DateAdd, "0000-00-00", "day", 1, nil, ErrIncorrectDatetimeValue},
+		{ast.DateSub, "0000-00-00 00:00:00", "second", 1, nil, ErrIncorrectDatetimeValue},
+		{ast.DateAdd, "2017-00-10", "day", 1, nil, ErrIncorrectDatetimeValue},
+		{ast.DateAdd, "2017-01-00", "month", 1, nil, ErrIncorrectDatetimeValue},
+	}
+	for _, t := range tbl {
+		args := types.MakeDatums(t.op, t.date, ast.DateArithInterval{Unit: t.unit, Interval: ast.NewValueExpr(t.interval)})
+		warnCnt := len(sc.GetWarnings())
+		v, err := builtinDateArith(args, s.ctx)
+		c.Assert(err, IsNil)
+		warnings := sc.GetWarnings()
+		if t.warn == nil {
+			c.Assert(v.GetMysqlTime().String(), Equals, t.expect)
+			c.Assert(warnings, HasLen, warnCnt)
+			continue
+		}
+		c.Assert(v.Kind(), Equals, types.KindNull, Commentf("%v", t))
+		c.Assert(warnings, HasLen, warnCnt+1)
+		c.Assert(t.warn.Equal(warnings[warnCnt]), IsTrue, Commentf("%v", warnings[warnCnt]))
+	}
+}
+
+func (s *testEvaluatorSuite) TestCastRoundFrac(c *C) {
+	defer testleak.AfterTest(c)()
+	tbl := []struct {
+		input  string
+		tp     byte
+		fsp    int
+		expect string
+	}{
+		{"2017-01-01 10:10:10.123500", mysql.TypeDatetime, 3, "2017-01-01 10:10:10.124"},
+		{"2017-01-01 10:10:10.123499", mysql.TypeDatetime, 3, "2017-01-01 10:10:10.123"},
+		{"2017-01-01 10:10:10.500000", mysql.TypeDatetime, 0, "2017-01-01 10:10:11"},
+		{"2017-01-01 10:10:10.499999", mysql.TypeDatetime, 0, "2017-01-01 10:10:10"},
+		{"2017-12-31 23:59:59.500000", mysql.TypeDatetime, 0, "2018-01-01 00:00:00"},
+		{"2017-01-01 10:10:10.500000", mysql.TypeDate, 0, "2017-01-01"},
+		{"10:10:10.123500", mysql.TypeDuration, 3, "10:10:10.124"},
+		{"10:10:10.500000", mysql.TypeDuration, 0, "10:10:11"},
+		{"-10:10:10.500000", mysql.TypeDuration, 0, "-10:10:11"},
+		{"-10:10:10.499999", mysql.TypeDuration, 0, "-10:10:10"},
+	}
+	for _, t := range tbl {
+		// The argument is a value of precision 6, like a DATETIME(6) or TIME(6) column.
+		var arg types.Datum
+		if t.tp == mysql.TypeDuration {
+			dur, err := types.ParseDuration(t.input, types.MaxFsp)
+			c.Assert(err, IsNil)
+			arg.SetMysqlDuration(dur)
+		} else {
+			tm, err := types.ParseTime(t.input, mysql.TypeDatetime, types.MaxFsp)
+			c.Assert(err, IsNil)
+			arg.SetMysqlTime(tm)
+		}
+		tp := types.NewFieldType(t.tp)
+		tp.Decimal = t.fsp
+		f, err := CastFuncFactory(tp)
+		c.Assert(err, IsNil)
+		v, err := f([]types.Datum{arg}, s.ctx)
+		c.Assert(err, IsNil)
+		str, err := v.ToString()
+		c.Assert(err, IsNil)
+		c.Assert(str, Equals, t.expect, Commentf("%s %d", t.input, t.fsp))
+
+		// A string argument rounds the same way.
+		v, err = f(types.MakeDatums(t.input), s.ctx)
+		c.Assert(err, IsNil)
+		str, err = v.ToString()
+		c.Assert(err, IsNil)
+		c.Assert(str, Equals, t.expect, Commentf("%s %d", t.input, t.fsp))
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package evaluator
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/util/charset"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/transform"
+)
+
+// Transcoder converts strings between UTF-8, which strings are evaluated in, and the encoding of
+// a charset.
+type Transcoder interface {
+	// Encode returns the UTF-8 string s in the encoding of the charset. It fails if the charset
+	// can't represent a character of s.
+	Encode(s string) (string, error)
+	// Decode returns s, a string in the encoding of the charset, as UTF-8. It fails if s isn't
+	// valid in the charset.
+	Decode(s string) (string, error)
+}
+
+// transcoders holds the transcoders of the charsets CONVERT() and CAST() convert to, keyed by
+// lower case charset names.
+var transcoders = map[string]Transcoder{
+	charset.CharsetBin:     binaryTranscoder{},
+	charset.CharsetUTF8:    utf8Transcoder{maxLen: 3},
+	charset.CharsetUTF8MB4: utf8Transcoder{maxLen: 4},
+	"ascii":                utf8Transcoder{maxLen: 1},
+	"latin1":               newEncodingTranscoder("latin1"),
+	"gbk":                  newEncodingTranscoder("gbk"),
+}
+
+// RegisterTranscoder adds t to the transcoders under the charset name cs, which is
+// case-insensitive. Registering a charset which already has a transcoder is an error. Like
+// RegisterFunction, it must be called at init time, before any statement is evaluated.
+func RegisterTranscoder(cs string, t Transcoder) error {
+	cs = strings.ToLower(cs)
+	if _, ok := transcoders[cs]; ok {
+		return errors.Errorf("charset %s already has a transcoder", cs)
+	}
+	if t == nil {
+		return errors.Errorf("charset %s has no transcoder", cs)
+	}
+	transcoders[cs] = t
+	return nil
+}
+
+// transcoderOf returns the transcoder of the charset cs, or ErrUnknownCharacterSet if it has none.
+func transcoderOf(cs string) (Transcoder, error) {
+	t, ok := transcoders[strings.ToLower(cs)]
+	if !ok {
+		return nil, ErrUnknownCharacterSet.GenByArgs(cs)
+	}
+	return t, nil
+}
+
+// binaryTranscoder transcodes the binary charset, whose strings are bytes kept as they are.
+type binaryTranscoder struct{}
+
+func (binaryTranscoder) Encode(s string) (string, error) { return s, nil }
+func (binaryTranscoder) Decode(s string) (string, error) { return s, nil }
+
+// utf8Transcoder transcodes the charsets which are UTF-8 limited to characters of at most maxLen
+// bytes: ascii takes 1 byte a character, the utf8 of MySQL 3 and utf8mb4 4.
+type utf8Transcoder struct {
+	maxLen int
+}
+
+func (t utf8Transcoder) Encode(s string) (string, error) {
+	return s, errors.Trace(t.check(s))
+}
+
+func (t utf8Transcoder) Decode(s string) (string, error) {
+	return s, errors.Trace(t.check(s))
+}
+
+func (t utf8Transcoder) check(s string) error {
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if (r == utf8.RuneError && size == 1) || size > t.maxLen {
+			return errors.Errorf("invalid character at byte %d of %q", i, s)
+		}
+		i += size
+	}
+	return nil
+}
+
+// encodingTranscoder transcodes a charset with an encoding of golang.org/x/text.
+type encodingTranscoder struct {
+	e encoding.Encoding
+}
+
+func newEncodingTranscoder(label string) encodingTranscoder {
+	e, _ := charset.Lookup(label)
+	return encodingTranscoder{e: e}
+}
+
+// Encoders and decoders replace what they can't convert, so a conversion is checked by converting
+// the result back.
+func (t encodingTranscoder) Encode(s string) (string, error) {
+	encoded, _, err := transform.String(t.e.NewEncoder(), s)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	if decoded, _, err := transform.String(t.e.NewDecoder(), encoded); err != nil || decoded != s {
+		return "", errors.Errorf("invalid character in %q", s)
+	}
+	return encoded, nil
+}
+
+func (t encodingTranscoder) Decode(s string) (string, error) {
+	decoded, _, err := transform.String(t.e.NewDecoder(), s)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	if encoded, _, err := transform.String(t.e.NewEncoder(), decoded); err != nil || encoded != s {
+		return "", errors.Errorf("invalid byte in %q", s)
+	}
+	return decoded, nil
+}
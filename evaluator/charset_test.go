@@ -0,0 +1,110 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package evaluator
+
+import (
+	"strings"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/terror"
+	"github.com/pingcap/tidb/util/testleak"
+	"github.com/pingcap/tidb/util/testutil"
+	"github.com/pingcap/tidb/util/types"
+)
+
+func (s *testEvaluatorSuite) TestTranscoders(c *C) {
+	defer testleak.AfterTest(c)()
+	tbl := []struct {
+		cs      string
+		str     string
+		encoded string
+		ok      bool
+	}{
+		{"binary", "\xff\x00a", "\xff\x00a", true},
+		{"utf8", "数据库", "数据库", true},
+		{"UTF8", "a😀", "", false},
+		{"utf8mb4", "a😀", "a😀", true},
+		{"ascii", "abc", "abc", true},
+		{"ascii", "é", "", false},
+		{"latin1", "héllo", "h\xe9llo", true},
+		{"latin1", "€", "\x80", true},
+		{"latin1", "数", "", false},
+		{"gbk", "中文", "\xd6\xd0\xce\xc4", true},
+		{"gbk", "😀", "", false},
+	}
+	for _, t := range tbl {
+		tc, err := transcoderOf(t.cs)
+		c.Assert(err, IsNil)
+		encoded, err := tc.Encode(t.str)
+		if !t.ok {
+			c.Assert(err, NotNil, Commentf("%s %q", t.cs, t.str))
+			continue
+		}
+		c.Assert(err, IsNil, Commentf("%s %q", t.cs, t.str))
+		c.Assert(encoded, Equals, t.encoded, Commentf("%s %q", t.cs, t.str))
+		// What is encoded decodes back.
+		decoded, err := tc.Decode(encoded)
+		c.Assert(err, IsNil, Commentf("%s %q", t.cs, t.str))
+		c.Assert(decoded, Equals, t.str, Commentf("%s %q", t.cs, t.str))
+	}
+
+	// Bytes which aren't valid in the charset don't decode.
+	for _, t := range []struct {
+		cs  string
+		str string
+	}{
+		{"utf8", "\xff"},
+		{"utf8", "\xf0\x9f\x98\x80"},
+		{"utf8mb4", "a\xe4\xbd"},
+		{"ascii", "\x80"},
+		{"gbk", "\xd6"},
+	} {
+		tc, err := transcoderOf(t.cs)
+		c.Assert(err, IsNil)
+		_, err = tc.Decode(t.str)
+		c.Assert(err, NotNil, Commentf("%s %q", t.cs, t.str))
+	}
+
+	_, err := transcoderOf("wrongcharset")
+	c.Assert(terror.ErrorEqual(err, ErrUnknownCharacterSet), IsTrue)
+}
+
+// upperTranscoder is a charset whose encoding of a string is its upper case form.
+type upperTranscoder struct{}
+
+func (upperTranscoder) Encode(s string) (string, error) { return strings.ToUpper(s), nil }
+func (upperTranscoder) Decode(s string) (string, error) { return strings.ToLower(s), nil }
+
+func (s *testEvaluatorSuite) TestRegisterTranscoder(c *C) {
+	defer testleak.AfterTest(c)()
+	c.Assert(RegisterTranscoder("utf8", upperTranscoder{}), NotNil)
+	c.Assert(RegisterTranscoder("test_upper", nil), NotNil)
+	c.Assert(RegisterTranscoder("TEST_Upper", upperTranscoder{}), IsNil)
+	defer delete(transcoders, "test_upper")
+	c.Assert(RegisterTranscoder("test_upper", upperTranscoder{}), NotNil)
+
+	// CONVERT() goes through the registered transcoder, both ways.
+	tc, err := transcoderOf("test_upper")
+	c.Assert(err, IsNil)
+	encoded, err := tc.Encode("abc")
+	c.Assert(err, IsNil)
+	c.Assert(encoded, Equals, "ABC")
+	bin := types.NewBytesDatum([]byte(encoded))
+	bin.SetCollation(mysql.BinaryCollationID)
+	v, err := Funcs[ast.Convert].F([]types.Datum{bin, types.NewStringDatum("test_upper")}, s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(v, testutil.DatumEquals, types.NewStringDatum("abc"))
+}
@@ -0,0 +1,217 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package evaluator
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/util/charset"
+	"github.com/pingcap/tidb/util/types"
+)
+
+// isCICollation reports whether the collation compares strings case insensitively.
+func isCICollation(collation string) bool {
+	return strings.HasSuffix(collation, "_ci")
+}
+
+// latin1Folds maps the upper case letters of the Latin-1 Supplement, U+00C0 to U+00DF, to the
+// letters utf8_general_ci weighs them as, 0 for those weighed as themselves.
+const latin1Folds = "AAAAAA\x00CEEEEIIII\x00NOOOOO\x00\x00UUUUY\x00S"
+
+// foldCI returns the character r is weighed as by a _ci collation: its upper case form, with the
+// accents of Latin-1 letters removed as utf8_general_ci does, so 'é' is weighed as 'E'.
+func foldCI(r rune) rune {
+	r = unicode.ToUpper(r)
+	switch {
+	case r >= 0xC0 && r <= 0xDF:
+		if f := latin1Folds[r-0xC0]; f != 0 {
+			return rune(f)
+		}
+	case r == 0x178:
+		// The upper case form of 'ÿ'.
+		return 'Y'
+	}
+	return r
+}
+
+// SortKey returns the key of the string datum d under collation: the keys of strings which are
+// equal under the collation are equal, and keys compare bytewise in the order of the strings.
+// A _ci collation compares characters by foldCI, any other collation by bytes.
+// Datums of other kinds have no collation and no sort key, SortKey returns nil for them.
+func SortKey(d types.Datum, collation string) []byte {
+	if k := d.Kind(); k != types.KindString && k != types.KindBytes {
+		return nil
+	}
+	b := d.GetBytes()
+	if !isCICollation(collation) {
+		return b
+	}
+	key := make([]byte, 0, len(b))
+	for len(b) > 0 {
+		r, size := utf8.DecodeRune(b)
+		if r == utf8.RuneError && size <= 1 {
+			// Invalid UTF-8 is kept as it is.
+			key = append(key, b[:size]...)
+		} else {
+			key = append(key, string(foldCI(r))...)
+		}
+		b = b[size:]
+	}
+	return key
+}
+
+// CollationFuncs are the functions which compare their string arguments in the collation derived
+// for them, see AggregateCollation. Their arguments carry that collation when they are called.
+var CollationFuncs = map[string]struct{}{
+	ast.LT:     {},
+	ast.LE:     {},
+	ast.GE:     {},
+	ast.GT:     {},
+	ast.EQ:     {},
+	ast.NE:     {},
+	ast.NullEQ: {},
+	ast.In:     {},
+	// FIELD() compares its first argument with the others.
+	ast.Field:  {},
+	ast.Strcmp: {},
+	// LOCATE() and INSTR() compare the string searched for with the one searched.
+	ast.Locate: {},
+	ast.Instr:  {},
+}
+
+// compareCollation returns the name of the collation the strings a and b are compared in, the one
+// they carry, and the binary one if neither carries a collation.
+func compareCollation(a, b types.Datum) string {
+	if id := a.Collation(); id != 0 {
+		return mysql.Collations[id]
+	}
+	return mysql.Collations[b.Collation()]
+}
+
+// CollationOperand is a string operand of an operation as collation derivation sees it.
+type CollationOperand struct {
+	Charset string
+	// Collate is the default collation of Charset when empty.
+	Collate      string
+	Coercibility int
+}
+
+// coercibilityNames are the names of the coercibility values in error messages.
+var coercibilityNames = [...]string{
+	CoercibilityExplicit:  "EXPLICIT",
+	CoercibilityNone:      "NONE",
+	CoercibilityImplicit:  "IMPLICIT",
+	CoercibilitySysconst:  "SYSCONST",
+	CoercibilityCoercible: "COERCIBLE",
+	CoercibilityIgnorable: "IGNORABLE",
+}
+
+// AggregateCollation derives the charset and collation MySQL compares or builds the result of the
+// operation op in from its string operands. An operand of stronger coercibility wins, a Unicode
+// charset wins over the charsets whose characters it holds and binary wins over every charset.
+// Operands which can't be resolved, such as two different explicit collations, are an
+// ErrIllegalMixOfCollations. Ignorable operands, like NULL, take no part.
+// See https://dev.mysql.com/doc/refman/5.7/en/charset-collation-coercibility.html
+func AggregateCollation(op string, operands ...CollationOperand) (CollationOperand, error) {
+	res := CollationOperand{Coercibility: CoercibilityIgnorable}
+	for _, o := range operands {
+		if o.Coercibility == CoercibilityIgnorable {
+			continue
+		}
+		if o.Collate == "" {
+			var err error
+			o.Collate, err = charset.GetDefaultCollation(o.Charset)
+			if err != nil {
+				return res, errors.Trace(err)
+			}
+		}
+		if res.Coercibility == CoercibilityIgnorable {
+			res = o
+			continue
+		}
+		co, ok := aggregateCollation(res, o)
+		if !ok {
+			return res, ErrIllegalMixOfCollations.GenByArgs(res.Collate, coercibilityNames[res.Coercibility],
+				o.Collate, coercibilityNames[o.Coercibility], op)
+		}
+		res = co
+	}
+	return res, nil
+}
+
+// aggregateCollation derives the collation of a and b, if they have one.
+func aggregateCollation(a, b CollationOperand) (CollationOperand, bool) {
+	if a.Charset != b.Charset {
+		switch {
+		case a.Charset == charset.CharsetBin && a.Coercibility <= b.Coercibility:
+			return a, true
+		case b.Charset == charset.CharsetBin && b.Coercibility <= a.Coercibility:
+			return b, true
+		case a.Charset == charset.CharsetBin:
+			return b, true
+		case b.Charset == charset.CharsetBin:
+			return a, true
+		case isCharsetSuperset(a, b):
+			return a, true
+		case isCharsetSuperset(b, a):
+			return b, true
+		// A system constant or a literal converts to the charset of a stronger operand.
+		case a.Coercibility < b.Coercibility && b.Coercibility >= CoercibilitySysconst:
+			return a, true
+		case b.Coercibility < a.Coercibility && a.Coercibility >= CoercibilitySysconst:
+			return b, true
+		}
+		return a, false
+	}
+	switch {
+	case a.Coercibility < b.Coercibility:
+		return a, true
+	case b.Coercibility < a.Coercibility:
+		return b, true
+	case a.Collate == b.Collate:
+		return a, true
+	case a.Coercibility == CoercibilityExplicit:
+		return a, false
+	// Of two collations of the same charset, only a binary one wins.
+	case isBinCollation(a.Collate) && !isBinCollation(b.Collate):
+		return a, true
+	case isBinCollation(b.Collate) && !isBinCollation(a.Collate):
+		return b, true
+	}
+	return a, false
+}
+
+// isCharsetSuperset tells whether the charset of l can hold the strings of r: a Unicode charset
+// holds those of any other, utf8mb4 those of utf8, and any charset those of ascii.
+func isCharsetSuperset(l, r CollationOperand) bool {
+	if isUnicodeCharset(l.Charset) && (l.Coercibility < r.Coercibility || l.Coercibility == r.Coercibility &&
+		(!isUnicodeCharset(r.Charset) || l.Charset == charset.CharsetUTF8MB4 && r.Charset == charset.CharsetUTF8)) {
+		return true
+	}
+	return r.Charset == charset.CharsetASCII && (l.Coercibility < r.Coercibility ||
+		l.Coercibility == r.Coercibility && l.Charset != charset.CharsetASCII)
+}
+
+func isUnicodeCharset(cs string) bool {
+	return cs == charset.CharsetUTF8 || cs == charset.CharsetUTF8MB4
+}
+
+func isBinCollation(collate string) bool {
+	return collate == charset.CollationBin || strings.HasSuffix(collate, "_bin")
+}
@@ -0,0 +1,111 @@
+// Copyright 2017 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package evaluator
+
+import (
+	"bytes"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/terror"
+	"github.com/pingcap/tidb/util/testleak"
+	"github.com/pingcap/tidb/util/types"
+)
+
+func (s *testEvaluatorSuite) TestSortKey(c *C) {
+	defer testleak.AfterTest(c)()
+	tbl := []struct {
+		a         string
+		b         string
+		collation string
+		cmp       int
+	}{
+		{"abc", "ABC", "utf8_general_ci", 0},
+		{"abc", "aBc", "utf8mb4_unicode_ci", 0},
+		{"été", "ÉTÉ", "utf8_general_ci", 0},
+		{"é", "e", "utf8_general_ci", 0},
+		{"ÿ", "y", "utf8_general_ci", 0},
+		{"é", "f", "utf8_general_ci", -1},
+		{"é", "e", "utf8_bin", 1},
+		{"a", "B", "utf8_general_ci", -1},
+		{"b", "A", "utf8_general_ci", 1},
+		{"ab", "AB ", "utf8_general_ci", -1},
+		{"abc", "ABC", "utf8_bin", 1},
+		{"abc", "ABC", "binary", 1},
+		{"abc", "ABC", "", 1},
+		{"a", "B", "utf8_bin", 1},
+		{"abc", "abc", "utf8_bin", 0},
+		{"\xffa", "\xffA", "utf8_general_ci", 0},
+	}
+	for _, t := range tbl {
+		ka := SortKey(types.NewStringDatum(t.a), t.collation)
+		kb := SortKey(types.NewBytesDatum([]byte(t.b)), t.collation)
+		c.Assert(bytes.Compare(ka, kb), Equals, t.cmp, Commentf("%v", t))
+	}
+
+	for _, d := range types.MakeDatums(nil, 1, 1.5, types.NewDecFromInt(1)) {
+		c.Assert(SortKey(d, "utf8_general_ci"), IsNil)
+	}
+}
+
+func (s *testEvaluatorSuite) TestAggregateCollation(c *C) {
+	defer testleak.AfterTest(c)()
+	explicit := func(cs, co string) CollationOperand { return CollationOperand{cs, co, CoercibilityExplicit} }
+	implicit := func(cs, co string) CollationOperand { return CollationOperand{cs, co, CoercibilityImplicit} }
+	coercible := func(cs, co string) CollationOperand { return CollationOperand{cs, co, CoercibilityCoercible} }
+	null := CollationOperand{"binary", "binary", CoercibilityIgnorable}
+
+	tbl := []struct {
+		operands []CollationOperand
+		collate  string
+		co       int
+	}{
+		// The stronger coercibility wins.
+		{[]CollationOperand{explicit("utf8", "utf8_bin"), implicit("utf8", "utf8_general_ci")}, "utf8_bin", CoercibilityExplicit},
+		{[]CollationOperand{implicit("utf8", "utf8_general_ci"), explicit("utf8", "utf8_unicode_ci")}, "utf8_unicode_ci", CoercibilityExplicit},
+		{[]CollationOperand{coercible("utf8", "utf8_bin"), implicit("utf8", "utf8_general_ci")}, "utf8_general_ci", CoercibilityImplicit},
+		// A literal converts to the charset of a column.
+		{[]CollationOperand{implicit("latin1", "latin1_swedish_ci"), coercible("utf8", "utf8_bin")}, "latin1_swedish_ci", CoercibilityImplicit},
+		// Unicode holds the other charsets and utf8mb4 holds utf8.
+		{[]CollationOperand{implicit("latin1", ""), implicit("utf8", "")}, "utf8_general_ci", CoercibilityImplicit},
+		{[]CollationOperand{implicit("utf8", ""), implicit("utf8mb4", "")}, "utf8mb4_general_ci", CoercibilityImplicit},
+		{[]CollationOperand{explicit("utf8", "utf8_bin"), implicit("utf8mb4", "")}, "utf8_bin", CoercibilityExplicit},
+		{[]CollationOperand{explicit("latin1", "latin1_bin"), explicit("utf8", "utf8_bin")}, "utf8_bin", CoercibilityExplicit},
+		{[]CollationOperand{implicit("ascii", ""), implicit("latin1", "")}, "latin1_swedish_ci", CoercibilityImplicit},
+		// Binary wins unless it is weaker.
+		{[]CollationOperand{implicit("utf8", ""), implicit("binary", "binary")}, "binary", CoercibilityImplicit},
+		{[]CollationOperand{implicit("utf8", ""), coercible("binary", "binary")}, "utf8_general_ci", CoercibilityImplicit},
+		// Of the same charset and coercibility, a binary collation wins.
+		{[]CollationOperand{implicit("utf8", "utf8_unicode_ci"), implicit("utf8", "utf8_bin")}, "utf8_bin", CoercibilityImplicit},
+		// NULL takes no part.
+		{[]CollationOperand{null, explicit("utf8", "utf8_bin")}, "utf8_bin", CoercibilityExplicit},
+		{[]CollationOperand{null}, "", CoercibilityIgnorable},
+	}
+	for _, t := range tbl {
+		co, err := AggregateCollation("=", t.operands...)
+		c.Assert(err, IsNil, Commentf("%v", t.operands))
+		c.Assert(co.Collate, Equals, t.collate, Commentf("%v", t.operands))
+		c.Assert(co.Coercibility, Equals, t.co, Commentf("%v", t.operands))
+	}
+
+	for _, operands := range [][]CollationOperand{
+		{explicit("utf8", "utf8_bin"), explicit("utf8", "utf8_general_ci")},
+		{implicit("utf8", "utf8_general_ci"), implicit("utf8", "utf8_unicode_ci")},
+		{implicit("latin1", ""), implicit("utf8", ""), explicit("utf8", "utf8_bin"), explicit("utf8", "utf8_unicode_ci")},
+	} {
+		_, err := AggregateCollation("=", operands...)
+		c.Assert(terror.ErrorEqual(err, ErrIllegalMixOfCollations), IsTrue, Commentf("%v", operands))
+	}
+	_, err := AggregateCollation("=", explicit("utf8", "utf8_bin"), explicit("utf8", "utf8_general_ci"))
+	c.Assert(err.Error(), Matches, ".*\\(utf8_bin,EXPLICIT\\) and \\(utf8_general_ci,EXPLICIT\\) for operation '='")
+}
@@ -14,7 +14,11 @@
 package evaluator
 
 import (
+	"github.com/juju/errors"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/terror"
+	"github.com/pingcap/tidb/util/types"
 )
 
 const (
@@ -24,17 +28,125 @@ const (
 
 // Error instances.
 var (
-	ErrInvalidOperation = terror.ClassEvaluator.New(CodeInvalidOperation, "invalid operation")
+	ErrInvalidOperation            = terror.ClassEvaluator.New(CodeInvalidOperation, "invalid operation")
+	ErrInvalidJSONText             = terror.ClassEvaluator.New(CodeInvalidJSONText, "Invalid JSON text: %s")
+	ErrInvalidJSONPath             = terror.ClassEvaluator.New(CodeInvalidJSONPath, "Invalid JSON path expression %s")
+	ErrIncorrectParameterCount     = terror.ClassEvaluator.New(CodeIncorrectParameterCount, "Incorrect parameter count in the call to native function '%s'")
+	ErrInvalidJSONContainsPathType = terror.ClassEvaluator.New(CodeInvalidJSONContainsPathType,
+		"The oneOrAll argument to json_contains_path may take these values: 'one' or 'all'.")
+	ErrDataOutOfRange              = terror.ClassEvaluator.New(CodeDataOutOfRange, "%s value is out of range in '%s'")
+	ErrNoDefaultValue              = terror.ClassEvaluator.New(CodeNoDefaultValue, "Field '%s' doesn't have a default value")
+	ErrWarnAllowedPacketOverflowed = terror.ClassEvaluator.New(CodeWarnAllowedPacketOverflowed,
+		"Result of %s() was larger than max_allowed_packet (%d) - truncated")
+	ErrIllegalMixOfCollations = terror.ClassEvaluator.New(CodeIllegalMixOfCollations,
+		"Illegal mix of collations (%s,%s) and (%s,%s) for operation '%s'")
+	ErrUnknownCharacterSet      = terror.ClassEvaluator.New(CodeUnknownCharacterSet, "Unknown character set: '%s'")
+	ErrIncorrectDatetimeValue   = terror.ClassEvaluator.New(CodeIncorrectDatetimeValue, "Incorrect datetime value: '%s'")
+	ErrDatetimeFunctionOverflow = terror.ClassEvaluator.New(CodeDatetimeFunctionOverflow, "Datetime function: %s field overflow")
+	ErrInvalidCharacterString   = terror.ClassEvaluator.New(CodeInvalidCharacterString, "Invalid %s character string: '%s'")
+	ErrWrongValueForType        = terror.ClassEvaluator.New(CodeWrongValueForType, "Incorrect %s value: '%s' for function %s")
+	ErrTruncatedWrongValue      = terror.ClassEvaluator.New(CodeTruncatedWrongValue, "Truncated incorrect %s value: '%v'")
+	ErrUnknownCollation         = terror.ClassEvaluator.New(CodeUnknownCollation, "Unknown collation: '%s'")
+	ErrCollationCharsetMismatch = terror.ClassEvaluator.New(CodeCollationCharsetMismatch,
+		"COLLATION '%s' is not valid for CHARACTER SET '%s'")
 )
 
 // Error codes.
 const (
-	CodeInvalidOperation terror.ErrCode = 1
+	CodeInvalidOperation            terror.ErrCode = 1
+	CodeInvalidJSONText             terror.ErrCode = 2
+	CodeInvalidJSONPath             terror.ErrCode = 3
+	CodeIncorrectParameterCount     terror.ErrCode = 4
+	CodeInvalidJSONContainsPathType terror.ErrCode = 5
+	CodeDataOutOfRange              terror.ErrCode = 6
+	CodeNoDefaultValue              terror.ErrCode = 7
+	CodeWarnAllowedPacketOverflowed terror.ErrCode = 8
+	CodeIllegalMixOfCollations      terror.ErrCode = 9
+	CodeUnknownCharacterSet         terror.ErrCode = 10
+	CodeIncorrectDatetimeValue      terror.ErrCode = 11
+	CodeDatetimeFunctionOverflow    terror.ErrCode = 12
+	CodeInvalidCharacterString      terror.ErrCode = 13
+	CodeWrongValueForType           terror.ErrCode = 14
+	CodeTruncatedWrongValue         terror.ErrCode = 15
+	CodeUnknownCollation            terror.ErrCode = 16
+	CodeCollationCharsetMismatch    terror.ErrCode = 17
 )
 
+func init() {
+	evaluatorMySQLErrCodes := map[terror.ErrCode]uint16{
+		CodeInvalidOperation:            mysql.ErrUnknown,
+		CodeInvalidJSONText:             mysql.ErrInvalidJSONText,
+		CodeInvalidJSONPath:             mysql.ErrInvalidJSONPath,
+		CodeIncorrectParameterCount:     mysql.ErrWrongParamcountToNativeFct,
+		CodeInvalidJSONContainsPathType: mysql.ErrJSONBadOneOrAllArg,
+		CodeDataOutOfRange:              mysql.ErrDataOutOfRange,
+		CodeNoDefaultValue:              mysql.ErrNoDefaultForField,
+		CodeWarnAllowedPacketOverflowed: mysql.ErrWarnAllowedPacketOverflowed,
+		CodeIllegalMixOfCollations:      mysql.ErrCantAggregate2collations,
+		CodeUnknownCharacterSet:         mysql.ErrUnknownCharacterSet,
+		CodeIncorrectDatetimeValue:      mysql.ErrTruncatedWrongValue,
+		CodeDatetimeFunctionOverflow:    mysql.ErrDatetimeFunctionOverflow,
+		CodeInvalidCharacterString:      mysql.ErrInvalidCharacterString,
+		CodeWrongValueForType:           mysql.ErrWrongValueForType,
+		CodeTruncatedWrongValue:         mysql.ErrTruncatedWrongValue,
+		CodeUnknownCollation:            mysql.ErrUnknownCollation,
+		CodeCollationCharsetMismatch:    mysql.ErrCollationCharsetMismatch,
+	}
+	terror.ErrClassToMySQLCodes[terror.ClassEvaluator] = evaluatorMySQLErrCodes
+}
+
 func boolToInt64(v bool) int64 {
 	if v {
 		return int64(1)
 	}
 	return int64(0)
 }
+
+// convertError wraps err, the failure to convert d to a tp value, as ErrTruncatedWrongValue, so
+// that it is reported with the MySQL code of a truncated value.
+func convertError(err error, tp string, d types.Datum) error {
+	if err == nil {
+		return nil
+	}
+	return errors.Wrap(err, ErrTruncatedWrongValue.GenByArgs(tp, d.GetValue()))
+}
+
+// isOverflowError checks whether err is caused by a numeric value going out of range.
+func isOverflowError(err error) bool {
+	return terror.ErrorEqual(err, types.ErrOverflow) || terror.ErrorEqual(err, types.ErrArithOverflow)
+}
+
+// handleDivisionByZero reports a division by zero, whose result is NULL. Only the
+// ERROR_FOR_DIVISION_BY_ZERO SQL mode reports it, as an error when strict SQL mode applies to an
+// INSERT or UPDATE, and as a warning otherwise.
+func handleDivisionByZero(ctx context.Context) error {
+	sessVars := ctx.GetSessionVars()
+	if !sessVars.ErrorForDivisionByZero {
+		return nil
+	}
+	sc := sessVars.StmtCtx
+	if sessVars.StrictSQLMode && (sc.InInsertStmt || sc.InUpdateStmt) {
+		return errors.Trace(types.ErrDivByZero)
+	}
+	sc.AppendWarning(types.ErrDivByZero)
+	return nil
+}
+
+// handleOverflow reports the overflow of expr, whose result type is tp, as ErrDataOutOfRange.
+// In strict SQL mode the error is returned; otherwise it is appended to the statement
+// warnings and d is returned as the result. Errors other than overflow are returned as is.
+func handleOverflow(ctx context.Context, d types.Datum, err error, tp, expr string) (types.Datum, error) {
+	if err == nil {
+		return d, nil
+	}
+	if !isOverflowError(err) {
+		return d, errors.Trace(err)
+	}
+	err = ErrDataOutOfRange.GenByArgs(tp, expr)
+	sessVars := ctx.GetSessionVars()
+	if sessVars.StrictSQLMode {
+		return d, errors.Trace(err)
+	}
+	sessVars.StmtCtx.AppendWarning(err)
+	return d, nil
+}
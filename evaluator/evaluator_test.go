@@ -14,6 +14,8 @@
 package evaluator
 
 import (
+	"errors"
+	"math"
 	"testing"
 	"time"
 
@@ -24,6 +26,7 @@ import (
 	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/parser"
+	"github.com/pingcap/tidb/terror"
 	"github.com/pingcap/tidb/util/mock"
 	"github.com/pingcap/tidb/util/testleak"
 	"github.com/pingcap/tidb/util/testutil"
@@ -47,6 +50,40 @@ func (s *testEvaluatorSuite) SetUpSuite(c *C) {
 	s.ctx = mock.NewContext()
 }
 
+func (s *testEvaluatorSuite) TestErrorCodes(c *C) {
+	defer testleak.AfterTest(c)()
+	tbl := []struct {
+		err  *terror.Error
+		code uint16
+	}{
+		{ErrInvalidOperation, mysql.ErrUnknown},
+		{ErrInvalidJSONText, mysql.ErrInvalidJSONText},
+		{ErrInvalidJSONPath, mysql.ErrInvalidJSONPath},
+		{ErrIncorrectParameterCount, mysql.ErrWrongParamcountToNativeFct},
+		{ErrInvalidJSONContainsPathType, mysql.ErrJSONBadOneOrAllArg},
+		{ErrDataOutOfRange, mysql.ErrDataOutOfRange},
+		{ErrNoDefaultValue, mysql.ErrNoDefaultForField},
+		{ErrWarnAllowedPacketOverflowed, mysql.ErrWarnAllowedPacketOverflowed},
+		{ErrIllegalMixOfCollations, mysql.ErrCantAggregate2collations},
+		{ErrUnknownCharacterSet, mysql.ErrUnknownCharacterSet},
+		{ErrIncorrectDatetimeValue, mysql.ErrTruncatedWrongValue},
+		{ErrDatetimeFunctionOverflow, mysql.ErrDatetimeFunctionOverflow},
+		{ErrInvalidCharacterString, mysql.ErrInvalidCharacterString},
+		{ErrWrongValueForType, mysql.ErrWrongValueForType},
+		{ErrTruncatedWrongValue, mysql.ErrTruncatedWrongValue},
+		{ErrUnknownCollation, mysql.ErrUnknownCollation},
+		{ErrCollationCharsetMismatch, mysql.ErrCollationCharsetMismatch},
+	}
+	for _, t := range tbl {
+		c.Assert(t.err.ToSQLError().Code, Equals, t.code, Commentf("%v", t.err))
+	}
+
+	err := convertError(errors.New("must error"), "INTEGER", types.NewDatum("a"))
+	c.Assert(terror.ErrorEqual(err, ErrTruncatedWrongValue), IsTrue)
+	c.Assert(err.Error(), Matches, ".*Truncated incorrect INTEGER value: 'a'.*")
+	c.Assert(convertError(nil, "INTEGER", types.NewDatum("a")), IsNil)
+}
+
 func (s *testEvaluatorSuite) TestSleep(c *C) {
 	defer testleak.AfterTest(c)()
 	ctx := mock.NewContext()
@@ -156,6 +193,144 @@ func (s *testEvaluatorSuite) TestBinopComparison(c *C) {
 	}
 }
 
+func (s *testEvaluatorSuite) TestCompareCoercion(c *C) {
+	defer testleak.AfterTest(c)()
+	dt, err := types.ParseTime("2016-01-02 10:00:00", mysql.TypeDatetime, 0)
+	c.Assert(err, IsNil)
+	date, err := types.ParseTime("2016-01-02", mysql.TypeDate, 0)
+	c.Assert(err, IsNil)
+	dur, err := types.ParseDuration("10:00:00", 0)
+	c.Assert(err, IsNil)
+	tbl := []struct {
+		lhs    interface{}
+		op     string
+		rhs    interface{}
+		result interface{}
+	}{
+		// int
+		{int64(-1), ast.LT, uint64(1), 1},
+		{uint64(math.MaxUint64), ast.GT, int64(math.MaxInt64), 1},
+		// decimal
+		{types.NewDecFromStringForTest("1.10"), ast.EQ, types.NewDecFromStringForTest("1.1"), 1},
+		{1, ast.LT, types.NewDecFromStringForTest("1.01"), 1},
+		// real
+		{1.5, ast.GT, 1, 1},
+		{types.NewDecFromStringForTest("1.5"), ast.EQ, 1.5, 1},
+		// strings mixed with numbers are compared as real numbers
+		{"10", ast.GT, 9, 1},
+		{"1e1", ast.EQ, 10, 1},
+		{types.NewDecFromStringForTest("0.5"), ast.EQ, "0.5", 1},
+		// strings are compared as strings
+		{"10", ast.LT, "9", 1},
+		{"abc", ast.EQ, "abc", 1},
+		// temporal values parse the string they are compared with
+		{dt, ast.EQ, "2016-01-02 10:00:00", 1},
+		{"2016-1-2 10:00:00", ast.EQ, dt, 1},
+		{dt, ast.GT, "2016-01-02", 1},
+		{date, ast.EQ, "2016-01-02", 1},
+		{date, ast.LT, dt, 1},
+		{dur, ast.EQ, "10:00:00", 1},
+		{dur, ast.LT, "10:00:01", 1},
+		// NULL
+		{nil, ast.EQ, 1, nil},
+		{1, ast.NE, nil, nil},
+		{nil, ast.LT, "a", nil},
+		{nil, ast.NullEQ, nil, 1},
+		{nil, ast.NullEQ, 1, 0},
+		{1, ast.NullEQ, nil, 0},
+		{1, ast.NullEQ, types.NewDecFromStringForTest("1.0"), 1},
+		{"1", ast.NullEQ, 1, 1},
+	}
+	for _, t := range tbl {
+		d, err := Funcs[t.op].F(types.MakeDatums(t.lhs, t.rhs), s.ctx)
+		c.Assert(err, IsNil, Commentf("%v %s %v", t.lhs, t.op, t.rhs))
+		c.Assert(d, testutil.DatumEquals, types.NewDatum(t.result), Commentf("%v %s %v", t.lhs, t.op, t.rhs))
+	}
+}
+
+func (s *testEvaluatorSuite) TestCompareCollation(c *C) {
+	defer testleak.AfterTest(c)()
+	tbl := []struct {
+		lhs       string
+		op        string
+		rhs       string
+		collation string
+		result    int64
+	}{
+		{"a", ast.EQ, "A", "utf8_general_ci", 1},
+		{"a", ast.EQ, "A", "utf8_unicode_ci", 1},
+		{"a", ast.EQ, "A", "utf8_bin", 0},
+		{"a", ast.EQ, "A", "", 0},
+		{"a", ast.LT, "B", "utf8_general_ci", 1},
+		{"a", ast.LT, "B", "utf8_bin", 0},
+		{"abc", ast.NE, "ABC", "utf8_general_ci", 0},
+		{"abc", ast.GE, "ABD", "utf8_general_ci", 0},
+	}
+	for _, t := range tbl {
+		args := types.MakeDatums(t.lhs, t.rhs)
+		if t.collation != "" {
+			for i := range args {
+				args[i].SetCollation(mysql.CollationNames[t.collation])
+			}
+		}
+		d, err := Funcs[t.op].F(args, s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(d.GetInt64(), Equals, t.result, Commentf("%s %s %s %s", t.lhs, t.op, t.rhs, t.collation))
+	}
+}
+
+func (s *testEvaluatorSuite) TestCompareNullSafe(c *C) {
+	defer testleak.AfterTest(c)()
+	dt, err := types.ParseTime("2016-01-02 10:00:00", mysql.TypeDatetime, 0)
+	c.Assert(err, IsNil)
+	tbl := []struct {
+		lhs       interface{}
+		rhs       interface{}
+		collation string
+		result    int
+	}{
+		{nil, nil, "", 0},
+		{nil, 1, "", -1},
+		{1, nil, "", 1},
+		{nil, "", "", -1},
+		{1, 1, "", 0},
+		{1, 2, "", -1},
+		{uint64(math.MaxUint64), int64(-1), "", 1},
+		{1, types.NewDecFromStringForTest("1.0"), "", 0},
+		{1.5, types.NewDecFromStringForTest("1.5"), "", 0},
+		{"1", 1, "", 0},
+		{"abc", 0, "", 0},
+		{"10", "9", "", -1},
+		{dt, "2016-01-02 10:00:00", "", 0},
+		{"abc", "ABC", "utf8_bin", 1},
+		{"abc", "ABC", "utf8_general_ci", 0},
+		{"abc", "ABD", "utf8_general_ci", -1},
+		{[]byte("abc"), "abc", "binary", 0},
+	}
+	for _, t := range tbl {
+		n := CompareNullSafe(types.NewDatum(t.lhs), types.NewDatum(t.rhs), t.collation)
+		c.Assert(n, Equals, t.result, Commentf("%v <=> %v %s", t.lhs, t.rhs, t.collation))
+		// The comparison is antisymmetric.
+		n = CompareNullSafe(types.NewDatum(t.rhs), types.NewDatum(t.lhs), t.collation)
+		c.Assert(n, Equals, -t.result, Commentf("%v <=> %v %s", t.rhs, t.lhs, t.collation))
+	}
+	// Without a collation, strings are compared under the one they carry, as <=> compares them.
+	a, b := types.NewDatum("abc"), types.NewDatum("ABC")
+	a.SetCollation(mysql.CollationNames["utf8_general_ci"])
+	b.SetCollation(mysql.CollationNames["utf8_general_ci"])
+	c.Assert(CompareNullSafe(a, b, ""), Equals, 0)
+	d, err := Funcs[ast.NullEQ].F([]types.Datum{a, b}, s.ctx)
+	c.Assert(err, IsNil)
+	c.Assert(d.GetInt64(), Equals, int64(1))
+	// Values which can't be compared are ordered by their kinds, then by their encodings.
+	notTime, otherNotTime := types.NewDatum("not a time"), types.NewDatum("other")
+	n := CompareNullSafe(types.NewDatum(dt), notTime, "")
+	c.Assert(n, Not(Equals), 0)
+	c.Assert(CompareNullSafe(notTime, types.NewDatum(dt), ""), Equals, -n)
+	c.Assert(CompareNullSafe(types.NewDatum(dt), otherNotTime, ""), Equals, n)
+	c.Assert(CompareNullSafe(types.NewDatum(dt), types.NewDatum(dt), ""), Equals, 0)
+}
+
 func (s *testEvaluatorSuite) TestBinopLogic(c *C) {
 	defer testleak.AfterTest(c)()
 	tbl := []struct {
@@ -193,6 +368,56 @@ func (s *testEvaluatorSuite) TestBinopLogic(c *C) {
 	}
 }
 
+func (s *testEvaluatorSuite) TestLogicTruthTables(c *C) {
+	defer testleak.AfterTest(c)()
+	// Each operand is one of true, false and NULL, written in a few numeric forms.
+	trues := []interface{}{1, -1, uint64(2), 0.1, types.NewDecFromStringForTest("0.01"), "0.5"}
+	falses := []interface{}{0, uint64(0), 0.0, types.NewDecFromStringForTest("0.00"), "0"}
+	values := func(v interface{}) []interface{} {
+		switch v {
+		case true:
+			return trues
+		case false:
+			return falses
+		}
+		return []interface{}{nil}
+	}
+	truth := []interface{}{true, false, nil}
+
+	binTbl := []struct {
+		op    string
+		table [3][3]interface{}
+	}{
+		// Rows are the left operand and columns are the right one, both ordered as true, false, NULL.
+		{ast.AndAnd, [3][3]interface{}{{1, 0, nil}, {0, 0, 0}, {nil, 0, nil}}},
+		{ast.OrOr, [3][3]interface{}{{1, 1, 1}, {1, 0, nil}, {1, nil, nil}}},
+		{ast.LogicXor, [3][3]interface{}{{0, 1, nil}, {1, 0, nil}, {nil, nil, nil}}},
+	}
+	for _, t := range binTbl {
+		f := Funcs[t.op].F
+		for i, l := range truth {
+			for j, r := range truth {
+				for _, lv := range values(l) {
+					for _, rv := range values(r) {
+						d, err := f(types.MakeDatums(lv, rv), s.ctx)
+						c.Assert(err, IsNil)
+						c.Assert(d, testutil.DatumEquals, types.NewDatum(t.table[i][j]), Commentf("%v %s %v", lv, t.op, rv))
+					}
+				}
+			}
+		}
+	}
+
+	notTbl := []interface{}{0, 1, nil}
+	for i, v := range truth {
+		for _, arg := range values(v) {
+			d, err := Funcs[ast.UnaryNot].F(types.MakeDatums(arg), s.ctx)
+			c.Assert(err, IsNil)
+			c.Assert(d, testutil.DatumEquals, types.NewDatum(notTbl[i]), Commentf("not %v", arg))
+		}
+	}
+}
+
 func (s *testEvaluatorSuite) TestBinopBitop(c *C) {
 	defer testleak.AfterTest(c)()
 	tbl := []struct {
@@ -328,6 +553,150 @@ func (s *testEvaluatorSuite) TestBinopNumeric(c *C) {
 	}
 }
 
+func (s *testEvaluatorSuite) TestArithmeticTypes(c *C) {
+	defer testleak.AfterTest(c)()
+	ctx := mock.NewContext()
+	tbl := []struct {
+		f    BuiltinFunc
+		lhs  interface{}
+		rhs  interface{}
+		kind byte
+		ret  string
+	}{
+		{builtinArithmeticPlus, 1, 2, types.KindInt64, "3"},
+		{builtinArithmeticMinus, 1, uint64(1), types.KindUint64, "0"},
+		{builtinArithmeticPlus, 1, types.NewDecFromStringForTest("1.5"), types.KindMysqlDecimal, "2.5"},
+		{builtinArithmeticPlus, 1, 1.5, types.KindFloat64, "2.5"},
+		{builtinArithmeticMul, 2, 3, types.KindInt64, "6"},
+		{builtinArithmeticMul, types.NewDecFromStringForTest("1.25"), types.NewDecFromStringForTest("0.5"), types.KindMysqlDecimal, "0.625"},
+		{builtinArithmeticMul, types.NewDecFromStringForTest("1.10"), 3, types.KindMysqlDecimal, "3.30"},
+		{builtinArithmeticDiv, 1, 3, types.KindMysqlDecimal, "0.3333"},
+		{builtinArithmeticDiv, types.NewDecFromStringForTest("1.00"), 3, types.KindMysqlDecimal, "0.333333"},
+		{builtinArithmeticDiv, 1.0, 4, types.KindFloat64, "0.25"},
+		{builtinArithmeticDiv, 1, 0, types.KindNull, ""},
+		{builtinArithmeticDiv, types.NewDecFromStringForTest("1.5"), 0, types.KindNull, ""},
+		{builtinArithmeticDiv, 1.5, 0, types.KindNull, ""},
+		{builtinArithmeticDiv, nil, 1, types.KindNull, ""},
+		{builtinArithmeticPlus, 1, nil, types.KindNull, ""},
+	}
+	for _, t := range tbl {
+		d, err := t.f(types.MakeDatums(t.lhs, t.rhs), ctx)
+		c.Assert(err, IsNil)
+		c.Assert(d.Kind(), Equals, t.kind, Commentf("%v %v", t.lhs, t.rhs))
+		if t.kind != types.KindNull {
+			str, err := d.ToString()
+			c.Assert(err, IsNil)
+			c.Assert(str, Equals, t.ret)
+		}
+	}
+
+	// Integer arithmetic stays integer, so it may overflow.
+	_, err := builtinArithmeticPlus(types.MakeDatums(int64(math.MaxInt64), 1), ctx)
+	c.Assert(ErrDataOutOfRange.Equal(err), IsTrue)
+	_, err = builtinArithmeticMinus(types.MakeDatums(1, uint64(2)), ctx)
+	c.Assert(ErrDataOutOfRange.Equal(err), IsTrue)
+
+	// The scale of a quotient follows div_precision_increment.
+	ctx.GetSessionVars().Systems["div_precision_increment"] = "2"
+	d, err := builtinArithmeticDiv(types.MakeDatums(1, 3), ctx)
+	c.Assert(err, IsNil)
+	c.Assert(d.GetMysqlDecimal().String(), Equals, "0.33")
+}
+
+func (s *testEvaluatorSuite) TestHandleOverflow(c *C) {
+	defer testleak.AfterTest(c)()
+	ctx := mock.NewContext()
+	sessVars := ctx.GetSessionVars()
+	castFunc, err := CastFuncFactory(types.NewFieldType(mysql.TypeLonglong))
+	c.Assert(err, IsNil)
+	tbl := []struct {
+		f    BuiltinFunc
+		args []interface{}
+		ret  interface{}
+	}{
+		{Funcs[ast.Abs].F, []interface{}{int64(math.MinInt64)}, nil},
+		{Funcs[ast.Plus].F, []interface{}{int64(math.MaxInt64), 1}, nil},
+		{Funcs[ast.Minus].F, []interface{}{int64(math.MinInt64), 1}, nil},
+		{Funcs[ast.Mul].F, []interface{}{int64(math.MaxInt64), 2}, nil},
+		{Funcs[ast.Minus].F, []interface{}{uint64(1), uint64(2)}, nil},
+		{castFunc, []interface{}{1e30}, int64(math.MaxInt64)},
+	}
+
+	// In strict mode the overflow is an error.
+	sessVars.StrictSQLMode = true
+	for _, t := range tbl {
+		_, err = t.f(types.MakeDatums(t.args...), ctx)
+		c.Assert(ErrDataOutOfRange.Equal(err), IsTrue, Commentf("%v", t.args))
+	}
+	c.Assert(sessVars.StmtCtx.GetWarnings(), HasLen, 0)
+
+	// Otherwise every overflow adds a warning.
+	sessVars.StrictSQLMode = false
+	for i, t := range tbl {
+		d, err := t.f(types.MakeDatums(t.args...), ctx)
+		c.Assert(err, IsNil)
+		c.Assert(d, testutil.DatumEquals, types.NewDatum(t.ret))
+		warnings := sessVars.StmtCtx.GetWarnings()
+		c.Assert(warnings, HasLen, i+1)
+		c.Assert(ErrDataOutOfRange.Equal(warnings[i]), IsTrue)
+	}
+	c.Assert(sessVars.StmtCtx.GetWarnings()[1].Error(), Matches, ".*BIGINT value is out of range in '\\(9223372036854775807 \\+ 1\\)'")
+}
+
+func (s *testEvaluatorSuite) TestDivisionByZero(c *C) {
+	defer testleak.AfterTest(c)()
+	ctx := mock.NewContext()
+	sessVars := ctx.GetSessionVars()
+	tbl := []struct {
+		f    BuiltinFunc
+		args []interface{}
+	}{
+		{Funcs[ast.Div].F, []interface{}{1, 0}},
+		{Funcs[ast.Div].F, []interface{}{1.5, 0}},
+		{Funcs[ast.Div].F, []interface{}{types.NewDecFromStringForTest("1.5"), types.NewDecFromStringForTest("0.0")}},
+		{Funcs[ast.Mod].F, []interface{}{5, 0}},
+		{Funcs[ast.Mod].F, []interface{}{5.5, 0}},
+		{Funcs[ast.IntDiv].F, []interface{}{5, 0}},
+	}
+	check := func(expectErr bool, expectWarnings int) {
+		sessVars.StmtCtx.SetWarnings(nil)
+		for _, t := range tbl {
+			d, err := t.f(types.MakeDatums(t.args...), ctx)
+			if expectErr {
+				c.Assert(terror.ErrorEqual(err, types.ErrDivByZero), IsTrue, Commentf("%v", t.args))
+				continue
+			}
+			c.Assert(err, IsNil, Commentf("%v", t.args))
+			c.Assert(d.IsNull(), IsTrue, Commentf("%v", t.args))
+		}
+		c.Assert(sessVars.StmtCtx.GetWarnings(), HasLen, expectWarnings)
+	}
+
+	// Without ERROR_FOR_DIVISION_BY_ZERO the result is silently NULL.
+	sessVars.StrictSQLMode = true
+	sessVars.StmtCtx.InInsertStmt = true
+	check(false, 0)
+
+	// With it the result is NULL with a warning, unless strict mode applies to an INSERT or UPDATE.
+	sessVars.ErrorForDivisionByZero = true
+	check(true, 0)
+	sessVars.StmtCtx.InInsertStmt = false
+	sessVars.StmtCtx.InUpdateStmt = true
+	check(true, 0)
+	sessVars.StmtCtx.InUpdateStmt = false
+	check(false, len(tbl))
+	sessVars.StrictSQLMode = false
+	sessVars.StmtCtx.InInsertStmt = true
+	check(false, len(tbl))
+	c.Assert(terror.ErrorEqual(sessVars.StmtCtx.GetWarnings()[0], types.ErrDivByZero), IsTrue)
+
+	// A nonzero divisor is not reported.
+	sessVars.StrictSQLMode = true
+	d, err := Funcs[ast.Mod].F(types.MakeDatums(5, 3), ctx)
+	c.Assert(err, IsNil)
+	c.Assert(d.GetInt64(), Equals, int64(2))
+}
+
 func (s *testEvaluatorSuite) TestExtract(c *C) {
 	defer testleak.AfterTest(c)()
 	str := "2011-11-11 10:10:10.123456"
@@ -566,6 +935,33 @@ func (s *testEvaluatorSuite) TestUnaryOp(c *C) {
 	}
 }
 
+func (s *testEvaluatorSuite) TestUnaryMinus(c *C) {
+	defer testleak.AfterTest(c)()
+	tbl := []struct {
+		arg    interface{}
+		result interface{}
+	}{
+		{int64(math.MaxInt64), int64(-math.MaxInt64)},
+		{int64(math.MinInt64 + 1), int64(math.MaxInt64)},
+		{uint64(1 << 63), int64(math.MinInt64)},
+		{float32(1.5), float32(-1.5)},
+		{types.NewDecFromStringForTest("1.50"), types.NewDecFromStringForTest("-1.50")},
+		{types.NewDecFromStringForTest("-9223372036854775808"), types.NewDecFromStringForTest("9223372036854775808")},
+		{"-1.5e1", float64(15)},
+	}
+	for _, t := range tbl {
+		d, err := builtinUnaryMinus(types.MakeDatums(t.arg), s.ctx)
+		c.Assert(err, IsNil)
+		c.Assert(d, testutil.DatumEquals, types.NewDatum(t.result), Commentf("%v", t.arg))
+	}
+
+	// The negation of the smallest BIGINT doesn't fit into a BIGINT.
+	_, err := builtinUnaryMinus(types.MakeDatums(int64(math.MinInt64)), s.ctx)
+	c.Assert(ErrDataOutOfRange.Equal(err), IsTrue)
+	_, err = builtinUnaryMinus(types.MakeDatums(uint64(math.MaxUint64)), s.ctx)
+	c.Assert(ErrDataOutOfRange.Equal(err), IsTrue)
+}
+
 func (s *testEvaluatorSuite) TestMod(c *C) {
 	f := Funcs[ast.Mod]
 	r, err := f.F(types.MakeDatums(234, 10), s.ctx)
@@ -191,7 +191,13 @@ func (b *executorBuilder) buildLimit(v *plan.Limit) Executor {
 }
 
 func (b *executorBuilder) buildDistinct(v *plan.Distinct) Executor {
-	return &DistinctExec{Src: b.build(v.GetChildByIndex(0)), schema: v.GetSchema()}
+	collations := make([]string, len(v.GetSchema()))
+	if proj, ok := v.GetChildByIndex(0).(*plan.Projection); ok {
+		for i, expr := range proj.Exprs {
+			collations[i] = expression.ExplicitCollation(expr)
+		}
+	}
+	return &DistinctExec{Src: b.build(v.GetChildByIndex(0)), schema: v.GetSchema(), collations: collations}
 }
 
 func (b *executorBuilder) buildPrepare(v *plan.Prepare) Executor {
@@ -352,6 +358,14 @@ func (b *executorBuilder) buildUnionScanExec(v *plan.PhysicalUnionScan) Executor
 	return us
 }
 
+// joinKeyType returns the type both sides of the join condition eqCond are converted to before
+// they are hashed, in the collation eqCond compares strings in.
+func joinKeyType(eqCond *expression.ScalarFunction) *types.FieldType {
+	tp := types.NewFieldType(types.MergeFieldType(eqCond.Args[0].GetType().Tp, eqCond.Args[1].GetType().Tp))
+	tp.Collate = eqCond.Collation()
+	return tp
+}
+
 func (b *executorBuilder) buildJoin(v *plan.PhysicalHashJoin) Executor {
 	var leftHashKey, rightHashKey []*expression.Column
 	var targetTypes []*types.FieldType
@@ -360,7 +374,7 @@ func (b *executorBuilder) buildJoin(v *plan.PhysicalHashJoin) Executor {
 		rn, _ := eqCond.Args[1].(*expression.Column)
 		leftHashKey = append(leftHashKey, ln)
 		rightHashKey = append(rightHashKey, rn)
-		targetTypes = append(targetTypes, types.NewFieldType(types.MergeFieldType(ln.GetType().Tp, rn.GetType().Tp)))
+		targetTypes = append(targetTypes, joinKeyType(eqCond))
 	}
 	e := &HashJoinExec{
 		schema:        v.GetSchema(),
@@ -417,7 +431,7 @@ func (b *executorBuilder) buildSemiJoin(v *plan.PhysicalHashSemiJoin) Executor {
 		rn, _ := eqCond.Args[1].(*expression.Column)
 		leftHashKey = append(leftHashKey, ln)
 		rightHashKey = append(rightHashKey, rn)
-		targetTypes = append(targetTypes, types.NewFieldType(types.MergeFieldType(ln.GetType().Tp, rn.GetType().Tp)))
+		targetTypes = append(targetTypes, joinKeyType(eqCond))
 	}
 	e := &HashSemiJoinExec{
 		schema:       v.GetSchema(),
@@ -22,6 +22,7 @@ import (
 	"github.com/juju/errors"
 	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/evaluator"
 	"github.com/pingcap/tidb/expression"
 	"github.com/pingcap/tidb/infoschema"
 	"github.com/pingcap/tidb/inspectkv"
@@ -313,6 +314,27 @@ type orderByRow struct {
 	row *Row
 }
 
+// compareOrderByKey compares two values of the ORDER BY item by. JSON documents are compared in
+// the order MySQL sorts JSON values.
+func compareOrderByKey(sc *variable.StatementContext, by *plan.ByItems, a, b types.Datum) (int, error) {
+	if tp := by.Expr.GetType(); tp != nil && tp.Tp == mysql.TypeJSON {
+		n, err := evaluator.CompareJSON(a, b)
+		return n, errors.Trace(err)
+	}
+	n, err := a.CompareDatum(sc, b)
+	return n, errors.Trace(err)
+}
+
+// collatedDatum returns the sort key of v under collation in place of the string v, so strings
+// equal under the collation fall into the same group and sort next to each other. An empty
+// collation leaves v alone.
+func collatedDatum(v types.Datum, collation string) types.Datum {
+	if k := v.Kind(); collation == "" || (k != types.KindString && k != types.KindBytes) {
+		return v
+	}
+	return types.NewBytesDatum(evaluator.SortKey(v, collation))
+}
+
 // DistinctExec represents Distinct executor.
 // It ignores duplicate rows from source Executor by using a *distinct.Checker which maintains
 // a map to check duplication.
@@ -321,6 +343,8 @@ type DistinctExec struct {
 	Src     Executor
 	checker *distinct.Checker
 	schema  expression.Schema
+	// collations are the collations COLLATE clauses ask for the columns to be deduplicated in.
+	collations []string
 }
 
 // Schema implements the Executor Schema interface.
@@ -341,7 +365,11 @@ func (e *DistinctExec) Next() (*Row, error) {
 		if row == nil {
 			return nil, nil
 		}
-		ok, err := e.checker.Check(types.DatumsToInterfaces(row.Data))
+		keys := make([]types.Datum, len(row.Data))
+		for i, v := range row.Data {
+			keys[i] = collatedDatum(v, e.collations[i])
+		}
+		ok, err := e.checker.Check(types.DatumsToInterfaces(keys))
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
@@ -509,6 +537,7 @@ func getHashKey(sc *variable.StatementContext, cols []*expression.Column, row *R
 				return false, nil, errors.Trace(err)
 			}
 		}
+		vals[i] = collatedDatum(vals[i], targetTypes[i].Collate)
 	}
 	if len(vals) == 0 {
 		return false, nil, nil
@@ -1034,7 +1063,7 @@ func (e *HashAggExec) getGroupKey(row *Row) ([]byte, error) {
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
-		vals = append(vals, v)
+		vals = append(vals, collatedDatum(v, expression.ExplicitCollation(item)))
 	}
 	bs, err := codec.EncodeValue([]byte{}, vals...)
 	if err != nil {
@@ -1169,6 +1198,7 @@ func (e *StreamAggExec) meetNewGroup(row *Row) (bool, error) {
 		if err != nil {
 			return false, errors.Trace(err)
 		}
+		v = collatedDatum(v, expression.ExplicitCollation(item))
 		if matched {
 			c, err := v.CompareDatum(sc, e.curGroupKey[i])
 			if err != nil {
@@ -1492,7 +1522,7 @@ func (e *SortExec) Less(i, j int) bool {
 		v1 := e.Rows[i].key[index]
 		v2 := e.Rows[j].key[index]
 
-		ret, err := v1.CompareDatum(sc, v2)
+		ret, err := compareOrderByKey(sc, by, v1, v2)
 		if err != nil {
 			e.err = errors.Trace(err)
 			return true
@@ -1528,10 +1558,11 @@ func (e *SortExec) Next() (*Row, error) {
 				key: make([]types.Datum, len(e.ByItems)),
 			}
 			for i, byItem := range e.ByItems {
-				orderRow.key[i], err = byItem.Expr.Eval(srcRow.Data, e.ctx)
+				key, err := byItem.Expr.Eval(srcRow.Data, e.ctx)
 				if err != nil {
 					return nil, errors.Trace(err)
 				}
+				orderRow.key[i] = collatedDatum(key, expression.ExplicitCollation(byItem.Expr))
 			}
 			e.Rows = append(e.Rows, orderRow)
 		}
@@ -1565,7 +1596,7 @@ func (e *TopnExec) Less(i, j int) bool {
 		v1 := e.Rows[i].key[index]
 		v2 := e.Rows[j].key[index]
 
-		ret, err := v1.CompareDatum(sc, v2)
+		ret, err := compareOrderByKey(sc, by, v1, v2)
 		if err != nil {
 			e.err = errors.Trace(err)
 			return true
@@ -1623,10 +1654,11 @@ func (e *TopnExec) Next() (*Row, error) {
 				key: make([]types.Datum, len(e.ByItems)),
 			}
 			for i, byItem := range e.ByItems {
-				orderRow.key[i], err = byItem.Expr.Eval(srcRow.Data, e.ctx)
+				key, err := byItem.Expr.Eval(srcRow.Data, e.ctx)
 				if err != nil {
 					return nil, errors.Trace(err)
 				}
+				orderRow.key[i] = collatedDatum(key, expression.ExplicitCollation(byItem.Expr))
 			}
 			if e.totalCount == e.heapSize {
 				// An equivalent of Push and Pop. We don't use the standard Push and Pop
@@ -14,7 +14,6 @@
 package executor
 
 import (
-	"fmt"
 	"strings"
 
 	"github.com/juju/errors"
@@ -74,15 +73,7 @@ func (e *SetExecutor) executeSet() error {
 				return errors.Trace(err)
 			}
 
-			if value.IsNull() {
-				delete(sessionVars.Users, name)
-			} else {
-				svalue, err1 := value.ToString()
-				if err1 != nil {
-					return errors.Trace(err1)
-				}
-				sessionVars.Users[name] = fmt.Sprintf("%v", svalue)
-			}
+			varsutil.SetUserVar(sessionVars, name, value)
 			continue
 		}
 
@@ -21,10 +21,12 @@ import (
 	"testing"
 	"time"
 
+	"github.com/juju/errors"
 	"github.com/ngaut/log"
 	. "github.com/pingcap/check"
 	"github.com/pingcap/tidb"
 	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/evaluator"
 	"github.com/pingcap/tidb/executor"
 	"github.com/pingcap/tidb/inspectkv"
 	"github.com/pingcap/tidb/kv"
@@ -32,7 +34,9 @@ import (
 	"github.com/pingcap/tidb/parser"
 	"github.com/pingcap/tidb/plan"
 	"github.com/pingcap/tidb/sessionctx"
+	"github.com/pingcap/tidb/sessionctx/variable"
 	"github.com/pingcap/tidb/store/tikv"
+	"github.com/pingcap/tidb/terror"
 	"github.com/pingcap/tidb/util/testkit"
 	"github.com/pingcap/tidb/util/testleak"
 	"github.com/pingcap/tidb/util/types"
@@ -1001,6 +1005,29 @@ func (s *testSuite) TestUnsignedPKColumn(c *C) {
 	result.Check(testkit.Rows("1 1 2"))
 }
 
+func (s *testSuite) TestRegisteredFunction(c *C) {
+	defer testleak.AfterTest(c)()
+	tk := testkit.NewTestKit(c, s.store)
+	reverseWords := func(args []types.Datum, _ context.Context) (d types.Datum, err error) {
+		str, err := args[0].ToString()
+		if err != nil {
+			return d, errors.Trace(err)
+		}
+		words := strings.Fields(str)
+		for i, j := 0, len(words)-1; i < j; i, j = i+1, j-1 {
+			words[i], words[j] = words[j], words[i]
+		}
+		d.SetString(strings.Join(words, " "))
+		return d, nil
+	}
+	c.Assert(evaluator.RegisterFunction("reverse_words", evaluator.Func{F: reverseWords, MinArgs: 1, MaxArgs: 1}), IsNil)
+	defer evaluator.UnregisterFunction("reverse_words")
+
+	tk.MustQuery("select reverse_words('a b c'), REVERSE_WORDS('hello world')").Check(testkit.Rows("c b a world hello"))
+	_, err := tk.Exec("select no_such_function(1)")
+	c.Assert(err, NotNil)
+}
+
 func (s *testSuite) TestBuiltin(c *C) {
 	defer func() {
 		s.cleanEnv(c)
@@ -1023,6 +1050,20 @@ func (s *testSuite) TestBuiltin(c *C) {
 	result.Check(nil)
 	result = tk.MustQuery("select * from t where a is not true")
 	result.Check(nil)
+	// for is null and is unknown, which are never NULL
+	result = tk.MustQuery("select null is null, 1 is not null, null is unknown, 0 is not unknown, (null > 1) is not null")
+	result.Check(testkit.Rows("1 1 1 1 0"))
+	// for between, which NULL operands leave unknown unless the other bound decides it
+	result = tk.MustQuery("select 2 between 1 and 3, 4 between 1 and 3, 2 between 3 and 1, 1.5 between 1 and 2, '1.5' between 1 and 2")
+	result.Check(testkit.Rows("1 0 0 1 1"))
+	result = tk.MustQuery("select 2 not between 1 and 3, 4 not between 1 and 3")
+	result.Check(testkit.Rows("0 1"))
+	result = tk.MustQuery("select cast('2016-01-15' as date) between '2016-01-01' and '2016-01-31', cast('2016-02-01' as date) not between '2016-01-01' and '2016-01-31'")
+	result.Check(testkit.Rows("1 1"))
+	result = tk.MustQuery("select null between 1 and 3, 2 between null and 3, 0 between null and -1, 4 between 5 and null, 4 not between null and null")
+	result.Check(testkit.Rows("<nil> <nil> 0 0 <nil>"))
+	result = tk.MustQuery("select a from t where b between 2 and 3 order by a")
+	result.Check(testkit.Rows("2", "3"))
 	// for in
 	result = tk.MustQuery("select * from t where b in (a)")
 	result.Check(testkit.Rows("1 1", "2 2"))
@@ -1069,6 +1110,19 @@ func (s *testSuite) TestBuiltin(c *C) {
 	unixTime = time.Unix(1451606400, 999999000).String()[:26]
 	result.Check(testkit.Rows(unixTime))
 
+	// test the session time zone
+	tk.MustExec("set time_zone = 'America/New_York'")
+	result = tk.MustQuery("select from_unixtime(1451606400), unix_timestamp('2015-12-31 19:00:00')")
+	result.Check(testkit.Rows("2015-12-31 19:00:00 1451606400"))
+	tk.MustExec("set time_zone = '+08:00'")
+	result = tk.MustQuery("select from_unixtime(1451606400), unix_timestamp('2016-01-01 08:00:00.5')")
+	result.Check(testkit.Rows("2016-01-01 08:00:00 1451606400.5"))
+	_, err := tk.Exec("set time_zone = 'Mars/Olympus_Mons'")
+	c.Assert(terror.ErrorEqual(err, variable.ErrUnknownTimeZone), IsTrue)
+	result = tk.MustQuery("select @@time_zone, from_unixtime(1451606400)")
+	result.Check(testkit.Rows("+08:00 2016-01-01 08:00:00"))
+	tk.MustExec("set time_zone = 'SYSTEM'")
+
 	// test strcmp
 	result = tk.MustQuery("select strcmp('abc', 'def')")
 	result.Check(testkit.Rows("-1"))
@@ -1077,6 +1131,431 @@ func (s *testSuite) TestBuiltin(c *C) {
 	result = tk.MustQuery("select strcmp('abc', 'abc')")
 	result.Check(testkit.Rows("0"))
 
+	// test soundex and sounds like
+	result = tk.MustQuery("select soundex('Robert'), soundex('Rupert'), soundex('Quadratically')")
+	result.Check(testkit.Rows("R163 R163 Q36324"))
+	result = tk.MustQuery("select 'Robert' sounds like 'Rupert', 'Robert' sounds like 'Rubin', null sounds like 'a'")
+	result.Check(testkit.Rows("1 0 <nil>"))
+
+	// test encode and decode
+	result = tk.MustQuery("select decode(encode('pingcap', 'pwd'), 'pwd'), decode(encode('pingcap', 'pwd'), 'pwd2') = 'pingcap', encode(null, 'pwd')")
+	result.Check(testkit.Rows(fmt.Sprintf("%v 0 <nil>", []byte("pingcap"))))
+
+	// test timestamp
+	result = tk.MustQuery("select timestamp('2003-12-31'), timestamp('2003-12-31 12:00:00', '12:00:00'), timestamp('abc')")
+	result.Check(testkit.Rows("2003-12-31 00:00:00 2004-01-01 00:00:00 <nil>"))
+
+	// test ord
+	result = tk.MustQuery("select ord('A'), ord('你好'), ascii('你好'), ord(''), ord(null)")
+	result.Check(testkit.Rows("65 14990752 228 0 <nil>"))
+
+	// test locate and instr
+	tk.MustExec("drop table if exists t")
+	tk.MustExec("create table t(a varbinary(10), b varchar(10))")
+	tk.MustExec("insert into t values('xabcy', 'xabcy')")
+	result = tk.MustQuery("select locate('ABC', a), locate('ABC', b), instr(a, 'ABC'), instr(b, 'ABC'), locate('ABC', 'xabcy'), locate(binary 'ABC', 'xabcy') from t")
+	result.Check(testkit.Rows("0 0 0 0 0 0"))
+	result = tk.MustQuery("select locate('ABC' collate utf8_general_ci, b), instr(b, 'ABC' collate utf8_general_ci), locate('ABC', 'xabcy' collate utf8_general_ci, 2) from t")
+	result.Check(testkit.Rows("2 2 2"))
+	result = tk.MustQuery("select 'a' = 'A', locate('A', 'a'), instr('a', 'A'), field('A', 'a')")
+	result.Check(testkit.Rows("0 0 0 0"))
+	result = tk.MustQuery("select locate('bar', 'foobarbar', 0), locate('bar', 'foobarbar', -1), locate('bar', 'foobarbar', 10), locate('bar', 'foobarbar', 5), locate('好', '你好你好', 3), locate('', 'abc', 4), locate('a', 'abc', null)")
+	result.Check(testkit.Rows("0 0 0 7 4 4 <nil>"))
+
+	// test point, st_astext and st_geomfromtext
+	result = tk.MustQuery("select st_astext(point(1, 2.5)), st_astext(st_geomfromtext('point(-3 4)')), st_geomfromtext('linestring(0 0)'), hex(point(1, 2))")
+	result.Check(testkit.Rows("POINT(1 2.5) POINT(-3 4) <nil> 0101000000000000000000F03F0000000000000040"))
+
+	// test any_value
+	result = tk.MustQuery("select any_value(a) = b, length(any_value(b)), count(*) from t group by b")
+	result.Check(testkit.Rows("1 5 1"))
+	result = tk.MustQuery("select any_value(null), any_value(1.5), any_value('x')")
+	result.Check(testkit.Rows("<nil> 1.5 x"))
+
+	// test date_add and date_sub out of range
+	result = tk.MustQuery("select date_add('0000-00-00', interval 1 day), date_add('9999-12-31', interval 1 day), date_sub('0000-01-01', interval 1 day), date_add('9999-12-30', interval 1 day)")
+	result.Check(testkit.Rows("<nil> <nil> <nil> 9999-12-31"))
+
+	// test collation of group by, distinct and order by, which go by the bytes of strings like
+	// indexes do unless a COLLATE clause asks for another collation
+	tk.MustExec("drop table if exists tc")
+	tk.MustExec("create table tc(a varchar(10), b varbinary(10))")
+	tk.MustExec(`insert into tc values ("a", "a"), ("B", "B"), ("A", "A"), ("b", "b")`)
+	result = tk.MustQuery("select count(*) from tc group by a")
+	result.Check(testkit.Rows("1", "1", "1", "1"))
+	result = tk.MustQuery("select count(*) from tc group by a collate utf8_general_ci")
+	result.Check(testkit.Rows("2", "2"))
+	result = tk.MustQuery("select count(*) from tc group by b")
+	result.Check(testkit.Rows("1", "1", "1", "1"))
+	result = tk.MustQuery("select count(distinct a), count(distinct a collate utf8_general_ci), count(distinct b) from tc")
+	result.Check(testkit.Rows("4 2 4"))
+	c.Assert(tk.MustQuery("select distinct a from tc").Rows(), HasLen, 4)
+	c.Assert(tk.MustQuery("select distinct a collate utf8_general_ci from tc").Rows(), HasLen, 2)
+	result = tk.MustQuery("select hex(a) from tc order by a")
+	result.Check(testkit.Rows("41", "42", "61", "62"))
+	result = tk.MustQuery("select upper(a) from tc order by a collate utf8_general_ci, b")
+	result.Check(testkit.Rows("A", "A", "B", "B"))
+	result = tk.MustQuery("select hex(b) from tc order by b")
+	result.Check(testkit.Rows("41", "42", "61", "62"))
+	tk.MustExec("drop table tc")
+	tk.MustExec("create table tc(id int, a varchar(10), index ia(a))")
+	tk.MustExec(`insert into tc values (1, "a"), (2, "B"), (3, "A"), (4, "b")`)
+	result = tk.MustQuery("select count(*) from tc group by a collate utf8_general_ci")
+	result.Check(testkit.Rows("2", "2"))
+	result = tk.MustQuery("select id from tc use index(ia) where a > '' order by a")
+	result.Check(testkit.Rows("3", "2", "1", "4"))
+	result = tk.MustQuery("select upper(a) from tc use index(ia) where a > '' order by a collate utf8_general_ci, id")
+	result.Check(testkit.Rows("A", "A", "B", "B"))
+	result = tk.MustQuery("select id from tc use index(ia) where a > '' order by a collate utf8_general_ci, id")
+	result.Check(testkit.Rows("1", "3", "2", "4"))
+	tk.MustExec("drop table tc")
+
+	// test cast as json
+	result = tk.MustQuery(`select cast('{"b": 1, "a": [1, 2]}' as json), cast(1 as json), cast(null as json), cast('[1, 2]' as json) = cast('[1,2]' as json), cast('"x"' as json) = 'x'`)
+	result.Check(testkit.Rows(`{"a": [1, 2], "b": 1} 1 <nil> 1 1`))
+	rs, err := tk.Exec("select cast('x' as json)")
+	c.Assert(err, IsNil)
+	_, err = rs.Next()
+	c.Assert(evaluator.ErrInvalidJSONText.Equal(err), IsTrue)
+	rs.Close()
+	tk.MustExec("drop table if exists tj")
+	tk.MustExec("create table tj(a varchar(20))")
+	tk.MustExec(`insert into tj values ('true'), ('"b"'), ('[1]'), ('10'), ('{"a": 1}'), ('9'), ('null'), (null)`)
+	result = tk.MustQuery("select cast(a as json) from tj order by cast(a as json)")
+	result.Check(testkit.Rows("<nil>", "null", "9", "10", `"b"`, `{"a": 1}`, "[1]", "true"))
+	result = tk.MustQuery("select cast(a as json) from tj order by cast(a as json) desc limit 2")
+	result.Check(testkit.Rows("true", "[1]"))
+	tk.MustExec("drop table tj")
+
+	// test name_const
+	result = tk.MustQuery("select name_const('x', 14), name_const('y', 'abc') + 1")
+	result.Check(testkit.Rows("14 1"))
+	_, err = tk.Exec("select name_const(a, 1) from t")
+	c.Assert(plan.ErrWrongArguments.Equal(err), IsTrue)
+
+	// test greatest and least
+	tk.MustExec("drop table if exists tg")
+	tk.MustExec("create table tg(d1 date, d2 date, dt datetime)")
+	tk.MustExec("insert into tg values ('2017-01-10', '2017-01-02', '2017-01-09 10:00:00')")
+	result = tk.MustQuery("select greatest(d1, d2), least(d1, dt), greatest(d2, dt), least(d1, d2, null) from tg")
+	result.Check(testkit.Rows("2017-01-10 2017-01-09 10:00:00 2017-01-09 10:00:00 <nil>"))
+	tk.MustExec("drop table tg")
+	result = tk.MustQuery("select greatest(date('2017-01-01'), cast('2017-01-01 10:00:00' as datetime))")
+	result.Check(testkit.Rows("2017-01-01 10:00:00"))
+	result = tk.MustQuery("select greatest(1, null, 3), least(2, 1, null), least(3, 1, 2)")
+	result.Check(testkit.Rows("<nil> <nil> 1"))
+
+	// test lpad and rpad
+	result = tk.MustQuery("select lpad('hi', 5, 'ab'), rpad('a', 3, '你'), lpad('你好', 1, '?'), rpad('hi', -1, '?')")
+	result.Check(testkit.Rows("abahi a你你 你 <nil>"))
+
+	// test format_bytes and format_pico_time
+	result = tk.MustQuery("select format_bytes(512), format_bytes(1536), format_bytes(null), format_pico_time(3501), format_pico_time(1230000000), format_pico_time(null)")
+	result.Check(testkit.Rows("512 bytes 1.50 KiB <nil> 3.50 ns 1.23 ms <nil>"))
+
+	// test the branches not taken are not evaluated
+	result = tk.MustQuery("select if(1, 1, cast('x' as json)), if(null, cast('x' as json), 2), ifnull(3, cast('x' as json)), coalesce(4, cast('x' as json)), case when 1 then 5 else cast('x' as json) end")
+	result.Check(testkit.Rows("1 2 3 4 5"))
+
+	// test cast as char with a charset
+	result = tk.MustQuery("select cast('héllo' as char character set ascii), cast('数据库' as char(3) character set utf8), cast('é' as char(1) charset latin1)")
+	result.Check(testkit.Rows("h?llo 数据库 é"))
+	_, err = tk.Exec("select cast('a' as char character set wrongcharset)")
+	c.Assert(terror.ErrorEqual(err, evaluator.ErrUnknownCharacterSet), IsTrue)
+
+	// test export_set and make_set with the highest bit
+	result = tk.MustQuery("select export_set(5, 'Y', 'N', ',', 4), export_set(6, '1', '0', '', 70) = concat('011', repeat('0', 61)), export_set(null, 'Y', 'N'), make_set(9223372036854775809, 'a', 'b')")
+	result.Check(testkit.Rows("Y,N,Y,N 1 <nil> a"))
+
+	// test repeat with counts out of range
+	result = tk.MustQuery("select repeat('a', 18446744073709551615), repeat('a', -1), repeat('ab', 2)")
+	result.Check(testkit.Rows("<nil>  abab"))
+
+	// test numbers taken as strings at their extremes
+	result = tk.MustQuery("select length(-9223372036854775808), length(18446744073709551615), concat(1e20), length(1e-16), reverse(1.5e15)")
+	result.Check(testkit.Rows("20 20 1e20 5 51e5.1"))
+
+	// test convert
+	result = tk.MustQuery("select convert(x'd6d0cec4' using gbk), convert('数据' using latin1), convert('é' using latin1), convert(12 using ascii), convert(x'ff' using utf8)")
+	result.Check(testkit.Rows("中文 ?? é 12 <nil>"))
+
+	// test collations of comparisons and concat
+	tk.MustExec("drop table if exists tco")
+	tk.MustExec("create table tco(a varchar(10) charset latin1, b varchar(10) charset utf8 collate utf8_general_ci)")
+	tk.MustExec("insert into tco values ('x', 'y')")
+	result = tk.MustQuery("select b = 'y' collate utf8_bin, a = 'x', concat(a, b collate utf8_unicode_ci), coercibility(b collate utf8_bin) from tco")
+	result.Check(testkit.Rows("1 1 xy 0"))
+	_, err = tk.Exec("select 'a' collate utf8_bin = 'a' collate utf8_general_ci")
+	c.Assert(terror.ErrorEqual(err, evaluator.ErrIllegalMixOfCollations), IsTrue)
+	_, err = tk.Exec("select concat(b collate utf8_bin, b collate utf8_unicode_ci) from tco")
+	c.Assert(terror.ErrorEqual(err, evaluator.ErrIllegalMixOfCollations), IsTrue)
+	_, err = tk.Exec("select a collate utf8_bin from tco")
+	c.Assert(terror.ErrorEqual(err, evaluator.ErrCollationCharsetMismatch), IsTrue)
+	_, err = tk.Exec("select 'a' collate utf8_wrong_ci")
+	c.Assert(terror.ErrorEqual(err, evaluator.ErrUnknownCollation), IsTrue)
+
+	// test comparisons of strings, which compare their bytes like indexes, unique keys and the
+	// storage layer do unless a COLLATE clause asks for another collation
+	tk.MustExec("drop table if exists tci, tci2")
+	tk.MustExec("create table tci(id int, c varchar(10), b varchar(10) charset utf8 collate utf8_general_ci, a varchar(10) charset latin1, index ic(c))")
+	tk.MustExec("insert into tci values (1, 'a', 'x', 'X'), (2, 'B', 'Y', 'z')")
+	tk.MustExec("create table tci2(id int, c varchar(10) charset utf8 collate utf8_bin, d varchar(10))")
+	tk.MustExec("insert into tci2 values (1, 'A', 'b'), (2, 'a', 'B')")
+	tk.MustQuery("select id from tci where c = 'A'").Check(testkit.Rows())
+	tk.MustQuery("select id from tci where c = 'a'").Check(testkit.Rows("1"))
+	tk.MustQuery("select id from tci where c = 'A' collate utf8_general_ci").Check(testkit.Rows("1"))
+	tk.MustQuery("select id from tci where c > 'a'").Check(testkit.Rows())
+	tk.MustQuery("select id from tci where c collate utf8_general_ci > 'a'").Check(testkit.Rows("2"))
+	tk.MustQuery("select id from tci where b = a").Check(testkit.Rows())
+	tk.MustQuery("select id from tci where b collate utf8_general_ci = a").Check(testkit.Rows("1"))
+	tk.MustQuery("select id from tci where c in ('A', 'b')").Check(testkit.Rows())
+	tk.MustQuery("select id from tci where c in ('A', 'b' collate utf8_general_ci) order by id").Check(testkit.Rows("1", "2"))
+	tk.MustQuery("select id from tci where c not in ('A') order by id").Check(testkit.Rows("1", "2"))
+	tk.MustQuery("select id from tci where c collate utf8_general_ci not in ('A')").Check(testkit.Rows("2"))
+	tk.MustQuery("select field(c, 'B', 'A'), field(c collate utf8_general_ci, 'B', 'A'), field('1', 1, 2), field(1, '1', '2') from tci where id = 1").Check(testkit.Rows("0 2 1 1"))
+	tk.MustQuery("select id from tci where c <=> 'A'").Check(testkit.Rows())
+	tk.MustQuery("select id from tci where c <=> 'A' collate utf8_general_ci").Check(testkit.Rows("1"))
+	tk.MustQuery("select 'a' collate utf8_general_ci = 'A', 'a' collate utf8_bin = 'A', 'a' = 'A'").Check(testkit.Rows("1 0 0"))
+	tk.MustQuery("select charset(c), collation(c), charset(b), collation(b), collation(c collate utf8_bin), charset(1) from tci where id = 1").Check(testkit.Rows("utf8 utf8_unicode_ci utf8 utf8_general_ci utf8_bin binary"))
+	tk.MustQuery("select charset(convert('abc' using latin1)), collation(convert('abc' using latin1)), charset(convert(c using ascii)), collation(convert(c using utf8mb4)) from tci where id = 1").Check(testkit.Rows("latin1 latin1_swedish_ci ascii utf8mb4_general_ci"))
+	tk.MustQuery("select 'é' = 'e', 'é' collate utf8_general_ci = 'e', hex(weight_string('é'))").Check(testkit.Rows("0 1 0045"))
+	tk.MustQuery("select id from tci2 where c collate utf8_general_ci = 'a' order by id").Check(testkit.Rows("1", "2"))
+	tk.MustQuery("select id from tci where concat(c, 'x') = 'AX'").Check(testkit.Rows())
+	tk.MustQuery("select id from tci where concat(c collate utf8_general_ci, 'x') = 'AX'").Check(testkit.Rows("1"))
+	tk.MustQuery("select strcmp(c, 'A'), strcmp(c collate utf8_general_ci, 'A') from tci where id = 1").Check(testkit.Rows("1 0"))
+	tk.MustQuery("select tci2.id from tci join tci2 on tci.c = tci2.d").Check(testkit.Rows("2"))
+	tk.MustQuery("select tci2.id from tci join tci2 on tci.c collate utf8_general_ci = tci2.d order by tci2.id").Check(testkit.Rows("1", "2"))
+	tk.MustQuery("select tci.id from tci join tci2 on tci.c = tci2.c").Check(testkit.Rows("1"))
+	// A unique index and a lookup through it agree on which strings are equal.
+	tk.MustExec("drop table if exists tu")
+	tk.MustExec("create table tu(id int primary key, u varchar(20), unique index iu(u))")
+	tk.MustExec("insert into tu values (1, 'x'), (2, 'X')")
+	tk.MustQuery("select id from tu where u = 'x'").Check(testkit.Rows("1"))
+	tk.MustQuery("select id from tu use index(iu) where u = 'X'").Check(testkit.Rows("2"))
+	tk.MustQuery("select id from tu where u = 'x' collate utf8_general_ci order by id").Check(testkit.Rows("1", "2"))
+	tk.MustQuery("select id from tu use index(iu) where u in ('X', 'y')").Check(testkit.Rows("2"))
+	tk.MustQuery("select id from tu where u in ('X', 'y' collate utf8_general_ci) order by id").Check(testkit.Rows("1", "2"))
+
+	// test hex of binary strings, blobs and bits
+	tk.MustExec("drop table if exists thex")
+	tk.MustExec("create table thex(vb varbinary(10), bl blob, b bit(16))")
+	tk.MustExec("insert into thex values (unhex('00ff61'), 'ab', 258)")
+	result = tk.MustQuery("select hex(vb), hex(bl), hex(b), unhex(hex(vb)) = vb from thex")
+	result.Check(testkit.Rows("00FF61 6162 102 1"))
+
+	// test trim with an empty remstr
+	result = tk.MustQuery("select concat('[', trim(both '' from ' xx '), ']'), concat('[', trim(leading '' from ' xx '), ']'), concat('[', trim(trailing '' from ' xx '), ']'), concat('[', trim('' from ' xx '), ']')")
+	result.Check(testkit.Rows("[ xx ] [ xx ] [ xx ] [ xx ]"))
+
+	// test concat_ws with numbers
+	tk.MustExec("drop table if exists tcw")
+	tk.MustExec("create table tcw(i int, d decimal(10, 2), f float, db double)")
+	tk.MustExec("insert into tcw values (-3, 2.5, 0.1, 1e20)")
+	result = tk.MustQuery("select concat_ws(',', 1, 2.5), concat_ws(0, 'a', 'b'), concat_ws(',', i, d, f, db), concat_ws(i, 'a', 'b') from tcw")
+	result.Check(testkit.Rows("1,2.5 a0b -3,2.50,0.1,1e20 a-3b"))
+
+	// test the zero date sql modes
+	tk.MustExec("set @@sql_mode = 'NO_ZERO_DATE,NO_ZERO_IN_DATE'")
+	result = tk.MustQuery("select str_to_date('0000-00-00', '%Y-%m-%d'), cast('2020-00-15' as date), date_add('2020-00-15', interval 1 day), cast('2020-01-15' as date)")
+	result.Check(testkit.Rows("<nil> <nil> <nil> 2020-01-15"))
+	tk.MustExec("set @@sql_mode = 'STRICT_TRANS_TABLES,NO_ZERO_DATE'")
+	rs, err = tk.Exec("select cast('0000-00-00' as date)")
+	c.Assert(err, IsNil)
+	_, err = rs.Next()
+	c.Assert(terror.ErrorEqual(err, evaluator.ErrIncorrectDatetimeValue), IsTrue)
+	rs.Close()
+	result = tk.MustQuery("select cast('2020-00-15' as date)")
+	result.Check(testkit.Rows("2020-00-15"))
+	tk.MustExec("set @@sql_mode = 'STRICT_TRANS_TABLES,NO_ENGINE_SUBSTITUTION'")
+	result = tk.MustQuery("select cast('0000-00-00' as date), str_to_date('0000-00-00', '%Y-%m-%d')")
+	result.Check(testkit.Rows("0000-00-00 0000-00-00 00:00:00"))
+
+	// test rounding fractional seconds to a lower precision
+	tk.MustExec("drop table if exists tfsp")
+	tk.MustExec("create table tfsp(dt datetime(6), t time(6))")
+	tk.MustExec("insert into tfsp values ('2017-12-31 23:59:59.999500', '-10:10:10.500000'), ('2017-01-01 10:10:10.123499', '10:10:10.499999')")
+	result = tk.MustQuery("select cast(dt as datetime(3)), cast(dt as datetime), cast(t as time(3)), cast(t as time) from tfsp order by dt")
+	result.Check(testkit.Rows("2017-01-01 10:10:10.123 2017-01-01 10:10:10 10:10:10.500 10:10:10", "2018-01-01 00:00:00.000 2018-01-01 00:00:00 -10:10:10.500 -10:10:11"))
+	tk.MustExec("create table tfsp0(dt datetime(3))")
+	tk.MustExec("insert into tfsp0 select dt from tfsp")
+	result = tk.MustQuery("select dt from tfsp0 order by dt")
+	result.Check(testkit.Rows("2017-01-01 10:10:10.123", "2018-01-01 00:00:00.000"))
+	tk.MustExec("drop table tfsp, tfsp0")
+
+	// test <=> in joins
+	tk.MustExec("drop table if exists tns1, tns2")
+	tk.MustExec("create table tns1(a int)")
+	tk.MustExec("create table tns2(a int)")
+	tk.MustExec("insert into tns1 values (1), (2), (null)")
+	tk.MustExec("insert into tns2 values (1), (3), (null)")
+	result = tk.MustQuery("select tns1.a, tns2.a from tns1 join tns2 on tns1.a <=> tns2.a order by tns1.a")
+	result.Check(testkit.Rows("<nil> <nil>", "1 1"))
+	result = tk.MustQuery("select tns1.a, tns2.a from tns1 join tns2 on tns1.a = tns2.a order by tns1.a")
+	result.Check(testkit.Rows("1 1"))
+	result = tk.MustQuery("select a from tns1 where (a <=> null) in (1) or a <=> 2 order by a")
+	result.Check(testkit.Rows("<nil>", "2"))
+	tk.MustExec("drop table tns1, tns2")
+
+	// test comparing enums and sets
+	tk.MustExec("drop table if exists tes")
+	tk.MustExec("create table tes(e1 enum('z', 'b', 'a'), e2 enum('z', 'b', 'a'), s1 set('z', 'b', 'a'), s2 set('z', 'b', 'a'))")
+	tk.MustExec("insert into tes values ('z', 'a', 'z', 'a'), ('a', 'b', 'a,z', 'b'), ('b', 'b', 'b', 'z,b')")
+	result = tk.MustQuery("select e1 < e2, strcmp(e1, e2), s1 < s2, strcmp(s1, s2), e1 < 'b', strcmp(e1, 'b') from tes order by e1")
+	result.Check(testkit.Rows("1 -1 1 -1 0 1", "0 0 1 -1 0 0", "0 1 0 1 1 -1"))
+	result = tk.MustQuery("select e1 from tes order by e1 desc")
+	result.Check(testkit.Rows("a", "b", "z"))
+	tk.MustExec("drop table tes")
+
+	// test leap years
+	result = tk.MustQuery("select last_day('1900-02-01'), last_day('2000-02-01'), last_day('2100-02-15'), last_day('1900-02-29'), dayofyear('2000-12-31'), dayofyear('2100-12-31'), week('2000-02-29')")
+	result.Check(testkit.Rows("1900-02-28 2000-02-29 2100-02-28 <nil> 366 365 9"))
+
+	// test to_days and from_days
+	result = tk.MustQuery("select to_days('2007-10-07'), to_days(950501), to_days('0000-00-00'), from_days(733321), from_days(365), from_days(to_days('2000-02-29'))")
+	result.Check(testkit.Rows("733321 728779 <nil> 2007-10-07 0000-00-00 2000-02-29"))
+
+	// test bit_count
+	result = tk.MustQuery("select bit_count(0), bit_count(29), bit_count(-1), bit_count(18446744073709551615), bit_count('7'), bit_count(null)")
+	result.Check(testkit.Rows("0 4 64 64 3 <nil>"))
+
+	// test substring with lengths computed from columns
+	tk.MustExec("drop table if exists tsub")
+	tk.MustExec("create table tsub(s varchar(20), n int)")
+	tk.MustExec("insert into tsub values ('Sakila', -1), ('Sakila', 0), ('Sakila', 2), ('Sakila', 100)")
+	result = tk.MustQuery("select substring(s, 2, n), substring(s, 2, n - 2), mid(s, n, 1) from tsub order by n")
+	result.Check(testkit.Rows("  a", "  ", "ak  a", "akila akila "))
+
+	// test upper, lower and reverse of binary strings
+	tk.MustExec("drop table if exists tbin")
+	tk.MustExec("create table tbin(b varbinary(10), s varchar(10))")
+	tk.MustExec("insert into tbin values ('AbC', 'AbC')")
+	result = tk.MustQuery("select upper(b), lower(b), reverse(b), upper(s), lower(s) from tbin")
+	result.Check(testkit.Rows("AbC AbC CbA ABC abc"))
+
+	// test length and char_length of set, enum and bit
+	tk.MustExec("drop table if exists tl")
+	tk.MustExec("create table tl(s set('a', '你好'), e enum('x', '好'), b bit(12))")
+	tk.MustExec("insert into tl values ('a,你好', '好', 5)")
+	result = tk.MustQuery("select length(s), char_length(s), length(e), char_length(e), length(b), char_length(b) from tl")
+	result.Check(testkit.Rows("8 4 3 1 2 2"))
+
+	// test is_uuid, uuid_to_bin and bin_to_uuid
+	result = tk.MustQuery("select is_uuid('6ccd780c-baba-1026-9564-5b8c656024db'), is_uuid('6ccd780c'), hex(uuid_to_bin('6ccd780c-baba-1026-9564-5b8c656024db', 1)), bin_to_uuid(uuid_to_bin('{6CCD780C-BABA-1026-9564-5B8C656024DB}', 1), 1), uuid_to_bin('x')")
+	result.Check(testkit.Rows("1 0 1026BABA6CCD780C95645B8C656024DB 6ccd780c-baba-1026-9564-5b8c656024db <nil>"))
+
+	// test dayofweek, weekday and dayname
+	result = tk.MustQuery("select dayofweek('2017-01-07'), weekday('2017-01-07'), dayname('2017-01-07'), dayofweek('2017-01-08'), weekday('2017-01-08'), dayname('2017-01-08'), dayofweek('2017-00-10')")
+	result.Check(testkit.Rows("7 5 Saturday 1 6 Sunday <nil>"))
+
+	// test adddate and subdate
+	result = tk.MustQuery("select adddate('2011-11-11', 1), subdate('2011-11-11', 1), adddate('2011-11-11', interval 2 month), subdate('2011-11-11 10:00:00', interval 10 hour), adddate('2011-11-11', null)")
+	result.Check(testkit.Rows("2011-11-12 2011-11-10 2012-01-11 2011-11-11 00:00:00 <nil>"))
+	tk.MustExec("drop table if exists tad")
+	tk.MustExec("create table tad(d date, n int)")
+	tk.MustExec("insert into tad values ('2011-11-11', 3)")
+	result = tk.MustQuery("select adddate(d, n), subdate(d, n + 1) from tad")
+	result.Check(testkit.Rows("2011-11-14 2011-11-07"))
+
+	// test time_format
+	result = tk.MustQuery("select time_format('23:12:34.123456', '%H %h %i %s %f %p'), time_format('100:00:01', '%k %r'), time_format(null, '%T')")
+	result.Check(testkit.Rows("23 11 12 34 123456 PM 100 04:00:01 AM <nil>"))
+
+	// test isnull
+	result = tk.MustQuery("select isnull(null), isnull(1), isnull(0), isnull(''), isnull(1/0), isnull(null + 1), isnull(a) from t")
+	result.Check(testkit.Rows("1 0 0 0 1 1 0"))
+
+	// test round, format and cast as decimal agree on halves
+	result = tk.MustQuery("select round(2.5), round(-2.5), round(1.005, 2), round(15, -1), format(2.5, 0), format(-1.005, 2), cast(2.5 as decimal(2, 0)), cast(1.005 as decimal(4, 2))")
+	result.Check(testkit.Rows("3 -3 1.01 20 3 -1.01 3 1.01"))
+	result = tk.MustQuery("select format(1234567.891, 2), format(12, 3), format(null, 1)")
+	result.Check(testkit.Rows("1,234,567.89 12.000 <nil>"))
+
+	// test replace
+	result = tk.MustQuery("select replace('Abc', 'a', 'x'), replace(b, 'A', 'x'), replace(b, '', 'x'), replace('数据库', '库', 'DB') from t")
+	result.Check(testkit.Rows("Abc xabcy xabcy 数据DB"))
+
+	// test field, elt and make_set
+	result = tk.MustQuery("select field('1', 1, 2), field(1, '1', '2'), field('abc', 0), field(null, null), elt(2, 'a', 'b'), elt(3, 'a', 'b'), make_set(5, 'a', 'b', 'c')")
+	result.Check(testkit.Rows("1 1 1 0 b <nil> a,c"))
+	result = tk.MustQuery("select elt(0, 'a'), elt(null, 'a'), field(3, 1, 2), field(null, 1), interval(3, 1, 2), interval(null, 1)")
+	result.Check(testkit.Rows("<nil> <nil> 0 0 2 -1"))
+	_, err = tk.Exec("select elt(1)")
+	c.Assert(terror.ErrorEqual(err, evaluator.ErrIncorrectParameterCount), IsTrue)
+
+	// test substring from for
+	for _, ca := range []struct {
+		keywordForm string
+		commaForm   string
+		result      string
+	}{
+		{"'Quadratically' from 5", "'Quadratically', 5", "ratically"},
+		{"'Sakila' from -3", "'Sakila', -3", "ila"},
+		{"'Sakila' from -5 for 3", "'Sakila', -5, 3", "aki"},
+		{"'Quadratically' from 5 for 6", "'Quadratically', 5, 6", "ratica"},
+		{"'Sakila' from -1000 for 3", "'Sakila', -1000, 3", ""},
+		{"'你好世界' from -3 for 2", "'你好世界', -3, 2", "好世"},
+		{"null from 2", "null, 2", "<nil>"},
+	} {
+		result = tk.MustQuery(fmt.Sprintf("select substring(%s), substring(%s)", ca.keywordForm, ca.commaForm))
+		result.Check(testkit.Rows(ca.result + " " + ca.result))
+	}
+
+	// test mid
+	result = tk.MustQuery("select mid('Quadratically', 5, 6), mid('Sakila', -3), mid('Sakila', -5, 3), mid('你好世界', 2), mid(null, 1, 1)")
+	result.Check(testkit.Rows("ratica ila aki 好世界 <nil>"))
+
+	// test substr, substring and mid agree
+	result = tk.MustQuery("select substr('Quadratically', 5, 6), substr('Sakila' from -5 for 3), substr('Sakila' from -3), substr('你好世界', 2), substr(null, 1)")
+	result.Check(testkit.Rows("ratica aki ila 好世界 <nil>"))
+	result = tk.MustQuery("select substr(s, 2, n) = substring(s, 2, n), substr(s, -n) = mid(s, -n), substr(s from 2 for n) = substring(s from 2 for n) from tsub")
+	result.Check(testkit.Rows("1 1 1", "1 1 1", "1 1 1", "1 1 1"))
+
+	// test left, right and substring
+	result = tk.MustQuery("select left('你好abc', 2), right('你好abc', 4), substring('你好abc', 2, 2), hex(left(binary '你好', 1)), hex(substring(binary '你好', 2, 2))")
+	result.Check(testkit.Rows("你好 好abc 好a E4 BDA0"))
+
+	// test octet_length and char_length
+	result = tk.MustQuery("select octet_length('你好'), char_length('你好'), character_length(x'e4bda0'), length(x'e4bda0')")
+	result.Check(testkit.Rows("6 2 3 3"))
+
+	// test trim
+	result = tk.MustQuery("select trim('x' from 'xxyyxx'), trim(both 'x' from 'xxyyxx'), trim(leading from '  bar '), trim(null from 'x')")
+	result.Check(testkit.Rows("yy yy bar  <nil>"))
+	result = tk.MustQuery("select trim(leading null from 'x'), trim(trailing null from 'x'), trim(both null from 'x'), concat('[', trim(trailing from '  bar '), ']')")
+	result.Check(testkit.Rows("<nil> <nil> <nil> [  bar]"))
+
+	// test sec_to_time
+	result = tk.MustQuery("select sec_to_time(2378), sec_to_time(2378.5), sec_to_time(null)")
+	result.Check(testkit.Rows("00:39:38 00:39:38.5 <nil>"))
+
+	// test get_format
+	result = tk.MustQuery("select get_format(date, 'usa'), date_format('2003-10-03', get_format(date, 'eur')), get_format(time, 'xyz')")
+	result.Check(testkit.Rows("%m.%d.%Y 03.10.2003 <nil>"))
+
+	// test period_add, period_diff and quarter
+	result = tk.MustQuery("select period_add(801, 2), period_diff(200802, 200703), quarter('2008-04-01')")
+	result.Check(testkit.Rows("200803 11 2"))
+
+	// test convert_tz
+	result = tk.MustQuery("select convert_tz('2004-01-01 12:00:00', '+00:00', '+10:00'), convert_tz('2004-01-01 12:00:00', 'UTC', 'Europe/Paris'), convert_tz('2004-01-01 12:00:00', 'UTC', 'abc')")
+	result.Check(testkit.Rows("2004-01-01 22:00:00 2004-01-01 13:00:00 <nil>"))
+
+	// test addtime and subtime
+	result = tk.MustQuery("select addtime('2003-12-31 23:59:59', '0:00:01'), subtime('20:00:00', '30:00:00'), addtime('abc', '1:00:00')")
+	result.Check(testkit.Rows("2004-01-01 00:00:00 -10:00:00 <nil>"))
+
+	// test interval
+	result = tk.MustQuery("select interval(23, 1, 15, 17, 30, 44, 200), interval(10, 1, 10, 100), interval(null, 1)")
+	result.Check(testkit.Rows("3 2 -1"))
+
+	// test random_bytes
+	result = tk.MustQuery("select length(random_bytes(16)), random_bytes(16) = random_bytes(16), random_bytes(null)")
+	result.Check(testkit.Rows("16 0 <nil>"))
+
+	// test weight_string
+	result = tk.MustQuery("select weight_string('ab '), weight_string('ab' as char(3)), weight_string('ab' as binary(3))")
+	result.Check(testkit.Rows(fmt.Sprintf("%v %v %v", []byte{0, 'A', 0, 'B'}, []byte{0, 'A', 0, 'B', 0, ' '}, []byte{'a', 'b', 0})))
+
 	// for case
 	tk.MustExec("drop table if exists t")
 	tk.MustExec("create table t (a varchar(255), b int)")
@@ -1288,6 +1767,24 @@ func (s *testSuite) TestSQLMode(c *C) {
 	c.Check(err, NotNil)
 	// Restore original global strict mode.
 	tk.MustExec("set @@global.sql_mode = 'STRICT_TRANS_TABLES'")
+
+	// ERROR_FOR_DIVISION_BY_ZERO makes a division by zero an error of a strict INSERT or UPDATE,
+	// and a warning otherwise.
+	tk.MustExec("create table td (a int)")
+	tk.MustExec("insert td values (1 / 0), (mod(5, 0))")
+	c.Assert(tk.Se.(context.Context).GetSessionVars().StmtCtx.GetWarnings(), HasLen, 0)
+	tk.MustExec("set sql_mode = 'STRICT_TRANS_TABLES,ERROR_FOR_DIVISION_BY_ZERO'")
+	_, err = tk.Exec("insert td values (1 / 0)")
+	c.Check(terror.ErrorEqual(err, types.ErrDivByZero), IsTrue)
+	_, err = tk.Exec("update td set a = 5 % 0")
+	c.Check(terror.ErrorEqual(err, types.ErrDivByZero), IsTrue)
+	tk.MustQuery("select 1 / 0, mod(5, 0), 5 div 0").Check(testkit.Rows("<nil> <nil> <nil>"))
+	c.Assert(tk.Se.(context.Context).GetSessionVars().StmtCtx.GetWarnings(), HasLen, 3)
+	tk.MustExec("set sql_mode = 'ERROR_FOR_DIVISION_BY_ZERO'")
+	tk.MustExec("insert td values (1 / 0)")
+	c.Assert(tk.Se.(context.Context).GetSessionVars().StmtCtx.GetWarnings(), HasLen, 1)
+	tk.MustQuery("select count(*) from td where a is null").Check(testkit.Rows("3"))
+	tk.MustExec("set sql_mode = 'STRICT_TRANS_TABLES'")
 }
 
 func (s *testSuite) TestSubquery(c *C) {
@@ -1484,10 +1981,32 @@ func (s *testSuite) TestSelectVar(c *C) {
 	tk.MustExec("drop table if exists t")
 	tk.MustExec("create table t (d int)")
 	tk.MustExec("insert into t values(1), (2), (1)")
+	// An assignment is seen by the references evaluated after it, in the same statement too.
 	result := tk.MustQuery("select @a, @a := d+1 from t")
-	result.Check(testkit.Rows("<nil> 2", "<nil> 3", "<nil> 2"))
+	result.Check(testkit.Rows("<nil> 2", "2 3", "3 2"))
 	result = tk.MustQuery("select @a, @a := d+1 from t")
 	result.Check(testkit.Rows("2 2", "2 3", "3 2"))
+	result = tk.MustQuery("select (@b := 5) + @b, @b")
+	result.Check(testkit.Rows("10 5"))
+
+	// User variables keep the type and the letter case of their value.
+	tk.MustExec("set @i = 10, @s = 'AbC', @f = 1.5e0, @dec = 1.50, @n = null")
+	result = tk.MustQuery("select @i / 4, @s, @f, @dec, @n, @i + 1 = 11")
+	result.Check(testkit.Rows("2.5000 AbC 1.5 1.50 <nil> 1"))
+	result = tk.MustQuery("select @c := 'XyZ', @c")
+	result.Check(testkit.Rows("XyZ XyZ"))
+
+	// System variables are read when the statement is executed.
+	tk.MustExec("set @@session.low_priority_updates = 'ON', @@global.low_priority_updates = 'OFF'")
+	result = tk.MustQuery("select @@low_priority_updates, @@session.low_priority_updates, @@global.low_priority_updates, @unset_var")
+	result.Check(testkit.Rows("ON ON OFF <nil>"))
+	tk.MustExec("prepare stmt from 'select @@low_priority_updates, @@global.low_priority_updates'")
+	tk.MustQuery("execute stmt").Check(testkit.Rows("ON OFF"))
+	tk.MustExec("set @@session.low_priority_updates = 'OFF', @@global.low_priority_updates = 'ON'")
+	tk.MustQuery("execute stmt").Check(testkit.Rows("OFF ON"))
+	tk.MustExec("set @@global.low_priority_updates = 'OFF'")
+	_, err := tk.Exec("select @@no_such_variable")
+	c.Assert(terror.ErrorEqual(err, variable.UnknownSystemVar), IsTrue)
 }
 
 func (s *testSuite) TestHistoryRead(c *C) {
@@ -120,6 +120,18 @@ func (s *testSuite) TestInsert(c *C) {
 	rowStr = fmt.Sprintf("%v %v %v %v", "1", "1", "10", "6")
 	r.Check(testkit.Rows(rowStr))
 
+	tk.MustExec("create table insert_dup (id int primary key, c1 int default 5, c2 int)")
+	tk.MustExec("insert into insert_dup values (1, 1, 1)")
+	tk.MustExec("insert into insert_dup values (1, 2, 3) on duplicate key update c1 = default(c1) + 1, c2 = values(c1)")
+	r = tk.MustQuery("select * from insert_dup")
+	r.Check(testkit.Rows("1 6 2"))
+	r = tk.MustQuery("select default(c1), values(c1) from insert_dup")
+	r.Check(testkit.Rows("5 <nil>"))
+	r = tk.MustQuery("select default(c1), default(x.c1) from insert_dup as x")
+	r.Check(testkit.Rows("5 5"))
+	r = tk.MustQuery("select default(x.c1), default(y.c2) from insert_dup x join insert_dup y")
+	r.Check(testkit.Rows("5 <nil>"))
+
 	tk.MustExec("create table insert_err (id int, c1 varchar(8))")
 	_, err = tk.Exec("insert insert_err values (1, 'abcdabcdabcd')")
 	c.Assert(types.ErrDataTooLong.Equal(err), IsTrue)
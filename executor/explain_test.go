@@ -26,9 +26,10 @@ func (s *testSuite) TestExplain(c *C) {
 	}()
 	tk := testkit.NewTestKit(c, s.store)
 	tk.MustExec("use test")
-	tk.MustExec("drop table if exists t1, t2")
+	tk.MustExec("drop table if exists t1, t2, t3")
 	tk.MustExec("create table t1 (c1 int primary key, c2 int, c3 int, index c2 (c2))")
 	tk.MustExec("create table t2 (c1 int unique, c2 int)")
+	tk.MustExec("create table t3 (c1 int primary key, c2 varchar(20), index c2 (c2))")
 
 	cases := []struct {
 		sql       string
@@ -437,6 +438,93 @@ func (s *testSuite) TestExplain(c *C) {
             "gt(test.t1.c2, 1)"
         ]
     }
+}`,
+			},
+		},
+		{
+			"select * from t1 where c2 = @@auto_increment_increment",
+			[]string{
+				"IndexScan_5",
+			},
+			[]string{
+				"",
+			},
+			[]string{
+				`{
+    "db": "test",
+    "table": "t1",
+    "index": "c2",
+    "ranges": "[[1,1]]",
+    "desc": false,
+    "out of order": true,
+    "double read": true,
+    "push down info": {
+        "limit": 0,
+        "access conditions": [
+            "eq(test.t1.c2, 1)"
+        ],
+        "index filter conditions": null,
+        "table filter conditions": null
+    }
+}`,
+			},
+		},
+		{
+			"select * from t3 order by c2",
+			[]string{
+				"IndexScan_5",
+			},
+			[]string{
+				"",
+			},
+			[]string{
+				`{
+    "db": "test",
+    "table": "t3",
+    "index": "c2",
+    "ranges": "[[\u003cnil\u003e,+inf]]",
+    "desc": false,
+    "out of order": false,
+    "double read": false,
+    "push down info": {
+        "limit": 0,
+        "access conditions": null,
+        "index filter conditions": null,
+        "table filter conditions": null
+    }
+}`,
+			},
+		},
+		{
+			"select * from t3 order by c2 collate utf8_general_ci",
+			[]string{
+				"TableScan_4", "Sort_3",
+			},
+			[]string{
+				"Sort_3", "",
+			},
+			[]string{
+				`{
+    "db": "test",
+    "table": "t3",
+    "desc": false,
+    "keep order": false,
+    "push down info": {
+        "limit": 0,
+        "access conditions": null,
+        "index filter conditions": null,
+        "table filter conditions": null
+    }
+}`,
+				`{
+    "exprs": [
+        {
+            "Expr": "setcollation(t3.c2, utf8_general_ci)",
+            "Desc": false
+        }
+    ],
+    "limit": null,
+    "child": "TableScan_4"
 }`,
 			},
 		},
@@ -23,6 +23,7 @@ import (
 	"github.com/ngaut/log"
 	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/evaluator"
 	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/util/charset"
 	"github.com/pingcap/tidb/util/distinct"
@@ -402,6 +403,16 @@ func (cf *countFunction) GetType() *types.FieldType {
 	return ft
 }
 
+// distinctValue returns the value by which a distinct aggregate function tells the values of arg
+// apart, for a string its sort key under the collation a COLLATE clause asks for.
+func distinctValue(value types.Datum, arg Expression) interface{} {
+	collation := ExplicitCollation(arg)
+	if k := value.Kind(); collation != "" && (k == types.KindString || k == types.KindBytes) {
+		return evaluator.SortKey(value, collation)
+	}
+	return value.GetValue()
+}
+
 // Update implements AggregationFunction interface.
 func (cf *countFunction) Update(row []types.Datum, groupKey []byte, ectx context.Context) error {
 	ctx := cf.getContext(groupKey)
@@ -421,7 +432,7 @@ func (cf *countFunction) Update(row []types.Datum, groupKey []byte, ectx context
 			ctx.Count += value.GetInt64()
 		}
 		if cf.Distinct {
-			vals = append(vals, value.GetValue())
+			vals = append(vals, distinctValue(value, a))
 		}
 	}
 	if cf.Distinct {
@@ -455,7 +466,7 @@ func (cf *countFunction) StreamUpdate(row []types.Datum, ectx context.Context) e
 			return nil
 		}
 		if cf.Distinct {
-			vals = append(vals, value.GetValue())
+			vals = append(vals, distinctValue(value, a))
 		}
 	}
 	if cf.Distinct {
@@ -36,7 +36,9 @@ func FoldConstant(ctx context.Context, expr Expression) Expression {
 		foldedArg := FoldConstant(ctx, args[i])
 		scalarFunc.Args[i] = foldedArg
 		if con, ok := foldedArg.(*Constant); ok {
-			datums = append(datums, con.Value)
+			d := con.Value
+			setCollation(&d, scalarFunc.argCollation(con))
+			datums = append(datums, d)
 		} else {
 			canFold = false
 		}
@@ -18,9 +18,12 @@ import (
 	"fmt"
 
 	"github.com/juju/errors"
+	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/context"
 	"github.com/pingcap/tidb/evaluator"
 	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/util/charset"
 	"github.com/pingcap/tidb/util/codec"
 	"github.com/pingcap/tidb/util/types"
 )
@@ -33,6 +36,12 @@ type ScalarFunction struct {
 	RetType   *types.FieldType
 	Function  evaluator.BuiltinFunc
 	ArgValues []types.Datum
+	// lazyFunction, if set, is called by Eval instead of Function with the unevaluated Args.
+	lazyFunction evaluator.LazyBuiltinFunc
+	// collation is the collation derived for the string arguments of the functions in
+	// evaluator.CollationFuncs, a binary one unless a COLLATE clause asks for another one, empty
+	// if they aren't all strings.
+	collation string
 }
 
 // String implements fmt.Stringer interface.
@@ -60,18 +69,91 @@ func NewFunction(funcName string, retType *types.FieldType, args ...Expression)
 	if !ok {
 		return nil, errors.Errorf("Function %s is not implemented.", funcName)
 	}
-	if len(args) < f.MinArgs || (f.MaxArgs != -1 && len(args) > f.MaxArgs) {
-		return nil, evaluator.ErrInvalidOperation.Gen("number of function arguments must in [%d, %d].",
-			f.MinArgs, f.MaxArgs)
+	if err := f.CheckArgCount(funcName, len(args)); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if funcName == ast.Coercibility {
+		return &Constant{
+			Value:   types.NewIntDatum(int64(Coercibility(args[0]))),
+			RetType: retType,
+		}, nil
+	}
+	var collation string
+	if _, ok := evaluator.CollationFuncs[funcName]; ok {
+		var err error
+		strArgs := args
+		if funcName == ast.Locate {
+			// The position LOCATE() starts from takes no part.
+			strArgs = args[:2]
+		}
+		collation, err = deriveCollation(funcName, strArgs)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
 	}
 	funcArgs := make([]Expression, len(args))
 	copy(funcArgs, args)
+	fn := f.F
+	if isJSON, ok := jsonArgs(args); ok {
+		if jsonFn, ok := evaluator.JSONCompareFuncFactory(funcName, isJSON); ok {
+			fn = jsonFn
+		}
+	}
 	return &ScalarFunction{
-		Args:      funcArgs,
-		FuncName:  model.NewCIStr(funcName),
-		RetType:   retType,
-		Function:  f.F,
-		ArgValues: make([]types.Datum, len(funcArgs))}, nil
+		Args:         funcArgs,
+		FuncName:     model.NewCIStr(funcName),
+		RetType:      retType,
+		Function:     fn,
+		ArgValues:    make([]types.Datum, len(funcArgs)),
+		lazyFunction: evaluator.LazyFuncs[funcName],
+		collation:    collation}, nil
+}
+
+// deriveCollation returns the collation the function fn compares its string args in, empty if
+// some of them aren't strings, so that they are compared as numbers or temporal values.
+// Indexes, unique keys and the storage layer compare strings by their bytes, so strings are
+// compared as binary unless a COLLATE clause asks for another collation.
+func deriveCollation(fn string, args []Expression) (string, error) {
+	operands := make([]evaluator.CollationOperand, 0, len(args))
+	for _, arg := range args {
+		tp := arg.GetType()
+		if tp == nil || tp.Tp == mysql.TypeNull {
+			continue
+		}
+		if !types.IsTypeString(tp.Tp) || tp.Charset == "" {
+			return "", nil
+		}
+		operands = append(operands, evaluator.CollationOperand{
+			Charset:      tp.Charset,
+			Collate:      tp.Collate,
+			Coercibility: Coercibility(arg),
+		})
+	}
+	co, err := evaluator.AggregateCollation(fn, operands...)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	if co.Coercibility != evaluator.CoercibilityExplicit {
+		// The _bin collation of the charset compares bytes like binary does, but leaves the
+		// arguments strings of characters, for the functions which count them.
+		if bin := co.Charset + "_bin"; mysql.CollationNames[bin] != 0 {
+			return bin, nil
+		}
+		return charset.CollationBin, nil
+	}
+	return co.Collate, nil
+}
+
+// jsonArgs tells which of args are JSON documents, and whether any of them is.
+func jsonArgs(args []Expression) ([]bool, bool) {
+	isJSON := make([]bool, len(args))
+	var hasJSON bool
+	for i, arg := range args {
+		if tp := arg.GetType(); tp != nil && tp.Tp == mysql.TypeJSON {
+			isJSON[i], hasJSON = true, true
+		}
+	}
+	return isJSON, hasJSON
 }
 
 //ScalarFuncs2Exprs converts []*ScalarFunction to []Expression.
@@ -86,10 +168,12 @@ func ScalarFuncs2Exprs(funcs []*ScalarFunction) []Expression {
 // Clone implements Expression interface.
 func (sf *ScalarFunction) Clone() Expression {
 	newFunc := &ScalarFunction{
-		FuncName:  sf.FuncName,
-		Function:  sf.Function,
-		RetType:   sf.RetType,
-		ArgValues: make([]types.Datum, len(sf.Args))}
+		FuncName:     sf.FuncName,
+		Function:     sf.Function,
+		RetType:      sf.RetType,
+		ArgValues:    make([]types.Datum, len(sf.Args)),
+		lazyFunction: sf.lazyFunction,
+		collation:    sf.collation}
 	newFunc.Args = make([]Expression, 0, len(sf.Args))
 	for _, arg := range sf.Args {
 		newFunc.Args = append(newFunc.Args, arg.Clone())
@@ -102,6 +186,12 @@ func (sf *ScalarFunction) GetType() *types.FieldType {
 	return sf.RetType
 }
 
+// Collation returns the collation the function compares its string arguments in, empty if it
+// doesn't compare strings.
+func (sf *ScalarFunction) Collation() string {
+	return sf.collation
+}
+
 // Equal implements Expression interface.
 func (sf *ScalarFunction) Equal(e Expression, ctx context.Context) bool {
 	fun, ok := e.(*ScalarFunction)
@@ -142,16 +232,70 @@ func (sf *ScalarFunction) Decorrelate(schema Schema) Expression {
 
 // Eval implements Expression interface.
 func (sf *ScalarFunction) Eval(row []types.Datum, ctx context.Context) (types.Datum, error) {
+	if sf.lazyFunction != nil {
+		return sf.lazyFunction(&lazyArgs{sf: sf, row: row, ctx: ctx}, ctx)
+	}
 	var err error
 	for i, arg := range sf.Args {
 		sf.ArgValues[i], err = arg.Eval(row, ctx)
 		if err != nil {
 			return types.Datum{}, errors.Trace(err)
 		}
+		setCollation(&sf.ArgValues[i], sf.argCollation(arg))
 	}
 	return sf.Function(sf.ArgValues, ctx)
 }
 
+// lazyArgs evaluates the arguments of a lazy function on the row as the function asks for them.
+type lazyArgs struct {
+	sf  *ScalarFunction
+	row []types.Datum
+	ctx context.Context
+}
+
+// Len implements evaluator.LazyArgs interface.
+func (a *lazyArgs) Len() int {
+	return len(a.sf.Args)
+}
+
+// Eval implements evaluator.LazyArgs interface.
+func (a *lazyArgs) Eval(i int) (types.Datum, error) {
+	arg := a.sf.Args[i]
+	d, err := arg.Eval(a.row, a.ctx)
+	if err != nil {
+		return d, errors.Trace(err)
+	}
+	setCollation(&d, a.sf.argCollation(arg))
+	return d, nil
+}
+
+// argCollation returns the collation of the string argument arg: the one derived for the
+// arguments of the function if it compares them, the one of the type of arg otherwise.
+func (sf *ScalarFunction) argCollation(arg Expression) string {
+	if sf.collation != "" {
+		return sf.collation
+	}
+	if tp := arg.GetType(); tp != nil {
+		return tp.Collate
+	}
+	return ""
+}
+
+// setCollation attaches the collation resolved for a string argument to its
+// value, so builtins like CHARSET() and COLLATION() can see it. The collation
+// resolved wins over a collation the value carries from its own arguments.
+func setCollation(d *types.Datum, collation string) {
+	if collation == "" {
+		return
+	}
+	if k := d.Kind(); k != types.KindString && k != types.KindBytes {
+		return
+	}
+	if id, ok := mysql.CollationNames[collation]; ok {
+		d.SetCollation(id)
+	}
+}
+
 // HashCode implements Expression interface.
 func (sf *ScalarFunction) HashCode() []byte {
 	var bytes []byte
@@ -172,3 +316,34 @@ func (sf *ScalarFunction) ResolveIndices(schema Schema) {
 		arg.ResolveIndices(schema)
 	}
 }
+
+// Coercibility returns the collation coercibility of expr: columns are implicit,
+// literals are coercible, NULL is ignorable and a function takes the coercibility
+// evaluator.FuncCoercibility derives from its arguments.
+func Coercibility(expr Expression) int {
+	switch x := expr.(type) {
+	case *Column, *CorrelatedColumn:
+		return evaluator.CoercibilityImplicit
+	case *Constant:
+		return evaluator.ValueCoercibility(x.Value)
+	case *ScalarFunction:
+		argTypes := make([]*types.FieldType, len(x.Args))
+		for i, arg := range x.Args {
+			argTypes[i] = arg.GetType()
+		}
+		return evaluator.FuncCoercibility(x.FuncName.L, argTypes, func(i int) int {
+			return Coercibility(x.Args[i])
+		})
+	}
+	return evaluator.CoercibilityCoercible
+}
+
+// ExplicitCollation returns the collation a COLLATE clause asks the string expr to be grouped,
+// deduplicated and sorted in, empty if there's none and expr goes by its bytes like indexes do.
+func ExplicitCollation(expr Expression) string {
+	tp := expr.GetType()
+	if tp == nil || !types.IsTypeString(tp.Tp) || Coercibility(expr) != evaluator.CoercibilityExplicit {
+		return ""
+	}
+	return tp.Collate
+}
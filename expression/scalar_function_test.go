@@ -0,0 +1,199 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expression
+
+import (
+	"errors"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/evaluator"
+	"github.com/pingcap/tidb/model"
+	"github.com/pingcap/tidb/mysql"
+	"github.com/pingcap/tidb/util/charset"
+	"github.com/pingcap/tidb/util/mock"
+	"github.com/pingcap/tidb/util/testleak"
+	"github.com/pingcap/tidb/util/testutil"
+	"github.com/pingcap/tidb/util/types"
+)
+
+func (*testExpressionSuite) TestCharsetAndCollation(c *C) {
+	defer testleak.AfterTest(c)()
+	newStringColumn := func(name string, index int, cs, co string) *Column {
+		tp := types.NewFieldType(mysql.TypeVarchar)
+		tp.Charset, tp.Collate = cs, co
+		return &Column{
+			FromID:  name,
+			ColName: model.NewCIStr(name),
+			TblName: model.NewCIStr("t"),
+			DBName:  model.NewCIStr("test"),
+			RetType: tp,
+			Index:   index,
+		}
+	}
+	// t(a varchar charset latin1, b varchar charset utf8 collate utf8_bin, c varbinary, d bigint)
+	cols := []Expression{
+		newStringColumn("a", 0, "latin1", "latin1_swedish_ci"),
+		newStringColumn("b", 1, charset.CharsetUTF8, "utf8_bin"),
+		newStringColumn("c", 2, charset.CharsetBin, charset.CollationBin),
+		newColumn("d"),
+	}
+	cols[3].(*Column).Index = 3
+	row := types.MakeDatums("a", "b", []byte("c"), 1)
+	literal := &Constant{Value: types.NewStringDatum("abc"), RetType: types.NewFieldType(mysql.TypeVarString)}
+	types.DefaultTypeForValue(literal.Value.GetValue(), literal.RetType)
+
+	tbl := []struct {
+		arg       Expression
+		charset   string
+		collation string
+	}{
+		{cols[0], "latin1", "latin1_swedish_ci"},
+		{cols[1], "utf8", "utf8_bin"},
+		{cols[2], "binary", "binary"},
+		{cols[3], "binary", "binary"},
+		{literal, "utf8", "utf8_general_ci"},
+	}
+	ctx := mock.NewContext()
+	for _, t := range tbl {
+		f, err := NewFunction(ast.Charset, types.NewFieldType(mysql.TypeVarString), t.arg)
+		c.Assert(err, IsNil)
+		d, err := f.Eval(row, ctx)
+		c.Assert(err, IsNil)
+		c.Assert(d, testutil.DatumEquals, types.NewDatum(t.charset))
+
+		f, err = NewFunction(ast.Collation, types.NewFieldType(mysql.TypeVarString), t.arg)
+		c.Assert(err, IsNil)
+		d, err = f.Eval(row, ctx)
+		c.Assert(err, IsNil)
+		c.Assert(d, testutil.DatumEquals, types.NewDatum(t.collation))
+	}
+}
+
+func (*testExpressionSuite) TestCoercibility(c *C) {
+	defer testleak.AfterTest(c)()
+	strType := types.NewFieldType(mysql.TypeVarString)
+	strCol := newColumn("a")
+	strCol.RetType = strType
+	x := &Constant{Value: types.NewDatum("x"), RetType: strType}
+	user, err := NewFunction(ast.User, strType)
+	c.Assert(err, IsNil)
+	concat, err := NewFunction(ast.Concat, strType, strCol, x)
+	c.Assert(err, IsNil)
+	collate, err := NewFunction(ast.SetCollation, strType, strCol, &Constant{Value: types.NewDatum("utf8_bin")})
+	c.Assert(err, IsNil)
+	// The index of ELT() isn't a string, it takes no part.
+	elt, err := NewFunction(ast.Elt, strType, newColumn("b"), x)
+	c.Assert(err, IsNil)
+	tbl := []struct {
+		arg    Expression
+		expect int64
+	}{
+		{&Constant{Value: types.NewDatum("abc")}, 4},
+		{newLonglong(1), 4},
+		{&Constant{Value: types.Datum{}}, 5},
+		{strCol, 2},
+		{user, 3},
+		{concat, 2},
+		{collate, 0},
+		{elt, 4},
+	}
+	ctx := mock.NewContext()
+	for _, t := range tbl {
+		f, err := NewFunction(ast.Coercibility, types.NewFieldType(mysql.TypeLonglong), t.arg)
+		c.Assert(err, IsNil)
+		d, err := f.Eval(nil, ctx)
+		c.Assert(err, IsNil)
+		c.Assert(d, testutil.DatumEquals, types.NewDatum(t.expect))
+	}
+}
+
+func (*testExpressionSuite) TestArgCount(c *C) {
+	defer testleak.AfterTest(c)()
+	arg := &Constant{Value: types.NewStringDatum("abc"), RetType: types.NewFieldType(mysql.TypeVarString)}
+	args := func(n int) []Expression {
+		res := make([]Expression, n)
+		for i := range res {
+			res[i] = arg
+		}
+		return res
+	}
+	tp := types.NewFieldType(mysql.TypeVarString)
+	tbl := []struct {
+		funcName string
+		valid    []int
+		invalid  []int
+	}{
+		{ast.Substring, []int{2, 3}, []int{1, 4}},
+		{ast.Concat, []int{1, 2, 10}, []int{0}},
+		{ast.Trim, []int{1, 2, 3}, []int{0, 4}},
+	}
+	for _, t := range tbl {
+		for _, n := range t.valid {
+			_, err := NewFunction(t.funcName, tp, args(n)...)
+			c.Assert(err, IsNil, Commentf("%s with %d arguments", t.funcName, n))
+		}
+		for _, n := range t.invalid {
+			_, err := NewFunction(t.funcName, tp, args(n)...)
+			c.Assert(evaluator.ErrIncorrectParameterCount.Equal(err), IsTrue, Commentf("%s with %d arguments", t.funcName, n))
+			c.Assert(err.Error(), Matches, ".*Incorrect parameter count in the call to native function '"+t.funcName+"'")
+		}
+	}
+}
+
+func (*testExpressionSuite) TestLazyFunction(c *C) {
+	defer testleak.AfterTest(c)()
+	typeLong := types.NewFieldType(mysql.TypeLonglong)
+	// fail stands for an argument that must not be evaluated.
+	fail := &ScalarFunction{
+		FuncName: model.NewCIStr("fail"),
+		RetType:  typeLong,
+		Function: func(_ []types.Datum, _ context.Context) (types.Datum, error) {
+			return types.Datum{}, errors.New("must not be evaluated")
+		},
+	}
+	null := &Constant{Value: types.Datum{}, RetType: typeLong}
+	tbl := []struct {
+		funcName string
+		args     []Expression
+		ret      interface{}
+	}{
+		{ast.If, []Expression{newLonglong(1), newLonglong(1), fail}, int64(1)},
+		{ast.If, []Expression{newLonglong(0), fail, newLonglong(2)}, int64(2)},
+		{ast.If, []Expression{null, fail, newLonglong(3)}, int64(3)},
+		{ast.Ifnull, []Expression{newLonglong(1), fail}, int64(1)},
+		{ast.Ifnull, []Expression{null, newLonglong(2)}, int64(2)},
+		{ast.Coalesce, []Expression{null, newLonglong(2), fail}, int64(2)},
+		{ast.Case, []Expression{newLonglong(0), fail, newLonglong(1), newLonglong(5), fail}, int64(5)},
+		{ast.Case, []Expression{null, fail, newLonglong(0), fail, newLonglong(6)}, int64(6)},
+		{ast.Case, []Expression{newLonglong(0), fail}, nil},
+	}
+	ctx := mock.NewContext()
+	for _, t := range tbl {
+		f, err := NewFunction(t.funcName, typeLong, t.args...)
+		c.Assert(err, IsNil)
+		d, err := f.Eval(nil, ctx)
+		c.Assert(err, IsNil, Commentf("%s", f))
+		c.Assert(d, testutil.DatumEquals, types.NewDatum(t.ret), Commentf("%s", f))
+	}
+
+	// The argument taken is still evaluated.
+	f, err := NewFunction(ast.If, typeLong, newLonglong(1), fail, newLonglong(1))
+	c.Assert(err, IsNil)
+	_, err = f.Eval(nil, ctx)
+	c.Assert(err, NotNil)
+	_, err = f.Clone().Eval(nil, ctx)
+	c.Assert(err, NotNil)
+}
@@ -883,3 +883,10 @@ const (
 	ErrRowInWrongPartition                                          = 1863
 	ErrErrorLast                                                    = 1863
 )
+
+// MySQL 5.7 error codes of the JSON functions.
+const (
+	ErrInvalidJSONText    uint16 = 3140
+	ErrInvalidJSONPath           = 3143
+	ErrJSONBadOneOrAllArg        = 3154
+)
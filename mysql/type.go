@@ -34,6 +34,9 @@ const (
 	TypeBit
 )
 
+// TypeJSON is the type code of JSON.
+const TypeJSON byte = 0xf5
+
 // TypeUnspecified is an uninitialized type. TypeDecimal is not used in MySQL.
 var TypeUnspecified = TypeDecimal
 
@@ -17,6 +17,7 @@ import (
 	"bytes"
 	"strings"
 
+	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/util/charset"
 	"github.com/pingcap/tidb/util/hack"
 )
@@ -138,6 +139,7 @@ var tokenMap = map[string]int{
 	"ABS":                 abs,
 	"ADD":                 add,
 	"ADDDATE":             addDate,
+	"ADDTIME":             addTime,
 	"ADMIN":               admin,
 	"AFTER":               after,
 	"ALL":                 all,
@@ -182,6 +184,7 @@ var tokenMap = map[string]int{
 	"CONCAT_WS":           concatWs,
 	"CONNECTION":          connection,
 	"CONNECTION_ID":       connectionID,
+	"CONVERT_TZ":          convertTz,
 	"CONSTRAINT":          constraint,
 	"CONSISTENT":          consistent,
 	"CONVERT":             convert,
@@ -190,6 +193,8 @@ var tokenMap = map[string]int{
 	"CROSS":               cross,
 	"CURDATE":             curDate,
 	"UTC_DATE":            utcDate,
+	"UTC_TIME":            utcTime,
+	"UTC_TIMESTAMP":       utcTimestamp,
 	"CURRENT_DATE":        currentDate,
 	"CURTIME":             curTime,
 	"CURRENT_TIME":        currentTime,
@@ -207,6 +212,7 @@ var tokenMap = map[string]int{
 	"DAYOFYEAR":           dayofyear,
 	"DDL":                 ddl,
 	"DEALLOCATE":          deallocate,
+	"DECODE":              decode,
 	"DEFAULT":             defaultKwd,
 	"DELAYED":             delayed,
 	"DELAY_KEY_WRITE":     delayKeyWrite,
@@ -224,6 +230,7 @@ var tokenMap = map[string]int{
 	"ELSE":                elseKwd,
 	"ENABLE":              enable,
 	"ENCLOSED":            enclosed,
+	"ENCODE":              encode,
 	"END":                 end,
 	"ENGINE":              engine,
 	"ENGINES":             engines,
@@ -249,6 +256,7 @@ var tokenMap = map[string]int{
 	"FULLTEXT":            fulltext,
 	"FUNCTION":            function,
 	"FLUSH":               flush,
+	"GET_FORMAT":          getFormat,
 	"GET_LOCK":            getLock,
 	"GLOBAL":              global,
 	"GRANT":               grant,
@@ -278,6 +286,7 @@ var tokenMap = map[string]int{
 	"ISNULL":              isNull,
 	"ISOLATION":           isolation,
 	"JOIN":                join,
+	"JSON":                jsonType,
 	"KEY":                 key,
 	"KEY_BLOCK_SIZE":      keyBlockSize,
 	"KEYS":                keys,
@@ -321,6 +330,7 @@ var tokenMap = map[string]int{
 	"NULL":                null,
 	"NULLIF":              nullIf,
 	"OFFSET":              offset,
+	"OLD_PASSWORD":        oldPassword,
 	"ON":                  on,
 	"ONLY":                only,
 	"OPTION":              option,
@@ -328,6 +338,8 @@ var tokenMap = map[string]int{
 	"ORDER":               order,
 	"OUTER":               outer,
 	"PASSWORD":            password,
+	"PERIOD_ADD":          periodAdd,
+	"PERIOD_DIFF":         periodDiff,
 	"POW":                 pow,
 	"POWER":               power,
 	"PREPARE":             prepare,
@@ -339,6 +351,7 @@ var tokenMap = map[string]int{
 	"QUICK":               quick,
 	"RANGE":               rangeKwd,
 	"RAND":                rand,
+	"RANDOM_BYTES":        randomBytes,
 	"READ":                read,
 	"REDUNDANT":           redundant,
 	"REFERENCES":          references,
@@ -358,6 +371,7 @@ var tokenMap = map[string]int{
 	"SCHEMA":              schema,
 	"SCHEMAS":             schemas,
 	"SECOND":              second,
+	"SEC_TO_TIME":         secToTime,
 	"SELECT":              selectKwd,
 	"SERIALIZABLE":        serializable,
 	"SESSION":             session,
@@ -368,6 +382,8 @@ var tokenMap = map[string]int{
 	"SIGNED":              signed,
 	"SNAPSHOT":            snapshot,
 	"SOME":                some,
+	"SOUNDEX":             soundex,
+	"SOUNDS":              sounds,
 	"SPACE":               space,
 	"START":               start,
 	"STARTING":            starting,
@@ -379,6 +395,7 @@ var tokenMap = map[string]int{
 	"SUBSTR":              substring,
 	"SUBSTRING":           substring,
 	"SUBSTRING_INDEX":     substringIndex,
+	"SUBTIME":             subTime,
 	"SUM":                 sum,
 	"SYSDATE":             sysDate,
 	"TABLE":               tableKwd,
@@ -415,6 +432,7 @@ var tokenMap = map[string]int{
 	"WEEK":                week,
 	"WEEKDAY":             weekday,
 	"WEEKOFYEAR":          weekofyear,
+	"WEIGHT_STRING":       weightString,
 	"WHEN":                when,
 	"WHERE":               where,
 	"WITH":                with,
@@ -480,6 +498,44 @@ var tokenMap = map[string]int{
 	"PARTITION":           partition,
 	"PARTITIONS":          partitions,
 	"RPAD":                rpad,
+	"ANY_VALUE":           anyValue,
+	"BIN_TO_UUID":         binToUUID,
+	"BIT_COUNT":           bitCount,
+	"CHAR_LENGTH":         charLength,
+	"CHARACTER_LENGTH":    characterLength,
+	"COERCIBILITY":        coercibility,
+	"ELT":                 elt,
+	"EXPORT_SET":          exportSet,
+	"FIELD":               fieldFunc,
+	"FORMAT":              formatFunc,
+	"FORMAT_BYTES":        formatBytes,
+	"FORMAT_PICO_TIME":    formatPicoTime,
+	"FROM_DAYS":           fromDays,
+	"INSTR":               instr,
+	"IS_UUID":             isUUID,
+	"JSON_ARRAY_APPEND":   jsonArrayAppend,
+	"JSON_ARRAY_INSERT":   jsonArrayInsert,
+	"JSON_CONTAINS":       jsonContains,
+	"JSON_CONTAINS_PATH":  jsonContainsPath,
+	"JSON_MERGE":          jsonMerge,
+	"JSON_MERGE_PRESERVE": jsonMergePreserve,
+	"JSON_TYPE":           jsonTypeFunc,
+	"JSON_VALID":          jsonValid,
+	"LAST_DAY":            lastDay,
+	"LEAST":               least,
+	"LPAD":                lpad,
+	"MAKE_SET":            makeSet,
+	"MID":                 mid,
+	"NAME_CONST":          nameConst,
+	"OCTET_LENGTH":        octetLength,
+	"ORD":                 ord,
+	"POINT":               pointFunc,
+	"ST_ASTEXT":           stAsText,
+	"ST_GEOMFROMTEXT":     stGeomFromText,
+	"TIME_FORMAT":         timeFormat,
+	"TO_DAYS":             toDays,
+	"UNIX_TIMESTAMP":      unixTimestamp,
+	"UUID_TO_BIN":         uuidToBin,
 }
 
 func isTokenIdentifier(s string, buf *bytes.Buffer) int {
@@ -494,6 +550,10 @@ func isTokenIdentifier(s string, buf *bytes.Buffer) int {
 		}
 	}
 	tok := tokenMap[hack.String(data)]
+	if tok == 0 && ast.IsRegisteredFuncName(s) {
+		// A function added by evaluator.RegisterFunction, see ast.RegisterFuncName.
+		tok = registeredFunc
+	}
 	return tok
 }
 
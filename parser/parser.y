@@ -200,6 +200,8 @@ import (
 	use		"USE"
 	using		"USING"
 	utcDate 	"UTC_DATE"
+	utcTime		"UTC_TIME"
+	utcTimestamp	"UTC_TIMESTAMP"
 	values		"VALUES"
 	varcharType	"VARCHAR"
 	varbinaryType	"VARBINARY"
@@ -214,6 +216,7 @@ import (
 	/* the following tokens belong to NotKeywordToken*/
 	abs		"ABS"
 	addDate		"ADDDATE"
+	addTime		"ADDTIME"
 	admin		"ADMIN"
 	ceil		"CEIL"
 	ceiling		"CEILING"
@@ -221,6 +224,7 @@ import (
 	concat		"CONCAT"
 	concatWs	"CONCAT_WS"
 	connectionID 	"CONNECTION_ID"
+	convertTz	"CONVERT_TZ"
 	curTime 	"CUR_TIME"
 	count		"COUNT"
 	day		"DAY"
@@ -231,6 +235,8 @@ import (
 	dayofmonth	"DAYOFMONTH"
 	dayofweek	"DAYOFWEEK"
 	dayofyear	"DAYOFYEAR"
+	decode		"DECODE"
+	encode		"ENCODE"
 	events		"EVENTS"
 	foundRows	"FOUND_ROWS"
 	fromUnixTime	"FROM_UNIXTIME"
@@ -260,17 +266,25 @@ import (
 	month		"MONTH"
 	monthname	"MONTHNAME"
 	now		"NOW"
+	oldPassword	"OLD_PASSWORD"
+	periodAdd	"PERIOD_ADD"
+	periodDiff	"PERIOD_DIFF"
 	pow 		"POW"
 	power 		"POWER"
 	rand		"RAND"
+	randomBytes	"RANDOM_BYTES"
+	registeredFunc	"registered function name"
 	second		"SECOND"
+	secToTime	"SEC_TO_TIME"
 	sleep		"SLEEP"
+	soundex		"SOUNDEX"
 	calcFoundRows	"SQL_CALC_FOUND_ROWS"
 	strcmp		"STRCMP"
 	strToDate	"STR_TO_DATE"
 	subDate		"SUBDATE"
 	substring	"SUBSTRING"
 	substringIndex	"SUBSTRING_INDEX"
+	subTime		"SUBTIME"
 	sum		"SUM"
 	sysDate		"SYSDATE"
 	timediff	"TIMEDIFF"
@@ -280,13 +294,53 @@ import (
 	upper 		"UPPER"
 	version		"VERSION"
 	weekday		"WEEKDAY"
+	weightString	"WEIGHT_STRING"
 	weekofyear	"WEEKOFYEAR"
 	yearweek	"YEARWEEK"
 	round		"ROUND"
 	statsPersistent	"STATS_PERSISTENT"
+	getFormat	"GET_FORMAT"
 	getLock		"GET_LOCK"
 	releaseLock	"RELEASE_LOCK"
 	rpad		"RPAD"
+	anyValue	"ANY_VALUE"
+	binToUUID	"BIN_TO_UUID"
+	bitCount	"BIT_COUNT"
+	charLength	"CHAR_LENGTH"
+	characterLength	"CHARACTER_LENGTH"
+	coercibility	"COERCIBILITY"
+	elt		"ELT"
+	exportSet	"EXPORT_SET"
+	fieldFunc	"FIELD"
+	formatFunc	"FORMAT"
+	formatBytes	"FORMAT_BYTES"
+	formatPicoTime	"FORMAT_PICO_TIME"
+	fromDays	"FROM_DAYS"
+	instr		"INSTR"
+	isUUID		"IS_UUID"
+	jsonArrayAppend	"JSON_ARRAY_APPEND"
+	jsonArrayInsert	"JSON_ARRAY_INSERT"
+	jsonContains	"JSON_CONTAINS"
+	jsonContainsPath	"JSON_CONTAINS_PATH"
+	jsonMerge	"JSON_MERGE"
+	jsonMergePreserve	"JSON_MERGE_PRESERVE"
+	jsonTypeFunc	"JSON_TYPE"
+	jsonValid	"JSON_VALID"
+	lastDay		"LAST_DAY"
+	least		"LEAST"
+	lpad		"LPAD"
+	makeSet		"MAKE_SET"
+	mid		"MID"
+	nameConst	"NAME_CONST"
+	octetLength	"OCTET_LENGTH"
+	ord		"ORD"
+	pointFunc	"POINT"
+	stAsText	"ST_ASTEXT"
+	stGeomFromText	"ST_GEOMFROMTEXT"
+	timeFormat	"TIME_FORMAT"
+	toDays		"TO_DAYS"
+	unixTimestamp	"UNIX_TIMESTAMP"
+	uuidToBin	"UUID_TO_BIN"
 
 	/* the following tokens belong to UnReservedKeyword*/
 	action		"ACTION"
@@ -341,6 +395,7 @@ import (
 	identified	"IDENTIFIED"
 	isolation	"ISOLATION"
 	indexes		"INDEXES"
+	jsonType	"JSON"
 	keyBlockSize	"KEY_BLOCK_SIZE"
 	local		"LOCAL"
 	less		"LESS"
@@ -371,6 +426,7 @@ import (
 	share		"SHARE"
 	signed		"SIGNED"
 	snapshot	"SNAPSHOT"
+	sounds		"SOUNDS"
 	space 		"SPACE"
 	sqlCache	"SQL_CACHE"
 	sqlNoCache	"SQL_NO_CACHE"
@@ -465,7 +521,6 @@ import (
 	CreateTableStmt		"CREATE TABLE statement"
 	CreateUserStmt		"CREATE User statement"
 	DateArithOpt		"Date arith dateadd or datesub option"
-	DateArithMultiFormsOpt	"Date arith adddate or subdate option"
 	DateArithInterval       "Date arith interval part"
 	DBName			"Database Name"
 	DeallocateStmt		"Deallocate prepared statement"
@@ -669,6 +724,7 @@ import (
 	LengthNum		"Field length num(uint64)"
 
 %type	<ident>
+	DateArithMultiFormsOpt	"Date arith adddate or subdate option"
 	KeyOrIndex		"{KEY|INDEX}"
 	ColumnKeywordOpt	"Column keyword or empty"
 	PrimaryOpt		"Optional primary keyword"
@@ -680,6 +736,7 @@ import (
 	IntoOpt			"INTO or EmptyString"
 	ValueSym		"Value or Values"
 	TimeUnit		"Time unit"
+	GetFormatSelector	"{DATE|DATETIME|TIME|TIMESTAMP}"
 	DeallocateSym		"Deallocate or drop"
 	OuterOpt		"optional OUTER clause"
 	CrossOpt		"Cross join option"
@@ -735,7 +792,7 @@ import (
 %left 	andand and
 %left 	between
 %precedence	lowerThanEq
-%left 	eq ge le neq neqSynonym '>' '<' is like in
+%left 	eq ge le neq neqSynonym '>' '<' is like in sounds
 %left 	'|'
 %left 	'&'
 %left 	rsh lsh
@@ -1713,7 +1770,7 @@ logAnd:
 "&&" | "AND"
 
 ExpressionList:
-	Expression
+	Expression %prec lowerThanComma
 	{
 		$$ = []ast.ExprNode{$1.(ast.ExprNode)}
 	}
@@ -1845,7 +1902,16 @@ PredicateExpr:
 	{
 		$$ = &ast.PatternRegexpExpr{Expr: $1.(ast.ExprNode), Pattern: $4.(ast.ExprNode), Not: $2.(bool)}
 	}
-|	PrimaryFactor
+|	PrimaryFactor "SOUNDS" "LIKE" PrimaryExpression
+	{
+		// See https://dev.mysql.com/doc/refman/5.7/en/string-functions.html#operator_sounds-like
+		$$ = &ast.BinaryOperationExpr{
+			Op:	opcode.EQ,
+			L:	&ast.FuncCallExpr{FnName: model.NewCIStr(ast.Soundex), Args: []ast.ExprNode{$1.(ast.ExprNode)}},
+			R:	&ast.FuncCallExpr{FnName: model.NewCIStr(ast.Soundex), Args: []ast.ExprNode{$4.(ast.ExprNode)}},
+		}
+	}
+|	PrimaryFactor %prec lowerThanEq
 
 RegexpSym:
 "REGEXP" | "RLIKE"
@@ -2056,7 +2122,8 @@ UnReservedKeyword:
 | "COLLATION" | "COMMENT" | "AVG_ROW_LENGTH" | "CONNECTION" | "CHECKSUM" | "COMPRESSION" | "KEY_BLOCK_SIZE" | "MAX_ROWS"
 | "MIN_ROWS" | "NATIONAL" | "ROW" | "ROW_FORMAT" | "QUARTER" | "GRANTS" | "TRIGGERS" | "DELAY_KEY_WRITE" | "ISOLATION"
 | "REPEATABLE" | "COMMITTED" | "UNCOMMITTED" | "ONLY" | "SERIALIZABLE" | "LEVEL" | "VARIABLES" | "SQL_CACHE" | "INDEXES" | "PROCESSLIST"
-| "SQL_NO_CACHE" | "DISABLE"  | "ENABLE" | "REVERSE" | "SPACE" | "PRIVILEGES" | "NO" | "BINLOG" | "FUNCTION" | "VIEW" | "MODIFY" | "EVENTS" | "PARTITIONS"
+| "SQL_NO_CACHE" | "DISABLE"  | "ENABLE" | "REVERSE" | "SPACE" | "SOUNDS" | "PRIVILEGES" | "NO" | "BINLOG" | "FUNCTION" | "VIEW" | "MODIFY" | "EVENTS" | "PARTITIONS"
+| "JSON"
 
 ReservedKeyword:
 "ADD" | "ALL" | "ALTER" | "ANALYZE" | "AND" | "AS" | "ASC" | "BETWEEN" | "BIGINT"
@@ -2076,7 +2143,7 @@ ReservedKeyword:
 | "SCHEMA" | "SCHEMAS" | "SECOND_MICROSECOND" | "SELECT" | "SET" | "SHOW" | "SMALLINT"
 | "STARTING" | "TABLE" | "TERMINATED" | "THEN" | "TINYBLOB" | "TINYINT" | "TINYTEXT" | "TO"
 | "TRAILING" | "TRUE" | "UNION" | "UNIQUE" | "UNLOCK" | "UNSIGNED"
-| "UPDATE" | "USE" | "USING" | "UTC_DATE" | "VALUES" | "VARBINARY" | "VARCHAR"
+| "UPDATE" | "USE" | "USING" | "UTC_DATE" | "UTC_TIME" | "UTC_TIMESTAMP" | "VALUES" | "VARBINARY" | "VARCHAR"
 | "WHEN" | "WHERE" | "WRITE" | "XOR" | "YEAR_MONTH" | "ZEROFILL"
  /*
 | "DELAYED" | "HIGH_PRIORITY" | "LOW_PRIORITY"| "WITH"
@@ -2085,12 +2152,17 @@ ReservedKeyword:
 
 NotKeywordToken:
 	"ABS" | "ADDDATE" | "ADMIN" | "COALESCE" | "CONCAT" | "CONCAT_WS" | "CONNECTION_ID" | "CUR_TIME"| "COUNT" | "DAY"
-|	"DATE_ADD" | "DATE_FORMAT" | "DATE_SUB" | "DAYNAME" | "DAYOFMONTH" | "DAYOFWEEK" | "DAYOFYEAR" | "FOUND_ROWS"
+|	"DATE_ADD" | "DATE_FORMAT" | "DATE_SUB" | "DAYNAME" | "DAYOFMONTH" | "DAYOFWEEK" | "DAYOFYEAR" | "DECODE" | "ENCODE" | "FOUND_ROWS"
 |	"GROUP_CONCAT"| "GREATEST" | "HOUR" | "HEX" | "UNHEX" | "IFNULL" | "ISNULL" | "LAST_INSERT_ID" | "LCASE" | "LENGTH" | "LOCATE" | "LOWER" | "LTRIM"
-|	"MAX" | "MICROSECOND" | "MIN" |	"MINUTE" | "NULLIF" | "MONTH" | "MONTHNAME" | "NOW" | "POW" | "POWER" | "RAND"
-|	"SECOND" | "SLEEP" | "SQL_CALC_FOUND_ROWS" | "STR_TO_DATE" | "SUBDATE" | "SUBSTRING" %prec lowerThanLeftParen |
-"SUBSTRING_INDEX" | "SUM" | "TRIM" | "RTRIM" | "UCASE" | "UPPER" | "VERSION" | "WEEKDAY" | "WEEKOFYEAR" | "YEARWEEK" | "ROUND"
+|	"MAX" | "MICROSECOND" | "MIN" |	"MINUTE" | "NULLIF" | "MONTH" | "MONTHNAME" | "NOW" | "OLD_PASSWORD" | "POW" | "POWER" | "RAND" | "RANDOM_BYTES"
+|	"SECOND" | "SLEEP" | "SOUNDEX" | "SQL_CALC_FOUND_ROWS" | "STR_TO_DATE" | "SUBDATE" | "SUBSTRING" %prec lowerThanLeftParen |
+"SUBSTRING_INDEX" | "SUM" | "TRIM" | "RTRIM" | "UCASE" | "UPPER" | "VERSION" | "WEEKDAY" | "WEEKOFYEAR" | "WEIGHT_STRING" | "YEARWEEK" | "ROUND"
 |	"STATS_PERSISTENT" | "GET_LOCK" | "RELEASE_LOCK" | "CEIL" | "CEILING" | "FROM_UNIXTIME" | "TIMEDIFF" | "LN" | "LOG" | "LOG2" | "LOG10"
+|	"ADDTIME" | "SUBTIME" | "CONVERT_TZ" | "PERIOD_ADD" | "PERIOD_DIFF" | "GET_FORMAT" | "SEC_TO_TIME"
+|	"ANY_VALUE" | "BIN_TO_UUID" | "BIT_COUNT" | "CHAR_LENGTH" | "CHARACTER_LENGTH" | "COERCIBILITY" | "ELT" | "EXPORT_SET" | "FIELD" | "FORMAT"
+|	"FORMAT_BYTES" | "FORMAT_PICO_TIME" | "FROM_DAYS" | "INSTR" | "IS_UUID" | "JSON_ARRAY_APPEND" | "JSON_ARRAY_INSERT" | "JSON_CONTAINS" | "JSON_CONTAINS_PATH" | "JSON_MERGE"
+|	"JSON_MERGE_PRESERVE" | "JSON_TYPE" | "JSON_VALID" | "LAST_DAY" | "LEAST" | "LPAD" | "MAKE_SET" | "MID" | "NAME_CONST" | "OCTET_LENGTH"
+|	"ORD" | "POINT" | "ST_ASTEXT" | "ST_GEOMFROMTEXT" | "TIME_FORMAT" | "TO_DAYS" | "UNIX_TIMESTAMP" | "UUID_TO_BIN" | registeredFunc
 
 /************************************************************************************
  *
@@ -2420,8 +2492,11 @@ PrimaryExpression:
 	}
 |	PrimaryExpression "COLLATE" StringName %prec neg
 	{
-		// TODO: Create a builtin function hold expr and collation. When do evaluation, convert expr result using the collation.
-		$$ = $1
+		// See https://dev.mysql.com/doc/refman/5.7/en/charset-collate.html
+		$$ = &ast.FuncCallExpr{
+			FnName: model.NewCIStr(ast.SetCollation),
+			Args: []ast.ExprNode{$1.(ast.ExprNode), ast.NewValueExpr($3)},
+		}
 	}
 
 Function:
@@ -2436,8 +2511,11 @@ FunctionNameConflict:
 |	"IF"
 |	"LEFT"
 |	"REPEAT"
+|	"RIGHT"
 |	"CURRENT_USER"
 |	"UTC_DATE"
+|	"UTC_TIME"
+|	"UTC_TIMESTAMP"
 |	"CURRENT_DATE"
 |	"VERSION"
 
@@ -2459,6 +2537,14 @@ FunctionCallConflict:
 	{
 		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1)}
 	}
+|	"UTC_TIME"
+	{
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1)}
+	}
+|	"UTC_TIMESTAMP"
+	{
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1)}
+	}
 |	"MOD" '(' PrimaryFactor ',' PrimaryFactor ')'
 	{
 		$$ = &ast.BinaryOperationExpr{Op: opcode.Mod, L: $3.(ast.ExprNode), R: $5.(ast.ExprNode)}
@@ -2524,10 +2610,24 @@ FunctionCallKeyword:
 	{
 		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
 	}
+|	"CHARSET" '(' Expression ')'
+	{
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
+	}
+|	"COLLATION" '(' Expression ')'
+	{
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
+	}
 |	"DATE" '(' Expression ')'
 	{
 		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
 	}
+|	"INTERVAL" '(' Expression ',' ExpressionList ')'
+	{
+		// See https://dev.mysql.com/doc/refman/5.7/en/comparison-operators.html#function_interval
+		args := append([]ast.ExprNode{$3.(ast.ExprNode)}, $5.([]ast.ExprNode)...)
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: args}
+	}
 |	"USER" '(' ')'
 	{
 		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1)}
@@ -2619,6 +2719,14 @@ FunctionCallNonKeyword:
 	{
 		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
 	}
+|	"DECODE" '(' Expression ',' Expression ')'
+	{
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode), $5.(ast.ExprNode)}}
+	}
+|	"ENCODE" '(' Expression ',' Expression ')'
+	{
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode), $5.(ast.ExprNode)}}
+	}
 |	DateArithOpt '(' Expression ',' "INTERVAL" Expression TimeUnit ')'
 	{
 		op := ast.NewValueExpr($1)
@@ -2640,14 +2748,11 @@ FunctionCallNonKeyword:
 	}
 |	DateArithMultiFormsOpt '(' Expression ',' DateArithInterval')'
 	{
-		op := ast.NewValueExpr($1)
-		dateArithInterval := ast.NewValueExpr($5)
 		$$ = &ast.FuncCallExpr{
-			FnName: model.NewCIStr("DATE_ARITH"),
+			FnName: model.NewCIStr($1),
 			Args: []ast.ExprNode{
-				op,
 				$3.(ast.ExprNode),
-				dateArithInterval,
+				$5.(ast.ExprNode),
 			},
 		}
 	}
@@ -2798,6 +2903,14 @@ FunctionCallNonKeyword:
 	{
 		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: $3.([]ast.ExprNode)}
 	}
+|	"OLD_PASSWORD" '(' Expression ')'
+	{
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
+	}
+|	"PASSWORD" '(' Expression ')'
+	{
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
+	}
 |	"POW" '(' Expression ',' Expression ')'
 	{
 		args := []ast.ExprNode{$3.(ast.ExprNode), $5.(ast.ExprNode)}
@@ -2817,6 +2930,10 @@ FunctionCallNonKeyword:
 		}
 		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: args}
 	}
+|	"RANDOM_BYTES" '(' Expression ')'
+	{
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
+	}
 |	"REPLACE" '(' Expression ',' Expression ',' Expression ')'
 	{
 		args := []ast.ExprNode{$3.(ast.ExprNode), $5.(ast.ExprNode), $7.(ast.ExprNode)}
@@ -2842,6 +2959,10 @@ FunctionCallNonKeyword:
 	{
 		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
 	}
+|	"SOUNDEX" '(' Expression ')'
+	{
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
+	}
 |	"STRCMP" '(' Expression ',' Expression ')'
 	{
 		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode), $5.(ast.ExprNode)}}
@@ -2904,6 +3025,60 @@ FunctionCallNonKeyword:
 			Args: []ast.ExprNode{$3.(ast.ExprNode), $5.(ast.ExprNode)},
 		}
 	}
+|	"CONVERT_TZ" '(' Expression ',' Expression ',' Expression ')'
+	{
+		$$ = &ast.FuncCallExpr{
+			FnName: model.NewCIStr($1),
+			Args: []ast.ExprNode{$3.(ast.ExprNode), $5.(ast.ExprNode), $7.(ast.ExprNode)},
+		}
+	}
+|	"PERIOD_ADD" '(' Expression ',' Expression ')'
+	{
+		$$ = &ast.FuncCallExpr{
+			FnName: model.NewCIStr($1),
+			Args: []ast.ExprNode{$3.(ast.ExprNode), $5.(ast.ExprNode)},
+		}
+	}
+|	"PERIOD_DIFF" '(' Expression ',' Expression ')'
+	{
+		$$ = &ast.FuncCallExpr{
+			FnName: model.NewCIStr($1),
+			Args: []ast.ExprNode{$3.(ast.ExprNode), $5.(ast.ExprNode)},
+		}
+	}
+|	"SEC_TO_TIME" '(' Expression ')'
+	{
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
+	}
+|	"QUARTER" '(' Expression ')'
+	{
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
+	}
+|	"ADDTIME" '(' Expression ',' Expression ')'
+	{
+		$$ = &ast.FuncCallExpr{
+			FnName: model.NewCIStr($1),
+			Args: []ast.ExprNode{$3.(ast.ExprNode), $5.(ast.ExprNode)},
+		}
+	}
+|	"SUBTIME" '(' Expression ',' Expression ')'
+	{
+		$$ = &ast.FuncCallExpr{
+			FnName: model.NewCIStr($1),
+			Args: []ast.ExprNode{$3.(ast.ExprNode), $5.(ast.ExprNode)},
+		}
+	}
+|	"TIMESTAMP" '(' Expression ')'
+	{
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
+	}
+|	"TIMESTAMP" '(' Expression ',' Expression ')'
+	{
+		$$ = &ast.FuncCallExpr{
+			FnName: model.NewCIStr($1),
+			Args: []ast.ExprNode{$3.(ast.ExprNode), $5.(ast.ExprNode)},
+		}
+	}
 |	"TRIM" '(' Expression ')'
 	{
 		$$ = &ast.FuncCallExpr{
@@ -2913,18 +3088,18 @@ FunctionCallNonKeyword:
 	}
 |	"TRIM" '(' Expression "FROM" Expression ')'
 	{
+		direction := ast.NewValueExpr(ast.TrimBothDefault)
 		$$ = &ast.FuncCallExpr{
 			FnName: model.NewCIStr($1),
-			Args: []ast.ExprNode{$5.(ast.ExprNode), $3.(ast.ExprNode)},
+			Args: []ast.ExprNode{$5.(ast.ExprNode), $3.(ast.ExprNode), direction},
 		}
 	}
 |	"TRIM" '(' TrimDirection "FROM" Expression ')'
 	{
-		nilVal := ast.NewValueExpr(nil)
 		direction := ast.NewValueExpr($3)
 		$$ = &ast.FuncCallExpr{
 			FnName: model.NewCIStr($1),
-			Args: []ast.ExprNode{$5.(ast.ExprNode), nilVal, direction},
+			Args: []ast.ExprNode{$5.(ast.ExprNode), direction},
 		}
 	}
 |	"TRIM" '(' TrimDirection Expression "FROM" Expression ')'
@@ -2951,6 +3126,24 @@ FunctionCallNonKeyword:
 	{
 		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
 	}
+|	"WEIGHT_STRING" '(' Expression ')'
+	{
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
+	}
+|	"WEIGHT_STRING" '(' Expression "AS" "CHAR" FieldLen ')'
+	{
+		$$ = &ast.FuncCallExpr{
+			FnName:	model.NewCIStr($1),
+			Args:	[]ast.ExprNode{$3.(ast.ExprNode), ast.NewValueExpr("CHAR"), ast.NewValueExpr($6)},
+		}
+	}
+|	"WEIGHT_STRING" '(' Expression "AS" "BINARY" FieldLen ')'
+	{
+		$$ = &ast.FuncCallExpr{
+			FnName:	model.NewCIStr($1),
+			Args:	[]ast.ExprNode{$3.(ast.ExprNode), ast.NewValueExpr("BINARY"), ast.NewValueExpr($6)},
+		}
+	}
 |	"YEARWEEK" '(' ExpressionList ')'
 	{
 		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: $3.([]ast.ExprNode)}
@@ -2963,6 +3156,13 @@ FunctionCallNonKeyword:
 	{
 		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: $3.([]ast.ExprNode)}
 	}
+|	"GET_FORMAT" '(' GetFormatSelector ',' Expression ')'
+	{
+		$$ = &ast.FuncCallExpr{
+			FnName: model.NewCIStr($1),
+			Args: []ast.ExprNode{ast.NewValueExpr($3), $5.(ast.ExprNode)},
+		}
+	}
 |	"GET_LOCK" '(' Expression ',' Expression ')'
 	{
 		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode), $5.(ast.ExprNode)}}
@@ -2978,6 +3178,170 @@ FunctionCallNonKeyword:
 			Args: []ast.ExprNode{$3.(ast.ExprNode), $5.(ast.ExprNode), $7.(ast.ExprNode)},
 		}
 	}
+|	"ANY_VALUE" '(' Expression ')'
+	{
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
+	}
+|	"BIN_TO_UUID" '(' ExpressionList ')'
+	{
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: $3.([]ast.ExprNode)}
+	}
+|	"BIT_COUNT" '(' Expression ')'
+	{
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
+	}
+|	"CHAR_LENGTH" '(' Expression ')'
+	{
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
+	}
+|	"CHARACTER_LENGTH" '(' Expression ')'
+	{
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
+	}
+|	"COERCIBILITY" '(' Expression ')'
+	{
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
+	}
+|	"ELT" '(' ExpressionList ')'
+	{
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: $3.([]ast.ExprNode)}
+	}
+|	"EXPORT_SET" '(' ExpressionList ')'
+	{
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: $3.([]ast.ExprNode)}
+	}
+|	"FIELD" '(' ExpressionList ')'
+	{
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: $3.([]ast.ExprNode)}
+	}
+|	"FORMAT" '(' ExpressionList ')'
+	{
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: $3.([]ast.ExprNode)}
+	}
+|	"FORMAT_BYTES" '(' Expression ')'
+	{
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
+	}
+|	"FORMAT_PICO_TIME" '(' Expression ')'
+	{
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
+	}
+|	"FROM_DAYS" '(' Expression ')'
+	{
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
+	}
+|	"INSTR" '(' Expression ',' Expression ')'
+	{
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode), $5.(ast.ExprNode)}}
+	}
+|	"IS_UUID" '(' Expression ')'
+	{
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
+	}
+|	"JSON_ARRAY_APPEND" '(' ExpressionList ')'
+	{
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: $3.([]ast.ExprNode)}
+	}
+|	"JSON_ARRAY_INSERT" '(' ExpressionList ')'
+	{
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: $3.([]ast.ExprNode)}
+	}
+|	"JSON_CONTAINS" '(' ExpressionList ')'
+	{
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: $3.([]ast.ExprNode)}
+	}
+|	"JSON_CONTAINS_PATH" '(' ExpressionList ')'
+	{
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: $3.([]ast.ExprNode)}
+	}
+|	"JSON_MERGE" '(' ExpressionList ')'
+	{
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: $3.([]ast.ExprNode)}
+	}
+|	"JSON_MERGE_PRESERVE" '(' ExpressionList ')'
+	{
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: $3.([]ast.ExprNode)}
+	}
+|	"JSON_TYPE" '(' Expression ')'
+	{
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
+	}
+|	"JSON_VALID" '(' Expression ')'
+	{
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
+	}
+|	"LAST_DAY" '(' Expression ')'
+	{
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
+	}
+|	"LEAST" '(' ExpressionList ')'
+	{
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: $3.([]ast.ExprNode)}
+	}
+|	"LPAD" '(' Expression ',' Expression ',' Expression ')'
+	{
+		$$ = &ast.FuncCallExpr{
+			FnName: model.NewCIStr($1),
+			Args: []ast.ExprNode{$3.(ast.ExprNode), $5.(ast.ExprNode), $7.(ast.ExprNode)},
+		}
+	}
+|	"MAKE_SET" '(' ExpressionList ')'
+	{
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: $3.([]ast.ExprNode)}
+	}
+|	"MID" '(' ExpressionList ')'
+	{
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: $3.([]ast.ExprNode)}
+	}
+|	"NAME_CONST" '(' Expression ',' Expression ')'
+	{
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode), $5.(ast.ExprNode)}}
+	}
+|	"OCTET_LENGTH" '(' Expression ')'
+	{
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
+	}
+|	"ORD" '(' Expression ')'
+	{
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
+	}
+|	"POINT" '(' Expression ',' Expression ')'
+	{
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode), $5.(ast.ExprNode)}}
+	}
+|	"ST_ASTEXT" '(' Expression ')'
+	{
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
+	}
+|	"ST_GEOMFROMTEXT" '(' Expression ')'
+	{
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
+	}
+|	"TIME_FORMAT" '(' Expression ',' Expression ')'
+	{
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode), $5.(ast.ExprNode)}}
+	}
+|	"TO_DAYS" '(' Expression ')'
+	{
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: []ast.ExprNode{$3.(ast.ExprNode)}}
+	}
+|	"UNIX_TIMESTAMP" '(' ExpressionOpt ')'
+	{
+		args := []ast.ExprNode{}
+		if $3 != nil {
+			args = append(args, $3.(ast.ExprNode))
+		}
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: args}
+	}
+|	"UUID_TO_BIN" '(' ExpressionList ')'
+	{
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: $3.([]ast.ExprNode)}
+	}
+|	registeredFunc '(' ExpressionListOpt ')'
+	{
+		// See ast.RegisterFuncName.
+		$$ = &ast.FuncCallExpr{FnName: model.NewCIStr($1), Args: $3.([]ast.ExprNode)}
+	}
 
 DateArithOpt:
 	"DATE_ADD"
@@ -2991,25 +3355,13 @@ DateArithOpt:
 
 DateArithMultiFormsOpt:
 	"ADDDATE"
-	{
-		$$ = ast.DateAdd
-	}
 |	"SUBDATE"
-	{
-		$$ = ast.DateSub
-	}
 
 DateArithInterval:
 	Expression
-	{
-		$$ = ast.DateArithInterval{
-					Unit: "day",
-					Interval: $1.(ast.ExprNode),
-		}
-	}
 |	"INTERVAL" Expression TimeUnit
 	{
-		$$ = ast.DateArithInterval{Unit: $3, Interval: $2.(ast.ExprNode)}
+		$$ = ast.NewValueExpr(ast.DateArithInterval{Unit: $3, Interval: $2.(ast.ExprNode)})
 	}
 
 TrimDirection:
@@ -3092,6 +3444,12 @@ TimeUnit:
 |	"DAY_HOUR"
 |	"YEAR_MONTH"
 
+GetFormatSelector:
+	"DATE"
+|	"DATETIME"
+|	"TIME"
+|	"TIMESTAMP"
+
 ExpressionOpt:
 	{
 		$$ = nil
@@ -3146,7 +3504,10 @@ CastType:
 		if $3.(bool) {
 			x.Flag |= mysql.BinaryFlag
 		}
-		x.Charset = $4.(string)
+		x.Charset = strings.ToLower($4.(string))
+		if x.Charset != "" {
+			x.Collate, _ = charset.GetDefaultCollation(x.Charset)
+		}
 		$$ = x
 	}
 |	"DATE"
@@ -3189,6 +3550,13 @@ CastType:
 		x.Flag |= mysql.UnsignedFlag
 		$$ = x
 	}
+|	"JSON"
+	{
+		x := types.NewFieldType(mysql.TypeJSON)
+		x.Charset = charset.CharsetUTF8MB4
+		x.Collate = "utf8mb4_bin"
+		$$ = x
+	}
 
 
 PrimaryFactor:
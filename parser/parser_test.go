@@ -56,7 +56,7 @@ func (s *testParserSuite) TestSimple(c *C) {
 		"schema", "schemas", "second_microsecond", "select", "set", "show", "smallint",
 		"starting", "table", "terminated", "then", "tinyblob", "tinyint", "tinytext", "to",
 		"trailing", "true", "union", "unique", "unlock", "unsigned",
-		"update", "use", "using", "utc_date", "values", "varbinary", "varchar",
+		"update", "use", "using", "utc_date", "utc_time", "utc_timestamp", "values", "varbinary", "varchar",
 		"when", "where", "write", "xor", "year_month", "zerofill",
 		// TODO: support the following keywords
 		// "delayed" , "high_priority" , "low_priority", "with",
@@ -89,7 +89,9 @@ func (s *testParserSuite) TestSimple(c *C) {
 		"compact", "redundant", "sql_no_cache sql_no_cache", "sql_cache sql_cache", "action", "round",
 		"enable", "disable", "reverse", "space", "privileges", "get_lock", "release_lock", "sleep", "no", "greatest",
 		"binlog", "hex", "unhex", "function", "indexes", "from_unixtime", "processlist", "events", "less", "than", "timediff",
-		"ln", "log", "log2", "log10",
+		"addtime", "subtime", "convert_tz", "period_add", "period_diff", "get_format",
+		"sec_to_time",
+		"ln", "log", "log2", "log10", "soundex", "sounds", "weight_string", "encode", "decode", "random_bytes", "old_password",
 	}
 	for _, kw := range unreservedKws {
 		src := fmt.Sprintf("SELECT %s FROM tbl;", kw)
@@ -537,6 +539,37 @@ func (s *testParserSuite) TestBuiltin(c *C) {
 		{"SELECT SUBSTRING_INDEX('www.mysql.com', '.', -2);", true},
 
 		{`SELECT ASCII(""), ASCII("A"), ASCII(1);`, true},
+		{`SELECT CHARSET('a'), COLLATION(c) FROM t;`, true},
+		{`SELECT JSON_TYPE('1'), JSON_VALID('1');`, true},
+		{`SELECT JSON_ARRAY_APPEND('[]', '$', 1), JSON_ARRAY_INSERT('[]', '$[0]', 1);`, true},
+		{`SELECT JSON_MERGE('1', '2'), JSON_MERGE_PRESERVE('1', '2', '3');`, true},
+		{`SELECT JSON_CONTAINS('1', '1'), JSON_CONTAINS('1', '1', '$'), JSON_CONTAINS_PATH('1', 'one', '$');`, true},
+		{`SELECT COERCIBILITY('a');`, true},
+		{`SELECT CHAR_LENGTH('a', 'b');`, false},
+		{`SELECT ORD('a');`, true},
+		{`SELECT ELT(1, 'a', 'b'), FIELD('a', 'a', 'b'), MAKE_SET(1, 'a', 'b');`, true},
+		{`SELECT field FROM t;`, true},
+		{`SELECT MID('foobar', 2), MID('foobar', 2, 3);`, true},
+		{`SELECT INSTR('foobar', 'bar');`, true},
+		{`SELECT INSTR('foobar');`, false},
+		{`SELECT ST_ASTEXT(POINT(1, 2)), ST_GEOMFROMTEXT('POINT(1 2)');`, true},
+		{`SELECT point FROM t;`, true},
+		{`SELECT UNIX_TIMESTAMP(), UNIX_TIMESTAMP('2015-11-13 10:20:19');`, true},
+		{`SELECT ANY_VALUE(c) FROM t;`, true},
+		{`SELECT NAME_CONST('a', 1);`, true},
+		{`SELECT FORMAT(12332.123456, 4), FORMAT(12332.2, 2, 'de_DE');`, true},
+		{`SELECT format FROM t;`, true},
+		{`SELECT LEAST(1, 2, 3);`, true},
+		{`SELECT least FROM t;`, true},
+		{`SELECT LPAD('a', 3, 'b');`, true},
+		{`SELECT LPAD('a', 3);`, false},
+		{`SELECT FORMAT_BYTES(512), FORMAT_PICO_TIME(3501);`, true},
+		{`SELECT TIME_FORMAT('100:00:00', '%H');`, true},
+		{`SELECT IS_UUID('x'), UUID_TO_BIN('x'), UUID_TO_BIN('x', 1), BIN_TO_UUID('x'), BIN_TO_UUID('x', 1);`, true},
+		{`SELECT BIT_COUNT(3);`, true},
+		{`SELECT EXPORT_SET(5, 'Y', 'N', ',', 4);`, true},
+		{`SELECT FROM_DAYS(730669), TO_DAYS('2007-10-07');`, true},
+		{`SELECT LAST_DAY('2003-02-05');`, true},
 
 		{`SELECT LOWER("A"), UPPER("a")`, true},
 		{`SELECT LCASE("A"), UCASE("a")`, true},
@@ -560,6 +593,10 @@ func (s *testParserSuite) TestBuiltin(c *C) {
 		// For cast with charset
 		{"SELECT *, CAST(data AS CHAR CHARACTER SET utf8) FROM t;", true},
 
+		// For cast as json
+		{`SELECT CAST('{"a": 1}' AS JSON), CAST(json AS json) FROM t;`, true},
+		{"create table t (json int)", true},
+
 		// For last_insert_id
 		{"SELECT last_insert_id();", true},
 		{"SELECT last_insert_id(1);", true},
@@ -649,16 +686,23 @@ func (s *testParserSuite) TestBuiltin(c *C) {
 		{`SELECT CAST('test collated returns' AS CHAR CHARACTER SET utf8) COLLATE utf8_bin;`, true},
 
 		// For string functions
+		// For octet_length and char_length
+		{`SELECT OCTET_LENGTH('abc'), CHAR_LENGTH('abc'), CHARACTER_LENGTH('abc');`, true},
+
 		// Trim
 		{`SELECT TRIM('  bar   ');`, true},
 		{`SELECT TRIM(LEADING 'x' FROM 'xxxbarxxx');`, true},
 		{`SELECT TRIM(BOTH 'x' FROM 'xxxbarxxx');`, true},
 		{`SELECT TRIM(TRAILING 'xyz' FROM 'barxxyz');`, true},
+		{`SELECT TRIM('x' FROM 'xxyy');`, true},
+		{`SELECT TRIM(LEADING FROM '  bar');`, true},
 		{`SELECT LTRIM(' foo ');`, true},
 		{`SELECT RTRIM(' bar ');`, true},
 
 		// Repeat
 		{`SELECT REPEAT("a", 10);`, true},
+		{`SELECT RIGHT("abc", 2);`, true},
+		{`SELECT LEFT("abc", 2), RIGHT("abc", 2) FROM t LEFT JOIN t1 ON t.a = t1.a RIGHT JOIN t2 ON t.a = t2.a;`, true},
 
 		// Sleep
 		{`SELECT SLEEP(10);`, true},
@@ -688,8 +732,59 @@ func (s *testParserSuite) TestBuiltin(c *C) {
 		// For strcmp
 		{`select strcmp('abc', 'def')`, true},
 
+		// For soundex and sounds like
+		{`select soundex('hello')`, true},
+		{`select soundex('a', 'b')`, false},
+		{`select 'Robert' sounds like 'Rupert'`, true},
+		{`select a from t where a sounds like 'b' and b sounds like c`, true},
+		{`select sounds from t where sounds sounds like 'x'`, true},
+
+		// For encode and decode
+		{`select decode(encode('abc', 'pwd'), 'pwd')`, true},
+		{`select encode('abc')`, false},
+
+		// For password and old_password
+		{`select password('abc'), old_password('abc')`, true},
+		{`select password(a) from t where old_password(b) = ''`, true},
+		{`set password = password('abc')`, true},
+
+		// For addtime and subtime
+		{`select addtime('2003-12-31 23:59:59', '1:00:00'), subtime('10:00:00', '20:00:00')`, true},
+
+		// For convert_tz
+		{`select convert_tz('2004-01-01 12:00:00', '+00:00', '+10:00'), convert_tz('2004-01-01 12:00:00', 'UTC', 'Europe/Paris')`, true},
+
+		// For period_add, period_diff and quarter
+		{`select period_add(200801, 2), period_diff(200802, 200703), quarter('2008-04-01')`, true},
+
+		// For get_format
+		{`select get_format(date, 'usa'), get_format(datetime, 'iso'), get_format(time, 'eur'), get_format(timestamp, 'jis')`, true},
+		{`select get_format(year, 'usa')`, false},
+
+		// For sec_to_time
+		{`select sec_to_time(2378), sec_to_time(2378.5)`, true},
+
+		// For timestamp
+		{`select timestamp('2003-12-31'), timestamp('2003-12-31 12:00:00', '12:00:00')`, true},
+
+		// For interval
+		{`select interval(23, 1, 15, 17, 30, 44, 200)`, true},
+		{`select interval(1)`, false},
+		{`select adddate('2016-01-01', interval 1 day), adddate('2016-01-01', interval(1, 2))`, true},
+
+		// For random_bytes
+		{`select random_bytes(16)`, true},
+		{`select random_bytes()`, false},
+
+		// For weight_string
+		{`select weight_string('abc')`, true},
+		{`select weight_string('abc' as char(5)), weight_string(a as binary(2)) from t`, true},
+		{`select weight_string('abc' as char)`, false},
+
 		// For utc_date
 		{`select utc_date(), utc_date()+0`, true},
+		// For utc_time and utc_timestamp
+		{`select utc_time, utc_time(), utc_time(3), utc_timestamp, utc_timestamp(), utc_timestamp(6)`, true},
 
 		// For adddate
 		{`select adddate("2011-11-11 10:10:10.123456", interval 10 microsecond)`, true},
@@ -770,6 +865,24 @@ func (s *testParserSuite) TestBuiltin(c *C) {
 	s.RunTest(c, table)
 }
 
+func (s *testParserSuite) TestRegisteredFunction(c *C) {
+	defer testleak.AfterTest(c)()
+	table := []testCase{
+		{`select my_func(), My_Func(1, 'a', c + 1)`, false},
+	}
+	s.RunTest(c, table)
+
+	ast.RegisterFuncName("MY_FUNC")
+	defer ast.UnregisterFuncName("my_func")
+	table = []testCase{
+		{`select my_func(), My_Func(1, 'a', c + 1)`, true},
+		// The name of a registered function is still an identifier.
+		{`select my_func from my_func`, true},
+		{`select other_func(1)`, false},
+	}
+	s.RunTest(c, table)
+}
+
 func (s *testParserSuite) TestIdentifier(c *C) {
 	defer testleak.AfterTest(c)()
 	table := []testCase{
@@ -1117,6 +1230,38 @@ func (s *testParserSuite) TestLikeEscape(c *C) {
 	s.RunTest(c, table)
 }
 
+func (s *testParserSuite) TestSubstringForms(c *C) {
+	defer testleak.AfterTest(c)()
+	// The FROM and FOR form of SUBSTRING builds the same call as the comma form.
+	table := []struct {
+		keywordForm string
+		commaForm   string
+	}{
+		{"SELECT SUBSTRING('Sakila' FROM 2)", "SELECT SUBSTRING('Sakila', 2)"},
+		{"SELECT SUBSTRING('Sakila' FROM -3)", "SELECT SUBSTRING('Sakila', -3)"},
+		{"SELECT SUBSTRING('Sakila' FROM 2 FOR 3)", "SELECT SUBSTRING('Sakila', 2, 3)"},
+		{"SELECT SUBSTRING('Sakila' FROM -5 FOR 3)", "SELECT SUBSTRING('Sakila', -5, 3)"},
+		{"SELECT SUBSTRING(a FROM b FOR c + 1) FROM t", "SELECT SUBSTRING(a, b, c + 1) FROM t"},
+	}
+	parser := New()
+	callOf := func(src string) *ast.FuncCallExpr {
+		stmt, err := parser.ParseOneStmt(src, "", "")
+		c.Assert(err, IsNil, Commentf("source %v", src))
+		return stmt.(*ast.SelectStmt).Fields.Fields[0].Expr.(*ast.FuncCallExpr)
+	}
+	for _, t := range table {
+		keywordCall, commaCall := callOf(t.keywordForm), callOf(t.commaForm)
+		c.Assert(keywordCall.FnName.L, Equals, commaCall.FnName.L)
+		c.Assert(keywordCall.Args, HasLen, len(commaCall.Args))
+		for i, arg := range keywordCall.Args {
+			c.Assert(fmt.Sprintf("%T", arg), Equals, fmt.Sprintf("%T", commaCall.Args[i]), Commentf("source %v", t.keywordForm))
+			if v, ok := arg.(*ast.ValueExpr); ok {
+				c.Assert(v.GetValue(), DeepEquals, commaCall.Args[i].GetValue())
+			}
+		}
+	}
+}
+
 func (s *testParserSuite) TestMysqlDump(c *C) {
 	defer testleak.AfterTest(c)()
 	// Statements used by mysqldump.
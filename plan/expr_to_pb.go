@@ -14,6 +14,8 @@
 package plan
 
 import (
+	"strings"
+
 	"github.com/ngaut/log"
 	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/expression"
@@ -141,6 +143,9 @@ func (pc pbConverter) scalarFuncToPBExpr(expr *expression.ScalarFunction) *tipb.
 }
 
 func (pc pbConverter) compareOpsToPBExpr(expr *expression.ScalarFunction) *tipb.Expr {
+	if isCICollation(expr.Collation()) {
+		return nil
+	}
 	var tp tipb.ExprType
 	switch expr.FuncName.L {
 	case ast.LT:
@@ -317,7 +322,23 @@ func (pc pbConverter) datumsToValueList(datums []types.Datum) *tipb.Expr {
 	return &tipb.Expr{Tp: tipb.ExprType_ValueList, Val: val}
 }
 
+// isCICollation reports whether the collation compares strings case insensitively. The storage
+// layer compares, groups and sorts strings by their bytes, so what works on strings in such a
+// collation is kept in TiDB.
+func isCICollation(collation string) bool {
+	return strings.HasSuffix(collation, "_ci")
+}
+
+// hasCICollation reports whether a COLLATE clause asks for the string expr to be grouped or sorted
+// case insensitively.
+func hasCICollation(expr expression.Expression) bool {
+	return isCICollation(expression.ExplicitCollation(expr))
+}
+
 func groupByItemToPB(sc *variable.StatementContext, client kv.Client, expr expression.Expression) *tipb.ByItem {
+	if hasCICollation(expr) {
+		return nil
+	}
 	pc := pbConverter{client: client, sc: sc}
 	e := pc.exprToPB(expr)
 	if e == nil {
@@ -327,6 +348,9 @@ func groupByItemToPB(sc *variable.StatementContext, client kv.Client, expr expre
 }
 
 func sortByItemToPB(sc *variable.StatementContext, client kv.Client, expr expression.Expression, desc bool) *tipb.ByItem {
+	if hasCICollation(expr) {
+		return nil
+	}
 	pc := pbConverter{client: client, sc: sc}
 	e := pc.exprToPB(expr)
 	if e == nil {
@@ -26,7 +26,6 @@ import (
 	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/parser/opcode"
 	"github.com/pingcap/tidb/sessionctx/variable"
-	"github.com/pingcap/tidb/sessionctx/varsutil"
 	"github.com/pingcap/tidb/util/types"
 )
 
@@ -198,6 +197,9 @@ func (er *expressionRewriter) Enter(inNode ast.Node) (ast.Node, bool) {
 	case *ast.ValuesExpr:
 		er.valuesToScalarFunc(v)
 		return inNode, true
+	case *ast.DefaultExpr:
+		er.defaultToScalarFunc(v)
+		return inNode, true
 	default:
 		er.asScalar = true
 	}
@@ -431,7 +433,7 @@ func (er *expressionRewriter) Leave(inNode ast.Node) (retNode ast.Node, ok bool)
 
 	switch v := inNode.(type) {
 	case *ast.AggregateFuncExpr, *ast.ColumnNameExpr, *ast.ParenthesesExpr, *ast.WhenClause,
-		*ast.SubqueryExpr, *ast.ExistsSubqueryExpr, *ast.CompareSubqueryExpr, *ast.ValuesExpr:
+		*ast.SubqueryExpr, *ast.ExistsSubqueryExpr, *ast.CompareSubqueryExpr, *ast.ValuesExpr, *ast.DefaultExpr:
 	case *ast.ValueExpr:
 		value := &expression.Constant{Value: v.Datum, RetType: &v.Type}
 		er.ctxStack = append(er.ctxStack, value)
@@ -486,8 +488,6 @@ func datumToConstant(d types.Datum, tp byte) *expression.Constant {
 func (er *expressionRewriter) rewriteVariable(v *ast.VariableExpr) {
 	stkLen := len(er.ctxStack)
 	name := strings.ToLower(v.Name)
-	sessionVars := er.b.ctx.GetSessionVars()
-	globalVars := sessionVars.GlobalVarsAccessor
 	if !v.IsSystem {
 		if v.Value != nil {
 			er.ctxStack[stkLen-1], er.err = expression.NewFunction(ast.SetVar,
@@ -496,20 +496,15 @@ func (er *expressionRewriter) rewriteVariable(v *ast.VariableExpr) {
 				er.ctxStack[stkLen-1])
 			return
 		}
-		if _, ok := sessionVars.Users[name]; ok {
-			f, err := expression.NewFunction(ast.GetVar,
-				// TODO: Here is wrong, the sessionVars should store a name -> Datum map. Will fix it later.
-				types.NewFieldType(mysql.TypeString),
-				datumToConstant(types.NewStringDatum(name), mysql.TypeString))
-			if err != nil {
-				er.err = errors.Trace(err)
-				return
-			}
-			er.ctxStack = append(er.ctxStack, f)
-		} else {
-			// select null user vars is permitted.
-			er.ctxStack = append(er.ctxStack, &expression.Constant{RetType: types.NewFieldType(mysql.TypeNull)})
+		// The variable is read when the expression is evaluated, so that it sees assignments made
+		// earlier in the statement. Its value may be of any type by then, so it has the generic
+		// string type the type inferrer gives it, while the value keeps its own type.
+		f, err := expression.NewFunction(ast.GetVar, &v.Type, datumToConstant(types.NewStringDatum(name), mysql.TypeString))
+		if err != nil {
+			er.err = errors.Trace(err)
+			return
 		}
+		er.ctxStack = append(er.ctxStack, f)
 		return
 	}
 
@@ -523,37 +518,21 @@ func (er *expressionRewriter) rewriteVariable(v *ast.VariableExpr) {
 		er.ctxStack = append(er.ctxStack, datumToConstant(types.NewDatum(sysVar.Value), mysql.TypeString))
 		return
 	}
-
-	if v.IsGlobal {
-		value, err := globalVars.GetGlobalSysVar(name)
-		if err != nil {
-			er.err = errors.Trace(err)
-			return
-		}
-		er.ctxStack = append(er.ctxStack, datumToConstant(types.NewDatum(value), mysql.TypeString))
+	// Other system variables may change between statements, they are read once for the statement
+	// when it is planned, which a prepared statement is again on every execution.
+	f, err := expression.NewFunction(ast.GetSysVar, types.NewFieldType(mysql.TypeString),
+		datumToConstant(types.NewStringDatum(name), mysql.TypeString),
+		datumToConstant(types.NewDatum(v.IsGlobal), mysql.TypeLonglong))
+	if err != nil {
+		er.err = errors.Trace(err)
 		return
 	}
-	d := varsutil.GetSystemVar(sessionVars, name)
-	if d.IsNull() {
-		if sysVar.Scope&variable.ScopeGlobal == 0 {
-			d.SetString(sysVar.Value)
-		} else {
-			// Get global system variable and fill it in session.
-			globalVal, err := globalVars.GetGlobalSysVar(name)
-			if err != nil {
-				er.err = errors.Trace(err)
-				return
-			}
-			d.SetString(globalVal)
-			err = varsutil.SetSystemVar(sessionVars, name, d)
-			if err != nil {
-				er.err = errors.Trace(err)
-				return
-			}
-		}
+	d, err := f.Eval(nil, er.b.ctx)
+	if err != nil {
+		er.err = errors.Trace(err)
+		return
 	}
 	er.ctxStack = append(er.ctxStack, datumToConstant(d, mysql.TypeString))
-	return
 }
 
 func (er *expressionRewriter) unaryOpToExpression(v *ast.UnaryOperationExpr) {
@@ -883,3 +862,71 @@ func (er *expressionRewriter) valuesToScalarFunc(v *ast.ValuesExpr) {
 	}
 	er.ctxStack = append(er.ctxStack, function)
 }
+
+func (er *expressionRewriter) defaultToScalarFunc(v *ast.DefaultExpr) {
+	if v.Name == nil {
+		er.err = ErrUnsupportedType.Gen("Unsupported DEFAULT without a column name in expression")
+		return
+	}
+	col, err := er.schema.FindColumn(v.Name)
+	if err != nil {
+		er.err = errors.Trace(err)
+		return
+	}
+	if col == nil {
+		er.err = ErrUnknownColumn.GenByArgs(v.Name.Name.O, "field_list")
+		return
+	}
+	var tblInfo *model.TableInfo
+	if ds := findDataSource(er.p, col.FromID); ds != nil {
+		// The column may be named after an alias of its table, so the table is the one it is read from.
+		tblInfo = ds.tableInfo
+	} else {
+		dbName := col.DBName
+		if dbName.L == "" {
+			dbName = model.NewCIStr(er.ctx.GetSessionVars().CurrentDB)
+		}
+		tbl, err := er.b.is.TableByName(dbName, col.TblName)
+		if err != nil {
+			er.err = errors.Trace(err)
+			return
+		}
+		tblInfo = tbl.Meta()
+	}
+	colInfo := findColumnByName(tblInfo, col.ColName)
+	if colInfo == nil {
+		er.err = ErrUnknownColumn.GenByArgs(v.Name.Name.O, "field_list")
+		return
+	}
+	function := &expression.ScalarFunction{
+		FuncName: model.NewCIStr(ast.Default),
+		RetType:  &colInfo.FieldType,
+		Function: evaluator.BuildinDefaultFactory(colInfo),
+	}
+	er.ctxStack = append(er.ctxStack, function)
+}
+
+// findDataSource returns the DataSource with the id in the plan tree p, nil if there is none.
+func findDataSource(p Plan, id string) *DataSource {
+	if p == nil {
+		return nil
+	}
+	if ds, ok := p.(*DataSource); ok && ds.id == id {
+		return ds
+	}
+	for _, child := range p.GetChildren() {
+		if ds := findDataSource(child, id); ds != nil {
+			return ds
+		}
+	}
+	return nil
+}
+
+func findColumnByName(tblInfo *model.TableInfo, name model.CIStr) *model.ColumnInfo {
+	for _, col := range tblInfo.Columns {
+		if col.Name.L == name.L {
+			return col
+		}
+	}
+	return nil
+}
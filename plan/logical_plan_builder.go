@@ -279,6 +279,8 @@ func (b *planBuilder) buildProjection(p LogicalPlan, fields []*ast.SelectField,
 		var tblName, colName model.CIStr
 		if field.AsName.L != "" {
 			colName = field.AsName
+		} else if name, ok := nameConstName(getInnerFromParentheses(field.Expr)); ok {
+			colName = name
 		} else if c, ok := newExpr.(*expression.Column); ok && !c.IsAggOrSubq {
 			if astCol, ok := getInnerFromParentheses(field.Expr).(*ast.ColumnNameExpr); ok {
 				colName = astCol.Name.Name
@@ -26,6 +26,7 @@ import (
 	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/parser"
 	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/sessionctx/varsutil"
 	"github.com/pingcap/tidb/terror"
 	"github.com/pingcap/tidb/util/mock"
 	"github.com/pingcap/tidb/util/testleak"
@@ -587,6 +588,29 @@ func (s *testPlanSuite) TestPlanBuilder(c *C) {
 	}
 }
 
+func (s *testPlanSuite) TestUserVarType(c *C) {
+	defer testleak.AfterTest(c)()
+	stmt, err := s.ParseOneStmt("select @a from t", "", "")
+	c.Assert(err, IsNil)
+	is, err := mockResolve(stmt)
+	c.Assert(err, IsNil)
+	ctx := mock.NewContext()
+	varsutil.SetUserVar(ctx.GetSessionVars(), "a", types.NewIntDatum(1))
+	builder := &planBuilder{
+		allocator: new(idAllocator),
+		ctx:       ctx,
+		colMapper: make(map[*ast.ColumnNameExpr]int),
+		is:        is,
+	}
+	p := builder.build(stmt)
+	c.Assert(builder.err, IsNil)
+	// The variable may hold a value of another type when it is read, so its type doesn't come
+	// from the value it holds when the statement is planned.
+	proj, ok := p.(*Projection)
+	c.Assert(ok, IsTrue)
+	c.Assert(proj.Exprs[0].GetType().Tp, Equals, mysql.TypeVarString)
+}
+
 func (s *testPlanSuite) TestJoinReOrder(c *C) {
 	defer testleak.AfterTest(c)()
 	cases := []struct {
@@ -903,6 +927,27 @@ func (s *testPlanSuite) TestRefine(c *C) {
 			sql:  `select a from t where c_str like 123`,
 			best: "Index(t.c_d_e_str)[[123,123]]->Projection",
 		},
+		{
+			sql:  `select a from t where c_str = 'abc'`,
+			best: "Index(t.c_d_e_str)[[abc,abc]]->Projection",
+		},
+		{
+			sql:  `select a from t where c_str > 'abc' and c_str <= 'abd'`,
+			best: "Index(t.c_d_e_str)[(abc,abd]]->Projection",
+		},
+		{
+			// A case insensitive COLLATE can't use the bytes stored in the index.
+			sql:  `select a from t where c_str = 'abc' collate utf8_general_ci`,
+			best: "Table(t)->Selection->Projection",
+		},
+		{
+			sql:  `select a from t where c_str in ('abc', 'abd')`,
+			best: "Index(t.c_d_e_str)[[abc,abc] [abd,abd]]->Projection",
+		},
+		{
+			sql:  `select a from t where c_str in ('abc', 'abd' collate utf8_general_ci)`,
+			best: "Table(t)->Selection->Projection",
+		},
 		{
 			// c is not string type, added cast to string during InferType, no index can be used.
 			sql:  `select a from t where c like '1'`,
@@ -91,9 +91,11 @@ func refineRangeDatum(v *types.Datum, ic *model.IndexColumn) {
 
 // getEQFunctionOffset judge if the expression is a eq function like A = 1 where a is an index.
 // If so, it will return the offset of A in index columns. e.g. for index(C,B,A), A's offset is 2.
+// Strings compared case insensitively don't match the byte order of the index, so A = 'a' isn't
+// such a function if A is compared in a _ci collation.
 func getEQFunctionOffset(expr expression.Expression, cols []*model.IndexColumn) int {
 	f, ok := expr.(*expression.ScalarFunction)
-	if !ok || f.FuncName.L != ast.EQ {
+	if !ok || f.FuncName.L != ast.EQ || isCICollation(f.Collation()) {
 		return -1
 	}
 	if c, ok := f.Args[0].(*expression.Column); ok {
@@ -340,6 +342,9 @@ func (c *conditionChecker) checkScalarFunction(scalar *expression.ScalarFunction
 	case ast.OrOr, ast.AndAnd:
 		return c.check(scalar.Args[0]) && c.check(scalar.Args[1])
 	case ast.EQ, ast.NE, ast.GE, ast.GT, ast.LE, ast.LT:
+		if isCICollation(scalar.Collation()) {
+			return false
+		}
 		if _, ok := scalar.Args[0].(*expression.Constant); ok {
 			return c.checkColumn(scalar.Args[1])
 		}
@@ -360,7 +365,7 @@ func (c *conditionChecker) checkScalarFunction(scalar *expression.ScalarFunction
 		}
 		return c.check(scalar.Args[0])
 	case ast.In:
-		if !c.checkColumn(scalar.Args[0]) {
+		if isCICollation(scalar.Collation()) || !c.checkColumn(scalar.Args[0]) {
 			return false
 		}
 		for _, v := range scalar.Args[1:] {
@@ -716,6 +716,19 @@ func getInnerFromParentheses(expr ast.ExprNode) ast.ExprNode {
 	return expr
 }
 
+// nameConstName returns the name NAME_CONST(name, value) gives its result column, if expr is such a call.
+func nameConstName(expr ast.ExprNode) (model.CIStr, bool) {
+	f, ok := expr.(*ast.FuncCallExpr)
+	if !ok || f.FnName.L != ast.NameConst || len(f.Args) != 2 {
+		return model.CIStr{}, false
+	}
+	name, ok := f.Args[0].(*ast.ValueExpr)
+	if !ok || name.GetDatum().Kind() != types.KindString {
+		return model.CIStr{}, false
+	}
+	return model.NewCIStr(name.GetDatum().GetString()), true
+}
+
 // createResultFields creates result field list for a single select field.
 func (nr *nameResolver) createResultFields(field *ast.SelectField) (rfs []*ast.ResultField) {
 	ctx := nr.currentContext()
@@ -780,6 +793,11 @@ func (nr *nameResolver) createResultFields(field *ast.SelectField) (rfs []*ast.R
 		rf.Expr = v
 	}
 	if field.AsName.L == "" {
+		if name, ok := nameConstName(innerExpr); ok {
+			rf.ColumnAsName = name
+			rfs = append(rfs, rf)
+			return
+		}
 		switch x := innerExpr.(type) {
 		case *ast.ColumnNameExpr:
 			rf.ColumnAsName = model.NewCIStr(x.Name.Name.O)
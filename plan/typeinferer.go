@@ -14,11 +14,13 @@
 package plan
 
 import (
+	"math"
 	"strings"
 
 	"github.com/juju/errors"
 	"github.com/ngaut/log"
 	"github.com/pingcap/tidb/ast"
+	"github.com/pingcap/tidb/evaluator"
 	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/parser/opcode"
 	"github.com/pingcap/tidb/sessionctx/variable"
@@ -165,6 +167,9 @@ func (v *typeInferrer) binaryOperation(x *ast.BinaryOperationExpr) {
 		x.Type.Init(mysql.TypeLonglong)
 	case opcode.LT, opcode.LE, opcode.GE, opcode.GT, opcode.EQ, opcode.NE, opcode.NullEQ:
 		x.Type.Init(mysql.TypeLonglong)
+		if _, err := aggregateCollation(cmpOpNames[x.Op], x.L, x.R); err != nil {
+			v.err = errors.Trace(err)
+		}
 	case opcode.RightShift, opcode.LeftShift, opcode.And, opcode.Or, opcode.Xor:
 		x.Type.Init(mysql.TypeLonglong)
 		x.Type.Flag |= mysql.UnsignedFlag
@@ -255,23 +260,54 @@ func (v *typeInferrer) handleFuncCallExpr(x *ast.FuncCallExpr) {
 		tp  *types.FieldType
 		chs = charset.CharsetBin
 	)
+	if x.FnName.L == ast.SetCollation {
+		v.handleSetCollation(x)
+		return
+	}
+	if infer, ok := evaluator.TypeInferers[x.FnName.L]; ok {
+		var co evaluator.CollationOperand
+		if x.FnName.L == ast.Concat || x.FnName.L == ast.ConcatWS {
+			var err error
+			co, err = aggregateCollation(x.FnName.L, x.Args...)
+			if err != nil {
+				v.err = errors.Trace(err)
+			}
+		}
+		argTypes := make([]*types.FieldType, len(x.Args))
+		for i, arg := range x.Args {
+			argTypes[i] = arg.GetType()
+			if co.Charset != "" && isStringType(argTypes[i]) && argTypes[i].Charset != "" {
+				// The strings are converted to the collation derived for them.
+				argTp := *argTypes[i]
+				argTp.Charset, argTp.Collate = co.Charset, co.Collate
+				argTypes[i] = &argTp
+			} else if _, ok := arg.(*ast.ValueExpr); ok && argTypes[i].Charset != "" {
+				// A literal is coercible to the charset of the other arguments.
+				argTp := *argTypes[i]
+				argTp.Charset, argTp.Collate = "", ""
+				argTypes[i] = &argTp
+			}
+		}
+		var err error
+		tp, err = infer(argTypes)
+		if err != nil {
+			v.err = errors.Trace(err)
+		}
+		if isStringType(tp) {
+			chs = v.defaultCharset
+		}
+		v.setFuncCallType(x, tp, chs)
+		return
+	}
 	switch x.FnName.L {
-	case "abs", "ifnull", "nullif":
+	case "abs", "nullif":
 		tp = x.Args[0].GetType()
 		// TODO: We should cover all types.
 		if x.FnName.L == "abs" && tp.Tp == mysql.TypeDatetime {
 			tp = types.NewFieldType(mysql.TypeDouble)
 		}
-	case "greatest":
-		for _, arg := range x.Args {
-			InferType(v.sc, arg)
-		}
-		if len(x.Args) > 0 {
-			tp = x.Args[0].GetType()
-			for i := 1; i < len(x.Args); i++ {
-				mergeArithType(tp.Tp, x.Args[i].GetType().Tp)
-			}
-		}
+	case "coalesce", "greatest", "least", "ifnull":
+		tp = v.aggregateType(x.FnName.L, x.Args)
 	case "ceil", "ceiling":
 		t := x.Args[0].GetType().Tp
 		if t == mysql.TypeNull || t == mysql.TypeFloat || t == mysql.TypeDouble || t == mysql.TypeVarchar ||
@@ -285,18 +321,30 @@ func (v *typeInferrer) handleFuncCallExpr(x *ast.FuncCallExpr) {
 		tp = types.NewFieldType(mysql.TypeDouble)
 	case "pow", "power", "rand":
 		tp = types.NewFieldType(mysql.TypeDouble)
-	case "curdate", "current_date", "date":
+	case "curdate", "current_date", "date", "utc_date", "from_days", "last_day":
 		tp = types.NewFieldType(mysql.TypeDate)
-	case "curtime", "current_time", "timediff":
+	case "curtime", "current_time", "timediff", "utc_time":
 		tp = types.NewFieldType(mysql.TypeDuration)
 		tp.Decimal = v.getFsp(x)
-	case "current_timestamp", "date_arith":
+	case "sec_to_time":
+		tp = types.NewFieldType(mysql.TypeDuration)
+	case "current_timestamp", "date_arith", "adddate", "subdate", "timestamp", "convert_tz":
 		tp = types.NewFieldType(mysql.TypeDatetime)
+	case "addtime", "subtime":
+		switch x.Args[0].GetType().Tp {
+		case mysql.TypeDatetime, mysql.TypeTimestamp, mysql.TypeDate:
+			tp = types.NewFieldType(mysql.TypeDatetime)
+		case mysql.TypeDuration:
+			tp = types.NewFieldType(mysql.TypeDuration)
+		default:
+			tp = types.NewFieldType(mysql.TypeVarString)
+			chs = v.defaultCharset
+		}
 	case "microsecond", "second", "minute", "hour", "day", "week", "month", "year",
 		"dayofweek", "dayofmonth", "dayofyear", "weekday", "weekofyear", "yearweek",
-		"found_rows", "length", "extract", "locate":
+		"found_rows", "extract", "quarter", "period_add", "period_diff", "to_days":
 		tp = types.NewFieldType(mysql.TypeLonglong)
-	case "now", "sysdate":
+	case "now", "sysdate", "utc_timestamp":
 		tp = types.NewFieldType(mysql.TypeDatetime)
 		tp.Decimal = v.getFsp(x)
 	case "from_unixtime":
@@ -308,31 +356,103 @@ func (v *typeInferrer) handleFuncCallExpr(x *ast.FuncCallExpr) {
 		}
 	case "str_to_date":
 		tp = types.NewFieldType(mysql.TypeDatetime)
-	case "dayname", "version", "database", "user", "current_user", "schema",
-		"concat", "concat_ws", "left", "lcase", "lower", "repeat",
-		"replace", "ucase", "upper", "convert", "substring",
-		"substring_index", "trim", "ltrim", "rtrim", "reverse", "hex", "unhex", "date_format", "rpad":
+	case "repeat":
+		tp = v.repeatType(x)
+		chs = v.defaultCharset
+	case "convert":
+		// CONVERT(expr USING charset_name) is a string of charset_name.
+		tp = types.NewFieldType(mysql.TypeVarString)
+		chs = strings.ToLower(x.Args[1].GetDatum().GetString())
+		if _, err := charset.GetDefaultCollation(chs); err != nil {
+			// The unknown charset fails the call.
+			chs = v.defaultCharset
+		}
+	case "dayname", "version", "database", "user", "current_user", "schema", "charset", "collation",
+		"replace", "substring_index", "hex", "unhex", "date_format", "time_format", "get_format", "lpad", "rpad",
+		"soundex", "password", "old_password", "json_type", "json_array_append", "json_array_insert", "json_merge", "json_merge_preserve":
 		tp = types.NewFieldType(mysql.TypeVarString)
 		chs = v.defaultCharset
-	case "strcmp", "isnull":
+	case "weight_string", "encode", "decode", "random_bytes":
+		tp = types.NewFieldType(mysql.TypeVarString)
+	case "isnull", "interval", "json_valid", "coercibility", "json_contains", "json_contains_path":
 		tp = types.NewFieldType(mysql.TypeLonglong)
 	case "connection_id":
 		tp = types.NewFieldType(mysql.TypeLonglong)
 		tp.Flag |= mysql.UnsignedFlag
 	case "if":
-		// TODO: fix this
-		// See https://dev.mysql.com/doc/refman/5.5/en/control-flow-functions.html#function_if
-		// The default return type of IF() (which may matter when it is stored into a temporary table) is calculated as follows.
-		// Expression	Return Value
-		// expr2 or expr3 returns a string	string
-		// expr2 or expr3 returns a floating-point value	floating-point
-		// expr2 or expr3 returns an integer	integer
-		tp = x.Args[1].GetType()
+		tp = v.aggregateType(x.FnName.L, x.Args[1:])
 	case "get_lock", "release_lock":
 		tp = types.NewFieldType(mysql.TypeLonglong)
 	default:
 		tp = types.NewFieldType(mysql.TypeUnspecified)
 	}
+	v.setFuncCallType(x, tp, chs)
+}
+
+// Maximum lengths of the TEXT types a long string result widens to.
+const (
+	maxTextLength       = 65535
+	maxMediumTextLength = 16777215
+	maxLongTextLength   = 4294967295
+)
+
+// constantInt returns the value of expr as an int64 if it is a literal, or a negated literal, that
+// converts to one.
+func (v *typeInferrer) constantInt(expr ast.ExprNode) (int64, bool) {
+	neg := false
+	if u, ok := expr.(*ast.UnaryOperationExpr); ok && u.Op == opcode.Minus {
+		neg, expr = true, u.V
+	}
+	val, ok := expr.(*ast.ValueExpr)
+	if !ok {
+		return 0, false
+	}
+	n, err := val.GetDatum().ToInt64(v.sc)
+	if err != nil || (neg && n == math.MinInt64) {
+		return 0, false
+	}
+	if neg {
+		n = -n
+	}
+	return n, true
+}
+
+// repeatType types REPEAT(str, count) by the longest string it may produce: a VARCHAR while that fits,
+// MEDIUMTEXT or LONGTEXT beyond. Without a constant count that fits a BIGINT the length is bounded
+// only by max_allowed_packet, so the result is a LONGTEXT.
+func (v *typeInferrer) repeatType(x *ast.FuncCallExpr) *types.FieldType {
+	flen := maxLongTextLength
+	if n, ok := v.constantInt(x.Args[1]); ok {
+		argLen := x.Args[0].GetType().Flen
+		if str, ok := x.Args[0].(*ast.ValueExpr); ok && argLen == types.UnspecifiedLength {
+			if s, err := str.GetDatum().ToString(); err == nil {
+				argLen = len(s)
+			}
+		}
+		switch {
+		case n <= 0:
+			flen = 0
+		case argLen == types.UnspecifiedLength:
+			return types.NewFieldType(mysql.TypeVarString)
+		case int64(argLen) <= maxLongTextLength/n:
+			flen = argLen * int(n)
+		}
+	}
+	var tp *types.FieldType
+	switch {
+	case flen <= maxTextLength:
+		tp = types.NewFieldType(mysql.TypeVarString)
+	case flen <= maxMediumTextLength:
+		tp = types.NewFieldType(mysql.TypeMediumBlob)
+	default:
+		tp = types.NewFieldType(mysql.TypeLongBlob)
+	}
+	tp.Flen = flen
+	return tp
+}
+
+// setFuncCallType sets tp as the type of x, with charset chs unless tp has a charset.
+func (v *typeInferrer) setFuncCallType(x *ast.FuncCallExpr, tp *types.FieldType, chs string) {
 	// If charset is unspecified.
 	if len(tp.Charset) == 0 {
 		tp.Charset = chs
@@ -349,42 +469,170 @@ func (v *typeInferrer) handleFuncCallExpr(x *ast.FuncCallExpr) {
 	x.SetType(tp)
 }
 
-// The return type of a CASE expression is the compatible aggregated type of all return values,
-// but also depends on the context in which it is used.
-// If used in a string context, the result is returned as a string.
-// If used in a numeric context, the result is returned as a decimal, real, or integer value.
-func (v *typeInferrer) handleCaseExpr(x *ast.CaseExpr) {
-	var currType types.FieldType
-	for _, w := range x.WhenClauses {
-		t := w.Result.GetType()
-		if currType.Tp == mysql.TypeUnspecified {
-			currType = *t
+// handleSetCollation types expr COLLATE collation_name as expr in the collation, which must be one
+// of the charset of expr.
+func (v *typeInferrer) handleSetCollation(x *ast.FuncCallExpr) {
+	x.SetType(x.Args[0].GetType())
+	name := x.Args[1].GetDatum().GetString()
+	co, err := charset.GetCollationByName(name)
+	if err != nil {
+		v.err = evaluator.ErrUnknownCollation.GenByArgs(name)
+		return
+	}
+	if co.CharsetName != x.Type.Charset {
+		v.err = evaluator.ErrCollationCharsetMismatch.GenByArgs(co.Name, x.Type.Charset)
+		return
+	}
+	x.Type.Collate = co.Name
+}
+
+// cmpOpNames are the operators comparisons are reported under in collation errors.
+var cmpOpNames = map[opcode.Op]string{
+	opcode.LT:     "<",
+	opcode.LE:     "<=",
+	opcode.GE:     ">=",
+	opcode.GT:     ">",
+	opcode.EQ:     "=",
+	opcode.NE:     "<>",
+	opcode.NullEQ: "<=>",
+}
+
+// aggregateCollation derives the collation the operation op works on its string args in.
+func aggregateCollation(op string, args ...ast.ExprNode) (evaluator.CollationOperand, error) {
+	operands := make([]evaluator.CollationOperand, 0, len(args))
+	for _, arg := range args {
+		tp := arg.GetType()
+		if tp == nil || !isStringType(tp) || tp.Charset == "" {
 			continue
 		}
-		mtp := types.MergeFieldType(currType.Tp, t.Tp)
-		if mtp == t.Tp && mtp != currType.Tp {
-			currType.Charset = t.Charset
-			currType.Collate = t.Collate
+		operands = append(operands, evaluator.CollationOperand{
+			Charset:      tp.Charset,
+			Collate:      tp.Collate,
+			Coercibility: coercibility(arg),
+		})
+	}
+	co, err := evaluator.AggregateCollation(op, operands...)
+	return co, errors.Trace(err)
+}
+
+// coercibility returns the collation coercibility of expr, as expression.Coercibility does for the
+// expression the plan builds from it.
+func coercibility(expr ast.ExprNode) int {
+	switch x := expr.(type) {
+	case *ast.ColumnNameExpr, *ast.AggregateFuncExpr, *ast.SubqueryExpr:
+		return evaluator.CoercibilityImplicit
+	case *ast.ValueExpr:
+		return evaluator.ValueCoercibility(*x.GetDatum())
+	case *ast.ParenthesesExpr:
+		return coercibility(x.Expr)
+	case *ast.FuncCastExpr:
+		return coercibility(x.Expr)
+	case *ast.FuncCallExpr:
+		return funcCoercibility(x.FnName.L, x.Args)
+	case *ast.CaseExpr:
+		results := make([]ast.ExprNode, 0, len(x.WhenClauses)+1)
+		for _, w := range x.WhenClauses {
+			results = append(results, w.Result)
+		}
+		if x.ElseClause != nil {
+			results = append(results, x.ElseClause)
 		}
-		currType.Tp = mtp
+		return funcCoercibility(ast.Case, results)
+	}
+	return evaluator.CoercibilityCoercible
+}
+
+func funcCoercibility(fn string, args []ast.ExprNode) int {
+	argTypes := make([]*types.FieldType, len(args))
+	for i, arg := range args {
+		argTypes[i] = arg.GetType()
+	}
+	return evaluator.FuncCoercibility(fn, argTypes, func(i int) int {
+		return coercibility(args[i])
+	})
+}
+
+func isStringType(tp *types.FieldType) bool {
+	return types.IsTypeString(tp.Tp)
+}
 
+// handleCaseExpr types a CASE expression as the aggregate of the types of its results.
+// See https://dev.mysql.com/doc/refman/5.7/en/control-flow-functions.html#operator_case
+func (v *typeInferrer) handleCaseExpr(x *ast.CaseExpr) {
+	results := make([]ast.ExprNode, 0, len(x.WhenClauses)+1)
+	for _, w := range x.WhenClauses {
+		results = append(results, w.Result)
 	}
 	if x.ElseClause != nil {
-		t := x.ElseClause.GetType()
-		if currType.Tp == mysql.TypeUnspecified {
-			currType = *t
-		} else {
-			mtp := types.MergeFieldType(currType.Tp, t.Tp)
-			if mtp == t.Tp && mtp != currType.Tp {
-				currType.Charset = t.Charset
-				currType.Collate = t.Collate
+		results = append(results, x.ElseClause)
+	}
+	x.SetType(v.aggregateType(ast.Case, results))
+}
+
+// aggregateType returns the type of the result of the function fn, which returns one of args, as
+// MySQL aggregates it for LEAST, GREATEST, COALESCE, IFNULL, IF and CASE: the merge of the types
+// of args, wide enough for each of them, unsigned if they all are and, for a string, in the
+// collation derived for args.
+// See https://dev.mysql.com/doc/refman/5.7/en/control-flow-functions.html#function_if
+func (v *typeInferrer) aggregateType(fn string, args []ast.ExprNode) *types.FieldType {
+	tp := types.NewFieldType(mysql.TypeNull)
+	tp.Flen, tp.Decimal = 0, 0
+	unsigned := true
+	for i, arg := range args {
+		argTp := arg.GetType()
+		if i == 0 {
+			tp.Tp = argTp.Tp
+		} else if tp.Tp != argTp.Tp {
+			tp.Tp = mergeFieldType(tp.Tp, argTp.Tp)
+		}
+		if argTp.Tp != mysql.TypeNull && !mysql.HasUnsignedFlag(argTp.Flag) {
+			unsigned = false
+		}
+		if tp.Flen != types.UnspecifiedLength && (argTp.Flen == types.UnspecifiedLength || argTp.Flen > tp.Flen) {
+			tp.Flen = argTp.Flen
+		}
+		if tp.Decimal != types.UnspecifiedLength && (argTp.Decimal == types.UnspecifiedLength || argTp.Decimal > tp.Decimal) {
+			tp.Decimal = argTp.Decimal
+		}
+	}
+	switch {
+	case tp.Tp == mysql.TypeEnum || tp.Tp == mysql.TypeSet || tp.Tp == mysql.TypeVarchar:
+		tp.Tp = mysql.TypeVarString
+	case tp.Tp == mysql.TypeNull:
+		tp.Flen, tp.Decimal = types.UnspecifiedLength, types.UnspecifiedLength
+	}
+	if !isStringType(tp) {
+		switch tp.Tp {
+		case mysql.TypeTiny, mysql.TypeShort, mysql.TypeInt24, mysql.TypeLong, mysql.TypeLonglong,
+			mysql.TypeNewDecimal, mysql.TypeFloat, mysql.TypeDouble:
+			if unsigned {
+				tp.Flag |= mysql.UnsignedFlag
 			}
-			currType.Tp = mtp
 		}
+		tp.Charset, tp.Collate = charset.CharsetBin, charset.CollationBin
+		return tp
+	}
+	co, err := aggregateCollation(fn, args...)
+	if err != nil {
+		v.err = errors.Trace(err)
+	}
+	tp.Charset, tp.Collate = co.Charset, co.Collate
+	if tp.Charset == "" {
+		tp.Charset = v.defaultCharset
+		tp.Collate, err = charset.GetDefaultCollation(tp.Charset)
+		if err != nil {
+			v.err = errors.Trace(err)
+		}
+	}
+	return tp
+}
+
+// mergeFieldType merges the types a and b as types.MergeFieldType does, for JSON too.
+func mergeFieldType(a, b byte) byte {
+	if a == mysql.TypeJSON || b == mysql.TypeJSON {
+		return mysql.TypeVarString
 	}
-	x.SetType(&currType)
-	// TODO: We need a better way to set charset/collation
-	x.Type.Charset, x.Type.Collate = types.DefaultCharsetForType(x.Type.Tp)
+	return types.MergeFieldType(a, b)
 }
 
 // like expression expects the target expression and pattern to be a string, if it's not, we add a cast function.
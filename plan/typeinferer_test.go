@@ -18,14 +18,17 @@ import (
 	"github.com/pingcap/tidb"
 	"github.com/pingcap/tidb/ast"
 	"github.com/pingcap/tidb/context"
+	"github.com/pingcap/tidb/evaluator"
 	"github.com/pingcap/tidb/model"
 	"github.com/pingcap/tidb/mysql"
 	"github.com/pingcap/tidb/plan"
 	"github.com/pingcap/tidb/sessionctx"
 	"github.com/pingcap/tidb/table"
+	"github.com/pingcap/tidb/terror"
 	"github.com/pingcap/tidb/util/charset"
 	"github.com/pingcap/tidb/util/testkit"
 	"github.com/pingcap/tidb/util/testleak"
+	"github.com/pingcap/tidb/util/types"
 )
 
 var _ = Suite(&testTypeInferrerSuite{})
@@ -113,6 +116,9 @@ func (ts *testTypeInferrerSuite) TestInferType(c *C) {
 		{"dayofweek('2009-12-31 23:59:59.000010')", mysql.TypeLonglong, charset.CharsetBin},
 		{"dayofmonth('2009-12-31 23:59:59.000010')", mysql.TypeLonglong, charset.CharsetBin},
 		{"dayofyear('2009-12-31 23:59:59.000010')", mysql.TypeLonglong, charset.CharsetBin},
+		{"to_days('2009-12-31')", mysql.TypeLonglong, charset.CharsetBin},
+		{"from_days(733321)", mysql.TypeDate, charset.CharsetBin},
+		{"last_day('2009-02-05')", mysql.TypeDate, charset.CharsetBin},
 		{"weekday('2009-12-31 23:59:59.000010')", mysql.TypeLonglong, charset.CharsetBin},
 		{"weekofyear('2009-12-31 23:59:59.000010')", mysql.TypeLonglong, charset.CharsetBin},
 		{"yearweek('2009-12-31 23:59:59.000010')", mysql.TypeLonglong, charset.CharsetBin},
@@ -135,6 +141,8 @@ func (ts *testTypeInferrerSuite) TestInferType(c *C) {
 		{"lcase('TiDB')", mysql.TypeVarString, "utf8"},
 		{"repeat('TiDB', 3)", mysql.TypeVarString, "utf8"},
 		{"replace('TiDB', 'D', 'd')", mysql.TypeVarString, "utf8"},
+		{"convert('TiDB' using latin1)", mysql.TypeVarString, "latin1"},
+		{"convert('TiDB' using UTF8MB4)", mysql.TypeVarString, "utf8mb4"},
 		{"upper('TiDB')", mysql.TypeVarString, "utf8"},
 		{"ucase('TiDB')", mysql.TypeVarString, "utf8"},
 		{"trim(' TiDB ')", mysql.TypeVarString, "utf8"},
@@ -143,17 +151,34 @@ func (ts *testTypeInferrerSuite) TestInferType(c *C) {
 		{"connection_id()", mysql.TypeLonglong, charset.CharsetBin},
 		{"if(1>2, 2, 3)", mysql.TypeLonglong, charset.CharsetBin},
 		{"case c1 when null then 2 when 2 then 1.1 else 1 END", mysql.TypeNewDecimal, charset.CharsetBin},
-		{"case c1 when null then 2 when 2 then 'tidb' else 1.1 END", mysql.TypeVarchar, "utf8"},
+		{"case c1 when null then 2 when 2 then 'tidb' else 1.1 END", mysql.TypeVarString, "utf8"},
 		{"greatest(1, 2, 3)", mysql.TypeLonglong, charset.CharsetBin},
 		{"greatest('TiDB', 'D', 'd')", mysql.TypeVarString, "utf8"},
 		{"greatest(1.1, 2.2)", mysql.TypeNewDecimal, charset.CharsetBin},
 		{"greatest('TiDB', 3)", mysql.TypeVarString, "utf8"},
+		{"greatest(curdate(), curdate())", mysql.TypeDate, charset.CharsetBin},
+		{"greatest(curdate(), now())", mysql.TypeDatetime, charset.CharsetBin},
+		{"least(1, 2, 3)", mysql.TypeLonglong, charset.CharsetBin},
+		{"least(now(), curdate())", mysql.TypeDatetime, charset.CharsetBin},
 		{"hex('TiDB')", mysql.TypeVarString, "utf8"},
 		{"hex(12)", mysql.TypeVarString, "utf8"},
 		{"unhex('TiDB')", mysql.TypeVarString, "utf8"},
 		{"unhex(12)", mysql.TypeVarString, "utf8"},
 		{"DATE_FORMAT('2009-10-04 22:23:00', '%W %M %Y')", mysql.TypeVarString, "utf8"},
 		{"rpad('TiDB', 12, 'go')", mysql.TypeVarString, charset.CharsetUTF8},
+		{"lpad('TiDB', 12, 'go')", mysql.TypeVarString, charset.CharsetUTF8},
+		{"time_format('10:00', '%H')", mysql.TypeVarString, charset.CharsetUTF8},
+		{"is_uuid('6ccd780c-baba-1026-9564-5b8c656024db')", mysql.TypeLonglong, charset.CharsetBin},
+		{"uuid_to_bin('6ccd780c-baba-1026-9564-5b8c656024db')", mysql.TypeVarString, charset.CharsetBin},
+		{"bin_to_uuid(uuid_to_bin('6ccd780c-baba-1026-9564-5b8c656024db'))", mysql.TypeVarString, charset.CharsetUTF8},
+		{"bit_count(29)", mysql.TypeLonglong, charset.CharsetBin},
+		{"export_set(5, 'Y', 'N')", mysql.TypeVarString, charset.CharsetUTF8},
+		{"locate('D', 'TiDB')", mysql.TypeLonglong, charset.CharsetBin},
+		{"ascii('TiDB')", mysql.TypeLonglong, charset.CharsetBin},
+		{"strcmp('TiDB', 'tidb')", mysql.TypeLonglong, charset.CharsetBin},
+		{"reverse('TiDB')", mysql.TypeVarString, "utf8"},
+		{"substring('TiDB', 2)", mysql.TypeVarString, "utf8"},
+		{"substr('TiDB', 2)", mysql.TypeVarString, "utf8"},
 	}
 	for _, ca := range cases {
 		ctx := testKit.Se.(context.Context)
@@ -172,6 +197,156 @@ func (ts *testTypeInferrerSuite) TestInferType(c *C) {
 	}
 }
 
+func (ts *testTypeInferrerSuite) TestInferFuncLength(c *C) {
+	defer testleak.AfterTest(c)()
+	store, err := tidb.NewStore(tidb.EngineGoLevelDBMemory)
+	c.Assert(err, IsNil)
+	defer store.Close()
+	testKit := testkit.NewTestKit(c, store)
+	testKit.MustExec("use test")
+	testKit.MustExec("create table t (a varchar(10), b varchar(20), c int)")
+	cases := []struct {
+		expr string
+		tp   byte
+		flen int
+	}{
+		{"length(a)", mysql.TypeLonglong, types.UnspecifiedLength},
+		{"locate('x', b)", mysql.TypeLonglong, types.UnspecifiedLength},
+		{"concat(a, b)", mysql.TypeVarString, 30},
+		{"concat(a, b, a)", mysql.TypeVarString, 40},
+		{"concat(a, 'x')", mysql.TypeVarString, types.UnspecifiedLength},
+		{"concat_ws(a, b, b, b)", mysql.TypeVarString, 80},
+		{"left(b, 3)", mysql.TypeVarString, 20},
+		{"upper(a)", mysql.TypeVarString, 10},
+		{"concat(a, c)", mysql.TypeVarString, types.UnspecifiedLength},
+		{"left(c, 3)", mysql.TypeVarString, types.UnspecifiedLength},
+		{"repeat(a, 3)", mysql.TypeVarString, 30},
+		{"repeat(a, 0)", mysql.TypeVarString, 0},
+		{"repeat(b, 5000)", mysql.TypeMediumBlob, 100000},
+		{"repeat(b, 1000000)", mysql.TypeLongBlob, 20000000},
+		{"repeat(a, c)", mysql.TypeLongBlob, 4294967295},
+		{"repeat(a, -1)", mysql.TypeVarString, 0},
+		{"repeat(a, 18446744073709551615)", mysql.TypeLongBlob, 4294967295},
+		{"repeat('abc', 100000)", mysql.TypeMediumBlob, 300000},
+		{"repeat('abc', 3)", mysql.TypeVarString, 9},
+	}
+	for _, ca := range cases {
+		ctx := testKit.Se.(context.Context)
+		stmts, err := tidb.Parse(ctx, "select "+ca.expr+" from t")
+		c.Assert(err, IsNil)
+		stmt := stmts[0].(*ast.SelectStmt)
+		is := sessionctx.GetDomain(ctx).InfoSchema()
+		err = plan.ResolveName(stmt, is, ctx)
+		c.Assert(err, IsNil)
+		err = plan.InferType(ctx.GetSessionVars().StmtCtx, stmt)
+		c.Assert(err, IsNil, Commentf("%s", ca.expr))
+		col := stmt.GetResultFields()[0].Column
+		c.Assert(col.Tp, Equals, ca.tp, Commentf("Tp for %s", ca.expr))
+		c.Assert(col.Flen, Equals, ca.flen, Commentf("Flen for %s", ca.expr))
+	}
+}
+
+func (ts *testTypeInferrerSuite) TestInferAggregateType(c *C) {
+	defer testleak.AfterTest(c)()
+	store, err := tidb.NewStore(tidb.EngineGoLevelDBMemory)
+	c.Assert(err, IsNil)
+	defer store.Close()
+	testKit := testkit.NewTestKit(c, store)
+	testKit.MustExec("use test")
+	testKit.MustExec("create table t (i int, u int unsigned, b bigint unsigned, d decimal(10, 2), f double, " +
+		"s varchar(10) charset latin1, dt date, e enum('a', 'b'))")
+	cases := []struct {
+		expr     string
+		tp       byte
+		unsigned bool
+		charset  string
+	}{
+		{"coalesce(i, 0)", mysql.TypeLonglong, false, charset.CharsetBin},
+		{"coalesce(null, i)", mysql.TypeLong, false, charset.CharsetBin},
+		{"coalesce(u, b)", mysql.TypeLonglong, true, charset.CharsetBin},
+		{"coalesce(u, null, b)", mysql.TypeLonglong, true, charset.CharsetBin},
+		{"coalesce(u, i)", mysql.TypeLong, false, charset.CharsetBin},
+		{"coalesce(i, d)", mysql.TypeNewDecimal, false, charset.CharsetBin},
+		{"coalesce(d, f)", mysql.TypeDouble, false, charset.CharsetBin},
+		{"coalesce(i, s)", mysql.TypeVarString, false, "latin1"},
+		{"coalesce(s, 'x')", mysql.TypeVarString, false, "latin1"},
+		{"coalesce(e, e)", mysql.TypeVarString, false, charset.CharsetUTF8},
+		{"coalesce(cast('1' as json), cast('[]' as json))", mysql.TypeJSON, false, charset.CharsetBin},
+		{"coalesce(null, null)", mysql.TypeNull, false, charset.CharsetBin},
+		{"ifnull(i, 1)", mysql.TypeLonglong, false, charset.CharsetBin},
+		{"ifnull(i, 1.5)", mysql.TypeNewDecimal, false, charset.CharsetBin},
+		{"if(i, u, b)", mysql.TypeLonglong, true, charset.CharsetBin},
+		{"if(i, 1, 'x')", mysql.TypeVarString, false, charset.CharsetUTF8},
+		{"if(i, f, 1)", mysql.TypeDouble, false, charset.CharsetBin},
+		{"greatest(i, u)", mysql.TypeLong, false, charset.CharsetBin},
+		{"least(dt, now())", mysql.TypeDatetime, false, charset.CharsetBin},
+		{"least(dt, dt)", mysql.TypeDate, false, charset.CharsetBin},
+		{"greatest(i, s)", mysql.TypeVarString, false, "latin1"},
+		{"case when i then u else b end", mysql.TypeLonglong, true, charset.CharsetBin},
+		{"case when i then i when u then d end", mysql.TypeNewDecimal, false, charset.CharsetBin},
+		{"case when i then s else 1 end", mysql.TypeVarString, false, "latin1"},
+	}
+	for _, ca := range cases {
+		ctx := testKit.Se.(context.Context)
+		stmts, err := tidb.Parse(ctx, "select "+ca.expr+" from t")
+		c.Assert(err, IsNil)
+		stmt := stmts[0].(*ast.SelectStmt)
+		is := sessionctx.GetDomain(ctx).InfoSchema()
+		err = plan.ResolveName(stmt, is, ctx)
+		c.Assert(err, IsNil)
+		err = plan.InferType(ctx.GetSessionVars().StmtCtx, stmt)
+		c.Assert(err, IsNil)
+		col := stmt.GetResultFields()[0].Column
+		c.Assert(col.Tp, Equals, ca.tp, Commentf("Tp for %s", ca.expr))
+		c.Assert(mysql.HasUnsignedFlag(col.Flag), Equals, ca.unsigned, Commentf("Unsigned for %s", ca.expr))
+		c.Assert(col.Charset, Equals, ca.charset, Commentf("Charset for %s", ca.expr))
+	}
+}
+
+func (ts *testTypeInferrerSuite) TestInferConcatCharset(c *C) {
+	defer testleak.AfterTest(c)()
+	store, err := tidb.NewStore(tidb.EngineGoLevelDBMemory)
+	c.Assert(err, IsNil)
+	defer store.Close()
+	testKit := testkit.NewTestKit(c, store)
+	testKit.MustExec("use test")
+	testKit.MustExec("create table t (a varchar(10) charset latin1, b varchar(10) charset utf8 collate utf8_general_ci, " +
+		"c varchar(10) charset utf8 collate utf8_unicode_ci)")
+	cases := []struct {
+		expr    string
+		charset string
+		collate string
+		err     bool
+	}{
+		{"concat(a, b)", "utf8", "utf8_general_ci", false},
+		{"concat(a, 'x')", "latin1", "latin1_swedish_ci", false},
+		{"concat(a, 1)", "latin1", "latin1_swedish_ci", false},
+		{"concat(b, c)", "", "", true},
+		{"concat(b collate utf8_unicode_ci, c)", "utf8", "utf8_unicode_ci", false},
+		{"concat(b, c collate utf8_bin)", "utf8", "utf8_bin", false},
+		{"concat_ws(',', a, b collate utf8_bin)", "utf8", "utf8_bin", false},
+		{"concat(b collate utf8_bin, c collate utf8_unicode_ci)", "", "", true},
+	}
+	for _, ca := range cases {
+		ctx := testKit.Se.(context.Context)
+		stmts, err := tidb.Parse(ctx, "select "+ca.expr+" from t")
+		c.Assert(err, IsNil)
+		stmt := stmts[0].(*ast.SelectStmt)
+		is := sessionctx.GetDomain(ctx).InfoSchema()
+		err = plan.ResolveName(stmt, is, ctx)
+		c.Assert(err, IsNil)
+		err = plan.InferType(ctx.GetSessionVars().StmtCtx, stmt)
+		if ca.err {
+			c.Assert(terror.ErrorEqual(err, evaluator.ErrIllegalMixOfCollations), IsTrue, Commentf("%s", ca.expr))
+			continue
+		}
+		c.Assert(err, IsNil)
+		col := stmt.GetResultFields()[0].Column
+		c.Assert(col.Charset, Equals, ca.charset, Commentf("Charset for %s", ca.expr))
+		c.Assert(col.Collate, Equals, ca.collate, Commentf("Collate for %s", ca.expr))
+	}
+}
+
 func (s *testTypeInferrerSuite) TestColumnInfoModified(c *C) {
 	defer testleak.AfterTest(c)()
 	store, err := tidb.NewStore(tidb.EngineGoLevelDBMemory)
@@ -83,11 +83,25 @@ func (v *validator) Leave(in ast.Node) (out ast.Node, ok bool) {
 		if x.Count > math.MaxUint64-x.Offset {
 			x.Count = math.MaxUint64 - x.Offset
 		}
+	case *ast.FuncCallExpr:
+		if x.FnName.L == ast.NameConst {
+			v.checkNameConst(x)
+		}
 	}
 
 	return in, v.err == nil
 }
 
+// checkNameConst checks that the name of NAME_CONST(name, value) is a constant string.
+func (v *validator) checkNameConst(x *ast.FuncCallExpr) {
+	if len(x.Args) != 2 {
+		return
+	}
+	if name, ok := x.Args[0].(*ast.ValueExpr); !ok || name.GetDatum().Kind() != types.KindString {
+		v.err = ErrWrongArguments.GenByArgs("NAME_CONST")
+	}
+}
+
 func checkAutoIncrementOp(colDef *ast.ColumnDef, num int) (bool, error) {
 	var hasAutoIncrement bool
 
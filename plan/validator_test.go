@@ -71,6 +71,10 @@ func (s *testValidatorSuite) TestValidator(c *C) {
 			errors.New("[schema:1068]Multiple primary key defined")},
 		{"create table t(c1 int not null, c2 int not null, primary key(c1), primary key(c2))", true,
 			errors.New("[schema:1068]Multiple primary key defined")},
+		{"select name_const('a', 1)", true, nil},
+		{"select name_const(a, 1) from t", true, plan.ErrWrongArguments},
+		{"select name_const(1, 1)", true, plan.ErrWrongArguments},
+		{"select name_const(concat('a', 'b'), 1)", true, plan.ErrWrongArguments},
 	}
 
 	store, err := tidb.NewStore(tidb.EngineGoLevelDBMemory)
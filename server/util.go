@@ -324,9 +324,9 @@ func dumpTextValue(mysqlType uint8, value types.Datum) ([]byte, error) {
 	case types.KindUint64:
 		return strconv.AppendUint(nil, value.GetUint64(), 10), nil
 	case types.KindFloat32:
-		return strconv.AppendFloat(nil, value.GetFloat64(), 'f', -1, 32), nil
+		return hack.Slice(types.StrFromFloat(value.GetFloat64(), 32)), nil
 	case types.KindFloat64:
-		return strconv.AppendFloat(nil, value.GetFloat64(), 'f', -1, 64), nil
+		return hack.Slice(types.StrFromFloat(value.GetFloat64(), 64)), nil
 	case types.KindString, types.KindBytes:
 		return value.GetBytes(), nil
 	case types.KindMysqlTime:
@@ -90,19 +90,19 @@ func (s *testSessionSuite) TestPrepare(c *C) {
 	rs := mustExecSQL(c, se, "execute stmt using @v1")
 	r, err := rs.Next()
 	c.Assert(err, IsNil)
-	c.Assert(r.Data[0].GetFloat64(), Equals, float64(101))
+	c.Assert(r.Data[0].GetInt64(), Equals, int64(101))
 
 	mustExecSQL(c, se, "set @v2=200")
 	rs = mustExecSQL(c, se, "execute stmt using @v2")
 	r, err = rs.Next()
 	c.Assert(err, IsNil)
-	c.Assert(r.Data[0].GetFloat64(), Equals, float64(201))
+	c.Assert(r.Data[0].GetInt64(), Equals, int64(201))
 
 	mustExecSQL(c, se, "set @v3=300")
 	rs = mustExecSQL(c, se, "execute stmt using @v3")
 	r, err = rs.Next()
 	c.Assert(err, IsNil)
-	c.Assert(r.Data[0].GetFloat64(), Equals, float64(301))
+	c.Assert(r.Data[0].GetInt64(), Equals, int64(301))
 	mustExecSQL(c, se, "deallocate prepare stmt")
 
 	err = store.Close()
@@ -1735,6 +1735,9 @@ func (s *testSessionSuite) TestFieldText(c *C) {
 		{"select (1+1)", "(1+1)"},
 		{"select a from t", "a"},
 		{"select        ((a+1))     from t", "((a+1))"},
+		{"select name_const('myname', 14)", "myname"},
+		{"select (name_const('myname', a)) from t", "myname"},
+		{"select name_const('myname', 14) as b", "b"},
 	}
 	for _, v := range cases {
 		results, err := se.Execute(v.sql)
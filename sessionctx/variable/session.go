@@ -14,8 +14,11 @@
 package variable
 
 import (
+	"regexp"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/juju/errors"
 	"github.com/pingcap/tidb/mysql"
@@ -23,14 +26,16 @@ import (
 )
 
 const (
-	codeCantGetValidID terror.ErrCode = 1
-	codeCantSetToNull  terror.ErrCode = 2
+	codeCantGetValidID  terror.ErrCode = 1
+	codeCantSetToNull   terror.ErrCode = 2
+	codeUnknownTimeZone terror.ErrCode = 1298
 )
 
 // Error instances.
 var (
-	errCantGetValidID = terror.ClassVariable.New(codeCantGetValidID, "cannot get valid auto-increment id in retry")
-	ErrCantSetToNull  = terror.ClassVariable.New(codeCantSetToNull, "cannot set variable to null")
+	errCantGetValidID  = terror.ClassVariable.New(codeCantGetValidID, "cannot get valid auto-increment id in retry")
+	ErrCantSetToNull   = terror.ClassVariable.New(codeCantSetToNull, "cannot set variable to null")
+	ErrUnknownTimeZone = terror.ClassVariable.New(codeUnknownTimeZone, mysql.MySQLErrName[mysql.ErrUnknownTimeZone])
 )
 
 // RetryInfo saves retry information.
@@ -81,8 +86,9 @@ type TransactionContext struct {
 
 // SessionVars is to handle user-defined or global variables in current session.
 type SessionVars struct {
-	// user-defined variables
-	Users map[string]string
+	// user-defined variables, holding the value of their datum so that they keep its type.
+	// See varsutil.GetUserVar and varsutil.SetUserVar.
+	Users map[string]interface{}
 	// system variables
 	Systems map[string]string
 	// prepared statement
@@ -115,6 +121,17 @@ type SessionVars struct {
 	// Strict SQL mode
 	StrictSQLMode bool
 
+	// ErrorForDivisionByZero is set by the ERROR_FOR_DIVISION_BY_ZERO SQL mode, which reports a
+	// division by zero.
+	ErrorForDivisionByZero bool
+
+	// NoZeroDate is set by the NO_ZERO_DATE SQL mode, which rejects the date '0000-00-00'.
+	NoZeroDate bool
+
+	// NoZeroInDate is set by the NO_ZERO_IN_DATE SQL mode, which rejects dates with a zero month
+	// or day, like '2020-00-15'.
+	NoZeroInDate bool
+
 	// CommonGlobalLoaded indicates if common global variable has been loaded for this session.
 	CommonGlobalLoaded bool
 
@@ -140,12 +157,32 @@ type SessionVars struct {
 	// CurrInsertValues is used to record current ValuesExpr's values.
 	// See http://dev.mysql.com/doc/refman/5.7/en/miscellaneous-functions.html#function_values
 	CurrInsertValues interface{}
+
+	// Clock is the source of the current time for time functions, the system clock if nil.
+	Clock Clock
+
+	// Rand is the source of random numbers for RAND(), the shared source of math/rand if nil.
+	Rand RandSource
+
+	// timeZone is the time zone time_zone was last set to, see SetTimeZone.
+	timeZone *time.Location
+}
+
+// Clock tells the current time.
+type Clock interface {
+	Now() time.Time
+}
+
+// RandSource generates pseudo-random numbers, *rand.Rand implements it.
+type RandSource interface {
+	Seed(seed int64)
+	Float64() float64
 }
 
 // NewSessionVars creates a session vars object.
 func NewSessionVars() *SessionVars {
 	return &SessionVars{
-		Users:                make(map[string]string),
+		Users:                make(map[string]interface{}),
 		Systems:              make(map[string]string),
 		PreparedStmts:        make(map[uint32]interface{}),
 		PreparedStmtNameToID: make(map[string]uint32),
@@ -209,6 +246,50 @@ func (s *SessionVars) IsAutocommit() bool {
 	return s.GetStatusFlag(mysql.ServerStatusAutocommit)
 }
 
+// TimeZone returns the time zone named by the time_zone session variable, in which temporal
+// values are interpreted and displayed. It is the local time zone, SYSTEM, until time_zone is set.
+func (s *SessionVars) TimeZone() *time.Location {
+	if s.timeZone == nil {
+		return time.Local
+	}
+	return s.timeZone
+}
+
+// SetTimeZone parses the zone name time_zone is set to, and keeps it for TimeZone.
+func (s *SessionVars) SetTimeZone(name string) error {
+	loc, err := ParseTimeZone(name)
+	if err != nil {
+		return ErrUnknownTimeZone.GenByArgs(name)
+	}
+	s.timeZone = loc
+	return nil
+}
+
+var timeZoneOffsetRegexp = regexp.MustCompile(`^([+-])(\d{1,2}):(\d{2})$`)
+
+// ParseTimeZone parses a time zone, which is either a named zone from the time zone database,
+// SYSTEM, or an offset from UTC in the form '+HH:MM' between '-12:59' and '+13:00'.
+func ParseTimeZone(name string) (*time.Location, error) {
+	if strings.EqualFold(name, "SYSTEM") {
+		return time.Local, nil
+	}
+	m := timeZoneOffsetRegexp.FindStringSubmatch(name)
+	if m == nil {
+		loc, err := time.LoadLocation(name)
+		return loc, errors.Trace(err)
+	}
+	hour, _ := strconv.Atoi(m[2])
+	minute, _ := strconv.Atoi(m[3])
+	offset := hour*60 + minute
+	if m[1] == "-" {
+		offset = -offset
+	}
+	if minute > 59 || offset < -(12*60+59) || offset > 13*60 {
+		return nil, errors.Errorf("unknown or incorrect time zone: %s", name)
+	}
+	return time.FixedZone(name, offset*60), nil
+}
+
 // GetNextPreparedStmtID generates and returns the next session scope prepared statement id.
 func (s *SessionVars) GetNextPreparedStmtID() uint32 {
 	s.preparedStmtID++
@@ -220,6 +301,7 @@ const (
 	SQLModeVar          = "sql_mode"
 	AutocommitVar       = "autocommit"
 	CharacterSetResults = "character_set_results"
+	TimeZoneVar         = "time_zone"
 )
 
 // GetTiDBSystemVar gets variable value for name.
@@ -243,6 +325,7 @@ func (s *SessionVars) GetTiDBSystemVar(name string) (string, error) {
 // It should be reset before executing a statement.
 type StatementContext struct {
 	/* Variables that are set before execution */
+	InInsertStmt      bool
 	InUpdateStmt      bool
 	IgnoreTruncate    bool
 	TruncateAsWarning bool
@@ -308,3 +391,16 @@ func (sc *StatementContext) AppendWarning(warn error) {
 	sc.mu.warnings = append(sc.mu.warnings, warn)
 	sc.mu.Unlock()
 }
+
+// HandleTruncate ignores the truncation error err, or turns it into a warning, as the statement
+// asks for; otherwise it returns err.
+func (sc *StatementContext) HandleTruncate(err error) error {
+	if sc.IgnoreTruncate {
+		return nil
+	}
+	if !sc.TruncateAsWarning {
+		return err
+	}
+	sc.AppendWarning(err)
+	return nil
+}
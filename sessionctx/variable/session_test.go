@@ -14,7 +14,11 @@
 package variable_test
 
 import (
+	"time"
+
 	. "github.com/pingcap/check"
+	"github.com/pingcap/tidb/sessionctx/variable"
+	"github.com/pingcap/tidb/terror"
 	"github.com/pingcap/tidb/util/mock"
 )
 
@@ -45,3 +49,35 @@ func (*testSessionSuite) TestSession(c *C) {
 	ctx.GetSessionVars().SetLastInsertID(1)
 	c.Assert(ctx.GetSessionVars().LastInsertID, Equals, uint64(1))
 }
+
+func (*testSessionSuite) TestTimeZone(c *C) {
+	vars := variable.NewSessionVars()
+	c.Assert(vars.TimeZone(), Equals, time.Local)
+
+	tbl := []struct {
+		name   string
+		offset int
+	}{
+		{"+00:00", 0},
+		{"+08:00", 8 * 3600},
+		{"-05:30", -(5*3600 + 30*60)},
+		{"+13:00", 13 * 3600},
+		{"UTC", 0},
+	}
+	for _, t := range tbl {
+		c.Assert(vars.SetTimeZone(t.name), IsNil, Commentf("%s", t.name))
+		_, offset := time.Date(2017, 1, 1, 0, 0, 0, 0, vars.TimeZone()).Zone()
+		c.Assert(offset, Equals, t.offset, Commentf("%s", t.name))
+	}
+
+	// An invalid zone is rejected and leaves the time zone as it was.
+	for _, name := range []string{"+13:01", "-13:00", "+08:60", "8:00", "No/Such_Zone"} {
+		_, err := variable.ParseTimeZone(name)
+		c.Assert(err, NotNil, Commentf("%s", name))
+		err = vars.SetTimeZone(name)
+		c.Assert(terror.ErrorEqual(err, variable.ErrUnknownTimeZone), IsTrue, Commentf("%s", name))
+		c.Assert(vars.TimeZone().String(), Equals, "UTC")
+	}
+	c.Assert(vars.SetTimeZone("system"), IsNil)
+	c.Assert(vars.TimeZone(), Equals, time.Local)
+}
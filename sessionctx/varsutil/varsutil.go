@@ -39,6 +39,30 @@ func GetSystemVar(s *variable.SessionVars, key string) types.Datum {
 	return d
 }
 
+// GetUserVar gets a user variable, NULL if it isn't set.
+func GetUserVar(s *variable.SessionVars, name string) types.Datum {
+	return types.NewDatum(s.Users[strings.ToLower(name)])
+}
+
+// SetUserVar sets a user variable to a copy of value, unsetting it if value is NULL.
+func SetUserVar(s *variable.SessionVars, name string, value types.Datum) {
+	name = strings.ToLower(name)
+	switch value.Kind() {
+	case types.KindNull:
+		delete(s.Users, name)
+	case types.KindString:
+		// The string may share the memory of a row that will be reused.
+		s.Users[name] = string(value.GetBytes())
+	case types.KindBytes:
+		s.Users[name] = append([]byte(nil), value.GetBytes()...)
+	case types.KindMysqlDecimal:
+		dec := *value.GetMysqlDecimal()
+		s.Users[name] = &dec
+	default:
+		s.Users[name] = value.GetValue()
+	}
+}
+
 // epochShiftBits is used to reserve logical part of the timestamp.
 const epochShiftBits = 18
 
@@ -64,6 +88,9 @@ func SetSystemVar(vars *variable.SessionVars, name string, value types.Datum) er
 		} else {
 			vars.StrictSQLMode = false
 		}
+		vars.ErrorForDivisionByZero = strings.Contains(sVal, "ERROR_FOR_DIVISION_BY_ZERO")
+		vars.NoZeroDate = strings.Contains(sVal, "NO_ZERO_DATE")
+		vars.NoZeroInDate = strings.Contains(sVal, "NO_ZERO_IN_DATE")
 	case variable.TiDBSnapshot:
 		err = setSnapshotTS(vars, sVal)
 		if err != nil {
@@ -77,6 +104,10 @@ func SetSystemVar(vars *variable.SessionVars, name string, value types.Datum) er
 		}
 	case variable.TiDBSkipConstraintCheck:
 		vars.SkipConstraintCheck = (sVal == "1")
+	case variable.TimeZoneVar:
+		if err = vars.SetTimeZone(sVal); err != nil {
+			return errors.Trace(err)
+		}
 	}
 	vars.Systems[name] = sVal
 	return nil
@@ -44,8 +44,20 @@ func (s *testVarsutilSuite) TestVarsutil(c *C) {
 	val = GetSystemVar(v, "sql_mode")
 	c.Assert(val.GetString(), Equals, "STRICT_TRANS_TABLES")
 	c.Assert(v.StrictSQLMode, IsTrue)
+	c.Assert(v.ErrorForDivisionByZero, IsFalse)
+	SetSystemVar(v, "sql_mode", types.NewStringDatum("error_for_division_by_zero,strict_all_tables"))
+	c.Assert(v.StrictSQLMode, IsTrue)
+	c.Assert(v.ErrorForDivisionByZero, IsTrue)
+	c.Assert(v.NoZeroDate, IsFalse)
+	SetSystemVar(v, "sql_mode", types.NewStringDatum("no_zero_date,no_zero_in_date"))
+	c.Assert(v.StrictSQLMode, IsFalse)
+	c.Assert(v.NoZeroDate, IsTrue)
+	c.Assert(v.NoZeroInDate, IsTrue)
 	SetSystemVar(v, "sql_mode", types.NewStringDatum(""))
 	c.Assert(v.StrictSQLMode, IsFalse)
+	c.Assert(v.ErrorForDivisionByZero, IsFalse)
+	c.Assert(v.NoZeroDate, IsFalse)
+	c.Assert(v.NoZeroInDate, IsFalse)
 
 	SetSystemVar(v, "character_set_connection", types.NewStringDatum("utf8"))
 	SetSystemVar(v, "collation_connection", types.NewStringDatum("utf8_general_ci"))
@@ -73,3 +85,25 @@ func (s *testVarsutilSuite) TestVarsutil(c *C) {
 	d = GetSystemVar(v, variable.TiDBSkipConstraintCheck)
 	c.Assert(d.GetString(), Equals, "1")
 }
+
+func (s *testVarsutilSuite) TestUserVar(c *C) {
+	defer testleak.AfterTest(c)()
+	v := variable.NewSessionVars()
+
+	val := GetUserVar(v, "a")
+	c.Assert(val.IsNull(), IsTrue)
+	SetUserVar(v, "A", types.NewIntDatum(3))
+	val = GetUserVar(v, "a")
+	c.Assert(val.Kind(), Equals, types.KindInt64)
+	c.Assert(val.GetInt64(), Equals, int64(3))
+
+	SetUserVar(v, "a", types.NewStringDatum("Xy"))
+	val = GetUserVar(v, "A")
+	c.Assert(val.Kind(), Equals, types.KindString)
+	c.Assert(val.GetString(), Equals, "Xy")
+
+	SetUserVar(v, "a", types.Datum{})
+	val = GetUserVar(v, "a")
+	c.Assert(val.IsNull(), IsTrue)
+	c.Assert(v.Users, HasLen, 0)
+}
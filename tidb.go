@@ -128,7 +128,10 @@ func resetStmtCtx(ctx context.Context, s ast.StmtNode) {
 	case *ast.UpdateStmt, *ast.InsertStmt, *ast.DeleteStmt:
 		sc.IgnoreTruncate = false
 		sc.TruncateAsWarning = !sessVars.StrictSQLMode
-		if _, ok := s.(*ast.UpdateStmt); ok {
+		switch s.(type) {
+		case *ast.InsertStmt:
+			sc.InInsertStmt = true
+		case *ast.UpdateStmt:
 			sc.InUpdateStmt = true
 		}
 	default:
@@ -66,3 +66,20 @@ func DecodePassword(pwd string) ([]byte, error) {
 	}
 	return x, nil
 }
+
+// OldPasswordHash is the hash used by pre-4.1 MySQL passwords, it also seeds
+// the cipher of ENCODE() and DECODE(). Spaces and tabs in pwd are skipped.
+// See hash_password in https://github.com/mysql/mysql-server/blob/5.7/sql/password.c
+func OldPasswordHash(pwd []byte) (uint32, uint32) {
+	nr, add, nr2 := uint32(1345345333), uint32(7), uint32(0x12345671)
+	for _, c := range pwd {
+		if c == ' ' || c == '\t' {
+			continue
+		}
+		tmp := uint32(c)
+		nr ^= (((nr & 63) + add) * tmp) + (nr << 8)
+		nr2 += (nr2 << 8) ^ nr
+		add += tmp
+	}
+	return nr & (1<<31 - 1), nr2 & (1<<31 - 1)
+}
@@ -43,3 +43,14 @@ func (s *testAuthSuite) TestCalcPassword(c *C) {
 	checkAuth := []byte{126, 168, 249, 64, 180, 223, 60, 240, 69, 249, 184, 57, 21, 34, 214, 219, 8, 193, 208, 55}
 	c.Assert(CalcPassword(salt, pwd), DeepEquals, checkAuth)
 }
+
+func (s *testAuthSuite) TestOldPasswordHash(c *C) {
+	defer testleak.AfterTest(c)()
+	nr, nr2 := OldPasswordHash([]byte("mypass"))
+	c.Assert(nr, Equals, uint32(0x6f8c114b))
+	c.Assert(nr2, Equals, uint32(0x58f2ce9e))
+	// Spaces and tabs are ignored.
+	nr, nr2 = OldPasswordHash([]byte(" my\tpass "))
+	c.Assert(nr, Equals, uint32(0x6f8c114b))
+	c.Assert(nr2, Equals, uint32(0x58f2ce9e))
+}
@@ -143,6 +143,27 @@ func GetCollations() []*Collation {
 	return collations
 }
 
+// GetCollationByID returns the collation for id.
+func GetCollationByID(id int) (*Collation, error) {
+	for _, c := range collations {
+		if c.ID == id {
+			return c, nil
+		}
+	}
+	return nil, errors.Errorf("Unknown collation id %d", id)
+}
+
+// GetCollationByName returns the collation named name, which is case-insensitive.
+func GetCollationByName(name string) (*Collation, error) {
+	name = strings.ToLower(name)
+	for _, c := range collations {
+		if c.Name == name {
+			return c, nil
+		}
+	}
+	return nil, errors.Errorf("Unknown collation %s", name)
+}
+
 const (
 	// CharsetBin is used for marking binary charset.
 	CharsetBin = "binary"
@@ -156,6 +177,10 @@ const (
 	CharsetUTF8MB4 = "utf8mb4"
 	// CollationUTF8MB4 is the default collation for CharsetUTF8MB4.
 	CollationUTF8MB4 = "utf8mb4_general_ci"
+	// CharsetLatin1 is the cp1252 West European charset.
+	CharsetLatin1 = "latin1"
+	// CharsetASCII is the US ASCII charset.
+	CharsetASCII = "ascii"
 )
 
 var collations = []*Collation{
@@ -87,3 +87,33 @@ func (s *testCharsetSuite) TestGetDefaultCollation(c *C) {
 		testGetDefaultCollation(c, t.cs, t.co, t.succ)
 	}
 }
+
+func (s *testCharsetSuite) TestGetCollationByID(c *C) {
+	defer testleak.AfterTest(c)()
+	co, err := GetCollationByID(8)
+	c.Assert(err, IsNil)
+	c.Assert(co.Name, Equals, "latin1_swedish_ci")
+	c.Assert(co.CharsetName, Equals, "latin1")
+
+	co, err = GetCollationByID(63)
+	c.Assert(err, IsNil)
+	c.Assert(co.Name, Equals, CollationBin)
+
+	_, err = GetCollationByID(0)
+	c.Assert(err, NotNil)
+}
+
+func (s *testCharsetSuite) TestGetCollationByName(c *C) {
+	defer testleak.AfterTest(c)()
+	co, err := GetCollationByName("UTF8_Unicode_CI")
+	c.Assert(err, IsNil)
+	c.Assert(co.Name, Equals, "utf8_unicode_ci")
+	c.Assert(co.CharsetName, Equals, CharsetUTF8)
+
+	co, err = GetCollationByName(CollationBin)
+	c.Assert(err, IsNil)
+	c.Assert(co.CharsetName, Equals, CharsetBin)
+
+	_, err = GetCollationByName("utf8_nonexistent_ci")
+	c.Assert(err, NotNil)
+}
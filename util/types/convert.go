@@ -162,10 +162,12 @@ func StrToUint(sc *variable.StatementContext, str string) (uint64, error) {
 // getValidIntPrefix gets prefix of the string which can be successfully parsed as int.
 func getValidIntPrefix(sc *variable.StatementContext, str string) (string, error) {
 	floatPrefix, err := getValidFloatPrefix(sc, str)
-	if err != nil {
-		return floatPrefix, errors.Trace(err)
+	// A truncated prefix still needs converting, the truncation error being kept.
+	intStr, err1 := floatStrToIntStr(floatPrefix)
+	if err1 != nil {
+		return intStr, errors.Trace(err1)
 	}
-	return floatStrToIntStr(floatPrefix)
+	return intStr, errors.Trace(err)
 }
 
 // floatStrToIntStr converts a valid float string into valid integer string which can be parsed by
@@ -256,7 +258,7 @@ func getValidFloatPrefix(sc *variable.StatementContext, s string) (valid string,
 				break
 			}
 		} else if c == '.' {
-			if sawDot { // "1.1."
+			if sawDot || eIdx > 0 { // "1.1.", "1e1."
 				break
 			}
 			sawDot = true
@@ -288,6 +290,23 @@ func getValidFloatPrefix(sc *variable.StatementContext, s string) (valid string,
 	return valid, err
 }
 
+// Floats of a magnitude in [floatStrMinPlain, floatStrMaxPlain) are written without an exponent.
+const (
+	floatStrMinPlain = 1e-15
+	floatStrMaxPlain = 1e15
+)
+
+// StrFromFloat returns the string form of the bitSize-bit float f, with the fewest digits that
+// read back as f. Like MySQL, only a value of at least 1e15 or below 1e-15 in magnitude is written
+// with an exponent, as in "1e15" or "-2.5e-16".
+func StrFromFloat(f float64, bitSize int) string {
+	abs := math.Abs(f)
+	if abs == 0 || (abs >= floatStrMinPlain && abs < floatStrMaxPlain) {
+		return strconv.FormatFloat(f, 'f', -1, bitSize)
+	}
+	return strings.Replace(strconv.FormatFloat(f, 'e', -1, bitSize), "e+", "e", 1)
+}
+
 // ToString converts an interface to a string.
 func ToString(value interface{}) (string, error) {
 	switch v := value.(type) {
@@ -303,9 +322,9 @@ func ToString(value interface{}) (string, error) {
 	case uint64:
 		return strconv.FormatUint(uint64(v), 10), nil
 	case float32:
-		return strconv.FormatFloat(float64(v), 'f', -1, 32), nil
+		return StrFromFloat(float64(v), 32), nil
 	case float64:
-		return strconv.FormatFloat(float64(v), 'f', -1, 64), nil
+		return StrFromFloat(v, 64), nil
 	case string:
 		return v, nil
 	case []byte:
@@ -383,6 +383,39 @@ func testStrToFloat(c *C, str string, expect float64, truncateAsErr bool, expect
 	}
 }
 
+func (s *testTypeConvertSuite) TestStrFromFloat(c *C) {
+	defer testleak.AfterTest(c)()
+	tbl := []struct {
+		f       float64
+		bitSize int
+		s       string
+	}{
+		{0, 64, "0"},
+		{3.14, 64, "3.14"},
+		{-0.5, 64, "-0.5"},
+		{123456789012345, 64, "123456789012345"},
+		{999999999999999.9, 64, "999999999999999.9"},
+		{1e15, 64, "1e15"},
+		{-1.5e15, 64, "-1.5e15"},
+		{math.MaxInt64, 64, "9.223372036854776e18"},
+		{math.MaxFloat64, 64, "1.7976931348623157e308"},
+		{0.000000000000001, 64, "0.000000000000001"},
+		{1e-16, 64, "1e-16"},
+		{-2.5e-20, 64, "-2.5e-20"},
+		{math.SmallestNonzeroFloat64, 64, "5e-324"},
+		{float64(float32(0.1)), 32, "0.1"},
+		{float64(float32(1e20)), 32, "1e20"},
+		{math.MaxFloat32, 32, "3.4028235e38"},
+	}
+	for _, t := range tbl {
+		c.Assert(StrFromFloat(t.f, t.bitSize), Equals, t.s, Commentf("%v", t.f))
+		// The string reads back as the same float.
+		f, err := strconv.ParseFloat(t.s, t.bitSize)
+		c.Assert(err, IsNil)
+		c.Assert(f, Equals, t.f, Commentf("%v", t.f))
+	}
+}
+
 func (s *testTypeConvertSuite) TestStrToNum(c *C) {
 	defer testleak.AfterTest(c)()
 	testStrToInt(c, "0", 0, true, nil)
@@ -628,6 +661,7 @@ func (s *testTypeConvertSuite) TestGetValidFloat(c *C) {
 		{"123..34", "123."},
 		{"123.23E-10", "123.23E-10"},
 		{"1.1e1.3", "1.1e1"},
+		{"0e1.", "0e1"},
 		{"1.1e-13a", "1.1e-13"},
 		{"1.", "1."},
 		{".1", ".1"},
@@ -646,4 +680,11 @@ func (s *testTypeConvertSuite) TestGetValidFloat(c *C) {
 	c.Assert(terror.ErrorEqual(err, ErrOverflow), IsTrue)
 	_, err = floatStrToIntStr("1e21")
 	c.Assert(terror.ErrorEqual(err, ErrOverflow), IsTrue)
+	for _, str := range []string{"0e1.", "1.5x", "2e1y"} {
+		_, err = StrToInt(sc, str)
+		c.Assert(terror.ErrorEqual(err, ErrTruncated), IsTrue, Commentf("%s", str))
+	}
+	v, err := StrToInt(&variable.StatementContext{IgnoreTruncate: true}, "2e1y")
+	c.Assert(err, IsNil)
+	c.Assert(v, Equals, int64(20))
 }
@@ -738,9 +738,9 @@ func (d *Datum) convertToString(sc *variable.StatementContext, target *FieldType
 	case KindUint64:
 		s = strconv.FormatUint(d.GetUint64(), 10)
 	case KindFloat32:
-		s = strconv.FormatFloat(d.GetFloat64(), 'f', -1, 32)
+		s = StrFromFloat(d.GetFloat64(), 32)
 	case KindFloat64:
-		s = strconv.FormatFloat(d.GetFloat64(), 'f', -1, 64)
+		s = StrFromFloat(d.GetFloat64(), 64)
 	case KindString, KindBytes:
 		s = d.GetString()
 	case KindMysqlTime:
@@ -763,9 +763,10 @@ func (d *Datum) convertToString(sc *variable.StatementContext, target *FieldType
 
 	var err error
 	if target.Flen >= 0 {
-		// Flen is the rune length, not binary length, for UTF8 charset, we need to calculate the
-		// rune count and truncate to Flen runes if it is too long.
-		if target.Charset == charset.CharsetUTF8 || target.Charset == charset.CharsetUTF8MB4 {
+		// Flen is the rune length, not binary length, for a charset other than binary, as
+		// strings are held in UTF8. We need to calculate the rune count and truncate to Flen
+		// runes if it is too long.
+		if target.Charset != "" && target.Charset != charset.CharsetBin {
 			var runeCount int
 			var truncateLen int
 			for i := range s {
@@ -875,7 +876,7 @@ func (d *Datum) convertToMysqlTime(sc *variable.StatementContext, target *FieldT
 			ret.SetValue(t)
 			return ret, errors.Trace(err)
 		}
-		t, err = t.roundFrac(fsp)
+		t, err = t.RoundFrac(fsp)
 		ret.SetValue(t)
 		if err != nil {
 			return ret, errors.Trace(err)
@@ -886,7 +887,7 @@ func (d *Datum) convertToMysqlTime(sc *variable.StatementContext, target *FieldT
 			ret.SetValue(t)
 			return ret, errors.Trace(err)
 		}
-		t, err = t.roundFrac(fsp)
+		t, err = t.RoundFrac(fsp)
 		ret.SetValue(t)
 		if err != nil {
 			return ret, errors.Trace(err)
@@ -1297,9 +1298,9 @@ func (d *Datum) ToString() (string, error) {
 	case KindUint64:
 		return strconv.FormatUint(d.GetUint64(), 10), nil
 	case KindFloat32:
-		return strconv.FormatFloat(float64(d.GetFloat32()), 'f', -1, 32), nil
+		return StrFromFloat(float64(d.GetFloat32()), 32), nil
 	case KindFloat64:
-		return strconv.FormatFloat(float64(d.GetFloat64()), 'f', -1, 64), nil
+		return StrFromFloat(d.GetFloat64(), 64), nil
 	case KindString:
 		return d.GetString(), nil
 	case KindBytes:
@@ -1557,12 +1558,5 @@ func (ds *datumsSorter) Swap(i, j int) {
 }
 
 func handleTruncateError(sc *variable.StatementContext) error {
-	if sc.IgnoreTruncate {
-		return nil
-	}
-	if !sc.TruncateAsWarning {
-		return ErrTruncated
-	}
-	sc.AppendWarning(ErrTruncated)
-	return nil
+	return sc.HandleTruncate(ErrTruncated)
 }
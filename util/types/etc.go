@@ -49,6 +49,12 @@ func IsTypeChar(tp byte) bool {
 	}
 }
 
+// IsTypeString returns a boolean indicating whether the tp is a string type,
+// a char, a varchar or a blob type.
+func IsTypeString(tp byte) bool {
+	return tp == mysql.TypeVarString || IsTypeChar(tp) || IsTypeBlob(tp)
+}
+
 var type2Str = map[byte]string{
 	mysql.TypeBit:        "bit",
 	mysql.TypeBlob:       "text",
@@ -61,6 +67,7 @@ var type2Str = map[byte]string{
 	mysql.TypeFloat:      "float",
 	mysql.TypeGeometry:   "geometry",
 	mysql.TypeInt24:      "mediumint",
+	mysql.TypeJSON:       "json",
 	mysql.TypeLong:       "int",
 	mysql.TypeLonglong:   "bigint",
 	mysql.TypeLongBlob:   "longtext",
@@ -118,5 +125,5 @@ func InvOp2(x, y interface{}, o opcode.Op) (interface{}, error) {
 
 // Overflow returns an overflowed error.
 func overflow(v interface{}, tp byte) error {
-	return errors.Errorf("constant %v overflows %s", v, TypeStr(tp))
+	return ErrOverflow.Gen("constant %v overflows %s", v, TypeStr(tp))
 }
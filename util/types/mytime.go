@@ -182,14 +182,83 @@ func calcDaynr(year, month, day int) int {
 	return delsum + year/4 - temp
 }
 
-// calcDaysInYear calculates days in one year, it works with 0 <= year <= 99.
+// maxDaynr is the day number of 9999-12-31, the last date.
+const maxDaynr = 3652424
+
+// daysInMonth are the days of the months of a year which isn't a leap year.
+var daysInMonth = []int{31, 28, 31, 30, 31, 30, 31, 31, 30, 31, 30, 31}
+
+// getDateFromDaynr calculates the date of the day number daynr, it is the inverse of calcDaynr.
+// The days of year 0 and those after 9999-12-31 give the zero date.
+func getDateFromDaynr(daynr int) (year, month, day int) {
+	if daynr <= 365 || daynr > maxDaynr {
+		return 0, 0, 0
+	}
+
+	year = daynr * 100 / 36525
+	temp := (((year-1)/100 + 1) * 3) / 4
+	dayOfYear := daynr - year*365 - (year-1)/4 + temp
+	daysInYear := calcDaysInYear(year)
+	for dayOfYear > daysInYear {
+		dayOfYear -= daysInYear
+		year++
+		daysInYear = calcDaysInYear(year)
+	}
+
+	leapDay := 0
+	if daysInYear == 366 && dayOfYear > 31+28 {
+		dayOfYear--
+		if dayOfYear == 31+28 {
+			leapDay = 1
+		}
+	}
+	month = 1
+	for _, days := range daysInMonth {
+		if dayOfYear <= days {
+			break
+		}
+		dayOfYear -= days
+		month++
+	}
+	return year, month, dayOfYear + leapDay
+}
+
+// DateToDays returns the number of days from year 0 to the date of t, it is what TO_DAYS returns.
+// The calendar is the proleptic Gregorian one, like MySQL's, even before the Gregorian cutover.
+func DateToDays(t TimeInternal) int {
+	return calcDaynr(t.Year(), t.Month(), t.Day())
+}
+
+// DateFromDays returns the date of the day number daynr, it is what FROM_DAYS returns and the
+// inverse of DateToDays. The days of year 0 and those after 9999-12-31 give the zero date.
+func DateFromDays(daynr int) TimeInternal {
+	year, month, day := getDateFromDaynr(daynr)
+	return newMysqlTime(year, month, day, 0, 0, 0, 0)
+}
+
+// isLeapYear reports whether year has a February 29: a year divisible by 4 does, except the
+// centuries not divisible by 400, like 1900 and 2100. Year 0, like in MySQL, doesn't.
+func isLeapYear(year int) bool {
+	return (year&3) == 0 && (year%100 != 0 || (year%400 == 0 && (year != 0)))
+}
+
+// calcDaysInYear calculates days in one year.
 func calcDaysInYear(year int) int {
-	if (year&3) == 0 && (year%100 != 0 || (year%400 == 0 && (year != 0))) {
+	if isLeapYear(year) {
 		return 366
 	}
 	return 365
 }
 
+// GetLastDay returns the last day of the month, 1 to 12, of the year, which is what LAST_DAY
+// returns for a date of that month.
+func GetLastDay(year, month int) int {
+	if month == 2 && isLeapYear(year) {
+		return 29
+	}
+	return daysInMonth[month-1]
+}
+
 // calcWeekday calculates weekday from daynr, returns 0 for Monday, 1 for Tuesday ...
 func calcWeekday(daynr int, sundayFirstDayOfWeek bool) int {
 	daynr += 5
@@ -104,3 +104,54 @@ func (s *testMyTimeSuite) TestCompareTime(c *C) {
 		c.Assert(compareTime(&t.T2, &t.T1), Equals, -t.Expect)
 	}
 }
+
+func (s *testMyTimeSuite) TestDateFromDays(c *C) {
+	cases := []struct {
+		Daynr  int
+		Expect mysqlTime
+	}{
+		{0, mysqlTime{0, 0, 0, 0, 0, 0, 0}},
+		{365, mysqlTime{0, 0, 0, 0, 0, 0, 0}},
+		{366, mysqlTime{1, 1, 1, 0, 0, 0, 0}},
+		{3654, mysqlTime{10, 1, 2, 0, 0, 0, 0}},
+		{577814, mysqlTime{1582, 1, 1, 0, 0, 0, 0}},
+		{719528, mysqlTime{1970, 1, 1, 0, 0, 0, 0}},
+		{730544, mysqlTime{2000, 2, 29, 0, 0, 0, 0}},
+		{733457, mysqlTime{2008, 2, 20, 0, 0, 0, 0}},
+		{3652424, mysqlTime{9999, 12, 31, 0, 0, 0, 0}},
+		{3652425, mysqlTime{0, 0, 0, 0, 0, 0, 0}},
+	}
+	for _, t := range cases {
+		c.Assert(DateFromDays(t.Daynr), Equals, t.Expect, Commentf("%d", t.Daynr))
+	}
+
+	// DateFromDays and DateToDays are inverses from 0001-01-01 on.
+	for daynr := 366; daynr <= maxDaynr; daynr += 97 {
+		t := DateFromDays(daynr)
+		c.Assert(DateToDays(t), Equals, daynr, Commentf("%d %v", daynr, t))
+	}
+	for year := 1; year <= 2400; year++ {
+		first := DateToDays(newMysqlTime(year, 1, 1, 0, 0, 0, 0))
+		last := DateToDays(newMysqlTime(year, 12, 31, 0, 0, 0, 0))
+		c.Assert(last-first+1, Equals, calcDaysInYear(year), Commentf("%d", year))
+		c.Assert(DateFromDays(last+1), Equals, newMysqlTime(year+1, 1, 1, 0, 0, 0, 0))
+	}
+}
+
+func (s *testMyTimeSuite) TestLeapYear(c *C) {
+	for _, year := range []int{4, 400, 1600, 2000, 2004, 2400} {
+		c.Assert(isLeapYear(year), IsTrue, Commentf("%d", year))
+		c.Assert(calcDaysInYear(year), Equals, 366)
+		c.Assert(GetLastDay(year, 2), Equals, 29)
+		c.Assert(checkMonthDay(year, 2, 29), IsNil)
+	}
+	for _, year := range []int{0, 1, 100, 1700, 1900, 2001, 2100} {
+		c.Assert(isLeapYear(year), IsFalse, Commentf("%d", year))
+		c.Assert(calcDaysInYear(year), Equals, 365)
+		c.Assert(GetLastDay(year, 2), Equals, 28)
+		c.Assert(checkMonthDay(year, 2, 29), NotNil)
+	}
+	c.Assert(GetLastDay(2001, 1), Equals, 31)
+	c.Assert(GetLastDay(2001, 4), Equals, 30)
+	c.Assert(GetLastDay(2001, 12), Equals, 31)
+}
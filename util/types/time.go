@@ -304,7 +304,11 @@ func roundTime(t gotime.Time, fsp int) gotime.Time {
 	return t.Round(d)
 }
 
-func (t Time) roundFrac(fsp int) (Time, error) {
+// RoundFrac rounds the fractional seconds of t to fsp digits and returns the result with
+// precision fsp. It is how a time of a higher precision is assigned or cast to a lower one,
+// and, like MySQL, it rounds rather than truncates, using the "round half up" rule:
+// 2011-11-11 10:10:10.5 round 0 -> 2011-11-11 10:10:11, and a carry goes on into the date.
+func (t Time) RoundFrac(fsp int) (Time, error) {
 	if t.Type == mysql.TypeDate {
 		// date type has no fsp
 		return t, nil
@@ -352,7 +356,7 @@ func RoundFrac(t gotime.Time, fsp int) (gotime.Time, error) {
 	if err != nil {
 		return t, errors.Trace(err)
 	}
-	return t.Round(gotime.Duration(math.Pow10(9-fsp)) * gotime.Nanosecond), nil
+	return roundTime(t, fsp), nil
 }
 
 // ToPackedUint encodes Time to a packed uint64 value.
@@ -575,17 +579,21 @@ func parseDatetime(str string, fsp int) (Time, error) {
 	if err != nil {
 		return ZeroDatetime, errors.Trace(err)
 	}
-	if overflow {
-		microsecond = 0
-		second++
-	}
 
 	nt := Time{
 		Time: FromDate(year, month, day, hour, minute, second, microsecond),
 		Type: mysql.TypeDatetime,
 		Fsp:  fsp}
+	if overflow {
+		// The fraction rounds up to a second, which carries on like RoundFrac does: the time is
+		// taken one microsecond before the next second and rounded.
+		nt.Time = FromDate(year, month, day, hour, minute, second, 999999)
+		nt.Fsp = MaxFsp
+		nt, err = nt.RoundFrac(MinFsp)
+		nt.Fsp = fsp
+	}
 
-	return nt, nil
+	return nt, errors.Trace(err)
 }
 
 func scanTimeArgs(seps []string, args ...*int) error {
@@ -723,8 +731,8 @@ func (d Duration) ConvertToTime(tp uint8) (Time, error) {
 }
 
 // RoundFrac rounds fractional seconds precision with new fsp and returns a new one.
-// We will use the “round half up” rule, e.g, >= 0.5 -> 1, < 0.5 -> 0,
-// so 10:10:10.999999 round 0 -> 10:10:11
+// We will use the “round half up” rule on the absolute value, e.g, >= 0.5 -> 1, < 0.5 -> 0,
+// so 10:10:10.999999 round 0 -> 10:10:11, -00:00:00.5 round 0 -> -00:00:01
 // and 10:10:10.000000 round 0 -> 10:10:10
 func (d Duration) RoundFrac(fsp int) (Duration, error) {
 	fsp, err := checkFsp(fsp)
@@ -732,12 +740,21 @@ func (d Duration) RoundFrac(fsp int) (Duration, error) {
 		return d, errors.Trace(err)
 	}
 
-	if fsp == d.Fsp {
-		return d, nil
+	// A duration may hold more digits than its Fsp, like one decoded from a row, so it is
+	// rounded even when fsp is the same. Halfway values are rounded away from zero.
+	unit := gotime.Duration(math.Pow10(9-fsp)) * gotime.Nanosecond
+	nd := d.Duration
+	r := nd % unit
+	if r < 0 {
+		r = -r
+	}
+	if r+r < unit {
+		nd -= nd % unit
+	} else if nd < 0 {
+		nd -= unit - r
+	} else {
+		nd += unit - r
 	}
-
-	n := gotime.Date(0, 0, 0, 0, 0, 0, 0, gotime.Local)
-	nd := n.Add(d.Duration).Round(gotime.Duration(math.Pow10(9-fsp)) * gotime.Nanosecond).Sub(n)
 	return Duration{Duration: nd, Fsp: fsp}, nil
 }
 
@@ -923,8 +940,6 @@ func splitDuration(t gotime.Duration) (int, int, int, int, int) {
 	return sign, int(hours), int(minutes), int(seconds), int(fraction)
 }
 
-var maxDaysInMonth = []int{31, 29, 31, 30, 31, 30, 31, 31, 30, 31, 30, 31}
-
 func getTime(num int64, tp byte) (Time, error) {
 	s1 := num / 1000000
 	s2 := num - s1*1000000
@@ -1145,10 +1160,7 @@ func checkMonthDay(year, month, day int) error {
 
 	maxDay := 31
 	if month > 0 {
-		maxDay = maxDaysInMonth[month-1]
-	}
-	if month == 2 && year%4 != 0 {
-		maxDay = 28
+		maxDay = GetLastDay(year, month)
 	}
 
 	if day < 0 || day > maxDay {
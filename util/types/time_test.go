@@ -588,6 +588,13 @@ func (s *testTimeSuite) TestRoundFrac(c *C) {
 		{"2012-12-31 11:30:45.999999", 4, "2012-12-31 11:30:46.0000"},
 		{"2012-12-31 11:30:45.999999", 0, "2012-12-31 11:30:46"},
 		{"2012-00-00 11:30:45.999999", 3, "2012-00-00 11:30:46.000"},
+		// Half a unit of the new precision rounds up, less than half rounds down.
+		{"2012-12-31 11:30:45.123500", 3, "2012-12-31 11:30:45.124"},
+		{"2012-12-31 11:30:45.123499", 3, "2012-12-31 11:30:45.123"},
+		{"2012-12-31 11:30:45.500000", 0, "2012-12-31 11:30:46"},
+		{"2012-12-31 11:30:45.499999", 0, "2012-12-31 11:30:45"},
+		{"2012-12-31 23:59:59.999500", 3, "2013-01-01 00:00:00.000"},
+		{"2012-12-31 23:59:59.500000", 0, "2013-01-01 00:00:00"},
 		// TODO: MySQL can handle this case, but we can't.
 		// {"2012-01-00 23:59:59.999999", 3, "2012-01-01 00:00:00.000"},
 	}
@@ -595,7 +602,7 @@ func (s *testTimeSuite) TestRoundFrac(c *C) {
 	for _, t := range tbl {
 		v, err := ParseTime(t.Input, mysql.TypeDatetime, MaxFsp)
 		c.Assert(err, IsNil)
-		nv, err := v.roundFrac(t.Fsp)
+		nv, err := v.RoundFrac(t.Fsp)
 		c.Assert(err, IsNil)
 		c.Assert(nv.String(), Equals, t.Except)
 	}
@@ -611,6 +618,13 @@ func (s *testTimeSuite) TestRoundFrac(c *C) {
 		{"1 11:30:45.123456", 1, "35:30:45.1"},
 		{"1 11:30:45.999999", 4, "35:30:46.0000"},
 		{"-1 11:30:45.999999", 0, "-35:30:46"},
+		{"11:30:45.123500", 3, "11:30:45.124"},
+		{"11:30:45.123499", 3, "11:30:45.123"},
+		{"11:30:45.500000", 0, "11:30:46"},
+		{"11:30:45.499999", 0, "11:30:45"},
+		{"-11:30:45.123500", 3, "-11:30:45.124"},
+		{"-00:00:00.500000", 0, "-00:00:01"},
+		{"-00:00:00.499999", 0, "00:00:00"},
 	}
 
 	for _, t := range tbl {
@@ -620,6 +634,26 @@ func (s *testTimeSuite) TestRoundFrac(c *C) {
 		c.Assert(err, IsNil)
 		c.Assert(nv.String(), Equals, t.Except)
 	}
+
+	// Parsing to a lower precision rounds the same way.
+	tbl = []struct {
+		Input  string
+		Fsp    int
+		Except string
+	}{
+		{"2012-12-31 11:30:45.1235", 3, "2012-12-31 11:30:45.124"},
+		{"2012-12-31 11:30:45.5", 0, "2012-12-31 11:30:46"},
+		{"2012-12-31 23:59:59.9995", 3, "2013-01-01 00:00:00.000"},
+		{"2012-12-31 23:59:59.5", 0, "2013-01-01 00:00:00"},
+		{"2012-12-31 23:59:59.9999995", 6, "2013-01-01 00:00:00.000000"},
+	}
+	for _, t := range tbl {
+		v, err := ParseTime(t.Input, mysql.TypeDatetime, t.Fsp)
+		c.Assert(err, IsNil)
+		c.Assert(v.String(), Equals, t.Except)
+	}
+	_, err := ParseTime("9999-12-31 23:59:59.5", mysql.TypeDatetime, 0)
+	c.Assert(err, NotNil)
 }
 
 func (s *testTimeSuite) TestConvert(c *C) {